@@ -14,6 +14,7 @@ import (
 	auditQueuePkg "metadata-platform/internal/module/audit/queue"
 	document "metadata-platform/internal/module/document"
 	metadata "metadata-platform/internal/module/metadata"
+	metadataService "metadata-platform/internal/module/metadata/service"
 	sso "metadata-platform/internal/module/sso"
 	user "metadata-platform/internal/module/user"
 	"metadata-platform/internal/utils"
@@ -35,6 +36,21 @@ func main() {
 	utils.InitLogger(cfg.LogLevel, cfg.LogFilePath)
 	defer utils.SyncLogger()
 
+	// 2.1 初始化字段加密密钥
+	if err := utils.InitFieldKeyRing(cfg.FieldEncryptKeys, cfg.FieldEncryptKeyVersion, cfg.FieldBlindIndexKey); err != nil {
+		utils.SugarLogger.Warnf("Field key ring is not configured (%v), writes to encrypted fields will fail", err)
+	}
+
+	// 2.2 初始化导出文件存储及下载链接签名
+	utils.InitFileStore(utils.NewLocalFileStore(cfg.ExportDir))
+	utils.InitURLSigner(cfg.ExportSignKey)
+	if cfg.ExportSignKey == "" {
+		utils.SugarLogger.Warn("EXPORT_SIGN_KEY is not configured, export jobs are disabled")
+	}
+
+	// 2.3 条件批量操作的最大行数上限
+	metadataService.InitBulkMaxRows(cfg.BulkMaxRows)
+
 	// 3. 初始化数据库管理器
 	fmt.Fprintln(os.Stderr, "DEBUG: Logger initialized. Creating DB manager...")
 	dbManager, err := utils.NewDBManager(cfg)
@@ -38,6 +38,18 @@ type Config struct {
 	JWTSecret      string `mapstructure:"JWT_SECRET"`
 	JWTExpireHours int    `mapstructure:"JWT_EXPIRE_HOURS"`
 
+	// 字段加密配置
+	FieldEncryptKeys       string `mapstructure:"FIELD_ENCRYPT_KEYS"`        // 格式: 版本:密钥,版本:密钥
+	FieldEncryptKeyVersion int    `mapstructure:"FIELD_ENCRYPT_KEY_VERSION"` // 当前加密使用的密钥版本
+	FieldBlindIndexKey     string `mapstructure:"FIELD_BLIND_INDEX_KEY"`     // 盲索引密钥
+
+	// 导出文件配置
+	ExportDir     string `mapstructure:"EXPORT_DIR"`      // 导出文件的本地存储目录
+	ExportSignKey string `mapstructure:"EXPORT_SIGN_KEY"` // 下载链接签名密钥
+
+	// 条件批量操作配置
+	BulkMaxRows int64 `mapstructure:"BULK_MAX_ROWS"` // 客户端可指定的最大影响行数上限
+
 	// 日志配置
 	LogLevel    string `mapstructure:"LOG_LEVEL"`
 	LogFilePath string `mapstructure:"LOG_FILE_PATH"`
@@ -153,6 +165,15 @@ func setDefaults() {
 	viper.SetDefault("JWT_SECRET", "your-secret-key")
 	viper.SetDefault("JWT_EXPIRE_HOURS", 24)
 
+	// 字段加密配置
+	viper.SetDefault("FIELD_ENCRYPT_KEY_VERSION", 1)
+
+	// 导出文件配置
+	viper.SetDefault("EXPORT_DIR", "data/exports")
+
+	// 条件批量操作配置
+	viper.SetDefault("BULK_MAX_ROWS", 10000)
+
 	// 日志配置
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("LOG_FILE_PATH", "logs/app.log")
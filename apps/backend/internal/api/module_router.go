@@ -23,7 +23,7 @@ import (
 // RegisterModuleRoutes 注册所有模块的路由
 func RegisterModuleRoutes(h *server.Hertz, metadataDB, userDB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
 	fmt.Fprintln(os.Stderr, ">>> Starting route registration...")
-	registerMetadataRoutes(h, metadataDB, auditDB, auditQueue)
+	registerMetadataRoutes(h, metadataDB, userDB, auditDB, auditQueue)
 	fmt.Fprintln(os.Stderr, ">>> Metadata routes registered")
 	registerUserRoutes(h, userDB, auditDB, auditQueue)
 	fmt.Fprintln(os.Stderr, ">>> User routes registered")
@@ -56,8 +56,8 @@ func RegisterModuleRoutes(h *server.Hertz, metadataDB, userDB, auditDB *gorm.DB,
 	})
 }
 
-func registerMetadataRoutes(h *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
-	metadata.RegisterRoutes(h, db, auditDB, auditQueue)
+func registerMetadataRoutes(h *server.Hertz, db *gorm.DB, userDB *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
+	metadata.RegisterRoutes(h, db, userDB, auditDB, auditQueue)
 }
 
 func registerUserRoutes(h *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
@@ -11,6 +11,7 @@ type SysDataChangeLog struct {
 	Action     string    `json:"action" gorm:"type:varchar(32);comment:操作类型"`   // CREATE, UPDATE, DELETE
 	BeforeData string    `json:"before_data" gorm:"type:text;comment:变更前数据"` // JSON string
 	AfterData  string    `json:"after_data" gorm:"type:text;comment:变更后数据"`  // JSON string
+	DiffData   string    `json:"diff_data" gorm:"type:text;comment:字段级差异"`   // JSON string: [{field, before, after}]
 	CreateBy   string    `json:"create_by" gorm:"type:varchar(64);comment:创建者"`
 	Source     string    `json:"source" gorm:"type:varchar(64);comment:来源模块"` // 来源模块
 	Remark       string    `json:"remark" gorm:"size:1024;default:'';comment:备注"`
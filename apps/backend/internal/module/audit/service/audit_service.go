@@ -22,6 +22,9 @@ type AuditService interface {
 	GetAllOperationLogs(filters map[string]interface{}) ([]model.SysOperationLog, error)
 	GetAllDataChangeLogs(filters map[string]interface{}) ([]model.SysDataChangeLog, error)
 
+	GetDataChangeLogByID(id string) (*model.SysDataChangeLog, error)
+	GetRecordChanges(modelID, recordID string) ([]model.SysDataChangeLog, error)
+
 	GetRecentLoginLogs(limit int) ([]model.SysLoginLog, error)
 	GetRecentOperationLogs(limit int) ([]model.SysOperationLog, error)
 }
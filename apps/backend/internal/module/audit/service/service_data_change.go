@@ -34,3 +34,20 @@ func (s *auditService) GetAllDataChangeLogs(filters map[string]interface{}) ([]m
 	err := db.Order("create_at DESC").Find(&logs).Error
 	return logs, err
 }
+
+// GetDataChangeLogByID 根据ID获取数据变更日志
+func (s *auditService) GetDataChangeLogByID(id string) (*model.SysDataChangeLog, error) {
+	var log model.SysDataChangeLog
+	if err := s.db.Where("id = ?", id).First(&log).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetRecordChanges 获取单条数据的全部变更日志，按时间正序
+func (s *auditService) GetRecordChanges(modelID, recordID string) ([]model.SysDataChangeLog, error) {
+	var logs []model.SysDataChangeLog
+	err := s.db.Where("model_id = ? AND record_id = ?", modelID, recordID).
+		Order("create_at ASC, id ASC").Find(&logs).Error
+	return logs, err
+}
@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -397,15 +398,22 @@ func (e *DamengExtractor) GetQueryColumns(query string, params []interface{}) ([
 
 // GetProcedures 获取存储过程列表
 func (e *DamengExtractor) GetProcedures(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return getOracleRoutines(e.db, schema, "PROCEDURE", "DMSQL")
 }
 
 // GetFunctions 获取函数列表
 func (e *DamengExtractor) GetFunctions(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return getOracleRoutines(e.db, schema, "FUNCTION", "DMSQL")
 }
 
 // Close 关闭连接
 func (e *DamengExtractor) Close() error {
 	return e.db.Close()
 }
+
+// CallProcedure 以 DMSQL 匿名块调用存储过程/函数，语法与 Oracle 兼容
+func (e *DamengExtractor) CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error) {
+	return callPLSQL(ctx, e.db, call, qualifiedName(call.Schema, call.Name, `"`, `"`), func(int) string {
+		return "?"
+	})
+}
@@ -43,14 +43,25 @@ type IndexInfo struct {
 
 // ProcedureInfo 存储过程/函数信息
 type ProcedureInfo struct {
-	Name        string `json:"name"`        // 名称
-	Type        string `json:"type"`        // 类型: PROCEDURE 或 FUNCTION
-	Definition  string `json:"definition"`  // 定义/代码
-	Comment     string `json:"comment"`     // 注释
-	ReturnType  string `json:"return_type"` // 返回类型 (仅函数有)
-	Parameters  string `json:"parameters"`  // 参数列表
-	Schema      string `json:"schema"`      // 模式
-	Language    string `json:"language"`    // 语言
+	Name       string           `json:"name"`        // 名称
+	Type       string           `json:"type"`        // 类型: PROCEDURE 或 FUNCTION
+	Definition string           `json:"definition"`  // 定义/代码
+	Comment    string           `json:"comment"`     // 注释
+	ReturnType string           `json:"return_type"` // 返回类型 (仅函数有)
+	Parameters []ProcedureParam `json:"parameters"`  // 参数列表
+	Columns    []ProcedureParam `json:"columns"`     // 返回列 (仅表函数有)
+	Schema     string           `json:"schema"`      // 模式
+	Language   string           `json:"language"`    // 语言
+}
+
+// ProcedureParam 存储过程/函数参数，也用于表函数的返回列
+type ProcedureParam struct {
+	Name         string `json:"name"`          // 名称
+	Mode         string `json:"mode"`          // 模式: IN, OUT, INOUT (返回列为 TABLE)
+	DataType     string `json:"data_type"`     // 数据类型
+	Length       int    `json:"length"`        // 长度
+	DefaultValue string `json:"default_value"` // 默认值
+	Position     int    `json:"position"`      // 位置，从 1 开始
 }
 
 // MetadataExtractor 元数据提取接口
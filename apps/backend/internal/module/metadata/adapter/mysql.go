@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -320,63 +321,129 @@ func (e *MySQLExtractor) GetFunctions(schema string) ([]ProcedureInfo, error) {
 	return functions, nil
 }
 
-// getProcedureParameters 获取存储过程/函数的参数列表
-func (e *MySQLExtractor) getProcedureParameters(schema, name string) (string, error) {
+// getProcedureParameters 获取存储过程/函数的参数列表，函数返回值 (位置 0) 不计入
+func (e *MySQLExtractor) getProcedureParameters(schema, name string) ([]ProcedureParam, error) {
 	query := `
 		SELECT 
+			ORDINAL_POSITION,
 			PARAMETER_NAME,
 			DATA_TYPE,
 			CHARACTER_MAXIMUM_LENGTH,
 			NUMERIC_PRECISION,
-			NUMERIC_SCALE,
 			PARAMETER_MODE
 		FROM information_schema.PARAMETERS
-		WHERE SPECIFIC_SCHEMA = ? AND SPECIFIC_NAME = ?
+		WHERE SPECIFIC_SCHEMA = ? AND SPECIFIC_NAME = ? AND ORDINAL_POSITION > 0
 		ORDER BY ORDINAL_POSITION
 	`
 	rows, err := e.db.Query(query, schema, name)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var params []string
+	params := make([]ProcedureParam, 0)
 	for rows.Next() {
+		var p ProcedureParam
 		var paramName, dataType, paramMode sql.NullString
-		var charLength, numPrecision, numScale sql.NullInt64
+		var charLength, numPrecision sql.NullInt64
 
-		if err := rows.Scan(&paramName, &dataType, &charLength, &numPrecision, &numScale, &paramMode); err != nil {
-			continue
+		if err := rows.Scan(&p.Position, &paramName, &dataType, &charLength, &numPrecision, &paramMode); err != nil {
+			return nil, err
 		}
 
-		paramStr := ""
-		if paramMode.Valid {
-			paramStr += paramMode.String + " "
-		}
-		if paramName.Valid {
-			paramStr += paramName.String + " "
-		}
-		if dataType.Valid {
-			paramStr += dataType.String
-			if charLength.Valid && charLength.Int64 > 0 {
-				paramStr += fmt.Sprintf("(%d)", charLength.Int64)
-			} else if numPrecision.Valid && numPrecision.Int64 > 0 {
-				if numScale.Valid && numScale.Int64 > 0 {
-					paramStr += fmt.Sprintf("(%d,%d)", numPrecision.Int64, numScale.Int64)
-				} else {
-					paramStr += fmt.Sprintf("(%d)", numPrecision.Int64)
-				}
-			}
-		}
-		if paramStr != "" {
-			params = append(params, paramStr)
+		p.Name = paramName.String
+		p.DataType = dataType.String
+		p.Mode = paramMode.String
+		if charLength.Valid {
+			p.Length = int(charLength.Int64)
+		} else if numPrecision.Valid {
+			p.Length = int(numPrecision.Int64)
 		}
+		params = append(params, p)
 	}
-
-	return strings.Join(params, ", "), nil
+	return params, rows.Err()
 }
 
 // Close 关闭连接
 func (e *MySQLExtractor) Close() error {
 	return e.db.Close()
 }
+
+// CallProcedure 调用存储过程/函数，OUT/INOUT 参数通过会话变量传递，需在同一连接上执行
+func (e *MySQLExtractor) CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result := newProcedureResult()
+	name := qualifiedName(call.Schema, call.Name, "`", "`")
+	var (
+		args  []any
+		binds []string
+		outs  []string
+		vars  []string
+	)
+
+	// 1. 函数：返回值作为单列查询结果
+	if call.IsFunction() {
+		for _, a := range call.Args {
+			args = append(args, a.Value)
+			binds = append(binds, "?")
+		}
+		row := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT %s(%s) AS return_value", name, strings.Join(binds, ", ")), args...)
+		var v any
+		if err := row.Scan(&v); err != nil {
+			return nil, err
+		}
+		result.ReturnValue = procedureValue(v)
+		return result, nil
+	}
+
+	// 2. 存储过程：OUT/INOUT 参数先写入会话变量
+	for i, a := range call.Args {
+		if !a.IsOut() {
+			args = append(args, a.Value)
+			binds = append(binds, "?")
+			continue
+		}
+		v := fmt.Sprintf("@_p%d", i)
+		var init any
+		if a.Mode == ParamModeInOut {
+			init = a.Value
+		}
+		if _, err := conn.ExecContext(ctx, "SET "+v+" = ?", init); err != nil {
+			return nil, err
+		}
+		binds = append(binds, v)
+		outs = append(outs, a.Name)
+		vars = append(vars, v)
+	}
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("CALL %s(%s)", name, strings.Join(binds, ", ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	result.ResultSets, err = readResultSets(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 读取会话变量中的 OUT 参数值
+	if len(vars) == 0 {
+		return result, nil
+	}
+	values := make([]any, len(vars))
+	ptrs := make([]any, len(vars))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT "+strings.Join(vars, ", ")).Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	for k, v := range procedureRow(outs, values) {
+		result.Outputs[k] = v
+	}
+	return result, nil
+}
@@ -1,8 +1,10 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/godror/godror"
 )
@@ -274,15 +276,155 @@ func (e *OracleExtractor) GetQueryColumns(query string, params []interface{}) ([
 
 // GetProcedures 获取存储过程列表
 func (e *OracleExtractor) GetProcedures(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return getOracleRoutines(e.db, schema, "PROCEDURE", "PL/SQL")
 }
 
 // GetFunctions 获取函数列表
 func (e *OracleExtractor) GetFunctions(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return getOracleRoutines(e.db, schema, "FUNCTION", "PL/SQL")
+}
+
+// getOracleRoutines 获取独立的存储过程/函数 (不含包中的子程序)，达梦兼容所用的数据字典视图
+func getOracleRoutines(db *sql.DB, schema, objectType, language string) ([]ProcedureInfo, error) {
+	query := `
+		SELECT OBJECT_NAME
+		FROM ALL_OBJECTS
+		WHERE OWNER = :1 AND OBJECT_TYPE = :2
+		ORDER BY OBJECT_NAME
+	`
+	rows, err := db.Query(query, schema, objectType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []ProcedureInfo
+	for rows.Next() {
+		r := ProcedureInfo{Type: objectType, Schema: schema, Language: language}
+		if err := rows.Scan(&r.Name); err != nil {
+			return nil, err
+		}
+		routines = append(routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range routines {
+		r := &routines[i]
+		if definition, err := getOracleSource(db, schema, r.Name, objectType); err == nil {
+			r.Definition = definition
+		}
+		if params, returnType, columns, err := getOracleArguments(db, schema, r.Name); err == nil {
+			r.Parameters = params
+			r.ReturnType = returnType
+			r.Columns = columns
+		}
+	}
+	return routines, nil
+}
+
+// getOracleSource 获取存储过程/函数的源码
+func getOracleSource(db *sql.DB, schema, name, objectType string) (string, error) {
+	query := `
+		SELECT TEXT
+		FROM ALL_SOURCE
+		WHERE OWNER = :1 AND NAME = :2 AND TYPE = :3
+		ORDER BY LINE
+	`
+	rows, err := db.Query(query, schema, name, objectType)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var source strings.Builder
+	for rows.Next() {
+		var text sql.NullString
+		if err := rows.Scan(&text); err != nil {
+			return "", err
+		}
+		source.WriteString(text.String)
+	}
+	return source.String(), rows.Err()
+}
+
+// getOracleArguments 获取存储过程/函数的参数列表、函数返回类型 (位置 0)，
+// 以及返回集合类型时集合元素的属性 (作为表函数的返回列)
+func getOracleArguments(db *sql.DB, schema, name string) ([]ProcedureParam, string, []ProcedureParam, error) {
+	query := `
+		SELECT 
+			ARGUMENT_NAME,
+			POSITION,
+			DATA_LEVEL,
+			DATA_TYPE,
+			DATA_LENGTH,
+			IN_OUT,
+			DEFAULT_VALUE
+		FROM ALL_ARGUMENTS
+		WHERE OWNER = :1 AND OBJECT_NAME = :2 AND PACKAGE_NAME IS NULL
+		ORDER BY SEQUENCE
+	`
+	rows, err := db.Query(query, schema, name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer rows.Close()
+
+	params := make([]ProcedureParam, 0)
+	var columns []ProcedureParam
+	returnType := ""
+	inReturn := false
+	for rows.Next() {
+		var argName, dataType, inOut, defaultValue sql.NullString
+		var position, level int
+		var dataLength sql.NullInt64
+		if err := rows.Scan(&argName, &position, &level, &dataType, &dataLength, &inOut, &defaultValue); err != nil {
+			return nil, "", nil, err
+		}
+		// 没有参数的存储过程返回一行空的数据类型
+		if !dataType.Valid {
+			continue
+		}
+		p := ProcedureParam{
+			Name:         argName.String,
+			DataType:     dataType.String,
+			Length:       int(dataLength.Int64),
+			DefaultValue: strings.TrimSpace(defaultValue.String),
+		}
+
+		switch {
+		case level == 0 && position == 0:
+			inReturn = true
+			returnType = p.DataType
+		case level == 0:
+			inReturn = false
+			p.Mode = strings.ReplaceAll(inOut.String, "/", "")
+			p.Position = len(params) + 1
+			params = append(params, p)
+		case inReturn && (level == 2 || (level == 1 && p.Name == "")):
+			// 对象集合取对象属性，标量集合的列名为 COLUMN_VALUE
+			if p.Name == "" {
+				p.Name = "COLUMN_VALUE"
+			}
+			p.Mode = ParamModeTable
+			p.DefaultValue = ""
+			p.Position = len(columns) + 1
+			columns = append(columns, p)
+		}
+	}
+	return params, returnType, columns, rows.Err()
 }
 
 // Close 关闭连接
 func (e *OracleExtractor) Close() error {
 	return e.db.Close()
 }
+
+// CallProcedure 以 PL/SQL 匿名块调用存储过程/函数，管道函数按表查询
+func (e *OracleExtractor) CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error) {
+	return callPLSQL(ctx, e.db, call, qualifiedName(call.Schema, call.Name, `"`, `"`), func(i int) string {
+		return fmt.Sprintf(":%d", i)
+	})
+}
@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -184,6 +185,7 @@ func (e *postgreSQLExtractor) GetQueryColumns(query string, params []interface{}
 func (e *postgreSQLExtractor) GetProcedures(schema string) ([]ProcedureInfo, error) {
 	query := `
 		SELECT 
+			p.oid,
 			p.proname AS routine_name,
 			pg_get_functiondef(p.oid) AS definition,
 			COALESCE(obj_description(p.oid, 'pg_proc'), '') AS comment,
@@ -204,9 +206,10 @@ func (e *postgreSQLExtractor) GetProcedures(schema string) ([]ProcedureInfo, err
 	var procedures []ProcedureInfo
 	for rows.Next() {
 		var p ProcedureInfo
+		var oid uint32
 		var definition, comment, language sql.NullString
 
-		if err := rows.Scan(&p.Name, &definition, &comment, &language); err != nil {
+		if err := rows.Scan(&oid, &p.Name, &definition, &comment, &language); err != nil {
 			return nil, err
 		}
 
@@ -217,7 +220,7 @@ func (e *postgreSQLExtractor) GetProcedures(schema string) ([]ProcedureInfo, err
 		p.Language = language.String
 
 		// 获取存储过程的参数信息
-		params, err := e.getProcedureParameters(schema, p.Name)
+		params, _, err := e.getProcedureParameters(oid)
 		if err == nil {
 			p.Parameters = params
 		}
@@ -231,6 +234,7 @@ func (e *postgreSQLExtractor) GetProcedures(schema string) ([]ProcedureInfo, err
 func (e *postgreSQLExtractor) GetFunctions(schema string) ([]ProcedureInfo, error) {
 	query := `
 		SELECT 
+			p.oid,
 			p.proname AS routine_name,
 			pg_get_functiondef(p.oid) AS definition,
 			COALESCE(obj_description(p.oid, 'pg_proc'), '') AS comment,
@@ -252,9 +256,10 @@ func (e *postgreSQLExtractor) GetFunctions(schema string) ([]ProcedureInfo, erro
 	var functions []ProcedureInfo
 	for rows.Next() {
 		var f ProcedureInfo
+		var oid uint32
 		var definition, comment, language, returnType sql.NullString
 
-		if err := rows.Scan(&f.Name, &definition, &comment, &language, &returnType); err != nil {
+		if err := rows.Scan(&oid, &f.Name, &definition, &comment, &language, &returnType); err != nil {
 			return nil, err
 		}
 
@@ -265,10 +270,11 @@ func (e *postgreSQLExtractor) GetFunctions(schema string) ([]ProcedureInfo, erro
 		f.Language = language.String
 		f.ReturnType = returnType.String
 
-		// 获取函数的参数信息
-		params, err := e.getProcedureParameters(schema, f.Name)
+		// 获取函数的参数信息，RETURNS TABLE 的列作为返回列
+		params, columns, err := e.getProcedureParameters(oid)
 		if err == nil {
 			f.Parameters = params
+			f.Columns = columns
 		}
 
 		functions = append(functions, f)
@@ -276,83 +282,110 @@ func (e *postgreSQLExtractor) GetFunctions(schema string) ([]ProcedureInfo, erro
 	return functions, nil
 }
 
-// getProcedureParameters 获取存储过程/函数的参数列表
-func (e *postgreSQLExtractor) getProcedureParameters(schema, name string) (string, error) {
+// getProcedureParameters 获取存储过程/函数的参数列表和 RETURNS TABLE 的返回列
+func (e *postgreSQLExtractor) getProcedureParameters(oid uint32) ([]ProcedureParam, []ProcedureParam, error) {
 	query := `
 		SELECT 
-			p.proname,
-			unnest(p.proargnames) AS param_name,
-			unnest(p.proargtypes::regtype[]) AS param_type,
-			unnest(p.proargmodes) AS param_mode
-		FROM pg_catalog.pg_proc p
-		JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
-		WHERE n.nspname = $1 AND p.proname = $2
+			COALESCE(p.proargnames[a.ord], '') AS param_name,
+			COALESCE(p.proargmodes[a.ord], 'i') AS param_mode,
+			format_type(a.typ, NULL) AS param_type,
+			COALESCE(pg_get_function_arg_default(p.oid, a.ord::int), '') AS param_default
+		FROM pg_catalog.pg_proc p,
+			unnest(COALESCE(p.proallargtypes, p.proargtypes::oid[])) WITH ORDINALITY AS a(typ, ord)
+		WHERE p.oid = $1
+		ORDER BY a.ord
 	`
-	rows, err := e.db.Query(query, schema, name)
+	rows, err := e.db.Query(query, oid)
 	if err != nil {
-		// 如果参数查询失败,尝试简化的查询方式
-		return e.getSimpleParameters(schema, name)
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var params []string
+	modeMap := map[string]string{
+		"i": ParamModeIn,
+		"o": ParamModeOut,
+		"b": ParamModeInOut,
+		"v": ParamModeIn, // VARIADIC 参数以数组传入
+		"t": ParamModeTable,
+	}
+	params := make([]ProcedureParam, 0)
+	var columns []ProcedureParam
 	for rows.Next() {
-		var procName, paramName, paramType, paramMode sql.NullString
+		var p ProcedureParam
+		var mode string
+		if err := rows.Scan(&p.Name, &mode, &p.DataType, &p.DefaultValue); err != nil {
+			return nil, nil, err
+		}
 
-		if err := rows.Scan(&procName, &paramName, &paramType, &paramMode); err != nil {
+		p.Mode = modeMap[mode]
+		if p.Mode == ParamModeTable {
+			p.Position = len(columns) + 1
+			columns = append(columns, p)
 			continue
 		}
+		p.Position = len(params) + 1
+		params = append(params, p)
+	}
+	return params, columns, rows.Err()
+}
 
-		paramStr := ""
-		if paramMode.Valid {
-			modeMap := map[string]string{
-				"i": "IN",
-				"o": "OUT",
-				"b": "INOUT",
-				"v": "VARIADIC",
-				"t": "TABLE",
-			}
-			if mode, ok := modeMap[paramMode.String]; ok {
-				paramStr += mode + " "
-			}
-		}
-		if paramName.Valid {
-			paramStr += paramName.String + " "
-		}
-		if paramType.Valid {
-			paramStr += paramType.String
+// Close 关闭连接
+func (e *postgreSQLExtractor) Close() error {
+	return e.db.Close()
+}
+
+// CallProcedure 调用函数或存储过程 (PostgreSQL 11+)，OUT/INOUT 参数值以结果行返回
+func (e *postgreSQLExtractor) CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error) {
+	result := newProcedureResult()
+	name := qualifiedName(call.Schema, call.Name, `"`, `"`)
+	var (
+		args  []any
+		binds []string
+		outs  []string
+	)
+	for _, a := range call.Args {
+		if a.IsOut() {
+			outs = append(outs, a.Name)
 		}
-		if paramStr != "" {
-			params = append(params, paramStr)
+		switch {
+		case a.IsIn():
+			args = append(args, a.Value)
+			binds = append(binds, fmt.Sprintf("$%d", len(args)))
+		case !call.IsFunction():
+			// 存储过程的 OUT 参数以 NULL 占位，函数调用时省略
+			binds = append(binds, "NULL")
 		}
 	}
 
-	if len(params) > 0 {
-		return strings.Join(params, ", "), nil
+	query := fmt.Sprintf("CALL %s(%s)", name, strings.Join(binds, ", "))
+	if call.IsFunction() {
+		query = fmt.Sprintf("SELECT * FROM %s(%s)", name, strings.Join(binds, ", "))
 	}
-
-	// 如果没有获取到参数,尝试简化方式
-	return e.getSimpleParameters(schema, name)
-}
-
-// getSimpleParameters 简化的参数获取方式
-func (e *postgreSQLExtractor) getSimpleParameters(schema, name string) (string, error) {
-	query := `
-		SELECT 
-			pg_get_function_arguments(p.oid) AS arguments
-		FROM pg_catalog.pg_proc p
-		JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
-		WHERE n.nspname = $1 AND p.proname = $2
-	`
-	var arguments sql.NullString
-	err := e.db.QueryRow(query, schema, name).Scan(&arguments)
+	rows, err := e.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer rows.Close()
+	sets, err := readResultSets(rows)
+	if err != nil {
+		return nil, err
 	}
-	return arguments.String, nil
-}
 
-// Close 关闭连接
-func (e *postgreSQLExtractor) Close() error {
-	return e.db.Close()
+	// 有 OUT 参数时首行为参数值 (返回多行的函数除外)；表函数返回结果集；否则为标量函数的返回值
+	returnType := strings.ToUpper(call.ReturnType)
+	multiRow := strings.Contains(returnType, "SETOF") || strings.Contains(returnType, "TABLE")
+	switch {
+	case call.IsFunction() && (multiRow || (len(outs) == 0 && call.ReturnsRows())):
+		result.ResultSets = sets
+	case len(sets) == 0 || len(sets[0]) == 0:
+	case len(outs) > 0:
+		for k, v := range sets[0][0] {
+			result.Outputs[k] = v
+		}
+	case call.IsFunction():
+		for _, v := range sets[0][0] {
+			result.ReturnValue = v
+		}
+	}
+	return result, nil
 }
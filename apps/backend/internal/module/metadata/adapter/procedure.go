@@ -0,0 +1,286 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 存储过程参数模式
+const (
+	ParamModeIn    = "IN"
+	ParamModeOut   = "OUT"
+	ParamModeInOut = "INOUT"
+	ParamModeTable = "TABLE" // 表函数的返回列
+)
+
+// ProcedureArg 存储过程/函数调用参数，OUT 参数忽略 Value
+type ProcedureArg struct {
+	Name  string `json:"name"`
+	Mode  string `json:"mode"` // IN, OUT, INOUT
+	Type  string `json:"type"` // 数据类型，用于 OUT 参数的接收类型
+	Value any    `json:"value"`
+}
+
+// ProcedureCall 存储过程/函数调用
+type ProcedureCall struct {
+	Schema     string         `json:"schema"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`        // PROCEDURE 或 FUNCTION
+	ReturnType string         `json:"return_type"` // 函数返回类型，表/游标类型的返回按结果集读取
+	Args       []ProcedureArg `json:"args"`
+}
+
+// ProcedureResult 存储过程/函数调用结果
+type ProcedureResult struct {
+	Outputs     map[string]any     `json:"outputs"`                // OUT/INOUT 参数的返回值
+	ReturnValue any                `json:"return_value,omitempty"` // 标量函数的返回值
+	ResultSets  [][]map[string]any `json:"result_sets"`            // 结果集，依次返回
+}
+
+// ProcedureCaller 支持调用存储过程/函数的元数据提取器
+type ProcedureCaller interface {
+	// CallProcedure 绑定 IN 参数调用存储过程/函数，返回 OUT/INOUT 参数值和结果集
+	CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error)
+}
+
+// IsFunction 是否为函数
+func (c ProcedureCall) IsFunction() bool {
+	return strings.EqualFold(c.Type, "FUNCTION")
+}
+
+// ReturnsRows 函数是否返回表或游标
+func (c ProcedureCall) ReturnsRows() bool {
+	t := strings.ToUpper(c.ReturnType)
+	return strings.Contains(t, "TABLE") || strings.Contains(t, "SETOF") || strings.Contains(t, "CURSOR") || strings.Contains(t, "RECORD")
+}
+
+// IsIn 参数是否需要传入值
+func (a ProcedureArg) IsIn() bool {
+	return a.Mode != ParamModeOut
+}
+
+// IsOut 参数是否有返回值
+func (a ProcedureArg) IsOut() bool {
+	return a.Mode == ParamModeOut || a.Mode == ParamModeInOut
+}
+
+// newProcedureResult 创建空的调用结果
+func newProcedureResult() *ProcedureResult {
+	return &ProcedureResult{Outputs: make(map[string]any), ResultSets: make([][]map[string]any, 0)}
+}
+
+// readResultSets 依次读取全部结果集，跳过没有列的结果 (如 MySQL CALL 的状态结果)
+func readResultSets(rows *sql.Rows) ([][]map[string]any, error) {
+	sets := make([][]map[string]any, 0)
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		set := make([]map[string]any, 0)
+		for rows.Next() {
+			values := make([]any, len(columns))
+			ptrs := make([]any, len(columns))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				return nil, err
+			}
+			set = append(set, procedureRow(columns, values))
+		}
+		if len(columns) > 0 {
+			sets = append(sets, set)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return sets, rows.Err()
+}
+
+// readDriverRows 读取游标类型 OUT 参数返回的结果集
+func readDriverRows(rows driver.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+	columns := rows.Columns()
+	set := make([]map[string]any, 0)
+	for {
+		dest := make([]driver.Value, len(columns))
+		if err := rows.Next(dest); err != nil {
+			if errors.Is(err, io.EOF) {
+				return set, nil
+			}
+			return nil, err
+		}
+		values := make([]any, len(dest))
+		for i, v := range dest {
+			values[i] = v
+		}
+		set = append(set, procedureRow(columns, values))
+	}
+}
+
+// procedureRow 将一行转换为 map
+func procedureRow(columns []string, values []any) map[string]any {
+	row := make(map[string]any, len(columns))
+	for i, col := range columns {
+		row[col] = procedureValue(values[i])
+	}
+	return row
+}
+
+// procedureValue 将 []byte 转为字符串，其他值原样返回
+func procedureValue(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// outDest 按数据类型创建 OUT 参数的接收变量，游标类型接收 driver.Rows
+func outDest(dataType string) any {
+	t := strings.ToLower(dataType)
+	switch {
+	case strings.Contains(t, "cursor"):
+		return new(driver.Rows)
+	case strings.Contains(t, "int"):
+		return new(sql.NullInt64)
+	case strings.Contains(t, "dec"), strings.Contains(t, "num"), strings.Contains(t, "float"),
+		strings.Contains(t, "double"), strings.Contains(t, "real"), strings.Contains(t, "money"):
+		return new(sql.NullFloat64)
+	case strings.Contains(t, "date"), strings.Contains(t, "time"):
+		return new(sql.NullTime)
+	case strings.Contains(t, "bool"), t == "bit":
+		return new(sql.NullBool)
+	default:
+		return new(sql.NullString)
+	}
+}
+
+// outArg 创建 OUT/INOUT 参数，INOUT 参数的接收变量以传入值初始化
+func outArg(a ProcedureArg) (sql.Out, error) {
+	dest := outDest(a.Type)
+	if a.Mode == ParamModeInOut && a.Value != nil {
+		scanner, ok := dest.(sql.Scanner)
+		if !ok {
+			return sql.Out{}, fmt.Errorf("参数 %s 不能作为 INOUT 参数", a.Name)
+		}
+		if err := scanner.Scan(a.Value); err != nil {
+			return sql.Out{}, fmt.Errorf("参数 %s 的值无效: %w", a.Name, err)
+		}
+	}
+	return sql.Out{Dest: dest, In: a.Mode == ParamModeInOut}, nil
+}
+
+// readOut 读取 OUT 参数的返回值，游标类型的参数读取为结果集
+func readOut(result *ProcedureResult, name string, dest any) error {
+	switch d := dest.(type) {
+	case *driver.Rows:
+		if *d == nil {
+			return nil
+		}
+		set, err := readDriverRows(*d)
+		if err != nil {
+			return err
+		}
+		result.ResultSets = append(result.ResultSets, set)
+	case driver.Valuer:
+		v, err := d.Value()
+		if err != nil {
+			return err
+		}
+		result.Outputs[name] = v
+	}
+	return nil
+}
+
+// callPLSQL 以匿名块调用 Oracle 风格的存储过程/函数，OUT 参数通过 sql.Out 接收，placeholder 返回第 i 个绑定变量
+func callPLSQL(ctx context.Context, db *sql.DB, call ProcedureCall, qualified string, placeholder func(i int) string) (*ProcedureResult, error) {
+	result := newProcedureResult()
+	var (
+		args  []any
+		binds []string
+		outs  = make(map[string]any)
+	)
+	bind := func(v any) string {
+		args = append(args, v)
+		return placeholder(len(args))
+	}
+
+	// 1. 管道函数按表查询
+	if call.IsFunction() && strings.Contains(strings.ToUpper(call.ReturnType), "TABLE") {
+		for _, a := range call.Args {
+			binds = append(binds, bind(a.Value))
+		}
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM TABLE(%s(%s))", qualified, strings.Join(binds, ", ")), args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		if result.ResultSets, err = readResultSets(rows); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// 2. 匿名块：函数返回值作为首个绑定变量
+	var ret any
+	prefix := ""
+	if call.IsFunction() {
+		ret = outDest(call.ReturnType)
+		prefix = bind(sql.Out{Dest: ret}) + " := "
+	}
+	for _, a := range call.Args {
+		if !a.IsOut() {
+			binds = append(binds, bind(a.Value))
+			continue
+		}
+		out, err := outArg(a)
+		if err != nil {
+			return nil, err
+		}
+		outs[a.Name] = out.Dest
+		binds = append(binds, bind(out))
+	}
+	block := fmt.Sprintf("BEGIN %s%s(%s); END;", prefix, qualified, strings.Join(binds, ", "))
+	if _, err := db.ExecContext(ctx, block, args...); err != nil {
+		return nil, err
+	}
+
+	// 3. 读取返回值和 OUT 参数
+	for _, a := range call.Args {
+		if dest, ok := outs[a.Name]; ok {
+			if err := readOut(result, a.Name, dest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ret != nil {
+		returned := newProcedureResult()
+		if err := readOut(returned, "", ret); err != nil {
+			return nil, err
+		}
+		if call.ReturnsRows() {
+			result.ResultSets = append(returned.ResultSets, result.ResultSets...)
+		} else {
+			result.ReturnValue = returned.Outputs[""]
+		}
+	}
+	return result, nil
+}
+
+// qualifiedName 以引号包裹模式名和对象名，引号字符在名称中时转义为两个
+func qualifiedName(schema, name, open, close string) string {
+	quote := func(s string) string {
+		return open + strings.ReplaceAll(s, close, close+close) + close
+	}
+	if schema == "" {
+		return quote(name)
+	}
+	return quote(schema) + "." + quote(name)
+}
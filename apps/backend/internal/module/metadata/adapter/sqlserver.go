@@ -1,8 +1,10 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/microsoft/go-mssqldb"
 )
@@ -303,15 +305,245 @@ func (e *SQLServerExtractor) GetQueryColumns(query string, params []interface{})
 
 // GetProcedures 获取存储过程列表
 func (e *SQLServerExtractor) GetProcedures(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return e.getRoutines(schema, "PROCEDURE", "'P'")
 }
 
-// GetFunctions 获取函数列表
+// GetFunctions 获取函数列表，包括标量函数、内联表值函数和多语句表值函数
 func (e *SQLServerExtractor) GetFunctions(schema string) ([]ProcedureInfo, error) {
-	return []ProcedureInfo{}, nil
+	return e.getRoutines(schema, "FUNCTION", "'FN', 'IF', 'TF'")
+}
+
+// getRoutines 获取指定对象类型的存储过程/函数及其参数，表值函数同时获取返回列
+func (e *SQLServerExtractor) getRoutines(schema, routineType, objectTypes string) ([]ProcedureInfo, error) {
+	if schema == "" {
+		schema = "dbo"
+	}
+
+	query := `
+		SELECT 
+			o.object_id,
+			o.name,
+			o.type,
+			ISNULL(m.definition, '') AS definition,
+			ISNULL(CAST(ep.value AS NVARCHAR(4000)), '') AS comment
+		FROM sys.objects o
+		JOIN sys.schemas s ON s.schema_id = o.schema_id
+		LEFT JOIN sys.sql_modules m ON m.object_id = o.object_id
+		LEFT JOIN sys.extended_properties ep 
+			ON ep.major_id = o.object_id
+			AND ep.minor_id = 0
+			AND ep.class = 1
+			AND ep.name = 'MS_Description'
+		WHERE s.name = @p1 AND o.type IN (` + objectTypes + `)
+		ORDER BY o.name
+	`
+	rows, err := e.db.Query(query, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []ProcedureInfo
+	var objectIDs []int
+	var tableValued []bool
+	for rows.Next() {
+		var r ProcedureInfo
+		var objectID int
+		var objectType string
+		if err := rows.Scan(&objectID, &r.Name, &objectType, &r.Definition, &r.Comment); err != nil {
+			return nil, err
+		}
+		r.Type = routineType
+		r.Schema = schema
+		r.Language = "T-SQL"
+		routines = append(routines, r)
+		objectIDs = append(objectIDs, objectID)
+		tableValued = append(tableValued, strings.TrimSpace(objectType) != "FN" && routineType == "FUNCTION")
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// 逐个获取参数，表值函数的返回类型为 TABLE
+	for i := range routines {
+		params, returnType, err := e.getProcedureParameters(objectIDs[i])
+		if err == nil {
+			routines[i].Parameters = params
+			routines[i].ReturnType = returnType
+		}
+		if tableValued[i] {
+			routines[i].ReturnType = "TABLE"
+			if columns, err := e.getFunctionColumns(objectIDs[i]); err == nil {
+				routines[i].Columns = columns
+			}
+		}
+	}
+	return routines, nil
+}
+
+// getProcedureParameters 获取存储过程/函数的参数列表，以及标量函数的返回类型 (参数编号 0)；
+// OUTPUT 参数同时可以传入值，模式为 INOUT
+func (e *SQLServerExtractor) getProcedureParameters(objectID int) ([]ProcedureParam, string, error) {
+	query := `
+		SELECT 
+			p.parameter_id,
+			p.name,
+			TYPE_NAME(p.user_type_id) AS data_type,
+			p.max_length,
+			p.is_output
+		FROM sys.parameters p
+		WHERE p.object_id = @p1
+		ORDER BY p.parameter_id
+	`
+	rows, err := e.db.Query(query, objectID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	params := make([]ProcedureParam, 0)
+	returnType := ""
+	for rows.Next() {
+		var p ProcedureParam
+		var maxLength int
+		var isOutput bool
+		if err := rows.Scan(&p.Position, &p.Name, &p.DataType, &maxLength, &isOutput); err != nil {
+			return nil, "", err
+		}
+		if p.Position == 0 {
+			returnType = p.DataType
+			continue
+		}
+		p.Mode = ParamModeIn
+		if isOutput {
+			p.Mode = ParamModeInOut
+		}
+		p.Length = sqlServerLength(p.DataType, maxLength)
+		params = append(params, p)
+	}
+	return params, returnType, rows.Err()
+}
+
+// getFunctionColumns 获取表值函数的返回列
+func (e *SQLServerExtractor) getFunctionColumns(objectID int) ([]ProcedureParam, error) {
+	query := `
+		SELECT 
+			c.column_id,
+			c.name,
+			TYPE_NAME(c.user_type_id) AS data_type,
+			c.max_length
+		FROM sys.columns c
+		WHERE c.object_id = @p1
+		ORDER BY c.column_id
+	`
+	rows, err := e.db.Query(query, objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ProcedureParam
+	for rows.Next() {
+		var c ProcedureParam
+		var maxLength int
+		if err := rows.Scan(&c.Position, &c.Name, &c.DataType, &maxLength); err != nil {
+			return nil, err
+		}
+		c.Mode = ParamModeTable
+		c.Length = sqlServerLength(c.DataType, maxLength)
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// sqlServerLength 将 Unicode 字符类型的字节长度换算为字符长度，MAX 类型 (-1) 返回 0
+func sqlServerLength(dataType string, maxLength int) int {
+	if maxLength < 0 {
+		return 0
+	}
+	switch strings.ToLower(dataType) {
+	case "nchar", "nvarchar":
+		return maxLength / 2
+	}
+	return maxLength
 }
 
 // Close 关闭连接
 func (e *SQLServerExtractor) Close() error {
 	return e.db.Close()
 }
+
+// CallProcedure 调用存储过程/函数，存储过程以 EXEC 命名参数调用，OUT/INOUT 参数标记为 OUTPUT
+func (e *SQLServerExtractor) CallProcedure(ctx context.Context, call ProcedureCall) (*ProcedureResult, error) {
+	result := newProcedureResult()
+	name := qualifiedName(call.Schema, call.Name, "[", "]")
+	var (
+		args  []any
+		binds []string
+		outs  = make(map[string]any)
+	)
+
+	// 1. 函数：表值函数按表查询，标量函数返回单个值
+	if call.IsFunction() {
+		for _, a := range call.Args {
+			args = append(args, a.Value)
+			binds = append(binds, fmt.Sprintf("@p%d", len(args)))
+		}
+		query := fmt.Sprintf("SELECT %s(%s) AS return_value", name, strings.Join(binds, ", "))
+		if call.ReturnsRows() {
+			query = fmt.Sprintf("SELECT * FROM %s(%s)", name, strings.Join(binds, ", "))
+		}
+		rows, err := e.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		sets, err := readResultSets(rows)
+		if err != nil {
+			return nil, err
+		}
+		if call.ReturnsRows() {
+			result.ResultSets = sets
+		} else if len(sets) > 0 && len(sets[0]) > 0 {
+			result.ReturnValue = sets[0][0]["return_value"]
+		}
+		return result, nil
+	}
+
+	// 2. 存储过程：全部参数按名称绑定
+	for _, a := range call.Args {
+		param := strings.TrimPrefix(a.Name, "@")
+		if !a.IsOut() {
+			args = append(args, sql.Named(param, a.Value))
+			binds = append(binds, fmt.Sprintf("@%s = @%s", param, param))
+			continue
+		}
+		out, err := outArg(a)
+		if err != nil {
+			return nil, err
+		}
+		outs[a.Name] = out.Dest
+		args = append(args, sql.Named(param, out))
+		binds = append(binds, fmt.Sprintf("@%s = @%s OUTPUT", param, param))
+	}
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf("EXEC %s %s", name, strings.Join(binds, ", ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	result.ResultSets, err = readResultSets(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. OUTPUT 参数在结果集读取完毕后可用
+	for _, a := range call.Args {
+		if dest, ok := outs[a.Name]; ok {
+			if err := readOut(result, a.Name, dest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
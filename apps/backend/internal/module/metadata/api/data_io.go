@@ -1,8 +1,14 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
 	"metadata-platform/internal/module/metadata/service"
 	"metadata-platform/internal/utils"
 
@@ -14,13 +20,15 @@ import (
 type DataIOHandler struct {
 	*utils.BaseHandler
 	ioService service.DataIOService
+	dataScope *DataScopeBinder
 }
 
 // NewDataIOHandler 创建数据导入导出处理器实例
-func NewDataIOHandler(ioService service.DataIOService) *DataIOHandler {
+func NewDataIOHandler(ioService service.DataIOService, dataScope service.DataScopeService) *DataIOHandler {
 	return &DataIOHandler{
 		BaseHandler: utils.NewBaseHandler(),
 		ioService:   ioService,
+		dataScope:   NewDataScopeBinder(dataScope),
 	}
 }
 
@@ -31,10 +39,17 @@ func (h *DataIOHandler) ExportData(c context.Context, ctx *app.RequestContext) {
 
 	queryParams := make(map[string]any)
 	ctx.BindQuery(&queryParams)
+	c = h.dataScope.Context(c, ctx)
 
 	// Hertz Response Writer adapter
 	ctx.SetStatusCode(consts.StatusOK)
 
+	encoding := ctx.Query("encoding")
+	delete(queryParams, "format")
+	delete(queryParams, "encoding")
+	delete(queryParams, "compression")
+	delete(queryParams, "row_group_size")
+
 	var err error
 	if format == "json" {
 		ctx.Header("Content-Type", "application/json")
@@ -42,14 +57,27 @@ func (h *DataIOHandler) ExportData(c context.Context, ctx *app.RequestContext) {
 
 		// Use stream writer
 		writer := ctx.Response.BodyWriter()
-		err = h.ioService.ExportToJSON(modelID, queryParams, writer)
+		err = h.ioService.ExportToJSON(c, modelID, queryParams, writer)
+	} else if format == "csv" {
+		ctx.Header("Content-Type", "text/csv")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", modelID))
+
+		writer := ctx.Response.BodyWriter()
+		err = h.ioService.ExportToCSV(c, modelID, queryParams, encoding, writer)
+	} else if format == service.ExportFormatParquet {
+		ctx.Header("Content-Type", "application/vnd.apache.parquet")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.parquet", modelID))
+
+		rowGroupSize, _ := strconv.Atoi(ctx.Query("row_group_size"))
+		writer := ctx.Response.BodyWriter()
+		err = h.ioService.ExportToParquet(c, modelID, queryParams, utils.ParquetOptions{Compression: ctx.Query("compression"), RowGroupSize: rowGroupSize}, writer)
 	} else {
 		// Default to Excel
 		ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", modelID))
 
 		writer := ctx.Response.BodyWriter()
-		err = h.ioService.ExportToExcel(modelID, queryParams, writer)
+		err = h.ioService.ExportToExcel(c, modelID, queryParams, writer)
 	}
 
 	if err != nil {
@@ -59,15 +87,47 @@ func (h *DataIOHandler) ExportData(c context.Context, ctx *app.RequestContext) {
 	}
 }
 
+// ExportPivot 导出透视结果，请求体为透视查询参数
+func (h *DataIOHandler) ExportPivot(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("model_id")
+	format := ctx.DefaultQuery("format", service.PivotExportCSV)
+
+	var params map[string]any
+	if err := ctx.BindJSON(&params); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	// 透视结果规模受限，先写入缓冲区以便出错时返回错误信息
+	var buf bytes.Buffer
+	if err := h.ioService.ExportPivot(h.dataScope.Context(c, ctx), modelID, params, format, &buf); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.PivotExportJSON {
+		contentType = "application/json"
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_pivot.%s", modelID, format))
+	ctx.Data(consts.StatusOK, contentType, buf.Bytes())
+}
+
 // ImportTemplate 下载导入模板
 func (h *DataIOHandler) ImportTemplate(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
 
-	ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_template.xlsx", modelID))
-
+	var err error
 	writer := ctx.Response.BodyWriter()
-	err := h.ioService.GenerateExcelTemplate(modelID, writer)
+	if ctx.Query("format") == "csv" {
+		ctx.Header("Content-Type", "text/csv")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_template.csv", modelID))
+		err = h.ioService.GenerateCSVTemplate(modelID, ctx.Query("encoding"), writer)
+	} else {
+		ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_template.xlsx", modelID))
+		err = h.ioService.GenerateExcelTemplate(modelID, writer)
+	}
 	if err != nil {
 		utils.SugarLogger.Errorf("Template generation failed: %v", err)
 	}
@@ -91,14 +151,16 @@ func (h *DataIOHandler) ImportData(c context.Context, ctx *app.RequestContext) {
 	defer file.Close()
 
 	// Check extension or content type to decide format
-	filename := fileHeader.Filename
 	var success int
 	var errors []string
 
-	if len(filename) > 5 && filename[len(filename)-5:] == ".json" {
-		success, errors, err = h.ioService.ImportFromJSON(modelID, file)
-	} else {
-		success, errors, err = h.ioService.ImportFromExcel(modelID, file)
+	switch importFormat(fileHeader.Filename) {
+	case service.ImportFormatJSON:
+		success, errors, err = h.ioService.ImportFromJSON(h.dataScope.Context(c, ctx), modelID, file)
+	case service.ImportFormatCSV:
+		success, errors, err = h.ioService.ImportFromCSV(h.dataScope.Context(c, ctx), modelID, ctx.Query("encoding"), file)
+	default:
+		success, errors, err = h.ioService.ImportFromExcel(h.dataScope.Context(c, ctx), modelID, file)
 	}
 
 	if err != nil {
@@ -111,3 +173,181 @@ func (h *DataIOHandler) ImportData(c context.Context, ctx *app.RequestContext) {
 		"errors":        errors,
 	})
 }
+
+// importFormat 按文件扩展名判断导入文件格式，默认为 Excel
+func importFormat(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".json":
+		return service.ImportFormatJSON
+	case ".csv":
+		return service.ImportFormatCSV
+	default:
+		return service.ImportFormatExcel
+	}
+}
+
+// StartImportJob 上传文件并创建后台导入任务
+// 表单字段: file 文件, strategy 导入策略, key_fields 逗号分隔的唯一键字段, dry_run 只校验不写入, encoding CSV 编码
+func (h *DataIOHandler) StartImportJob(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("model_id")
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "File is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, "Failed to open uploaded file")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, "Failed to read uploaded file")
+		return
+	}
+
+	opts := service.ImportOptions{
+		Format:   importFormat(fileHeader.Filename),
+		Encoding: string(ctx.FormValue("encoding")),
+		Strategy: string(ctx.FormValue("strategy")),
+	}
+	if keys := strings.TrimSpace(string(ctx.FormValue("key_fields"))); keys != "" {
+		for _, k := range strings.Split(keys, ",") {
+			opts.KeyFields = append(opts.KeyFields, strings.TrimSpace(k))
+		}
+	}
+	if dryRun := string(ctx.FormValue("dry_run")); dryRun != "" {
+		if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid dry_run")
+			return
+		}
+	}
+
+	job, err := h.ioService.StartImport(h.dataScope.Context(c, ctx), modelID, data, opts)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// GetImportJob 查询导入任务进度
+func (h *DataIOHandler) GetImportJob(c context.Context, ctx *app.RequestContext) {
+	job, err := h.ioService.GetImportJob(h.dataScope.Context(c, ctx), ctx.Param("model_id"), ctx.Param("job_id"))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusNotFound, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// CancelImportJob 取消导入任务
+func (h *DataIOHandler) CancelImportJob(c context.Context, ctx *app.RequestContext) {
+	if err := h.ioService.CancelImportJob(h.dataScope.Context(c, ctx), ctx.Param("model_id"), ctx.Param("job_id")); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, nil)
+}
+
+// ImportErrorReport 下载导入任务的错误报告
+func (h *DataIOHandler) ImportErrorReport(c context.Context, ctx *app.RequestContext) {
+	jobID := ctx.Param("job_id")
+
+	var buf bytes.Buffer
+	format, err := h.ioService.ImportErrorReport(h.dataScope.Context(c, ctx), ctx.Param("model_id"), jobID, &buf)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	contentType := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	switch format {
+	case service.ImportFormatCSV:
+		contentType = "text/csv"
+	case service.ImportFormatJSON:
+		contentType = "application/json"
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_errors.%s", jobID, format))
+	ctx.Data(consts.StatusOK, contentType, buf.Bytes())
+}
+
+// exportContentTypes 导出文件格式对应的内容类型
+var exportContentTypes = map[string]string{
+	service.ExportFormatJSON:    "application/json",
+	service.ExportFormatNDJSON:  "application/x-ndjson",
+	service.ExportFormatCSV:     "text/csv",
+	service.ExportFormatExcel:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	service.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// StartExportJob 创建后台导出任务，format、encoding、compression、row_group_size 为查询参数，请求体为列表查询参数
+func (h *DataIOHandler) StartExportJob(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("model_id")
+
+	params := make(map[string]any)
+	if len(ctx.Request.Body()) > 0 {
+		if err := ctx.BindJSON(&params); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
+
+	rowGroupSize, _ := strconv.Atoi(ctx.Query("row_group_size"))
+	job, err := h.ioService.StartExport(h.dataScope.Context(c, ctx), modelID, params, service.ExportOptions{
+		Format:       ctx.DefaultQuery("format", service.ExportFormatExcel),
+		Encoding:     ctx.Query("encoding"),
+		Compression:  ctx.Query("compression"),
+		RowGroupSize: rowGroupSize,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// ListExportJobs 查询当前用户的导出任务
+func (h *DataIOHandler) ListExportJobs(c context.Context, ctx *app.RequestContext) {
+	jobs, err := h.ioService.ListExportJobs(h.dataScope.Context(c, ctx))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, jobs)
+}
+
+// GetExportJob 查询导出任务进度
+func (h *DataIOHandler) GetExportJob(c context.Context, ctx *app.RequestContext) {
+	job, err := h.ioService.GetExportJob(h.dataScope.Context(c, ctx), ctx.Param("job_id"))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusNotFound, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// DeleteExportJob 删除导出任务及文件，运行中的任务会被取消
+func (h *DataIOHandler) DeleteExportJob(c context.Context, ctx *app.RequestContext) {
+	if err := h.ioService.DeleteExportJob(h.dataScope.Context(c, ctx), ctx.Param("job_id")); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusNotFound, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, nil)
+}
+
+// DownloadExport 通过签名链接下载导出文件，无需登录
+func (h *DataIOHandler) DownloadExport(c context.Context, ctx *app.RequestContext) {
+	job, file, err := h.ioService.OpenExportFile(ctx.Param("job_id"), ctx.Query("expires"), ctx.Query("signature"))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusForbidden, err.Error())
+		return
+	}
+
+	ctx.Header("Content-Type", exportContentTypes[job.Format])
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.%s", job.ModelID, job.ID, job.Format))
+	ctx.SetStatusCode(consts.StatusOK)
+	ctx.SetBodyStream(file, int(job.FileSize))
+}
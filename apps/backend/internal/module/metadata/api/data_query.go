@@ -1,8 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"metadata-platform/internal/module/metadata/engine"
 	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/service"
@@ -17,14 +19,16 @@ type DataQueryHandler struct {
 	*utils.BaseHandler
 	crudService  service.CRUDService
 	modelService service.MdModelService
+	dataScope    *DataScopeBinder
 }
 
 // NewDataQueryHandler 创建处理器实例
-func NewDataQueryHandler(crudService service.CRUDService, modelService service.MdModelService) *DataQueryHandler {
+func NewDataQueryHandler(crudService service.CRUDService, modelService service.MdModelService, dataScope service.DataScopeService) *DataQueryHandler {
 	return &DataQueryHandler{
 		BaseHandler:  utils.NewBaseHandler(),
 		crudService:  crudService,
 		modelService: modelService,
+		dataScope:    NewDataScopeBinder(dataScope),
 	}
 }
 
@@ -52,7 +56,7 @@ func (h *DataQueryHandler) HandleUnifiedQuery(c context.Context, ctx *app.Reques
 }
 
 // HandleUnifiedQueryWithModelID 供 DynamicRouter 调用的带 ModelID 的处理函数
-func (h *DataQueryHandler) HandleUnifiedQueryWithModelID(ctx *app.RequestContext, modelID string) {
+func (h *DataQueryHandler) HandleUnifiedQueryWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
 	// Bind JSON body directly to map 更好，因为结构不固定
 	var body map[string]any
 	if err := ctx.BindJSON(&body); err != nil {
@@ -60,7 +64,7 @@ func (h *DataQueryHandler) HandleUnifiedQueryWithModelID(ctx *app.RequestContext
 		return
 	}
 
-	results, count, err := h.crudService.List(modelID, body)
+	results, count, err := h.crudService.List(c, modelID, body)
 	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
@@ -75,7 +79,7 @@ func (h *DataQueryHandler) HandleUnifiedQueryWithModelID(ctx *app.RequestContext
 // HandleUnifiedQueryByID 处理通过 ID 的统一查询
 func (h *DataQueryHandler) HandleUnifiedQueryByID(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("id")
-	h.HandleUnifiedQueryWithModelID(ctx, modelID)
+	h.HandleUnifiedQueryWithModelID(h.dataScope.Context(c, ctx), ctx, modelID)
 }
 
 // HandleUnifiedQueryByCode 处理通过代码的统一查询
@@ -86,18 +90,49 @@ func (h *DataQueryHandler) HandleUnifiedQueryByCode(c context.Context, ctx *app.
 		utils.ErrorResponse(ctx, consts.StatusNotFound, "Model not found: "+code)
 		return
 	}
-	h.HandleUnifiedQueryWithModelID(ctx, model.ID)
+	h.HandleUnifiedQueryWithModelID(h.dataScope.Context(c, ctx), ctx, model.ID)
+}
+
+// BatchCreateRequest 批量创建请求，支持指定插入模式和每批行数
+type BatchCreateRequest struct {
+	List []map[string]any `json:"list"`
+	service.BatchInsertOptions
 }
 
 // HandleBatchCreateWithModelID 批量创建
-func (h *DataQueryHandler) HandleBatchCreateWithModelID(ctx *app.RequestContext, modelID string) {
+// 请求体为数组时任一行失败全部回滚；为对象时按 mode 执行并返回行级结果
+func (h *DataQueryHandler) HandleBatchCreateWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	body := bytes.TrimSpace(ctx.Request.Body())
+	if len(body) > 0 && body[0] == '{' {
+		var req BatchCreateRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+		result, err := h.crudService.BatchInsert(c, modelID, req.List, req.BatchInsertOptions)
+		if err != nil {
+			if result != nil && errors.Is(err, service.ErrBatchInsertFailed) {
+				ctx.JSON(consts.StatusUnprocessableEntity, utils.Response{
+					Code:    consts.StatusUnprocessableEntity,
+					Message: err.Error(),
+					Data:    result,
+				})
+				return
+			}
+			utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SuccessResponse(ctx, result)
+		return
+	}
+
 	var dataList []map[string]any
 	if err := ctx.BindJSON(&dataList); err != nil {
 		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Expected JSON array of objects")
 		return
 	}
 
-	results, err := h.crudService.BatchCreate(modelID, dataList)
+	results, err := h.crudService.BatchCreate(c, modelID, dataList)
 	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
@@ -106,15 +141,31 @@ func (h *DataQueryHandler) HandleBatchCreateWithModelID(ctx *app.RequestContext,
 	utils.SuccessResponse(ctx, results)
 }
 
+// HandleBatchUpdateWithModelID 批量更新
+func (h *DataQueryHandler) HandleBatchUpdateWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var dataList []map[string]any
+	if err := ctx.BindJSON(&dataList); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Expected JSON array of objects")
+		return
+	}
+
+	if err := h.crudService.BatchUpdate(c, modelID, dataList); err != nil {
+		crudErrorResponse(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, "Updated successfully")
+}
+
 // HandleBatchDeleteWithModelID 批量删除
-func (h *DataQueryHandler) HandleBatchDeleteWithModelID(ctx *app.RequestContext, modelID string) {
+func (h *DataQueryHandler) HandleBatchDeleteWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
 	var ids []string
 	if err := ctx.BindJSON(&ids); err != nil {
 		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Expected JSON array of IDs")
 		return
 	}
 
-	err := h.crudService.BatchDelete(modelID, ids)
+	err := h.crudService.BatchDelete(c, modelID, ids)
 	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
@@ -123,8 +174,112 @@ func (h *DataQueryHandler) HandleBatchDeleteWithModelID(ctx *app.RequestContext,
 	utils.SuccessResponse(ctx, "Deleted successfully")
 }
 
+// BulkWhereRequest 条件批量更新/删除请求，filter 与统一查询参数一致
+type BulkWhereRequest struct {
+	Filter map[string]any `json:"filter"`
+	Data   map[string]any `json:"data"`
+	service.BulkOptions
+}
+
+// HandleUpdateWhereWithModelID 按条件批量更新
+func (h *DataQueryHandler) HandleUpdateWhereWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var req BulkWhereRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.crudService.UpdateWhere(c, modelID, req.Filter, req.Data, req.BulkOptions)
+	if err != nil {
+		crudErrorResponse(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, result)
+}
+
+// HandleDeleteWhereWithModelID 按条件批量删除
+func (h *DataQueryHandler) HandleDeleteWhereWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var req BulkWhereRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.crudService.DeleteWhere(c, modelID, req.Filter, req.BulkOptions)
+	if err != nil {
+		crudErrorResponse(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, result)
+}
+
+// UpsertRequest 新增或更新请求，单条使用 data，批量使用 list
+type UpsertRequest struct {
+	Data map[string]any   `json:"data"`
+	List []map[string]any `json:"list"`
+	service.UpsertOptions
+}
+
+// HandleUpsertWithModelID 按唯一键新增或更新
+func (h *DataQueryHandler) HandleUpsertWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var req UpsertRequest
+	if err := ctx.BindJSON(&req); err != nil || req.Data == nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Expected JSON object with data")
+		return
+	}
+
+	result, err := h.crudService.Upsert(c, modelID, req.Data, req.UpsertOptions)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, result)
+}
+
+// HandleBatchUpsertWithModelID 按唯一键批量新增或更新
+func (h *DataQueryHandler) HandleBatchUpsertWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var req UpsertRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Expected JSON object with list")
+		return
+	}
+
+	results, err := h.crudService.BatchUpsert(c, modelID, req.List, req.UpsertOptions)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, results)
+}
+
+// HandleRestoreWithModelID 恢复已逻辑删除的数据
+func (h *DataQueryHandler) HandleRestoreWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	id := ctx.Param("id")
+	if err := h.crudService.Restore(c, modelID, id); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, "Restored successfully")
+}
+
+// HandlePurgeWithModelID 物理删除已逻辑删除的数据
+func (h *DataQueryHandler) HandlePurgeWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	id := ctx.Param("id")
+	if err := h.crudService.Purge(c, modelID, id); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, "Purged successfully")
+}
+
 // HandleStatisticsWithModelID 统计查询
-func (h *DataQueryHandler) HandleStatisticsWithModelID(ctx *app.RequestContext, modelID string) {
+func (h *DataQueryHandler) HandleStatisticsWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
 	var body map[string]any
 	// 允许空 body
 	if string(ctx.Request.Body()) != "" {
@@ -134,7 +289,18 @@ func (h *DataQueryHandler) HandleStatisticsWithModelID(ctx *app.RequestContext,
 		}
 	}
 
-	result, err := h.crudService.Statistics(modelID, body)
+	// 提供分组统计参数时按分组返回统计结果
+	if _, ok := body[engine.ParamStats]; ok {
+		results, err := h.crudService.Aggregate(c, modelID, body)
+		if err != nil {
+			utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SuccessResponse(ctx, results)
+		return
+	}
+
+	result, err := h.crudService.Statistics(c, modelID, body)
 	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
@@ -144,7 +310,7 @@ func (h *DataQueryHandler) HandleStatisticsWithModelID(ctx *app.RequestContext,
 }
 
 // HandleAggregateWithModelID 聚合查询
-func (h *DataQueryHandler) HandleAggregateWithModelID(ctx *app.RequestContext, modelID string) {
+func (h *DataQueryHandler) HandleAggregateWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
 	var body map[string]any
 	// 允许空 body
 	if string(ctx.Request.Body()) != "" {
@@ -154,7 +320,7 @@ func (h *DataQueryHandler) HandleAggregateWithModelID(ctx *app.RequestContext, m
 		}
 	}
 
-	results, err := h.crudService.Aggregate(modelID, body)
+	results, err := h.crudService.Aggregate(c, modelID, body)
 	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
@@ -163,6 +329,23 @@ func (h *DataQueryHandler) HandleAggregateWithModelID(ctx *app.RequestContext, m
 	utils.SuccessResponse(ctx, results)
 }
 
+// HandlePivotWithModelID 透视查询
+func (h *DataQueryHandler) HandlePivotWithModelID(c context.Context, ctx *app.RequestContext, modelID string) {
+	var body map[string]any
+	if err := ctx.BindJSON(&body); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.crudService.Pivot(c, modelID, body)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, result)
+}
+
 // PreviewVisualModelSQL 预览可视化模型 SQL
 func (h *DataQueryHandler) PreviewVisualModelSQL(c context.Context, ctx *app.RequestContext) {
 	var req SaveVisualModelRequest
@@ -227,7 +410,7 @@ func (h *DataQueryHandler) PreviewVisualModelSQL(c context.Context, ctx *app.Req
 	// 检查是否需要执行查询
 	execute := ctx.QueryArgs().Peek("execute")
 	if string(execute) == "true" {
-		data, count, err := h.crudService.ExecuteModelData(modelData, nil)
+		data, count, err := h.crudService.ExecuteModelData(h.dataScope.Context(c, ctx), modelData, nil)
 		if err != nil {
 			utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
 			return
@@ -257,3 +440,13 @@ func (h *DataQueryHandler) PreviewVisualModelSQL(c context.Context, ctx *app.Req
 		"args": args,
 	})
 }
+
+// crudErrorResponse 输出 CRUD 错误，乐观锁冲突时返回 409 并携带当前记录
+func crudErrorResponse(ctx *app.RequestContext, err error) {
+	var conflict *service.VersionConflictError
+	if errors.As(err, &conflict) {
+		utils.ConflictResponse(ctx, err.Error(), conflict.Current)
+		return
+	}
+	utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+}
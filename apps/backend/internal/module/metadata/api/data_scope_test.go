@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+func TestDataScopeBinder_Unauthenticated(t *testing.T) {
+	md := &engine.ModelData{Model: &model.MdModel{DataTenantField: "tenant_id"}}
+	column := func(name string) string { return name }
+
+	// 未认证时忽略客户端伪造的 X-User-ID，拒绝访问全部数据
+	ctx := app.NewContext(0)
+	ctx.Request.Header.Set("X-User-ID", "u1")
+	scope := engine.DataScopeFromContext(NewDataScopeBinder(nil).Context(context.Background(), ctx))
+	require.NotNil(t, scope)
+	assert.True(t, scope.Deny)
+	assert.Empty(t, scope.UserID)
+
+	cond, _ := md.DataScopeCondition(scope, column)
+	assert.Equal(t, "1 = 0", cond)
+	assert.False(t, md.DataScopeAllows(scope, map[string]any{"tenant_id": "1"}))
+
+	// 认证用户为管理员时不过滤
+	ctx = app.NewContext(0)
+	ctx.Set("user_id", "u1")
+	ctx.Set("is_admin", true)
+	c := NewDataScopeBinder(nil).Context(context.Background(), ctx)
+	assert.Nil(t, engine.DataScopeFromContext(c))
+}
+
+// failingScopeService 数据权限解析总是失败，如请求头指定了用户不属于的租户
+type failingScopeService struct{}
+
+func (failingScopeService) Resolve(userID, tenantID, path, method string) (*engine.DataScope, error) {
+	return nil, errors.New("用户不属于该租户")
+}
+
+func TestDataScopeBinder_ResolveFailure(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+	ctx := app.NewContext(0)
+	ctx.Set("user_id", "u1")
+	ctx.Request.Header.Set("X-Tenant-ID", "t2")
+	scope := engine.DataScopeFromContext(NewDataScopeBinder(failingScopeService{}).Context(context.Background(), ctx))
+	require.NotNil(t, scope)
+	assert.True(t, scope.Deny)
+	assert.Empty(t, scope.TenantID)
+}
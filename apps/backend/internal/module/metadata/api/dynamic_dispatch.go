@@ -0,0 +1,128 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/route/param"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// dynamicRoute 动态接口路由，路径支持 :name 参数和末尾的 *name 通配
+type dynamicRoute struct {
+	method   string
+	path     string
+	code     string
+	segments []string
+	static   int // 静态段数量，用于匹配优先级
+	wildcard bool
+	handler  app.HandlerFunc
+}
+
+// dispatchTable 动态接口分发表，只读，接口变更时整体替换
+type dispatchTable struct {
+	static map[string]*dynamicRoute // method + " " + path
+	routes []*dynamicRoute          // 带参数的路由，按优先级排序
+}
+
+// newDispatchTable 由启用状态的接口构建分发表，同一方法和路径重复时保留先出现的接口
+func newDispatchTable(apis []model.API, handlerFor func(apiCode string) app.HandlerFunc) *dispatchTable {
+	t := &dispatchTable{static: make(map[string]*dynamicRoute)}
+	seen := make(map[string]bool)
+	for _, a := range apis {
+		if a.Status != 1 || a.IsDeleted {
+			continue
+		}
+		route := &dynamicRoute{
+			method:   strings.ToUpper(a.Method),
+			path:     normalizeRoutePath(a.Path),
+			code:     a.Code,
+			segments: splitRoutePath(a.Path),
+		}
+		key := route.method + " " + route.path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		for i, seg := range route.segments {
+			switch {
+			case strings.HasPrefix(seg, "*") && i == len(route.segments)-1:
+				route.wildcard = true
+			case !strings.HasPrefix(seg, ":"):
+				route.static++
+			}
+		}
+		route.handler = handlerFor(a.Code)
+		if route.static == len(route.segments) {
+			t.static[key] = route
+			continue
+		}
+		t.routes = append(t.routes, route)
+	}
+
+	// 静态段多的优先，通配路由最后
+	sort.SliceStable(t.routes, func(i, j int) bool {
+		a, b := t.routes[i], t.routes[j]
+		if a.wildcard != b.wildcard {
+			return !a.wildcard
+		}
+		return a.static > b.static
+	})
+	return t
+}
+
+// match 查找请求对应的路由和路径参数
+func (t *dispatchTable) match(method, path string) (*dynamicRoute, param.Params) {
+	method = strings.ToUpper(method)
+	if route, ok := t.static[method+" "+normalizeRoutePath(path)]; ok {
+		return route, nil
+	}
+	segments := splitRoutePath(path)
+	for _, route := range t.routes {
+		if route.method != method {
+			continue
+		}
+		if params, ok := route.matchSegments(segments); ok {
+			return route, params
+		}
+	}
+	return nil, nil
+}
+
+// matchSegments 逐段匹配请求路径
+func (r *dynamicRoute) matchSegments(segments []string) (param.Params, bool) {
+	if len(segments) < len(r.segments) || (!r.wildcard && len(segments) != len(r.segments)) {
+		return nil, false
+	}
+	var params param.Params
+	for i, seg := range r.segments {
+		switch {
+		case r.wildcard && i == len(r.segments)-1:
+			params = append(params, param.Param{Key: seg[1:], Value: "/" + strings.Join(segments[i:], "/")})
+		case strings.HasPrefix(seg, ":"):
+			params = append(params, param.Param{Key: seg[1:], Value: segments[i]})
+		case seg != segments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// normalizeRoutePath 去掉路径末尾的斜杠
+func normalizeRoutePath(path string) string {
+	return "/" + strings.Join(splitRoutePath(path), "/")
+}
+
+// splitRoutePath 按斜杠拆分路径，忽略空段
+func splitRoutePath(path string) []string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
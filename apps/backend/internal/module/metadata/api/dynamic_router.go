@@ -4,64 +4,145 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 
 	globalMiddleware "metadata-platform/internal/middleware"
+	"metadata-platform/internal/module/metadata/engine"
 	"metadata-platform/internal/module/metadata/service"
 	"metadata-platform/internal/utils"
 )
 
-// DynamicRouter 动态路由注册器
+// DynamicAPIPollInterval 检查其他实例接口变更的间隔
+const DynamicAPIPollInterval = 5 * time.Second
+
+// DynamicRouter 动态路由分发器。Hertz 不支持注销路由，动态接口不注册到 Hertz，
+// 而是由未匹配静态路由的请求查找分发表，接口变更时整体替换分发表
 type DynamicRouter struct {
 	*utils.BaseHandler
 	hertz        *server.Hertz
 	svc          *service.Services
 	queryHandler *DataQueryHandler
+	middlewares  app.HandlersChain // 租户、认证及审计中间件
+	handlerFor   func(apiCode string) app.HandlerFunc
+
+	mu       sync.Mutex // 串行化重新加载
+	table    atomic.Pointer[dispatchTable]
+	revision atomic.Int64
 }
 
-// NewDynamicRouter 创建动态路由注册器实例
+// NewDynamicRouter 创建动态路由分发器实例
 func NewDynamicRouter(hertz *server.Hertz, svc *service.Services) *DynamicRouter {
-	return &DynamicRouter{
+	r := &DynamicRouter{
 		BaseHandler:  utils.NewBaseHandler(),
 		hertz:        hertz,
 		svc:          svc,
-		queryHandler: NewDataQueryHandler(svc.CRUD, svc.Model),
+		queryHandler: NewDataQueryHandler(svc.CRUD, svc.Model, svc.DataScope),
+		middlewares: app.HandlersChain{
+			globalMiddleware.TenantMiddleware(),
+			globalMiddleware.AuthMiddleware(),
+			globalMiddleware.AuditMiddleware(svc.Audit, "metadata"),
+		},
 	}
+	r.handlerFor = r.getGenericHandler
+	r.table.Store(newDispatchTable(nil, r.handlerFor))
+	return r
 }
 
-// LoadAndRegisterAll 加载并注册所有活跃的动态接口
+// LoadAndRegisterAll 加载所有启用的动态接口，订阅本实例的接口变更并轮询其他实例的变更
 func (r *DynamicRouter) LoadAndRegisterAll() error {
+	r.svc.API.OnChange(func() {
+		if err := r.Reload(); err != nil {
+			utils.SugarLogger.Errorf("Failed to reload dynamic routes: %v", err)
+		}
+	})
+	go r.watch(DynamicAPIPollInterval)
+	return r.Reload()
+}
+
+// Reload 重新加载动态接口并替换分发表，先读取版本号，加载期间发生的变更由下次检查处理
+func (r *DynamicRouter) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision, err := r.svc.API.GetRevision()
+	if err != nil {
+		return err
+	}
 	apis, err := r.svc.API.GetAllAPIs()
 	if err != nil {
 		return err
 	}
+	table := newDispatchTable(apis, r.handlerFor)
+	r.table.Store(table)
+	r.revision.Store(revision)
+	utils.SugarLogger.Infof("Loaded %d dynamic routes (revision %d)", len(table.static)+len(table.routes), revision)
+	return nil
+}
 
-	for _, a := range apis {
-		if a.Status == 1 { // 仅注册启用状态的接口
-			r.RegisterAPI(a.Method, a.Path, a.Code)
+// watch 定期检查接口版本号，其他实例变更接口后重新加载
+func (r *DynamicRouter) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		revision, err := r.svc.API.GetRevision()
+		if err != nil {
+			utils.SugarLogger.Errorf("Failed to check dynamic route revision: %v", err)
+			continue
+		}
+		if revision == r.revision.Load() {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			utils.SugarLogger.Errorf("Failed to reload dynamic routes: %v", err)
 		}
 	}
-
-	return nil
 }
 
-// RegisterAPI 注册单个动态路由
-func (r *DynamicRouter) RegisterAPI(method, path, apiCode string) {
-	utils.SugarLogger.Infof("Registering dynamic route: [%s] %s (code: %s)", method, path, apiCode)
+// Dispatch 按分发表处理请求，没有匹配的动态接口时返回 false
+func (r *DynamicRouter) Dispatch(c context.Context, ctx *app.RequestContext) bool {
+	route, params := r.table.Load().match(string(ctx.Method()), string(ctx.Request.URI().Path()))
+	if route == nil {
+		return false
+	}
 
-	// 绑定通用处理器
-	// 注意：apiCode 在这里被用作查找 modelID 的依据（目前简单约定 apiCode = modelCode + "_" + method）
-	// 后续可以增加 MdModelAPI 关联表来更精确对应
+	ctx.Params = params
+	ctx.SetFullPath(route.path)
+	ctx.SetStatusCode(consts.StatusOK)
 
-	handler := r.getGenericHandler(apiCode)
+	// 以中间件和接口处理器替换当前处理链执行，结束后恢复
+	handlers, index := ctx.Handlers(), ctx.GetIndex()
+	chain := make(app.HandlersChain, 0, len(r.middlewares)+1)
+	chain = append(append(chain, r.middlewares...), route.handler)
+	ctx.SetHandlers(chain)
+	ctx.SetIndex(-1)
+	ctx.Next(c)
+	ctx.SetHandlers(handlers)
+	ctx.SetIndex(index)
+	return true
+}
 
-	// 添加审计中间件
-	auditMiddleware := globalMiddleware.AuditMiddleware(r.svc.Audit, "metadata")
+// handleExecute 调用存储过程模型，请求体为 IN/INOUT 参数值
+func (r *DynamicRouter) handleExecute(c context.Context, ctx *app.RequestContext, modelID string) {
+	var body map[string]any
+	if len(ctx.Request.Body()) > 0 {
+		if err := ctx.BindJSON(&body); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
 
-	r.hertz.Handle(method, path, auditMiddleware, handler)
+	result, err := r.svc.Procedure.ExecuteModel(c, modelID, body)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, result)
 }
 
 func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
@@ -77,15 +158,42 @@ func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
 		} else if before, ok := strings.CutSuffix(apiCode, "_BATCH_CREATE"); ok {
 			modelCode = before
 			handlerType = "BATCH_CREATE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_BATCH_UPDATE"); ok {
+			modelCode = before
+			handlerType = "BATCH_UPDATE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_BATCH_UPSERT"); ok {
+			modelCode = before
+			handlerType = "BATCH_UPSERT"
+		} else if before, ok := strings.CutSuffix(apiCode, "_UPSERT"); ok {
+			modelCode = before
+			handlerType = "UPSERT"
 		} else if before, ok := strings.CutSuffix(apiCode, "_BATCH_DELETE"); ok {
 			modelCode = before
 			handlerType = "BATCH_DELETE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_UPDATE_WHERE"); ok {
+			modelCode = before
+			handlerType = "UPDATE_WHERE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_DELETE_WHERE"); ok {
+			modelCode = before
+			handlerType = "DELETE_WHERE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_RESTORE"); ok {
+			modelCode = before
+			handlerType = "RESTORE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_PURGE"); ok {
+			modelCode = before
+			handlerType = "PURGE"
 		} else if before, ok := strings.CutSuffix(apiCode, "_STATISTICS"); ok {
 			modelCode = before
 			handlerType = "STATISTICS"
 		} else if before, ok := strings.CutSuffix(apiCode, "_AGGREGATE"); ok {
 			modelCode = before
 			handlerType = "AGGREGATE"
+		} else if before, ok := strings.CutSuffix(apiCode, "_PIVOT"); ok {
+			modelCode = before
+			handlerType = "PIVOT"
+		} else if before, ok := strings.CutSuffix(apiCode, "_EXECUTE"); ok {
+			modelCode = before
+			handlerType = "EXECUTE"
 		} else {
 			// 默认逻辑：取最后一个下划线前缀
 			if idx := lastIndex(apiCode, "_"); idx != -1 {
@@ -103,22 +211,52 @@ func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
 
 		method := string(ctx.Method())
 
+		// 解析当前用户的数据权限
+		c = r.queryHandler.dataScope.Context(c, ctx)
+
 		// 特殊处理器分发
 		switch handlerType {
 		case "QUERY":
-			r.queryHandler.HandleUnifiedQueryWithModelID(ctx, md.ID)
+			r.queryHandler.HandleUnifiedQueryWithModelID(c, ctx, md.ID)
 			return
 		case "BATCH_CREATE":
-			r.queryHandler.HandleBatchCreateWithModelID(ctx, md.ID)
+			r.queryHandler.HandleBatchCreateWithModelID(c, ctx, md.ID)
+			return
+		case "BATCH_UPDATE":
+			r.queryHandler.HandleBatchUpdateWithModelID(c, ctx, md.ID)
+			return
+		case "UPSERT":
+			r.queryHandler.HandleUpsertWithModelID(c, ctx, md.ID)
+			return
+		case "BATCH_UPSERT":
+			r.queryHandler.HandleBatchUpsertWithModelID(c, ctx, md.ID)
 			return
 		case "BATCH_DELETE":
-			r.queryHandler.HandleBatchDeleteWithModelID(ctx, md.ID)
+			r.queryHandler.HandleBatchDeleteWithModelID(c, ctx, md.ID)
+			return
+		case "UPDATE_WHERE":
+			r.queryHandler.HandleUpdateWhereWithModelID(c, ctx, md.ID)
+			return
+		case "DELETE_WHERE":
+			r.queryHandler.HandleDeleteWhereWithModelID(c, ctx, md.ID)
+			return
+		case "RESTORE":
+			r.queryHandler.HandleRestoreWithModelID(c, ctx, md.ID)
+			return
+		case "PURGE":
+			r.queryHandler.HandlePurgeWithModelID(c, ctx, md.ID)
 			return
 		case "STATISTICS":
-			r.queryHandler.HandleStatisticsWithModelID(ctx, md.ID)
+			r.queryHandler.HandleStatisticsWithModelID(c, ctx, md.ID)
 			return
 		case "AGGREGATE":
-			r.queryHandler.HandleAggregateWithModelID(ctx, md.ID)
+			r.queryHandler.HandleAggregateWithModelID(c, ctx, md.ID)
+			return
+		case "PIVOT":
+			r.queryHandler.HandlePivotWithModelID(c, ctx, md.ID)
+			return
+		case "EXECUTE":
+			r.handleExecute(c, ctx, md.ID)
 			return
 		}
 
@@ -145,7 +283,11 @@ func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
 		case "GET":
 			id := ctx.Param("id")
 			if id != "" {
-				res, err := r.svc.CRUD.Get(md.ID, id)
+				var params map[string]any
+				if expand := ctx.Query(engine.ParamExpand); expand != "" {
+					params = map[string]any{engine.ParamExpand: expand}
+				}
+				res, err := r.svc.CRUD.GetWithParams(c, md.ID, id, params)
 				if err != nil {
 					utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 					return
@@ -156,7 +298,13 @@ func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
 				}
 				utils.SuccessResponse(ctx, res)
 			} else {
-				res, count, err := r.svc.CRUD.List(md.ID, nil)
+				params := make(map[string]any)
+				for _, key := range []string{engine.ParamIncludeDeleted, engine.ParamExpand} {
+					if value := ctx.Query(key); value != "" {
+						params[key] = value
+					}
+				}
+				res, count, err := r.svc.CRUD.List(c, md.ID, params)
 				if err != nil {
 					utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 					return
@@ -175,7 +323,7 @@ func (r *DynamicRouter) getGenericHandler(apiCode string) app.HandlerFunc {
 				return
 			}
 			if err := r.svc.CRUD.Update(c, md.ID, id, data); err != nil {
-				utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+				crudErrorResponse(ctx, err)
 				return
 			}
 			utils.SuccessResponse(ctx, nil)
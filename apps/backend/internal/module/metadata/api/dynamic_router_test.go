@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/service"
+	"metadata-platform/internal/utils"
+)
+
+// fakeAPIService 内存中的接口服务，只实现动态路由用到的方法
+type fakeAPIService struct {
+	service.APIService
+	apis      []model.API
+	revision  int64
+	listeners []func()
+}
+
+func (s *fakeAPIService) GetAllAPIs() ([]model.API, error) { return s.apis, nil }
+func (s *fakeAPIService) GetRevision() (int64, error)      { return s.revision, nil }
+func (s *fakeAPIService) OnChange(fn func())               { s.listeners = append(s.listeners, fn) }
+func (s *fakeAPIService) NotifyChange() error {
+	s.revision++
+	for _, fn := range s.listeners {
+		fn()
+	}
+	return nil
+}
+
+func codeHandler(apiCode string) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, apiCode+":"+ctx.Param("id")+ctx.Param("path"))
+	}
+}
+
+func TestDispatchTable_Match(t *testing.T) {
+	table := newDispatchTable([]model.API{
+		{Code: "USER_GET", Method: "GET", Path: "/api/data/user/:id", Status: 1},
+		{Code: "USER_ME", Method: "get", Path: "/api/data/user/me/", Status: 1},
+		{Code: "USER_UPDATE", Method: "PUT", Path: "/api/data/user/:id", Status: 1},
+		{Code: "FILES", Method: "GET", Path: "/api/files/*path", Status: 1},
+		{Code: "DISABLED", Method: "GET", Path: "/api/data/order", Status: 0},
+		{Code: "DELETED", Method: "GET", Path: "/api/data/item", Status: 1, IsDeleted: true},
+		{Code: "USER_GET_DUP", Method: "GET", Path: "/api/data/user/:id", Status: 1},
+	}, codeHandler)
+
+	tests := []struct {
+		method, path, code string
+		params             map[string]string
+	}{
+		{"GET", "/api/data/user/me", "USER_ME", nil},
+		{"GET", "/api/data/user/me/", "USER_ME", nil},
+		{"GET", "/api/data/user/42", "USER_GET", map[string]string{"id": "42"}},
+		{"PUT", "/api/data/user/42", "USER_UPDATE", map[string]string{"id": "42"}},
+		{"GET", "/api/files/a/b.txt", "FILES", map[string]string{"path": "/a/b.txt"}},
+		{"DELETE", "/api/data/user/42", "", nil},
+		{"GET", "/api/data/user/42/extra", "", nil},
+		{"GET", "/api/data/order", "", nil},
+		{"GET", "/api/data/item", "", nil},
+	}
+	for _, tt := range tests {
+		route, params := table.match(tt.method, tt.path)
+		if tt.code == "" {
+			assert.Nil(t, route, "%s %s", tt.method, tt.path)
+			continue
+		}
+		require.NotNil(t, route, "%s %s", tt.method, tt.path)
+		assert.Equal(t, tt.code, route.code)
+		for k, v := range tt.params {
+			got, _ := params.Get(k)
+			assert.Equal(t, v, got)
+		}
+	}
+}
+
+func TestDynamicRouter_HotReload(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	apiSvc := &fakeAPIService{apis: []model.API{
+		{Code: "USER_GET", Method: "GET", Path: "/api/data/user/:id", Status: 1},
+	}}
+	h := server.Default()
+	r := NewDynamicRouter(h, &service.Services{API: apiSvc})
+	r.handlerFor = codeHandler
+	h.NoRoute(func(c context.Context, ctx *app.RequestContext) {
+		if !r.Dispatch(c, ctx) {
+			ctx.String(consts.StatusNotFound, "not found")
+		}
+	})
+	require.NoError(t, r.LoadAndRegisterAll())
+
+	// 动态接口同样需要认证
+	w := ut.PerformRequest(h.Engine, "GET", "/api/data/user/7", nil)
+	assert.Equal(t, consts.StatusUnauthorized, w.Code)
+
+	r.middlewares = nil
+	w = ut.PerformRequest(h.Engine, "GET", "/api/data/user/7", nil)
+	assert.Equal(t, consts.StatusOK, w.Code)
+	assert.Equal(t, "USER_GET:7", w.Body.String())
+
+	// 新增接口和停用接口后无需重启即可生效
+	apiSvc.apis = []model.API{
+		{Code: "USER_GET", Method: "GET", Path: "/api/data/user/:id", Status: 0},
+		{Code: "ORDER_LIST", Method: "GET", Path: "/api/data/order", Status: 1},
+	}
+	require.NoError(t, apiSvc.NotifyChange())
+	assert.Equal(t, int64(1), r.revision.Load())
+
+	w = ut.PerformRequest(h.Engine, "GET", "/api/data/user/7", nil)
+	assert.Equal(t, consts.StatusNotFound, w.Code)
+	w = ut.PerformRequest(h.Engine, "GET", "/api/data/order", nil)
+	assert.Equal(t, consts.StatusOK, w.Code)
+	assert.Equal(t, "ORDER_LIST:", w.Body.String())
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"metadata-platform/internal/module/metadata/service"
 	"metadata-platform/internal/utils"
 
@@ -13,13 +14,15 @@ import (
 type MasterDetailHandler struct {
 	*utils.BaseHandler
 	mdService service.MasterDetailService
+	dataScope *DataScopeBinder
 }
 
 // NewMasterDetailHandler 创建主子表处理器实例
-func NewMasterDetailHandler(mdService service.MasterDetailService) *MasterDetailHandler {
+func NewMasterDetailHandler(mdService service.MasterDetailService, dataScope service.DataScopeService) *MasterDetailHandler {
 	return &MasterDetailHandler{
 		BaseHandler: utils.NewBaseHandler(),
 		mdService:   mdService,
+		dataScope:   NewDataScopeBinder(dataScope),
 	}
 }
 
@@ -34,9 +37,57 @@ func (h *MasterDetailHandler) CreateMasterDetail(c context.Context, ctx *app.Req
 		return
 	}
 
-	if err := h.mdService.CreateMasterDetail(c, masterModelID, detailModelID, payload); err != nil {
+	result, err := h.mdService.CreateMasterDetail(h.dataScope.Context(c, ctx), masterModelID, detailModelID, payload)
+	if err != nil {
 		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
 		return
 	}
-	utils.SuccessResponse(ctx, "Created successfully")
+	utils.SuccessResponse(ctx, result)
+}
+
+// GetMasterDetail 获取主表记录及嵌套的子表数据
+func (h *MasterDetailHandler) GetMasterDetail(c context.Context, ctx *app.RequestContext) {
+	result, err := h.mdService.GetMasterDetail(h.dataScope.Context(c, ctx), ctx.Param("master"), ctx.Param("detail"), ctx.Param("id"))
+	if err != nil {
+		masterDetailErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, result)
+}
+
+// UpdateMasterDetail 更新主表记录并同步子表数据
+func (h *MasterDetailHandler) UpdateMasterDetail(c context.Context, ctx *app.RequestContext) {
+	var payload map[string]any
+	if err := ctx.BindJSON(&payload); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.mdService.UpdateMasterDetail(h.dataScope.Context(c, ctx), ctx.Param("master"), ctx.Param("detail"), ctx.Param("id"), payload)
+	if err != nil {
+		masterDetailErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, result)
+}
+
+// DeleteMasterDetail 删除主表记录，子表数据按关系的删除规则处理
+func (h *MasterDetailHandler) DeleteMasterDetail(c context.Context, ctx *app.RequestContext) {
+	if err := h.mdService.DeleteMasterDetail(h.dataScope.Context(c, ctx), ctx.Param("master"), ctx.Param("detail"), ctx.Param("id")); err != nil {
+		masterDetailErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, "Deleted successfully")
+}
+
+// masterDetailErrorResponse 主表记录不存在返回 404，受限删除返回 409，其余同数据操作错误
+func masterDetailErrorResponse(ctx *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrMasterNotFound):
+		utils.NotFoundResponse(ctx, err.Error())
+	case errors.Is(err, service.ErrDetailRestricted):
+		utils.ConflictResponse(ctx, err.Error(), nil)
+	default:
+		crudErrorResponse(ctx, err)
+	}
 }
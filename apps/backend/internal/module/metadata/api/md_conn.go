@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"metadata-platform/internal/module/metadata/engine"
 	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/service"
 	"metadata-platform/internal/utils"
@@ -15,13 +16,17 @@ import (
 type MdConnHandler struct {
 	*utils.BaseHandler
 	connService service.MdConnService
+	enhService  service.MdModelFieldEnhancementService
+	dataScope   *DataScopeBinder
 }
 
 // NewMdConnHandler 创建数据连接API处理器实例
-func NewMdConnHandler(connService service.MdConnService) *MdConnHandler {
+func NewMdConnHandler(connService service.MdConnService, enhService service.MdModelFieldEnhancementService, dataScope service.DataScopeService) *MdConnHandler {
 	return &MdConnHandler{
 		BaseHandler: utils.NewBaseHandler(),
 		connService: connService,
+		enhService:  enhService,
+		dataScope:   NewDataScopeBinder(dataScope),
 	}
 }
 
@@ -388,6 +393,16 @@ func (h *MdConnHandler) PreviewTableData(c context.Context, ctx *app.RequestCont
 		return
 	}
 
+	// 应用模型字段上配置的脱敏策略
+	if h.enhService != nil {
+		policies, err := h.enhService.GetTableMaskPolicies(conn.ID, schema, table)
+		if err != nil {
+			utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+			return
+		}
+		engine.ApplyMasks(data, policies, engine.DataScopeFromContext(h.dataScope.Context(c, ctx)))
+	}
+
 	utils.SuccessResponse(ctx, data)
 }
 
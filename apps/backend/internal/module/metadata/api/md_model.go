@@ -87,26 +87,36 @@ type CreateModelRequest struct {
 	ParentID     string `json:"parent_id"`
 }
 
-// UpdateModelRequest 更新模型请求
+// UpdateModelRequest 更新模型请求，指针类型的配置未提交时保持不变，提交空字符串时清除
 type UpdateModelRequest struct {
-	ModelName    string `json:"model_name"`
-	ModelVersion string `json:"model_version"`
-	ModelLogo    string `json:"model_logo"`
-	IsPublic     bool   `json:"is_public"`
-	IsLocked     bool   `json:"is_locked"`
+	ModelName           string  `json:"model_name"`
+	ModelVersion        string  `json:"model_version"`
+	ModelLogo           string  `json:"model_logo"`
+	IsPublic            bool    `json:"is_public"`
+	IsLocked            bool    `json:"is_locked"`
+	LogicDeleteField    *string `json:"logic_delete_field"`
+	LogicDeleteValue    *string `json:"logic_delete_value"`
+	LogicNotDeleteValue *string `json:"logic_not_delete_value"`
+	VersionField        *string `json:"version_field"`
+	UniqueKeyFields     *string `json:"unique_key_fields"`
+	DataOrgField        *string `json:"data_org_field"`
+	DataOwnerField      *string `json:"data_owner_field"`
+	DataTenantField     *string `json:"data_tenant_field"`
 }
 
 // CreateModelFieldRequest 创建模型字段请求
 type CreateModelFieldRequest struct {
-	ModelID     string `json:"model_id" binding:"required"`
-	TableSchema string `json:"table_schema"`
-	TableName   string `json:"table_name" binding:"required"`
-	ColumnName  string `json:"column_name" binding:"required"`
-	ColumnTitle string `json:"column_title"`
-	Func        string `json:"func"`
-	AggFunc     string `json:"agg_func"`
-	ShowTitle   string `json:"show_title"`
-	ShowWidth   int    `json:"show_width"`
+	ModelID         string `json:"model_id" binding:"required"`
+	TableSchema     string `json:"table_schema"`
+	TableName       string `json:"table_name" binding:"required"`
+	ColumnName      string `json:"column_name" binding:"required"`
+	ColumnTitle     string `json:"column_title"`
+	Func            string `json:"func"`
+	AggFunc         string `json:"agg_func"`
+	ShowTitle       string `json:"show_title"`
+	ShowWidth       int    `json:"show_width"`
+	EncryptAlgo     string `json:"encrypt_algo"`
+	BlindIndexField string `json:"blind_index_field"`
 }
 
 // SaveVisualModelRequest 可视化构建保存模型请求
@@ -132,11 +142,13 @@ type SaveVisualModelRequest struct {
 
 // UpdateModelFieldRequest 更新模型字段请求
 type UpdateModelFieldRequest struct {
-	ColumnTitle string `json:"column_title"`
-	Func        string `json:"func"`
-	AggFunc     string `json:"agg_func"`
-	ShowTitle   string `json:"show_title"`
-	ShowWidth   int    `json:"show_width"`
+	ColumnTitle     string `json:"column_title"`
+	Func            string `json:"func"`
+	AggFunc         string `json:"agg_func"`
+	ShowTitle       string `json:"show_title"`
+	ShowWidth       int    `json:"show_width"`
+	EncryptAlgo     string `json:"encrypt_algo"`
+	BlindIndexField string `json:"blind_index_field"`
 }
 
 // BuildFromTable 从表构建模型
@@ -407,6 +419,30 @@ func (h *MdModelHandler) UpdateModel(c context.Context, ctx *app.RequestContext)
 	}
 	model.IsPublic = req.IsPublic
 	model.IsLocked = req.IsLocked
+	if req.LogicDeleteField != nil {
+		model.LogicDeleteField = *req.LogicDeleteField
+	}
+	if req.LogicDeleteValue != nil {
+		model.LogicDeleteValue = *req.LogicDeleteValue
+	}
+	if req.LogicNotDeleteValue != nil {
+		model.LogicNotDeleteValue = *req.LogicNotDeleteValue
+	}
+	if req.VersionField != nil {
+		model.VersionField = *req.VersionField
+	}
+	if req.UniqueKeyFields != nil {
+		model.UniqueKeyFields = *req.UniqueKeyFields
+	}
+	if req.DataOrgField != nil {
+		model.DataOrgField = *req.DataOrgField
+	}
+	if req.DataOwnerField != nil {
+		model.DataOwnerField = *req.DataOwnerField
+	}
+	if req.DataTenantField != nil {
+		model.DataTenantField = *req.DataTenantField
+	}
 
 	userID, _ := ctx.Get("user_id")
 	username, _ := ctx.Get("username")
@@ -547,20 +583,22 @@ func (h *MdModelHandler) CreateModelField(c context.Context, ctx *app.RequestCon
 	username, _ := ctx.Get("username")
 
 	field := &model.MdModelField{
-		ModelID:      req.ModelID,
-		TableSchema:  req.TableSchema,
-		TableNameStr: req.TableName,
-		ColumnName:   req.ColumnName,
-		ColumnTitle:  req.ColumnTitle,
-		Func:         req.Func,
-		AggFunc:      req.AggFunc,
-		ShowTitle:    req.ShowTitle,
-		ShowWidth:    req.ShowWidth,
-		TenantID:     strconv.FormatUint(uint64(tenantID.(uint)), 10),
-		CreateID:     userID.(string),
-		CreateBy:     username.(string),
-		UpdateID:     userID.(string),
-		UpdateBy:     username.(string),
+		ModelID:         req.ModelID,
+		TableSchema:     req.TableSchema,
+		TableNameStr:    req.TableName,
+		ColumnName:      req.ColumnName,
+		ColumnTitle:     req.ColumnTitle,
+		Func:            req.Func,
+		AggFunc:         req.AggFunc,
+		ShowTitle:       req.ShowTitle,
+		ShowWidth:       req.ShowWidth,
+		EncryptAlgo:     req.EncryptAlgo,
+		BlindIndexField: req.BlindIndexField,
+		TenantID:        strconv.FormatUint(uint64(tenantID.(uint)), 10),
+		CreateID:        userID.(string),
+		CreateBy:        username.(string),
+		UpdateID:        userID.(string),
+		UpdateBy:        username.(string),
 	}
 
 	if err := h.modelService.CreateField(field); err != nil {
@@ -589,14 +627,16 @@ func (h *MdModelHandler) UpdateModelField(c context.Context, ctx *app.RequestCon
 	username, _ := ctx.Get("username")
 
 	field := &model.MdModelField{
-		ID:          id,
-		ColumnTitle: req.ColumnTitle,
-		Func:        req.Func,
-		AggFunc:     req.AggFunc,
-		ShowTitle:   req.ShowTitle,
-		ShowWidth:   req.ShowWidth,
-		UpdateID:    userID.(string),
-		UpdateBy:    username.(string),
+		ID:              id,
+		ColumnTitle:     req.ColumnTitle,
+		Func:            req.Func,
+		AggFunc:         req.AggFunc,
+		ShowTitle:       req.ShowTitle,
+		ShowWidth:       req.ShowWidth,
+		EncryptAlgo:     req.EncryptAlgo,
+		BlindIndexField: req.BlindIndexField,
+		UpdateID:        userID.(string),
+		UpdateBy:        username.(string),
 	}
 
 	if err := h.modelService.UpdateField(field); err != nil {
@@ -60,6 +60,12 @@ type ImportProceduresRequest struct {
 	Procedures []map[string]interface{} `json:"procedures" binding:"required"`
 }
 
+// BuildProcedureModelRequest 由存储过程/函数创建模型请求
+type BuildProcedureModelRequest struct {
+	ModelName string `json:"model_name"`
+	ModelCode string `json:"model_code"`
+}
+
 // CreateProcedure 创建存储过程/函数
 func (h *MdModelProcedureHandler) CreateProcedure(c context.Context, ctx *app.RequestContext) {
 	var req CreateProcedureRequest
@@ -210,3 +216,50 @@ func (h *MdModelProcedureHandler) GetParamsByProcID(c context.Context, ctx *app.
 	}
 	utils.SuccessResponse(ctx, params)
 }
+
+// ExecuteProcedure 调用存储过程/函数，请求体为 IN/INOUT 参数值
+func (h *MdModelProcedureHandler) ExecuteProcedure(c context.Context, ctx *app.RequestContext) {
+	var values map[string]any
+	if len(ctx.Request.Body()) > 0 {
+		if err := ctx.BindJSON(&values); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	result, err := h.procService.Execute(c, ctx.Param("id"), values)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, result)
+}
+
+// BuildProcedureModel 由存储过程/函数创建模型
+func (h *MdModelProcedureHandler) BuildProcedureModel(c context.Context, ctx *app.RequestContext) {
+	var req BuildProcedureModelRequest
+	if len(ctx.Request.Body()) > 0 {
+		if err := ctx.BindJSON(&req); err != nil {
+			utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	tenantID, _ := ctx.Get("tenant_id")
+	userID, _ := ctx.Get("user_id")
+	username, _ := ctx.Get("username")
+
+	md, err := h.procService.BuildModel(&service.BuildFromProcedureRequest{
+		ProcID:    ctx.Param("id"),
+		ModelName: req.ModelName,
+		ModelCode: req.ModelCode,
+		TenantID:  strconv.FormatUint(uint64(tenantID.(uint)), 10),
+		UserID:    userID.(string),
+		Username:  username.(string),
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, md)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"strconv"
+
+	"metadata-platform/internal/module/metadata/service"
+	"metadata-platform/internal/utils"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// ReEncryptHandler 加密字段重新加密处理器
+type ReEncryptHandler struct {
+	*utils.BaseHandler
+	reEncryptService service.ReEncryptService
+	dataScope        *DataScopeBinder
+}
+
+// NewReEncryptHandler 创建重新加密处理器实例
+func NewReEncryptHandler(reEncryptService service.ReEncryptService, dataScope service.DataScopeService) *ReEncryptHandler {
+	return &ReEncryptHandler{
+		BaseHandler:      utils.NewBaseHandler(),
+		reEncryptService: reEncryptService,
+		dataScope:        NewDataScopeBinder(dataScope),
+	}
+}
+
+// StartJob 密钥轮换后创建后台任务重新加密模型的加密字段，返回任务用于查询进度
+func (h *ReEncryptHandler) StartJob(c context.Context, ctx *app.RequestContext) {
+	batchSize, _ := strconv.Atoi(ctx.DefaultQuery("batch_size", "500"))
+
+	job, err := h.reEncryptService.StartReEncrypt(h.dataScope.Context(c, ctx), ctx.Param("id"), batchSize)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// GetJob 查询重新加密任务进度
+func (h *ReEncryptHandler) GetJob(c context.Context, ctx *app.RequestContext) {
+	job, err := h.reEncryptService.GetReEncryptJob(h.dataScope.Context(c, ctx), ctx.Param("id"), ctx.Param("job_id"))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusNotFound, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, job)
+}
+
+// CancelJob 取消重新加密任务
+func (h *ReEncryptHandler) CancelJob(c context.Context, ctx *app.RequestContext) {
+	if err := h.reEncryptService.CancelReEncryptJob(h.dataScope.Context(c, ctx), ctx.Param("id"), ctx.Param("job_id")); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, nil)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"metadata-platform/internal/module/metadata/service"
+	"metadata-platform/internal/utils"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// RecordHistoryHandler 记录历史处理器
+type RecordHistoryHandler struct {
+	*utils.BaseHandler
+	historyService service.RecordHistoryService
+	dataScope      *DataScopeBinder
+}
+
+// NewRecordHistoryHandler 创建记录历史处理器实例
+func NewRecordHistoryHandler(historyService service.RecordHistoryService, dataScope service.DataScopeService) *RecordHistoryHandler {
+	return &RecordHistoryHandler{
+		BaseHandler:    utils.NewBaseHandler(),
+		historyService: historyService,
+		dataScope:      NewDataScopeBinder(dataScope),
+	}
+}
+
+// RestoreRecordRequest 恢复记录请求
+type RestoreRecordRequest struct {
+	At string `json:"at"` // RFC3339 时间
+}
+
+// GetHistory 获取记录变更时间线
+func (h *RecordHistoryHandler) GetHistory(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("id")
+	recordID := ctx.Param("record_id")
+
+	versions, err := h.historyService.GetHistory(h.dataScope.Context(c, ctx), modelID, recordID)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, versions)
+}
+
+// GetAsOf 获取记录在指定时间点的状态
+func (h *RecordHistoryHandler) GetAsOf(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("id")
+	recordID := ctx.Param("record_id")
+	at, err := time.Parse(time.RFC3339, ctx.Query("at"))
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "at 参数须为 RFC3339 时间")
+		return
+	}
+
+	record, err := h.historyService.GetAsOf(h.dataScope.Context(c, ctx), modelID, recordID, at)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SuccessResponse(ctx, record)
+}
+
+// Restore 将记录恢复为指定时间点的状态
+func (h *RecordHistoryHandler) Restore(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("id")
+	recordID := ctx.Param("record_id")
+	var req RestoreRecordRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	at, err := time.Parse(time.RFC3339, req.At)
+	if err != nil {
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, "at 参数须为 RFC3339 时间")
+		return
+	}
+
+	if err := h.historyService.RestoreAsOf(h.dataScope.Context(c, ctx), modelID, recordID, at); err != nil {
+		crudErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, nil)
+}
+
+// UndoChange 撤销一次数据变更
+func (h *RecordHistoryHandler) UndoChange(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("id")
+	changeID := ctx.Param("change_id")
+
+	if err := h.historyService.UndoChange(h.dataScope.Context(c, ctx), modelID, changeID); err != nil {
+		crudErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, nil)
+}
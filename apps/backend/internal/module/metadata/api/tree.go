@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"errors"
 	"metadata-platform/internal/module/metadata/service"
 	"metadata-platform/internal/utils"
+	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -13,38 +15,60 @@ import (
 type TreeHandler struct {
 	*utils.BaseHandler
 	treeService service.TreeService
+	dataScope   *DataScopeBinder
 }
 
 // NewTreeHandler 创建树形结构处理器实例
-func NewTreeHandler(treeService service.TreeService) *TreeHandler {
+func NewTreeHandler(treeService service.TreeService, dataScope service.DataScopeService) *TreeHandler {
 	return &TreeHandler{
 		BaseHandler: utils.NewBaseHandler(),
 		treeService: treeService,
+		dataScope:   NewDataScopeBinder(dataScope),
 	}
 }
 
-// GetTree 获取完整树结构
+// GetTree 获取树结构，查询参数 depth 指定加载层数，为空时加载全部
 func (h *TreeHandler) GetTree(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
-	tree, err := h.treeService.GetTree(modelID)
+	depth, _ := strconv.Atoi(ctx.Query("depth"))
+
+	tree, err := h.treeService.GetTree(h.dataScope.Context(c, ctx), modelID, depth)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
 	utils.SuccessResponse(ctx, tree)
 }
 
-// GetChildren 获取子节点
+// GetChildren 分页获取子节点，查询参数 page、page_size
 func (h *TreeHandler) GetChildren(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
 	id := ctx.Param("id")
+	page, _ := strconv.Atoi(ctx.Query("page"))
+	pageSize, _ := strconv.Atoi(ctx.Query("page_size"))
+
+	children, total, err := h.treeService.GetChildren(h.dataScope.Context(c, ctx), modelID, id, page, pageSize)
+	if err != nil {
+		treeErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, map[string]any{
+		"list":  children,
+		"total": total,
+	})
+}
+
+// SearchTree 搜索节点，查询参数 field、keyword、limit，返回匹配节点及其祖先组成的树
+func (h *TreeHandler) SearchTree(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("model_id")
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
 
-	children, err := h.treeService.GetChildren(modelID, id)
+	tree, err := h.treeService.SearchTree(h.dataScope.Context(c, ctx), modelID, ctx.Query("field"), ctx.Query("keyword"), limit)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
-	utils.SuccessResponse(ctx, children)
+	utils.SuccessResponse(ctx, tree)
 }
 
 // GetPath 获取节点路径
@@ -52,14 +76,27 @@ func (h *TreeHandler) GetPath(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
 	id := ctx.Param("id")
 
-	path, err := h.treeService.GetPath(modelID, id)
+	path, err := h.treeService.GetPath(h.dataScope.Context(c, ctx), modelID, id)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
 	utils.SuccessResponse(ctx, path)
 }
 
+// GetDescendants 获取全部子孙节点
+func (h *TreeHandler) GetDescendants(c context.Context, ctx *app.RequestContext) {
+	modelID := ctx.Param("model_id")
+	id := ctx.Param("id")
+
+	descendants, err := h.treeService.GetDescendants(h.dataScope.Context(c, ctx), modelID, id)
+	if err != nil {
+		treeErrorResponse(ctx, err)
+		return
+	}
+	utils.SuccessResponse(ctx, descendants)
+}
+
 // AddNode 添加节点
 func (h *TreeHandler) AddNode(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
@@ -69,9 +106,9 @@ func (h *TreeHandler) AddNode(c context.Context, ctx *app.RequestContext) {
 		return
 	}
 
-	newNode, err := h.treeService.AddNode(modelID, data)
+	newNode, err := h.treeService.AddNode(h.dataScope.Context(c, ctx), modelID, data)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
 	utils.SuccessResponse(ctx, newNode)
@@ -90,23 +127,38 @@ func (h *TreeHandler) MoveNode(c context.Context, ctx *app.RequestContext) {
 		return
 	}
 
-	err := h.treeService.MoveNode(modelID, id, req.TargetParentID)
+	err := h.treeService.MoveNode(h.dataScope.Context(c, ctx), modelID, id, req.TargetParentID)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
 	utils.SuccessResponse(ctx, "Moved successfully")
 }
 
-// DeleteNode 删除节点
+// DeleteNode 删除节点，查询参数 mode 为 cascade (默认，级联删除子树) 或 reparent (子节点上移)
 func (h *TreeHandler) DeleteNode(c context.Context, ctx *app.RequestContext) {
 	modelID := ctx.Param("model_id")
 	id := ctx.Param("id")
+	mode := ctx.DefaultQuery("mode", service.TreeDeleteCascade)
 
-	err := h.treeService.DeleteNode(modelID, id)
+	err := h.treeService.DeleteNode(h.dataScope.Context(c, ctx), modelID, id, mode)
 	if err != nil {
-		utils.ErrorResponse(ctx, consts.StatusInternalServerError, err.Error())
+		treeErrorResponse(ctx, err)
 		return
 	}
 	utils.SuccessResponse(ctx, "Deleted successfully")
 }
+
+// treeErrorResponse 按错误类型返回树形操作错误
+func treeErrorResponse(ctx *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, service.ErrTreeNodeNotFound):
+		utils.NotFoundResponse(ctx, err.Error())
+	case errors.Is(err, service.ErrTreeCycle):
+		utils.ConflictResponse(ctx, err.Error(), nil)
+	case errors.Is(err, service.ErrNotTreeModel), errors.Is(err, service.ErrTreeTooLarge):
+		utils.ErrorResponse(ctx, consts.StatusBadRequest, err.Error())
+	default:
+		crudErrorResponse(ctx, err)
+	}
+}
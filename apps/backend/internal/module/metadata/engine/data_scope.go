@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 数据范围，与角色/岗位/菜单的 DataRange 取值保持一致
+const (
+	DataRangeAll       = "1" // 全部数据
+	DataRangeCustom    = "2" // 自定义组织
+	DataRangeOrg       = "3" // 本部门
+	DataRangeOrgAndSub = "4" // 本部门及以下
+	DataRangeSelf      = "5" // 仅本人数据
+)
+
+// ParamDataScope 内部查询参数：当前请求的数据权限，不接受客户端传入
+const ParamDataScope = "__data_scope"
+
+type dataScopeKey struct{}
+
+// DataScope 当前用户的行级数据权限
+type DataScope struct {
+	UserID   string   // 当前用户ID
+	TenantID string   // 当前租户ID
+	All      bool     // 是否可访问全部数据
+	OrgIDs   []string // 可访问的组织ID
+	Self     bool     // 是否可访问本人数据
+	Admin    bool     // 是否管理员
+	Roles    []string // 角色编码，用于字段脱敏豁免
+	Perms    []string // 权限(菜单)编码，用于字段脱敏豁免
+	Deny     bool     // 拒绝访问全部数据，用于未认证的请求
+}
+
+// WithDataScope 将数据权限写入 context
+func WithDataScope(ctx context.Context, scope *DataScope) context.Context {
+	return context.WithValue(ctx, dataScopeKey{}, scope)
+}
+
+// WithoutDataScope 返回不做数据权限过滤的 context，用于级联删除、路径维护等结构性操作，调用方须已校验根记录的权限
+func WithoutDataScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dataScopeKey{}, (*DataScope)(nil))
+}
+
+// DataScopeFromContext 读取 context 中的数据权限，未设置时返回 nil（不做过滤）
+func DataScopeFromContext(ctx context.Context) *DataScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(dataScopeKey{}).(*DataScope)
+	return scope
+}
+
+// HasDataScope 模型是否配置了数据权限字段
+func (d *ModelData) HasDataScope() bool {
+	return d != nil && d.Model != nil &&
+		(d.Model.DataOrgField != "" || d.Model.DataOwnerField != "" || d.Model.DataTenantField != "")
+}
+
+// DataScopeCondition 根据数据权限构建行级过滤条件，column 负责生成带引号/表名的列引用
+func (d *ModelData) DataScopeCondition(scope *DataScope, column func(name string) string) (string, []any) {
+	if scope != nil && scope.Deny {
+		return "1 = 0", nil
+	}
+	if scope == nil || !d.HasDataScope() {
+		return "", nil
+	}
+
+	var conds []string
+	var args []any
+
+	if d.Model.DataTenantField != "" && scope.TenantID != "" {
+		conds = append(conds, column(d.Model.DataTenantField)+" = ?")
+		args = append(args, scope.TenantID)
+	}
+
+	if !scope.All && (d.Model.DataOrgField != "" || d.Model.DataOwnerField != "") {
+		var ors []string
+		if d.Model.DataOrgField != "" && len(scope.OrgIDs) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(scope.OrgIDs)), ",")
+			ors = append(ors, fmt.Sprintf("%s IN (%s)", column(d.Model.DataOrgField), placeholders))
+			for _, id := range scope.OrgIDs {
+				args = append(args, id)
+			}
+		}
+		if d.Model.DataOwnerField != "" && scope.Self && scope.UserID != "" {
+			ors = append(ors, column(d.Model.DataOwnerField)+" = ?")
+			args = append(args, scope.UserID)
+		}
+		if len(ors) == 0 {
+			// 无任何可访问范围
+			ors = append(ors, "1 = 0")
+		}
+		conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// DataScopeAllows 判断一条记录是否在数据权限范围内，用于无法下推到 SQL 的场景 (如历史镜像)
+func (d *ModelData) DataScopeAllows(scope *DataScope, row map[string]any) bool {
+	if scope != nil && scope.Deny {
+		return false
+	}
+	if scope == nil || !d.HasDataScope() || row == nil {
+		return true
+	}
+	value := func(field string) string {
+		v, ok := row[field]
+		if !ok || v == nil {
+			return ""
+		}
+		if b, isBytes := v.([]byte); isBytes {
+			return string(b)
+		}
+		return fmt.Sprintf("%v", v)
+	}
+
+	if d.Model.DataTenantField != "" && scope.TenantID != "" && value(d.Model.DataTenantField) != scope.TenantID {
+		return false
+	}
+	if scope.All || (d.Model.DataOrgField == "" && d.Model.DataOwnerField == "") {
+		return true
+	}
+	if d.Model.DataOrgField != "" && slices.Contains(scope.OrgIDs, value(d.Model.DataOrgField)) {
+		return true
+	}
+	return d.Model.DataOwnerField != "" && scope.Self && scope.UserID != "" && value(d.Model.DataOwnerField) == scope.UserID
+}
+
+// buildDataScopeCondition 构建元数据模型主表上的数据权限条件
+func (b *SQLBuilder) buildDataScopeCondition(data *ModelData, params map[string]any) (string, []any) {
+	scope, _ := params[ParamDataScope].(*DataScope)
+	return data.DataScopeCondition(scope, func(name string) string {
+		if mainTable := data.mainTable(); mainTable != nil {
+			return `"` + mainTable.TableNameStr + `"."` + name + `"`
+		}
+		return `"` + name + `"`
+	})
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// ParamExpand 查询参数：需要展开的关联，如 lines:5(id,sku).notes,customer(name)
+// 以 . 分隔下级关联，:N 限定每条记录最多展开的子表记录数，括号内为返回字段
+const ParamExpand = "expand"
+
+// 关联展开限制
+const (
+	MaxExpandDepth     = 3    // 最大展开层级
+	DefaultExpandLimit = 100  // 每条记录每个关联默认最多展开的子表记录数
+	MaxExpandLimit     = 1000 // 每条记录每个关联最多展开的子表记录数上限
+)
+
+// ExpandNode 关联展开节点
+type ExpandNode struct {
+	Name     string
+	Limit    int
+	Fields   []string
+	Children []*ExpandNode
+}
+
+// ParseExpand 解析展开参数，支持逗号分隔的字符串或字符串数组，相同路径合并
+func ParseExpand(value any) ([]*ExpandNode, error) {
+	var spec string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		spec = v
+	case []string:
+		spec = strings.Join(v, ",")
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprintf("%v", item)
+		}
+		spec = strings.Join(parts, ",")
+	default:
+		return nil, fmt.Errorf("展开参数格式错误: %v", value)
+	}
+
+	var roots []*ExpandNode
+	for _, path := range splitTopLevel(spec, ',') {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		segments := splitTopLevel(path, '.')
+		if len(segments) > MaxExpandDepth {
+			return nil, fmt.Errorf("展开层级不能超过 %d: %s", MaxExpandDepth, path)
+		}
+		level := &roots
+		for _, segment := range segments {
+			node, err := parseExpandSegment(segment)
+			if err != nil {
+				return nil, err
+			}
+			level = &mergeExpandNode(level, node).Children
+		}
+	}
+	return roots, nil
+}
+
+// parseExpandSegment 解析单个关联：name[:limit][(field,...)]
+func parseExpandSegment(segment string) (*ExpandNode, error) {
+	segment = strings.TrimSpace(segment)
+	node := &ExpandNode{}
+	if i := strings.IndexByte(segment, '('); i >= 0 {
+		if !strings.HasSuffix(segment, ")") {
+			return nil, fmt.Errorf("展开参数格式错误: %s", segment)
+		}
+		for _, f := range strings.Split(segment[i+1:len(segment)-1], ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				node.Fields = append(node.Fields, f)
+			}
+		}
+		segment = segment[:i]
+	}
+	if name, limit, ok := strings.Cut(segment, ":"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n <= 0 || n > MaxExpandLimit {
+			return nil, fmt.Errorf("展开数量须为 1-%d: %s", MaxExpandLimit, segment)
+		}
+		node.Limit = n
+		segment = name
+	}
+	node.Name = strings.TrimSpace(segment)
+	if node.Name == "" {
+		return nil, fmt.Errorf("展开参数格式错误: %s", segment)
+	}
+	return node, nil
+}
+
+// mergeExpandNode 将节点合并到同级节点中，已存在时以新指定的数量和字段为准
+func mergeExpandNode(level *[]*ExpandNode, node *ExpandNode) *ExpandNode {
+	for _, existing := range *level {
+		if existing.Name == node.Name {
+			if node.Limit > 0 {
+				existing.Limit = node.Limit
+			}
+			if len(node.Fields) > 0 {
+				existing.Fields = node.Fields
+			}
+			return existing
+		}
+	}
+	*level = append(*level, node)
+	return node
+}
+
+// splitTopLevel 按分隔符拆分，忽略括号内的分隔符
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// LoadRelations 加载模型作为主表或子表的全部关联关系
+func (b *SQLBuilder) LoadRelations(modelID string) ([]*model.MdModelRelation, error) {
+	var relations []*model.MdModelRelation
+	if err := b.db.Where("master_model_id = ? OR detail_model_id = ?", modelID, modelID).Order("id asc").Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
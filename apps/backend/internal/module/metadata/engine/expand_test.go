@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpand(t *testing.T) {
+	nodes, err := ParseExpand("lines:5(id,sku).notes, customer(name), lines.product")
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	lines := nodes[0]
+	assert.Equal(t, "lines", lines.Name)
+	assert.Equal(t, 5, lines.Limit)
+	assert.Equal(t, []string{"id", "sku"}, lines.Fields)
+	require.Len(t, lines.Children, 2)
+	assert.Equal(t, "notes", lines.Children[0].Name)
+	assert.Equal(t, "product", lines.Children[1].Name)
+	assert.Equal(t, []string{"name"}, nodes[1].Fields)
+
+	nodes, err = ParseExpand([]any{"a", "b.c"})
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	nodes, err = ParseExpand(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, nodes)
+
+	_, err = ParseExpand("a.b.c.d")
+	assert.Error(t, err, "depth limit")
+	_, err = ParseExpand("a:0")
+	assert.Error(t, err)
+	_, err = ParseExpand("a:5000")
+	assert.Error(t, err)
+	_, err = ParseExpand("a(id")
+	assert.Error(t, err)
+}
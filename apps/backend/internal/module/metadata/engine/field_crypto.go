@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+// ErrFieldKeyRingMissing 未初始化字段加密密钥
+var ErrFieldKeyRingMissing = errors.New("未初始化字段加密密钥")
+
+// EncryptedFields 返回配置了加密算法的字段
+func (d *ModelData) EncryptedFields() []*model.MdModelField {
+	var fields []*model.MdModelField
+	for _, f := range d.Fields {
+		if f.EncryptAlgo != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// BlindIndexColumns 返回不属于模型字段的盲索引列，这些列需在写入时额外维护
+func (d *ModelData) BlindIndexColumns() []string {
+	fieldSet := make(map[string]bool, len(d.Fields))
+	for _, f := range d.Fields {
+		fieldSet[f.ColumnName] = true
+	}
+	var columns []string
+	for _, f := range d.EncryptedFields() {
+		if f.BlindIndexField != "" && !fieldSet[f.BlindIndexField] {
+			columns = append(columns, f.BlindIndexField)
+		}
+	}
+	return columns
+}
+
+// EncryptRow 加密写入数据中的加密字段，并同步计算盲索引列
+func (d *ModelData) EncryptRow(data map[string]any) error {
+	fields := d.EncryptedFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	ring := utils.GetFieldKeyRing()
+	for _, f := range fields {
+		// 盲索引由系统维护，忽略客户端提交的值
+		if f.BlindIndexField != "" {
+			delete(data, f.BlindIndexField)
+		}
+		val, ok := data[f.ColumnName]
+		if !ok || val == nil {
+			continue
+		}
+		if ring == nil {
+			return ErrFieldKeyRingMissing
+		}
+		plain := fmt.Sprintf("%v", val)
+		if utils.IsFieldCiphertext(plain) {
+			return fmt.Errorf("加密字段 %s 不允许直接写入密文", f.ColumnName)
+		}
+		cipherText, err := ring.Encrypt(f.EncryptAlgo, plain)
+		if err != nil {
+			return fmt.Errorf("加密字段 %s 失败: %w", f.ColumnName, err)
+		}
+		data[f.ColumnName] = cipherText
+		if f.BlindIndexField != "" {
+			data[f.BlindIndexField] = ring.BlindIndex(plain)
+		}
+	}
+	return nil
+}
+
+// DecryptRows 解密结果集中的加密字段，并移除非模型字段的盲索引列
+func (d *ModelData) DecryptRows(rows []map[string]any) error {
+	fields := d.EncryptedFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	ring := utils.GetFieldKeyRing()
+	blindColumns := d.BlindIndexColumns()
+	for _, row := range rows {
+		for _, f := range fields {
+			for _, column := range []string{ResultColumn(f), f.ColumnName} {
+				s, ok := row[column].(string)
+				if !ok {
+					if b, isBytes := row[column].([]byte); isBytes {
+						s, ok = string(b), true
+					}
+				}
+				if !ok || !utils.IsFieldCiphertext(s) {
+					continue
+				}
+				if ring == nil {
+					return ErrFieldKeyRingMissing
+				}
+				plain, _, err := ring.Decrypt(s)
+				if err != nil {
+					return fmt.Errorf("解密字段 %s 失败: %w", f.ColumnName, err)
+				}
+				row[column] = plain
+			}
+		}
+		for _, column := range blindColumns {
+			delete(row, column)
+		}
+	}
+	return nil
+}
+
+// encryptedField 按条件的表名/列名查找加密字段
+func (d *ModelData) encryptedField(tableName, columnName string) *model.MdModelField {
+	for _, f := range d.Fields {
+		if f.EncryptAlgo == "" || f.ColumnName != columnName {
+			continue
+		}
+		if tableName == "" || f.TableNameStr == "" || f.TableNameStr == tableName {
+			return f
+		}
+	}
+	return nil
+}
+
+// buildEncryptedCondition 将加密字段上的等值条件改写为盲索引列上的条件
+func (b *SQLBuilder) buildEncryptedCondition(w *model.MdModelWhere, field *model.MdModelField, params map[string]any) (string, []any, error) {
+	op := strings.ToUpper(w.Operator2)
+	switch op {
+	case "", "=", "!=", "<>", "IN", "NOT IN", "IS NULL", "IS NOT NULL":
+	default:
+		return "", nil, fmt.Errorf("加密字段 %s 仅支持等值查询", field.ColumnName)
+	}
+	if w.Func != "" {
+		return "", nil, fmt.Errorf("加密字段 %s 不支持字段函数", field.ColumnName)
+	}
+	if op == "IS NULL" || op == "IS NOT NULL" {
+		cond, args := b.buildSingleCondition(w, params)
+		return cond, args, nil
+	}
+	if field.BlindIndexField == "" {
+		return "", nil, fmt.Errorf("加密字段 %s 未配置盲索引列", field.ColumnName)
+	}
+	ring := utils.GetFieldKeyRing()
+	if ring == nil {
+		return "", nil, ErrFieldKeyRingMissing
+	}
+
+	blind := *w
+	blind.ColumnName = field.BlindIndexField
+	cond, args := b.buildSingleCondition(&blind, params)
+	for i, arg := range args {
+		args[i] = ring.BlindIndex(fmt.Sprintf("%v", arg))
+	}
+	return cond, args, nil
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+func TestModelData_FieldEncryption(t *testing.T) {
+	require.NoError(t, utils.InitFieldKeyRing("1:test-key", 1, "test-index-key"))
+	ring := utils.GetFieldKeyRing()
+
+	data := &ModelData{
+		Model: &model.MdModel{ID: "m1"},
+		Tables: []*model.MdModelTable{
+			{TableNameStr: "users", IsMain: true},
+		},
+		Fields: []*model.MdModelField{
+			{TableNameStr: "users", ColumnName: "id", IsPrimaryKey: true},
+			{TableNameStr: "users", ColumnName: "mobile", EncryptAlgo: utils.FieldCipherSM4, BlindIndexField: "mobile_bidx"},
+			{TableNameStr: "users", ColumnName: "name"},
+		},
+	}
+
+	t.Run("Encrypt on write and maintain blind index", func(t *testing.T) {
+		row := map[string]any{"id": 1, "mobile": "13812345678", "name": "张三", "mobile_bidx": "forged"}
+		require.NoError(t, data.EncryptRow(row))
+		assert.True(t, utils.IsFieldCiphertext(row["mobile"].(string)))
+		assert.Equal(t, ring.BlindIndex("13812345678"), row["mobile_bidx"])
+		assert.Equal(t, "张三", row["name"])
+		assert.Equal(t, []string{"mobile_bidx"}, data.BlindIndexColumns())
+
+		require.NoError(t, data.DecryptRows([]map[string]any{row}))
+		assert.Equal(t, "13812345678", row["mobile"])
+		assert.NotContains(t, row, "mobile_bidx")
+	})
+
+	t.Run("Reject raw ciphertext", func(t *testing.T) {
+		cipherText, _ := ring.Encrypt(utils.FieldCipherSM4, "x")
+		assert.Error(t, data.EncryptRow(map[string]any{"mobile": cipherText}))
+	})
+
+	builder := &SQLBuilder{}
+	where := func(op, value string) *ModelData {
+		d := *data
+		d.Wheres = []*model.MdModelWhere{{TableNameStr: "users", ColumnName: "mobile", Operator2: op, ParamKey: "mobile", Value1: value}}
+		return &d
+	}
+
+	t.Run("Equality rewritten to blind index", func(t *testing.T) {
+		sql, args, err := builder.BuildFromMetadata(where("=", ""), map[string]any{"mobile": "13812345678"})
+		require.NoError(t, err)
+		assert.Contains(t, sql, `WHERE "users"."mobile_bidx" = ?`)
+		assert.Equal(t, []any{ring.BlindIndex("13812345678")}, args)
+	})
+
+	t.Run("IN rewritten to blind index", func(t *testing.T) {
+		_, args, err := builder.BuildFromMetadata(where("in", "a, b"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, []any{ring.BlindIndex("a"), ring.BlindIndex("b")}, args)
+	})
+
+	t.Run("Range and fuzzy search rejected", func(t *testing.T) {
+		_, _, err := builder.BuildFromMetadata(where("like", "138"), nil)
+		assert.ErrorContains(t, err, "仅支持等值查询")
+	})
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+// sequenceDefault 匹配序列默认值，如 seq_order.NEXTVAL
+var sequenceDefault = regexp.MustCompile(`(?i)^\s*([\w.$]+)\.nextval\s*$`)
+
+// KeySequence 返回字段默认值中配置的序列名 (Oracle/DM)，未配置时返回空
+func KeySequence(f *model.MdModelField) string {
+	if m := sequenceDefault.FindStringSubmatch(f.DefaultValue); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// GeneratedKeyField 返回由数据库生成值的主键字段：自增主键或默认值为序列的主键，没有时返回 nil
+func (d *ModelData) GeneratedKeyField() *model.MdModelField {
+	for _, f := range d.Fields {
+		if f.IsPrimaryKey && (f.IsAutoIncrement || KeySequence(f) != "") {
+			return f
+		}
+	}
+	return nil
+}
+
+// NextSequenceValues 在事务中从序列获取 n 个值
+func (e *SQLExecutor) NextSequenceValues(tx *gorm.DB, sequence string, n int) ([]any, error) {
+	sqlStr := fmt.Sprintf("SELECT %s.NEXTVAL AS id FROM DUAL CONNECT BY LEVEL <= %d", sequence, n)
+	rows, err := e.ExecuteWithTx(tx, sqlStr)
+	if err != nil {
+		return nil, fmt.Errorf("获取序列 %s 的值失败: %w", sequence, err)
+	}
+	values := make([]any, len(rows))
+	for i, row := range rows {
+		for _, v := range row {
+			values[i] = v
+		}
+	}
+	return values, nil
+}
+
+// InsertWithTx 在事务中执行 INSERT 语句并取回数据库生成的主键，返回值与插入行顺序一致：
+// MySQL/SQLite 使用 LastInsertId，PostgreSQL 使用 RETURNING，SQL Server 使用 OUTPUT INSERTED
+// MySQL 多行插入依赖同一语句内分配的自增值连续 (行数确定的插入在各 innodb_autoinc_lock_mode 下均成立)，
+// 步长按会话的 auto_increment_increment 计算
+func (e *SQLExecutor) InsertWithTx(tx *gorm.DB, sqlStr string, keyColumn string, rowCount int, args ...any) ([]any, error) {
+	start := time.Now()
+	var keys []any
+	switch dialect := tx.Dialector.Name(); dialect {
+	case "mysql", "sqlite":
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		result, err := tx.Statement.ConnPool.ExecContext(ctx, sqlStr, args...)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("获取自增主键失败: %w", err)
+		}
+		// 多行插入时 MySQL 返回第一行的主键，SQLite 返回最后一行的主键，同一语句内的自增值连续
+		first, step := id, int64(1)
+		if dialect == "sqlite" {
+			first = id - int64(rowCount) + 1
+		} else if rowCount > 1 {
+			if step, err = autoIncrementStep(tx); err != nil {
+				return nil, err
+			}
+		}
+		for i := 0; i < rowCount; i++ {
+			keys = append(keys, first+int64(i)*step)
+		}
+	case "postgres":
+		rows, err := e.ExecuteWithTx(tx, sqlStr+` RETURNING "`+keyColumn+`"`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			keys = append(keys, row[keyColumn])
+		}
+	case "sqlserver":
+		i := strings.Index(sqlStr, " VALUES ")
+		if i < 0 {
+			return nil, fmt.Errorf("无法在语句中添加 OUTPUT 子句: %s", sqlStr)
+		}
+		rows, err := e.ExecuteWithTx(tx, sqlStr[:i]+" OUTPUT INSERTED.["+keyColumn+"]"+sqlStr[i:], args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			keys = append(keys, row[keyColumn])
+		}
+	default:
+		return nil, fmt.Errorf("不支持获取 %s 数据库的生成主键", dialect)
+	}
+	if len(keys) != rowCount {
+		return nil, fmt.Errorf("生成主键数量 %d 与插入行数 %d 不一致", len(keys), rowCount)
+	}
+
+	utils.SugarLogger.Infof("SQL Insert [%v]: %s | Args: %v | Keys: %v", time.Since(start), sqlStr, args, keys)
+	return keys, nil
+}
+
+// autoIncrementStep 读取 MySQL 会话的自增步长 (auto_increment_increment)
+func autoIncrementStep(tx *gorm.DB) (int64, error) {
+	var step int64
+	if err := tx.Raw("SELECT @@auto_increment_increment").Scan(&step).Error; err != nil {
+		return 0, fmt.Errorf("读取自增步长失败: %w", err)
+	}
+	if step <= 0 {
+		return 0, fmt.Errorf("自增步长无效: %d", step)
+	}
+	return step, nil
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParamIncludeDeleted 查询参数：为 true 时查询结果包含已逻辑删除的数据
+const ParamIncludeDeleted = "include_deleted"
+
+// HasLogicDelete 模型是否配置了逻辑删除字段
+func (d *ModelData) HasLogicDelete() bool {
+	return d != nil && d.Model != nil && d.Model.LogicDeleteField != ""
+}
+
+// LogicDeletedValue 返回逻辑删除字段的"已删除"值，默认为 1
+func (d *ModelData) LogicDeletedValue() string {
+	if d.Model.LogicDeleteValue != "" {
+		return d.Model.LogicDeleteValue
+	}
+	return "1"
+}
+
+// LogicActiveValue 返回逻辑删除字段的"未删除"值，默认为 0
+func (d *ModelData) LogicActiveValue() string {
+	if d.Model.LogicNotDeleteValue != "" {
+		return d.Model.LogicNotDeleteValue
+	}
+	return "0"
+}
+
+// IncludeDeleted 判断查询参数中是否要求包含已删除数据
+func IncludeDeleted(params map[string]any) bool {
+	if params == nil {
+		return false
+	}
+	switch v := params[ParamIncludeDeleted].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true") || v == "1"
+	case float64:
+		return v == 1
+	case int:
+		return v == 1
+	}
+	return false
+}
+
+// buildLogicDeleteCondition 构建排除已删除数据的条件，未配置或显式要求包含已删除数据时返回空
+func (b *SQLBuilder) buildLogicDeleteCondition(data *ModelData, params map[string]any) (string, []any) {
+	if !data.HasLogicDelete() || IncludeDeleted(params) {
+		return "", nil
+	}
+
+	column := `"` + data.Model.LogicDeleteField + `"`
+	if mainTable := data.mainTable(); mainTable != nil {
+		column = `"` + mainTable.TableNameStr + `".` + column
+	}
+
+	return fmt.Sprintf("%s = ?", column), []any{data.LogicActiveValue()}
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// 字段脱敏方式
+const (
+	MaskHide    = "hide"    // 隐藏：结果中不返回该列
+	MaskPartial = "partial" // 部分遮盖：保留首尾，中间替换为 *
+	MaskHash    = "hash"    // 哈希：SHA-256 摘要
+	MaskFormat  = "format"  // 保留格式：数字/字母替换为同类字符，分隔符保持不变
+)
+
+// MaskPolicy 字段脱敏策略
+type MaskPolicy struct {
+	Column      string   // 结果集中的列名
+	RawColumn   string   // 物理列名 (SELECT * 时的列名)
+	Type        string   // 脱敏方式
+	Rule        string   // 脱敏规则
+	ExemptRoles []string // 豁免角色编码
+	ExemptPerms []string // 豁免权限编码
+}
+
+// NewMaskPolicy 根据字段增强配置创建脱敏策略，未配置脱敏时返回 nil
+func NewMaskPolicy(column, rawColumn string, enh *model.MdModelFieldEnhancement) *MaskPolicy {
+	if enh == nil || enh.MaskType == "" || column == "" {
+		return nil
+	}
+	return &MaskPolicy{
+		Column:      column,
+		RawColumn:   rawColumn,
+		Type:        enh.MaskType,
+		Rule:        enh.MaskRule,
+		ExemptRoles: splitCodes(enh.MaskExemptRoles),
+		ExemptPerms: splitCodes(enh.MaskExemptPerms),
+	}
+}
+
+// Exempt 当前用户是否豁免该策略；未识别用户时不豁免
+func (p *MaskPolicy) Exempt(scope *DataScope) bool {
+	if scope == nil {
+		return false
+	}
+	if scope.Admin {
+		return true
+	}
+	for _, r := range scope.Roles {
+		if slices.Contains(p.ExemptRoles, r) {
+			return true
+		}
+	}
+	for _, perm := range scope.Perms {
+		if slices.Contains(p.ExemptPerms, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask 对单个值脱敏，nil 保持不变
+func (p *MaskPolicy) Mask(v any) any {
+	if v == nil {
+		return nil
+	}
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case []byte:
+		s = string(val)
+	default:
+		s = fmt.Sprintf("%v", val)
+	}
+
+	switch p.Type {
+	case MaskPartial:
+		prefix, suffix := p.keepRange()
+		return maskRunes(s, prefix, suffix, func(r rune, _ int) rune { return '*' })
+	case MaskFormat:
+		prefix, suffix := p.keepRange()
+		sum := sha256.Sum256([]byte(s))
+		return maskRunes(s, prefix, suffix, func(r rune, i int) rune {
+			b := sum[i%len(sum)]
+			switch {
+			case unicode.IsDigit(r):
+				return rune('0' + b%10)
+			case unicode.IsUpper(r):
+				return rune('A' + b%26)
+			case unicode.IsLower(r):
+				return rune('a' + b%26)
+			}
+			return r
+		})
+	case MaskHash:
+		sum := sha256.Sum256([]byte(p.Rule + s))
+		return hex.EncodeToString(sum[:])
+	}
+	return v
+}
+
+// keepRange 解析"前保留位数,后保留位数"，默认前 3 后 4
+func (p *MaskPolicy) keepRange() (int, int) {
+	prefix, suffix := 3, 4
+	if p.Rule == "" {
+		return prefix, suffix
+	}
+	parts := strings.SplitN(p.Rule, ",", 2)
+	if n, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil && n >= 0 {
+		prefix = n
+	}
+	if len(parts) == 2 {
+		if n, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && n >= 0 {
+			suffix = n
+		}
+	}
+	return prefix, suffix
+}
+
+// maskRunes 保留首尾字符，对中间字符逐个替换；保留位数不小于总长度时整体替换
+func maskRunes(s string, prefix, suffix int, replace func(r rune, i int) rune) string {
+	runes := []rune(s)
+	if prefix+suffix >= len(runes) {
+		prefix, suffix = 0, 0
+	}
+	for i := prefix; i < len(runes)-suffix; i++ {
+		runes[i] = replace(runes[i], i)
+	}
+	return string(runes)
+}
+
+// ApplyMasks 按策略对结果集脱敏，豁免用户原样返回
+func ApplyMasks(rows []map[string]any, policies []*MaskPolicy, scope *DataScope) {
+	var active []*MaskPolicy
+	for _, p := range policies {
+		if !p.Exempt(scope) {
+			active = append(active, p)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for _, p := range active {
+			p.apply(row, p.Column)
+			if p.RawColumn != "" && p.RawColumn != p.Column {
+				p.apply(row, p.RawColumn)
+			}
+		}
+	}
+}
+
+func (p *MaskPolicy) apply(row map[string]any, column string) {
+	v, ok := row[column]
+	if !ok {
+		return
+	}
+	if p.Type == MaskHide {
+		delete(row, column)
+		return
+	}
+	row[column] = p.Mask(v)
+}
+
+// MaskedColumns 返回对当前用户生效的脱敏列，这些列不允许被修改
+func (d *ModelData) MaskedColumns(scope *DataScope) map[string]bool {
+	columns := make(map[string]bool)
+	for _, p := range d.Masks {
+		if !p.Exempt(scope) {
+			columns[p.RawColumn] = true
+		}
+	}
+	return columns
+}
+
+// buildMaskPolicies 将字段增强配置映射为结果集列上的脱敏策略
+func (d *ModelData) buildMaskPolicies(enhancements []*model.MdModelFieldEnhancement) []*MaskPolicy {
+	if len(enhancements) == 0 {
+		return nil
+	}
+	fieldByID := make(map[string]*model.MdModelField, len(d.Fields))
+	for _, f := range d.Fields {
+		fieldByID[f.ID] = f
+	}
+	var policies []*MaskPolicy
+	for _, enh := range enhancements {
+		field, ok := fieldByID[enh.FieldID]
+		if !ok {
+			continue
+		}
+		if p := NewMaskPolicy(ResultColumn(field), field.ColumnName, enh); p != nil {
+			policies = append(policies, p)
+		}
+	}
+	return policies
+}
+
+// ResultColumn 返回字段在查询结果中的列名，与 buildSelectClause 的别名规则一致
+func ResultColumn(field *model.MdModelField) string {
+	if field.AggFunc != "" || field.Func != "" || (field.ShowTitle != "" && field.ShowTitle != field.ColumnName) {
+		if field.ShowTitle != "" {
+			return field.ShowTitle
+		}
+	}
+	return field.ColumnName
+}
+
+func splitCodes(s string) []string {
+	var codes []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			codes = append(codes, c)
+		}
+	}
+	return codes
+}
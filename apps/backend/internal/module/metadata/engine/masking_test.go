@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+func TestMaskPolicy_Mask(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy MaskPolicy
+		input  any
+		want   any
+	}{
+		{"Partial default keeps 3 and 4", MaskPolicy{Type: MaskPartial}, "13812345678", "138****5678"},
+		{"Partial custom rule", MaskPolicy{Type: MaskPartial, Rule: "1,1"}, "张三丰", "张*丰"},
+		{"Partial short value fully masked", MaskPolicy{Type: MaskPartial}, "abc", "***"},
+		{"Nil stays nil", MaskPolicy{Type: MaskPartial}, nil, nil},
+		{"Unknown type unchanged", MaskPolicy{Type: "none"}, "x", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Mask(tt.input))
+		})
+	}
+
+	t.Run("Hash is deterministic and salted", func(t *testing.T) {
+		p := &MaskPolicy{Type: MaskHash}
+		salted := &MaskPolicy{Type: MaskHash, Rule: "salt"}
+		assert.Len(t, p.Mask("110101199001011234"), 64)
+		assert.Equal(t, p.Mask("a"), p.Mask("a"))
+		assert.NotEqual(t, p.Mask("a"), salted.Mask("a"))
+	})
+
+	t.Run("Format keeps separators and character classes", func(t *testing.T) {
+		p := &MaskPolicy{Type: MaskFormat, Rule: "0,0"}
+		masked := p.Mask("AB-12.cd").(string)
+		assert.Len(t, masked, 8)
+		assert.Equal(t, byte('-'), masked[2])
+		assert.Equal(t, byte('.'), masked[5])
+		assert.Regexp(t, `^[A-Z]{2}-[0-9]{2}\.[a-z]{2}$`, masked)
+		assert.Equal(t, masked, p.Mask("AB-12.cd"))
+	})
+}
+
+func TestApplyMasks(t *testing.T) {
+	enh := &model.MdModelFieldEnhancement{MaskType: MaskPartial, MaskExemptRoles: "hr, admin", MaskExemptPerms: "user:view_sensitive"}
+	policies := []*MaskPolicy{
+		NewMaskPolicy("mobile", "mobile", enh),
+		NewMaskPolicy("证件号", "idcard", &model.MdModelFieldEnhancement{MaskType: MaskHide}),
+	}
+	newRows := func() []map[string]any {
+		return []map[string]any{{"mobile": "13812345678", "证件号": "110101199001011234", "name": "张三"}}
+	}
+
+	t.Run("Anonymous caller gets masked data", func(t *testing.T) {
+		rows := newRows()
+		ApplyMasks(rows, policies, nil)
+		assert.Equal(t, "138****5678", rows[0]["mobile"])
+		assert.NotContains(t, rows[0], "证件号")
+		assert.Equal(t, "张三", rows[0]["name"])
+	})
+
+	t.Run("Exempt role sees partial column only", func(t *testing.T) {
+		rows := newRows()
+		ApplyMasks(rows, policies, &DataScope{Roles: []string{"hr"}})
+		assert.Equal(t, "13812345678", rows[0]["mobile"])
+		assert.NotContains(t, rows[0], "证件号")
+	})
+
+	t.Run("Exempt permission", func(t *testing.T) {
+		rows := newRows()
+		ApplyMasks(rows, policies, &DataScope{Perms: []string{"user:view_sensitive"}})
+		assert.Equal(t, "13812345678", rows[0]["mobile"])
+	})
+
+	t.Run("Admin sees everything", func(t *testing.T) {
+		rows := newRows()
+		ApplyMasks(rows, policies, &DataScope{Admin: true})
+		assert.Equal(t, newRows(), rows)
+	})
+
+	t.Run("Raw column masked for SELECT *", func(t *testing.T) {
+		rows := []map[string]any{{"idcard": "110101199001011234"}}
+		ApplyMasks(rows, policies, nil)
+		assert.NotContains(t, rows[0], "idcard")
+	})
+}
@@ -0,0 +1,20 @@
+package engine
+
+import "strings"
+
+// HasVersionField 模型是否配置了乐观锁版本字段
+func (d *ModelData) HasVersionField() bool {
+	return d != nil && d.Model != nil && d.Model.VersionField != ""
+}
+
+// IsTimestampVersion 版本字段是否为时间类型 (如 update_at)，否则按整数版本号递增
+func (d *ModelData) IsTimestampVersion() bool {
+	for _, f := range d.Fields {
+		if f.ColumnName != d.Model.VersionField {
+			continue
+		}
+		kind := strings.ToLower(f.FieldType + " " + f.ColumnType)
+		return strings.Contains(kind, "date") || strings.Contains(kind, "time")
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParamPivot 查询参数：透视请求，编译为模型 SQL 外层的条件聚合查询
+const ParamPivot = "pivot"
+
+// 透视结果规模限制
+const (
+	MaxPivotColumns = 200   // 列维度最多的取值数
+	MaxPivotRows    = 10000 // 最多返回的行数
+)
+
+// 透视结果中的列别名
+const (
+	PivotTotalAlias  = "pivot_total"
+	pivotColumnAlias = "pivot_c%d"
+)
+
+// PivotRequest 透视请求，行维度分组、列维度取值展开为列，单元格为度量的聚合值
+type PivotRequest struct {
+	Rows    []string `json:"rows"`    // 行维度
+	Column  string   `json:"column"`  // 列维度
+	Values  []any    `json:"values"`  // 列维度的固定取值，为空时取数据中的全部取值
+	Measure string   `json:"measure"` // 度量字段，count 时可为空
+	Agg     string   `json:"agg"`     // 聚合函数，默认有度量时为 sum，否则为 count
+}
+
+// ParsePivotRequest 解析透视请求，未提供时返回 nil
+func ParsePivotRequest(value any) (*PivotRequest, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("透视参数格式错误: %w", err)
+	}
+	var req PivotRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, fmt.Errorf("透视参数格式错误: %w", err)
+	}
+	if req.Column == "" {
+		return nil, fmt.Errorf("透视参数须包含列维度")
+	}
+	if req.Agg == "" {
+		req.Agg = StatsCount
+		if req.Measure != "" {
+			req.Agg = StatsSum
+		}
+	}
+	if len(req.Values) > MaxPivotColumns {
+		return nil, fmt.Errorf("列维度取值不能超过 %d 个", MaxPivotColumns)
+	}
+	return &req, nil
+}
+
+// PivotColumnAlias 返回第 i 个列维度取值对应的结果列别名
+func PivotColumnAlias(i int) string {
+	return fmt.Sprintf(pivotColumnAlias, i)
+}
+
+// BuildPivotValuesSQL 构建查询列维度全部取值的 SQL，多取一条用于判断是否超出上限
+func (b *SQLBuilder) BuildPivotValuesSQL(modelID string, params map[string]any) (string, []any, error) {
+	data, req, inner, args, err := b.pivotSource(modelID, params)
+	if err != nil {
+		return "", nil, err
+	}
+	return buildPivotValuesQuery(data, req, inner, args, params)
+}
+
+// BuildPivotSQL 按列维度取值构建透视 SQL，totals 为 true 时不按行维度分组，返回列合计
+func (b *SQLBuilder) BuildPivotSQL(modelID string, params map[string]any, values []any, totals bool) (string, []any, error) {
+	data, req, inner, args, err := b.pivotSource(modelID, params)
+	if err != nil {
+		return "", nil, err
+	}
+	return buildPivotQuery(data, req, values, totals, inner, args, params)
+}
+
+// pivotSource 解析透视请求并构建作为子查询的模型 SQL
+func (b *SQLBuilder) pivotSource(modelID string, params map[string]any) (*ModelData, *PivotRequest, string, []any, error) {
+	req, err := ParsePivotRequest(params[ParamPivot])
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	if req == nil {
+		return nil, nil, "", nil, fmt.Errorf("缺少透视参数 %s", ParamPivot)
+	}
+	data, err := b.LoadModelData(modelID)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	inner, args, err := b.BuildSQL(modelID, params)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	return data, req, inner, args, nil
+}
+
+// buildPivotValuesQuery 构建列维度去重取值查询
+func buildPivotValuesQuery(data *ModelData, req *PivotRequest, inner string, args []any, params map[string]any) (string, []any, error) {
+	if err := newStatsFields(data, params).checkPlain(req.Column); err != nil {
+		return "", nil, err
+	}
+	column := quoteStats(req.Column)
+	sql := fmt.Sprintf("SELECT DISTINCT %s FROM (%s) pivot_t ORDER BY %s LIMIT %d", column, inner, column, MaxPivotColumns+1)
+	return sql, args, nil
+}
+
+// buildPivotQuery 以条件聚合构建透视查询，每个列维度取值对应一个 CASE WHEN 聚合列，并附加行合计列
+func buildPivotQuery(data *ModelData, req *PivotRequest, values []any, totals bool, inner string, args []any, params map[string]any) (string, []any, error) {
+	// 1. 校验维度与度量
+	fields := newStatsFields(data, params)
+	if err := fields.checkPlain(req.Column); err != nil {
+		return "", nil, err
+	}
+	var rows []string
+	for _, r := range req.Rows {
+		if err := fields.check(r); err != nil {
+			return "", nil, err
+		}
+		if r == req.Column || slices.Contains(rows, quoteStats(r)) {
+			return "", nil, fmt.Errorf("行维度重复: %s", r)
+		}
+		rows = append(rows, quoteStats(r))
+	}
+	if len(values) > MaxPivotColumns {
+		return "", nil, fmt.Errorf("列维度取值不能超过 %d 个", MaxPivotColumns)
+	}
+
+	// 2. 条件聚合列，条件参数位于子查询参数之前
+	var selects []string
+	if !totals {
+		selects = append(selects, rows...)
+	}
+	column := quoteStats(req.Column)
+	var cellArgs []any
+	for i, v := range values {
+		cond := column + " = ?"
+		if v == nil {
+			cond = column + " IS NULL"
+		} else {
+			cellArgs = append(cellArgs, v)
+		}
+		expr, err := pivotExpression(req, fields, cond)
+		if err != nil {
+			return "", nil, err
+		}
+		selects = append(selects, expr+" AS "+quoteStats(PivotColumnAlias(i)))
+	}
+	total, err := pivotExpression(req, fields, "")
+	if err != nil {
+		return "", nil, err
+	}
+	selects = append(selects, total+" AS "+quoteStats(PivotTotalAlias))
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + strings.Join(selects, ", ") + " FROM (" + inner + ") pivot_t")
+	if !totals && len(rows) > 0 {
+		sb.WriteString(" GROUP BY " + strings.Join(rows, ", "))
+		sb.WriteString(" ORDER BY " + strings.Join(rows, ", "))
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", MaxPivotRows+1))
+	}
+
+	return sb.String(), append(cellArgs, args...), nil
+}
+
+// pivotExpression 构建单元格聚合表达式，cond 为空时聚合全部记录
+func pivotExpression(req *PivotRequest, fields *statsFields, cond string) (string, error) {
+	if req.Agg == StatsCount && req.Measure == "" {
+		if cond == "" {
+			return "COUNT(*)", nil
+		}
+		return "COUNT(CASE WHEN " + cond + " THEN 1 END)", nil
+	}
+	if err := fields.checkAggregate(req.Agg, req.Measure); err != nil {
+		return "", err
+	}
+	measure := quoteStats(req.Measure)
+	if cond != "" {
+		measure = "CASE WHEN " + cond + " THEN " + measure + " END"
+	}
+
+	switch req.Agg {
+	case StatsCount, StatsSum, StatsAvg, StatsMin, StatsMax:
+		return strings.ToUpper(req.Agg) + "(" + measure + ")", nil
+	case StatsCountDistinct:
+		return "COUNT(DISTINCT " + measure + ")", nil
+	default:
+		return "", fmt.Errorf("透视不支持聚合函数: %s", req.Agg)
+	}
+}
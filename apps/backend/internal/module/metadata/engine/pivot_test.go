@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+func TestBuildPivotQuery(t *testing.T) {
+	builder := &SQLBuilder{}
+	data := &ModelData{
+		Model:  &model.MdModel{ID: "m1"},
+		Tables: []*model.MdModelTable{{TableNameStr: "costs", IsMain: true}},
+		Fields: []*model.MdModelField{
+			{ID: "f1", TableNameStr: "costs", ColumnName: "dept"},
+			{ID: "f2", TableNameStr: "costs", ColumnName: "month"},
+			{ID: "f3", TableNameStr: "costs", ColumnName: "amount"},
+			{ID: "f4", TableNameStr: "costs", ColumnName: "phone"},
+		},
+		Masks: []*MaskPolicy{{Column: "phone", RawColumn: "phone", Type: MaskPartial}},
+	}
+	build := func(pivot map[string]any, values []any, totals bool) (string, []any, error) {
+		params := map[string]any{ParamPivot: pivot}
+		req, err := ParsePivotRequest(pivot)
+		if err != nil {
+			return "", nil, err
+		}
+		inner, args, err := builder.BuildFromMetadata(data, params)
+		require.NoError(t, err)
+		return buildPivotQuery(data, req, values, totals, inner, args, params)
+	}
+	inner := `(SELECT "costs"."dept", "costs"."month", "costs"."amount", "costs"."phone" FROM "costs") pivot_t`
+
+	t.Run("Conditional aggregation per column value", func(t *testing.T) {
+		pivot := map[string]any{"rows": []any{"dept"}, "column": "month", "measure": "amount", "agg": "avg"}
+		sql, args, err := build(pivot, []any{"01", nil}, false)
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT "dept", AVG(CASE WHEN "month" = ? THEN "amount" END) AS "pivot_c0", AVG(CASE WHEN "month" IS NULL THEN "amount" END) AS "pivot_c1",`+
+			` AVG("amount") AS "pivot_total" FROM `+inner+` GROUP BY "dept" ORDER BY "dept" LIMIT 10001`, sql)
+		assert.Equal(t, []any{"01"}, args)
+
+		sql, _, err = build(pivot, []any{"01"}, true)
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT AVG(CASE WHEN "month" = ? THEN "amount" END) AS "pivot_c0", AVG("amount") AS "pivot_total" FROM `+inner, sql)
+	})
+
+	t.Run("Count without measure", func(t *testing.T) {
+		sql, _, err := build(map[string]any{"column": "month"}, []any{"01"}, false)
+		require.NoError(t, err)
+		assert.Contains(t, sql, `COUNT(CASE WHEN "month" = ? THEN 1 END) AS "pivot_c0", COUNT(*) AS "pivot_total"`)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		for name, pivot := range map[string]map[string]any{
+			"missing column":  {"rows": []any{"dept"}},
+			"unknown row":     {"rows": []any{"missing"}, "column": "month"},
+			"row is column":   {"rows": []any{"month"}, "column": "month"},
+			"masked column":   {"column": "phone"},
+			"masked measure":  {"column": "month", "measure": "phone", "agg": "max"},
+			"unsupported agg": {"column": "month", "measure": "amount", "agg": "percentile"},
+		} {
+			_, _, err := build(pivot, []any{"01"}, false)
+			assert.Error(t, err, name)
+		}
+	})
+}
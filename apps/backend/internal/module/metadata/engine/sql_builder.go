@@ -23,6 +23,7 @@ type ModelData struct {
 	Orders     []*model.MdModelOrder
 	Limit      *model.MdModelLimit
 	SQL        *model.MdModelSql
+	Masks      []*MaskPolicy
 }
 
 // SQLBuilder SQL生成引擎主类
@@ -47,8 +48,15 @@ func (b *SQLBuilder) BuildSQL(modelID string, params map[string]any) (sql string
 	}
 
 	if data.Model.ModelKind == 1 {
-		// 原始 SQL
+		// 原始 SQL，数据权限通过外层包装过滤
 		sql, args, err = b.buildFromSQL(data, params)
+		if err == nil {
+			scope, _ := params[ParamDataScope].(*DataScope)
+			if cond, condArgs := data.DataScopeCondition(scope, func(name string) string { return `"t"."` + name + `"` }); cond != "" {
+				sql = fmt.Sprintf("SELECT * FROM (%s) t WHERE %s", sql, cond)
+				args = append(args, condArgs...)
+			}
+		}
 	} else {
 		// 元数据构建
 		sql, args, err = b.BuildFromMetadata(data, params)
@@ -150,6 +158,13 @@ func (b *SQLBuilder) LoadModelData(modelID string) (*ModelData, error) {
 		return nil, err
 	}
 
+	// 加载字段脱敏策略
+	var enhancements []*model.MdModelFieldEnhancement
+	if err := b.db.Where("model_id = ? AND mask_type <> '' AND is_deleted = ?", modelID, false).Find(&enhancements).Error; err != nil {
+		return nil, err
+	}
+	data.Masks = data.buildMaskPolicies(enhancements)
+
 	return data, nil
 }
 
@@ -177,6 +192,18 @@ func (b *SQLBuilder) BuildFromMetadata(data *ModelData, params map[string]any) (
 	}
 	args = append(args, whereArgs...)
 
+	// 逻辑删除过滤
+	if cond, condArgs := b.buildLogicDeleteCondition(data, params); cond != "" {
+		whereClause = appendWhere(whereClause, cond)
+		args = append(args, condArgs...)
+	}
+
+	// 数据权限过滤
+	if cond, condArgs := b.buildDataScopeCondition(data, params); cond != "" {
+		whereClause = appendWhere(whereClause, cond)
+		args = append(args, condArgs...)
+	}
+
 	groupByClause, err := b.buildGroupByClause(data)
 	if err != nil {
 		return "", nil, err
@@ -188,14 +215,17 @@ func (b *SQLBuilder) BuildFromMetadata(data *ModelData, params map[string]any) (
 	}
 	args = append(args, havingArgs...)
 
-	orderByClause, err := b.buildOrderByClause(data)
-	if err != nil {
-		return "", nil, err
-	}
-
-	limitClause, err := b.buildLimitClause(data, params)
-	if err != nil {
-		return "", nil, err
+	// 统计和透视查询以模型 SQL 为子查询，忽略模型的排序和分页
+	var orderByClause, limitClause string
+	_, stats := params[ParamStats]
+	_, pivot := params[ParamPivot]
+	if !stats && !pivot {
+		if orderByClause, err = b.buildOrderByClause(data); err != nil {
+			return "", nil, err
+		}
+		if limitClause, err = b.buildLimitClause(data, params); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// 组装最终 SQL
@@ -231,6 +261,14 @@ func (b *SQLBuilder) BuildFromMetadata(data *ModelData, params map[string]any) (
 	return sb.String(), args, nil
 }
 
+// appendWhere 将条件以 AND 追加到已有 WHERE 子句
+func appendWhere(whereClause, cond string) string {
+	if whereClause == "" {
+		return "WHERE " + cond
+	}
+	return "WHERE (" + strings.TrimPrefix(whereClause, "WHERE ") + ") AND " + cond
+}
+
 // buildSelectClause 构建 SELECT 子句
 func (b *SQLBuilder) buildSelectClause(data *ModelData) (string, error) {
 	if len(data.Fields) == 0 {
@@ -280,20 +318,22 @@ func (b *SQLBuilder) buildFieldExpression(field *model.MdModelField) string {
 	return columnExpr
 }
 
-// buildFromClause 构建 FROM 子句
-func (b *SQLBuilder) buildFromClause(data *ModelData) (string, error) {
-	var mainTable *model.MdModelTable
-	for _, t := range data.Tables {
+// mainTable 返回模型主表，未标记主表时取第一张表
+func (d *ModelData) mainTable() *model.MdModelTable {
+	for _, t := range d.Tables {
 		if t.IsMain {
-			mainTable = t
-			break
+			return t
 		}
 	}
-
-	if mainTable == nil && len(data.Tables) > 0 {
-		mainTable = data.Tables[0]
+	if len(d.Tables) > 0 {
+		return d.Tables[0]
 	}
+	return nil
+}
 
+// buildFromClause 构建 FROM 子句
+func (b *SQLBuilder) buildFromClause(data *ModelData) (string, error) {
+	mainTable := data.mainTable()
 	if mainTable == nil {
 		return "", fmt.Errorf("no table defined for model %s", data.Model.ID)
 	}
@@ -470,7 +510,16 @@ func (b *SQLBuilder) buildWhereClause(data *ModelData, params map[string]any) (s
 			sb.WriteString(w.Brackets1)
 		}
 
-		condSQL, condArgs := b.buildSingleCondition(w, params)
+		var condSQL string
+		var condArgs []any
+		if field := data.encryptedField(w.TableNameStr, w.ColumnName); field != nil {
+			var err error
+			if condSQL, condArgs, err = b.buildEncryptedCondition(w, field, params); err != nil {
+				return "", nil, err
+			}
+		} else {
+			condSQL, condArgs = b.buildSingleCondition(w, params)
+		}
 		sb.WriteString(condSQL)
 		args = append(args, condArgs...)
 
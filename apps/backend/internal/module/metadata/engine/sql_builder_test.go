@@ -191,3 +191,84 @@ func BenchmarkSQLBuilder_BuildFromMetadata(b *testing.B) {
 		}
 	})
 }
+
+func TestSQLBuilder_LogicDeleteFilter(t *testing.T) {
+	builder := &SQLBuilder{}
+
+	data := &ModelData{
+		Model: &model.MdModel{ID: "m1", LogicDeleteField: "is_deleted"},
+		Tables: []*model.MdModelTable{
+			{TableNameStr: "users", IsMain: true},
+		},
+		Wheres: []*model.MdModelWhere{
+			{TableNameStr: "users", ColumnName: "status", Operator2: "=", Value1: "1"},
+		},
+	}
+
+	t.Run("Exclude deleted rows by default", func(t *testing.T) {
+		sql, args, err := builder.BuildFromMetadata(data, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `WHERE ("users"."status" = ?) AND "users"."is_deleted" = ?`)
+		assert.Equal(t, []any{"1", "0"}, args)
+	})
+
+	t.Run("Include deleted rows on request", func(t *testing.T) {
+		sql, args, err := builder.BuildFromMetadata(data, map[string]any{ParamIncludeDeleted: true})
+		assert.NoError(t, err)
+		assert.NotContains(t, sql, "is_deleted")
+		assert.Equal(t, []any{"1"}, args)
+	})
+
+	t.Run("Custom active value without other conditions", func(t *testing.T) {
+		custom := &ModelData{
+			Model:  &model.MdModel{ID: "m2", LogicDeleteField: "deleted", LogicNotDeleteValue: "N"},
+			Tables: data.Tables,
+		}
+		sql, args, err := builder.BuildFromMetadata(custom, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `WHERE "users"."deleted" = ?`)
+		assert.Equal(t, []any{"N"}, args)
+	})
+}
+
+func TestSQLBuilder_DataScopeFilter(t *testing.T) {
+	builder := &SQLBuilder{}
+
+	data := &ModelData{
+		Model: &model.MdModel{ID: "m1", DataOrgField: "org_id", DataOwnerField: "create_id", DataTenantField: "tenant_id"},
+		Tables: []*model.MdModelTable{
+			{TableNameStr: "orders", IsMain: true},
+		},
+	}
+
+	t.Run("No scope means no filtering", func(t *testing.T) {
+		sql, args, err := builder.BuildFromMetadata(data, nil)
+		assert.NoError(t, err)
+		assert.NotContains(t, sql, "WHERE")
+		assert.Empty(t, args)
+	})
+
+	t.Run("All data still filtered by tenant", func(t *testing.T) {
+		scope := &DataScope{UserID: "u1", TenantID: "t1", All: true}
+		sql, args, err := builder.BuildFromMetadata(data, map[string]any{ParamDataScope: scope})
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `WHERE "orders"."tenant_id" = ?`)
+		assert.Equal(t, []any{"t1"}, args)
+	})
+
+	t.Run("Org and self ranges are combined", func(t *testing.T) {
+		scope := &DataScope{UserID: "u1", TenantID: "t1", OrgIDs: []string{"o1", "o2"}, Self: true}
+		sql, args, err := builder.BuildFromMetadata(data, map[string]any{ParamDataScope: scope})
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `WHERE "orders"."tenant_id" = ? AND ("orders"."org_id" IN (?,?) OR "orders"."create_id" = ?)`)
+		assert.Equal(t, []any{"t1", "o1", "o2", "u1"}, args)
+	})
+
+	t.Run("Empty scope denies all rows", func(t *testing.T) {
+		scope := &DataScope{UserID: "u1"}
+		sql, args, err := builder.BuildFromMetadata(data, map[string]any{ParamDataScope: scope})
+		assert.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (1 = 0)")
+		assert.Empty(t, args)
+	})
+}
@@ -58,6 +58,53 @@ func (e *SQLExecutor) Execute(connID string, sqlStr string, args ...any) ([]map[
 	return results, nil
 }
 
+// ExecuteStream 执行 SQL 查询并逐行读取结果，每读满 batchSize 行回调一次，内存占用与批大小相关
+func (e *SQLExecutor) ExecuteStream(connID string, sqlStr string, batchSize int, fn func([]map[string]any) error, args ...any) error {
+	db, err := e.GetConnection(connID)
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	start := time.Now()
+	rows, err := db.Raw(sqlStr, args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	batch := make([]map[string]any, 0, batchSize)
+	for rows.Next() {
+		entry, err := scanRow(rows, columns)
+		if err != nil {
+			return err
+		}
+		if batch = append(batch, entry); len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]map[string]any, 0, batchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+
+	utils.SugarLogger.Infof("SQL Streamed [%v]: %s | Args: %v", time.Since(start), sqlStr, args)
+	return nil
+}
+
 // ExecuteWithTx 在事务中执行 SQL
 func (e *SQLExecutor) ExecuteWithTx(tx *gorm.DB, sqlStr string, args ...any) ([]map[string]any, error) {
 	start := time.Now()
@@ -78,6 +125,30 @@ func (e *SQLExecutor) ExecuteWithTx(tx *gorm.DB, sqlStr string, args ...any) ([]
 	return results, nil
 }
 
+// Exec 执行 INSERT/UPDATE/DELETE 语句并返回受影响行数
+func (e *SQLExecutor) Exec(connID string, sqlStr string, args ...any) (int64, error) {
+	db, err := e.GetConnection(connID)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.ExecWithTx(db, sqlStr, args...)
+}
+
+// ExecWithTx 在事务中执行 INSERT/UPDATE/DELETE 语句并返回受影响行数
+func (e *SQLExecutor) ExecWithTx(tx *gorm.DB, sqlStr string, args ...any) (int64, error) {
+	start := time.Now()
+	result := tx.Exec(sqlStr, args...)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	duration := time.Since(start)
+	utils.SugarLogger.Infof("SQL Exec [%v]: %s | Args: %v | Affected: %d", duration, sqlStr, args, result.RowsAffected)
+
+	return result.RowsAffected, nil
+}
+
 // ExecuteCount 执行 COUNT 查询并返回总数
 func (e *SQLExecutor) ExecuteCount(connID string, sqlStr string, args ...any) (int64, error) {
 	db, err := e.GetConnection(connID)
@@ -170,29 +241,38 @@ func (e *SQLExecutor) parseRows(rows *sql.Rows) ([]map[string]any, error) {
 
 	results := make([]map[string]any, 0)
 	for rows.Next() {
-		values := make([]any, len(columns))
-		valuePtrs := make([]any, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
+		entry, err := scanRow(rows, columns)
+		if err != nil {
 			return nil, err
 		}
+		results = append(results, entry)
+	}
+	return results, nil
+}
 
-		entry := make(map[string]any)
-		for i, col := range columns {
-			val := values[i]
+// scanRow 读取当前行为 map，[]byte 转为字符串
+func scanRow(rows *sql.Rows, columns []string) (map[string]any, error) {
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
 
-			// 类型转换处理 (主要是处理 []byte)
-			b, ok := val.([]byte)
-			if ok {
-				entry[col] = string(b)
-			} else {
-				entry[col] = val
-			}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	entry := make(map[string]any, len(columns))
+	for i, col := range columns {
+		val := values[i]
+
+		// 类型转换处理 (主要是处理 []byte)
+		b, ok := val.([]byte)
+		if ok {
+			entry[col] = string(b)
+		} else {
+			entry[col] = val
 		}
-		results = append(results, entry)
 	}
-	return results, nil
+	return entry, nil
 }
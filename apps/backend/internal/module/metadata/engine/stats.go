@@ -0,0 +1,344 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ParamStats 查询参数：分组统计请求，编译为模型 SQL 外层的聚合查询
+const ParamStats = "stats"
+
+// 分组统计返回行数限制
+const (
+	DefaultStatsLimit = 1000  // 默认最多返回的分组数
+	MaxStatsLimit     = 10000 // 最多返回的分组数上限
+)
+
+// 聚合函数
+const (
+	StatsCount         = "count"
+	StatsCountDistinct = "count_distinct"
+	StatsSum           = "sum"
+	StatsAvg           = "avg"
+	StatsMin           = "min"
+	StatsMax           = "max"
+	StatsPercentile    = "percentile"
+)
+
+// statsIdentifier 统计请求中的字段名和别名
+var statsIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// statsOperators HAVING 支持的比较运算符
+var statsOperators = []string{"=", "!=", "<>", ">", ">=", "<", "<="}
+
+// StatsRequest 分组统计请求
+type StatsRequest struct {
+	GroupBy      []string           `json:"group_by"`
+	TimeBucket   *StatsTimeBucket   `json:"time_bucket"`
+	Aggregations []StatsAggregation `json:"aggregations"`
+	Windows      []StatsWindow      `json:"windows"`
+	Having       []StatsHaving      `json:"having"`
+	OrderBy      []StatsOrder       `json:"order_by"`
+	Limit        int                `json:"limit"`
+}
+
+// StatsAggregation 聚合项，count 未指定字段时统计行数
+type StatsAggregation struct {
+	Func    string  `json:"func"`
+	Field   string  `json:"field"`
+	Alias   string  `json:"alias"`
+	Percent float64 `json:"percent"` // percentile 的百分位，取值 0-1
+}
+
+// StatsHaving 按聚合项别名过滤分组
+type StatsHaving struct {
+	Alias    string `json:"alias"`
+	Operator string `json:"operator"`
+	Value    any    `json:"value"`
+}
+
+// StatsOrder 按分组字段或聚合项别名排序
+type StatsOrder struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// ParseStatsRequest 解析分组统计请求，未提供时返回 nil
+func ParseStatsRequest(value any) (*StatsRequest, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("统计参数格式错误: %w", err)
+	}
+	var req StatsRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, fmt.Errorf("统计参数格式错误: %w", err)
+	}
+	if len(req.GroupBy) == 0 && req.TimeBucket == nil && len(req.Aggregations) == 0 {
+		return nil, fmt.Errorf("统计参数须包含分组字段或聚合项")
+	}
+	return &req, nil
+}
+
+// BuildStatsSQL 以模型 SQL 为子查询构建分组统计 SQL，模型的排序和分页不参与统计
+func (b *SQLBuilder) BuildStatsSQL(modelID string, params map[string]any, dialect string) (string, []any, error) {
+	req, err := ParseStatsRequest(params[ParamStats])
+	if err != nil {
+		return "", nil, err
+	}
+	if req == nil {
+		return "", nil, fmt.Errorf("缺少统计参数 %s", ParamStats)
+	}
+	data, err := b.LoadModelData(modelID)
+	if err != nil {
+		return "", nil, err
+	}
+	inner, args, err := b.BuildSQL(modelID, params)
+	if err != nil {
+		return "", nil, err
+	}
+	return buildStatsQuery(data, req, inner, args, params, dialect)
+}
+
+// buildStatsQuery 在模型 SQL 外层构建分组、聚合、HAVING、排序和行数限制
+func buildStatsQuery(data *ModelData, req *StatsRequest, inner string, args []any, params map[string]any, dialect string) (string, []any, error) {
+	// 1. 分组字段，时间分桶列在最前
+	fields := newStatsFields(data, params)
+	var selects, groups, wheres []string
+	dimensions := slices.Clone(req.GroupBy)
+	if bucket := req.TimeBucket; bucket != nil {
+		if err := fields.checkPlain(bucket.Field); err != nil {
+			return "", nil, err
+		}
+		loc, err := bucket.location()
+		if err != nil {
+			return "", nil, err
+		}
+		expr, err := bucketExpression(dialect, quoteStats(bucket.Field), bucket.Unit, loc)
+		if err != nil {
+			return "", nil, err
+		}
+		if !statsIdentifier.MatchString(bucket.column()) || slices.Contains(req.GroupBy, bucket.column()) {
+			return "", nil, fmt.Errorf("时间分桶别名无效或重复: %s", bucket.column())
+		}
+		selects = append(selects, expr+" AS "+quoteStats(bucket.column()))
+		groups = append(groups, expr)
+		dimensions = append(dimensions, bucket.column())
+
+		// 时间范围按 UTC 比较原始字段
+		from, to, err := bucket.bucketRange(loc)
+		if err != nil {
+			return "", nil, err
+		}
+		if from != "" {
+			wheres = append(wheres, quoteStats(bucket.Field)+" >= ?")
+			args = append(args, from)
+		}
+		if to != "" {
+			wheres = append(wheres, quoteStats(bucket.Field)+" < ?")
+			args = append(args, to)
+		}
+	}
+	for _, g := range req.GroupBy {
+		if err := fields.check(g); err != nil {
+			return "", nil, err
+		}
+		selects = append(selects, quoteStats(g))
+		groups = append(groups, quoteStats(g))
+	}
+
+	// 2. 聚合项
+	exprs := make(map[string]string, len(req.Aggregations))
+	for _, agg := range req.Aggregations {
+		expr, err := statsExpression(agg, dialect, fields)
+		if err != nil {
+			return "", nil, err
+		}
+		alias := agg.alias()
+		if !statsIdentifier.MatchString(alias) || slices.Contains(dimensions, alias) || exprs[alias] != "" {
+			return "", nil, fmt.Errorf("聚合项别名无效或重复: %s", alias)
+		}
+		exprs[alias] = expr
+		selects = append(selects, expr+" AS "+quoteStats(alias))
+	}
+	if err := checkStatsWindows(req, exprs); err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + strings.Join(selects, ", ") + " FROM (" + inner + ") stats_t")
+	if len(wheres) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(wheres, " AND "))
+	}
+	if len(groups) > 0 {
+		sb.WriteString(" GROUP BY " + strings.Join(groups, ", "))
+	}
+
+	// 3. HAVING 使用聚合表达式，部分数据库不支持引用别名
+	var havings []string
+	for _, h := range req.Having {
+		expr, ok := exprs[h.Alias]
+		if !ok {
+			return "", nil, fmt.Errorf("HAVING 引用的聚合项不存在: %s", h.Alias)
+		}
+		if !slices.Contains(statsOperators, h.Operator) {
+			return "", nil, fmt.Errorf("HAVING 运算符无效: %s", h.Operator)
+		}
+		havings = append(havings, fmt.Sprintf("%s %s ?", expr, h.Operator))
+		args = append(args, h.Value)
+	}
+	if len(havings) > 0 {
+		sb.WriteString(" HAVING " + strings.Join(havings, " AND "))
+	}
+
+	// 4. 排序与行数限制，使用时间分桶且未指定排序时按桶的先后排序
+	var orders []string
+	orderBy := req.OrderBy
+	if len(orderBy) == 0 && req.TimeBucket != nil {
+		orderBy = []StatsOrder{{Field: req.TimeBucket.column()}}
+	}
+	for _, o := range orderBy {
+		if _, ok := exprs[o.Field]; !ok && !slices.Contains(dimensions, o.Field) {
+			return "", nil, fmt.Errorf("排序字段须为分组字段或聚合项: %s", o.Field)
+		}
+		order := quoteStats(o.Field)
+		if o.Desc {
+			order += " DESC"
+		}
+		orders = append(orders, order)
+	}
+	if len(orders) > 0 {
+		sb.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultStatsLimit
+	}
+	sb.WriteString(fmt.Sprintf(" LIMIT %d", min(limit, MaxStatsLimit)))
+
+	return sb.String(), args, nil
+}
+
+// alias 返回聚合项别名，默认为函数名与字段名的组合
+func (a StatsAggregation) alias() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	return strings.Trim(a.Func+"_"+a.Field, "_")
+}
+
+// checkStatsWindows 校验滚动窗口，窗口按时间分桶的顺序计算
+func checkStatsWindows(req *StatsRequest, exprs map[string]string) error {
+	if len(req.Windows) > 0 && req.TimeBucket == nil {
+		return fmt.Errorf("滚动窗口须配合时间分桶使用")
+	}
+	for _, w := range req.Windows {
+		if _, ok := exprs[w.Field]; !ok {
+			return fmt.Errorf("滚动窗口引用的聚合项不存在: %s", w.Field)
+		}
+		if w.Func != WindowMovingAvg && w.Func != WindowCumulativeSum {
+			return fmt.Errorf("不支持的滚动窗口函数: %s", w.Func)
+		}
+		if w.Func == WindowMovingAvg && w.Size <= 0 {
+			return fmt.Errorf("移动平均的窗口大小须大于 0")
+		}
+		if alias := w.alias(); !statsIdentifier.MatchString(alias) || exprs[alias] != "" || alias == req.TimeBucket.column() || slices.Contains(req.GroupBy, alias) {
+			return fmt.Errorf("滚动窗口别名无效或重复: %s", alias)
+		}
+	}
+	return nil
+}
+
+// statsFields 统计查询可引用的字段，模型未定义字段 (原始 SQL) 时只校验标识符格式
+type statsFields struct {
+	columns   []string
+	masked    map[string]bool // 对当前用户脱敏的字段
+	encrypted map[string]bool // 加密存储的字段
+}
+
+func newStatsFields(data *ModelData, params map[string]any) *statsFields {
+	f := &statsFields{masked: make(map[string]bool), encrypted: make(map[string]bool)}
+	for _, field := range data.Fields {
+		f.columns = append(f.columns, ResultColumn(field))
+		if field.EncryptAlgo != "" {
+			f.encrypted[ResultColumn(field)] = true
+		}
+	}
+	scope, _ := params[ParamDataScope].(*DataScope)
+	for _, p := range data.Masks {
+		if !p.Exempt(scope) {
+			f.masked[p.Column] = true
+		}
+	}
+	return f
+}
+
+// check 校验字段名
+func (f *statsFields) check(name string) error {
+	if !statsIdentifier.MatchString(name) || (len(f.columns) > 0 && !slices.Contains(f.columns, name)) {
+		return fmt.Errorf("统计字段无效: %s", name)
+	}
+	return nil
+}
+
+// checkAggregate 校验聚合字段，脱敏字段只允许计数，避免通过 min/max 等取得原值
+func (f *statsFields) checkAggregate(fn, name string) error {
+	if err := f.check(name); err != nil {
+		return err
+	}
+	if f.masked[name] && fn != StatsCount && fn != StatsCountDistinct {
+		return fmt.Errorf("脱敏字段 %s 仅支持计数统计", name)
+	}
+	return nil
+}
+
+// checkPlain 校验取值会原样返回的字段 (如透视列、时间分桶)，不允许使用脱敏或加密字段
+func (f *statsFields) checkPlain(name string) error {
+	if err := f.check(name); err != nil {
+		return err
+	}
+	if f.masked[name] || f.encrypted[name] {
+		return fmt.Errorf("脱敏或加密字段 %s 不能作为维度", name)
+	}
+	return nil
+}
+
+// statsExpression 构建单个聚合表达式
+func statsExpression(agg StatsAggregation, dialect string, fields *statsFields) (string, error) {
+	if agg.Func == StatsCount && agg.Field == "" {
+		return "COUNT(*)", nil
+	}
+	if err := fields.checkAggregate(agg.Func, agg.Field); err != nil {
+		return "", err
+	}
+	column := quoteStats(agg.Field)
+
+	switch agg.Func {
+	case StatsCount, StatsSum, StatsAvg, StatsMin, StatsMax:
+		return strings.ToUpper(agg.Func) + "(" + column + ")", nil
+	case StatsCountDistinct:
+		return "COUNT(DISTINCT " + column + ")", nil
+	case StatsPercentile:
+		if agg.Percent < 0 || agg.Percent > 1 {
+			return "", fmt.Errorf("百分位须在 0-1 之间: %v", agg.Percent)
+		}
+		switch dialect {
+		case "postgres", "oracle":
+			return fmt.Sprintf("PERCENTILE_CONT(%g) WITHIN GROUP (ORDER BY %s)", agg.Percent, column), nil
+		default:
+			return "", fmt.Errorf("%s 数据库不支持聚合函数 %s", dialect, agg.Func)
+		}
+	default:
+		return "", fmt.Errorf("不支持的聚合函数: %s", agg.Func)
+	}
+}
+
+// quoteStats 引用统计查询中的标识符，与模型 SQL 的引用方式一致
+func quoteStats(name string) string {
+	return `"` + name + `"`
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"metadata-platform/internal/module/metadata/model"
+)
+
+func TestBuildStatsQuery(t *testing.T) {
+	builder := &SQLBuilder{}
+	data := &ModelData{
+		Model:  &model.MdModel{ID: "m1"},
+		Tables: []*model.MdModelTable{{TableNameStr: "orders", IsMain: true}},
+		Fields: []*model.MdModelField{
+			{ID: "f1", TableNameStr: "orders", ColumnName: "status"},
+			{ID: "f2", TableNameStr: "orders", ColumnName: "region"},
+			{ID: "f3", TableNameStr: "orders", ColumnName: "amount"},
+			{ID: "f4", TableNameStr: "orders", ColumnName: "phone"},
+		},
+		Orders: []*model.MdModelOrder{{TableNameStr: "orders", ColumnName: "status"}},
+		Limit:  &model.MdModelLimit{Limit: 10},
+		Masks:  []*MaskPolicy{{Column: "phone", RawColumn: "phone", Type: MaskPartial}},
+	}
+	build := func(stats map[string]any, dialect string) (string, []any, error) {
+		params := map[string]any{ParamStats: stats}
+		req, err := ParseStatsRequest(stats)
+		if err != nil {
+			return "", nil, err
+		}
+		inner, args, err := builder.BuildFromMetadata(data, params)
+		require.NoError(t, err)
+		return buildStatsQuery(data, req, inner, args, params, dialect)
+	}
+
+	t.Run("Group by with aggregations, having and ordering", func(t *testing.T) {
+		sql, args, err := build(map[string]any{
+			"group_by": []any{"status", "region"},
+			"aggregations": []any{
+				map[string]any{"func": "count", "alias": "cnt"},
+				map[string]any{"func": "sum", "field": "amount"},
+				map[string]any{"func": "count_distinct", "field": "phone", "alias": "phones"},
+			},
+			"having":   []any{map[string]any{"alias": "cnt", "operator": ">", "value": 1}},
+			"order_by": []any{map[string]any{"field": "sum_amount", "desc": true}},
+			"limit":    50,
+		}, "sqlite")
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT "status", "region", COUNT(*) AS "cnt", SUM("amount") AS "sum_amount", COUNT(DISTINCT "phone") AS "phones"`+
+			` FROM (SELECT "orders"."status", "orders"."region", "orders"."amount", "orders"."phone" FROM "orders") stats_t`+
+			` GROUP BY "status", "region" HAVING COUNT(*) > ? ORDER BY "sum_amount" DESC LIMIT 50`, sql)
+		assert.Equal(t, []any{float64(1)}, args)
+	})
+
+	t.Run("Percentile depends on dialect", func(t *testing.T) {
+		stats := map[string]any{"aggregations": []any{map[string]any{"func": "percentile", "field": "amount", "percent": 0.9, "alias": "p90"}}}
+		sql, _, err := build(stats, "postgres")
+		require.NoError(t, err)
+		assert.Contains(t, sql, `PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY "amount") AS "p90"`)
+		assert.Contains(t, sql, "LIMIT 1000")
+
+		_, _, err = build(stats, "mysql")
+		assert.Error(t, err)
+	})
+
+	t.Run("Time bucket with range and default ordering", func(t *testing.T) {
+		sql, args, err := build(map[string]any{
+			"time_bucket":  map[string]any{"field": "status", "unit": "month", "alias": "month", "time_zone": "Asia/Shanghai", "from": "2024-01-15", "to": "2024-03-01"},
+			"aggregations": []any{map[string]any{"func": "sum", "field": "amount", "alias": "total"}},
+			"windows":      []any{map[string]any{"func": "cumulative_sum", "field": "total"}},
+		}, "postgres")
+		require.NoError(t, err)
+		bucket := `TO_CHAR(DATE_TRUNC('month', ("status" AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Shanghai')), 'YYYY-MM-DD HH24:MI:SS')`
+		assert.Equal(t, `SELECT `+bucket+` AS "month", SUM("amount") AS "total"`+
+			` FROM (SELECT "orders"."status", "orders"."region", "orders"."amount", "orders"."phone" FROM "orders") stats_t`+
+			` WHERE "status" >= ? AND "status" < ? GROUP BY `+bucket+` ORDER BY "month" LIMIT 1000`, sql)
+		assert.Equal(t, []any{"2023-12-31 16:00:00", "2024-03-31 16:00:00"}, args)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		for name, stats := range map[string]map[string]any{
+			"unknown field":      {"group_by": []any{"missing"}},
+			"injected field":     {"group_by": []any{`status" --`}},
+			"unknown function":   {"aggregations": []any{map[string]any{"func": "median", "field": "amount"}}},
+			"masked max":         {"aggregations": []any{map[string]any{"func": "max", "field": "phone"}}},
+			"having unknown":     {"group_by": []any{"status"}, "having": []any{map[string]any{"alias": "cnt", "operator": ">", "value": 1}}},
+			"order not in query": {"group_by": []any{"status"}, "order_by": []any{map[string]any{"field": "region"}}},
+			"empty":              {},
+			"bucket unit":        {"time_bucket": map[string]any{"field": "status", "unit": "minute"}},
+			"bucket masked":      {"time_bucket": map[string]any{"field": "phone", "unit": "day"}},
+			"bucket time zone":   {"time_bucket": map[string]any{"field": "status", "unit": "day", "time_zone": "Mars/Base"}},
+			"window no bucket":   {"aggregations": []any{map[string]any{"func": "count", "alias": "cnt"}}, "windows": []any{map[string]any{"func": "cumulative_sum", "field": "cnt"}}},
+			"window size": {
+				"time_bucket":  map[string]any{"field": "status", "unit": "day"},
+				"aggregations": []any{map[string]any{"func": "count", "alias": "cnt"}},
+				"windows":      []any{map[string]any{"func": "moving_avg", "field": "cnt"}},
+			},
+		} {
+			_, _, err := build(stats, "sqlite")
+			assert.Error(t, err, name)
+		}
+	})
+}
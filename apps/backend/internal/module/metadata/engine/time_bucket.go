@@ -0,0 +1,434 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 时间分桶粒度
+const (
+	BucketHour    = "hour"
+	BucketDay     = "day"
+	BucketWeek    = "week" // 以周一为一周的开始
+	BucketMonth   = "month"
+	BucketQuarter = "quarter"
+	BucketYear    = "year"
+)
+
+// 滚动窗口函数
+const (
+	WindowMovingAvg     = "moving_avg"
+	WindowCumulativeSum = "cumulative_sum"
+)
+
+// StatsBucketLayout 分桶值格式，各数据库均将桶的起始时间格式化为该形式的字符串
+const StatsBucketLayout = "2006-01-02 15:04:05"
+
+// StatsTimeBucket 按时间粒度分组，字段值按 UTC 存储，指定时区时转换到该时区后分桶
+type StatsTimeBucket struct {
+	Field    string `json:"field"`
+	Unit     string `json:"unit"`
+	Alias    string `json:"alias"`     // 分桶列别名，默认与字段同名
+	TimeZone string `json:"time_zone"` // IANA 时区，如 Asia/Shanghai
+	From     string `json:"from"`      // 起始时间 (含)，按分桶时区解释
+	To       string `json:"to"`        // 结束时间所在的桶 (含)
+	FillGaps bool   `json:"fill_gaps"` // 为没有数据的桶补零
+}
+
+// StatsWindow 滚动窗口，按分桶顺序对聚合项计算，有其他分组字段时各分组分别计算
+type StatsWindow struct {
+	Func  string `json:"func"`
+	Field string `json:"field"` // 聚合项别名
+	Size  int    `json:"size"`  // moving_avg 的窗口大小
+	Alias string `json:"alias"`
+}
+
+// bucketUnits 分桶粒度对应的 Oracle TRUNC 格式
+var bucketUnits = map[string]string{
+	BucketHour:    "HH24",
+	BucketDay:     "DD",
+	BucketWeek:    "IW",
+	BucketMonth:   "MM",
+	BucketQuarter: "Q",
+	BucketYear:    "YYYY",
+}
+
+// location 返回分桶时区，未指定时为 UTC
+func (t *StatsTimeBucket) location() (*time.Location, error) {
+	if t.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(t.TimeZone)
+	if err != nil || strings.ContainsAny(t.TimeZone, "'\\") {
+		return nil, fmt.Errorf("时区无效: %s", t.TimeZone)
+	}
+	return loc, nil
+}
+
+// column 返回分桶列别名
+func (t *StatsTimeBucket) column() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Field
+}
+
+// bucketExpression 按数据库构建分桶表达式，结果为桶起始时间的字符串
+func bucketExpression(dialect, column, unit string, loc *time.Location) (string, error) {
+	if _, ok := bucketUnits[unit]; !ok {
+		return "", fmt.Errorf("时间分桶粒度无效: %s", unit)
+	}
+	local := loc != time.UTC
+
+	switch dialect {
+	case "postgres":
+		if local {
+			column = fmt.Sprintf("(%s AT TIME ZONE 'UTC' AT TIME ZONE '%s')", column, loc)
+		}
+		return fmt.Sprintf("TO_CHAR(DATE_TRUNC('%s', %s), 'YYYY-MM-DD HH24:MI:SS')", unit, column), nil
+	case "oracle":
+		if local {
+			column = fmt.Sprintf("(FROM_TZ(CAST(%s AS TIMESTAMP), 'UTC') AT TIME ZONE '%s')", column, loc)
+		}
+		return fmt.Sprintf("TO_CHAR(TRUNC(%s, '%s'), 'YYYY-MM-DD HH24:MI:SS')", column, bucketUnits[unit]), nil
+	case "mysql":
+		// 按时区名换算以正确处理夏令时，须已向 MySQL 导入时区表 (mysql_tzinfo_to_sql)，否则结果为 NULL
+		if local {
+			column = fmt.Sprintf("CONVERT_TZ(%s, '+00:00', '%s')", column, loc)
+		}
+		switch unit {
+		case BucketWeek:
+			return fmt.Sprintf("DATE_FORMAT(DATE_SUB(%s, INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d 00:00:00')", column, column), nil
+		case BucketQuarter:
+			return fmt.Sprintf("CONCAT(YEAR(%s), '-', LPAD(QUARTER(%s) * 3 - 2, 2, '0'), '-01 00:00:00')", column, column), nil
+		}
+		return fmt.Sprintf("DATE_FORMAT(%s, '%s')", column, strftimeLayout(unit)), nil
+	case "sqlite":
+		// SQLite 没有时区库，只能按固定偏移量换算，不支持实行夏令时的时区
+		if local {
+			offset, ok := fixedOffset(loc)
+			if !ok {
+				return "", fmt.Errorf("%s 数据库不支持夏令时时区 %s", dialect, loc)
+			}
+			column = fmt.Sprintf("datetime(%s, '%+d minutes')", column, offset/60)
+		}
+		switch unit {
+		case BucketWeek:
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'weekday 0', '-6 days')", column), nil
+		case BucketQuarter:
+			return fmt.Sprintf("strftime('%%Y-', %s) || printf('%%02d', (CAST(strftime('%%m', %s) AS INTEGER) + 2) / 3 * 3 - 2) || '-01 00:00:00'", column, column), nil
+		}
+		return fmt.Sprintf("strftime('%s', %s)", strftimeLayout(unit), column), nil
+	default:
+		return "", fmt.Errorf("%s 数据库不支持时间分桶", dialect)
+	}
+}
+
+// strftimeLayout 返回 MySQL DATE_FORMAT 与 SQLite strftime 通用的格式
+func strftimeLayout(unit string) string {
+	switch unit {
+	case BucketHour:
+		return "%Y-%m-%d %H:00:00"
+	case BucketMonth:
+		return "%Y-%m-01 00:00:00"
+	case BucketYear:
+		return "%Y-01-01 00:00:00"
+	default:
+		return "%Y-%m-%d 00:00:00"
+	}
+}
+
+// fixedOffset 返回时区相对 UTC 的秒数偏移，近两年内偏移量有变化 (实行夏令时) 时返回 false
+func fixedOffset(loc *time.Location) (int, bool) {
+	year := time.Now().Year()
+	_, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	for _, t := range []time.Time{
+		time.Date(year, time.July, 1, 0, 0, 0, 0, loc),
+		time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc),
+		time.Date(year+1, time.July, 1, 0, 0, 0, 0, loc),
+	} {
+		if _, o := t.Zone(); o != offset {
+			return 0, false
+		}
+	}
+	return offset, true
+}
+
+// bucketRange 返回分桶范围在 UTC 下的起止时间 (左闭右开)，未指定的一端为空
+func (t *StatsTimeBucket) bucketRange(loc *time.Location) (from, to string, err error) {
+	if t.From != "" {
+		start, err := parseBucketTime(t.From, loc)
+		if err != nil {
+			return "", "", err
+		}
+		from = wallToUTC(truncateBucket(start, t.Unit), loc).Format(StatsBucketLayout)
+	}
+	if t.To != "" {
+		end, err := parseBucketTime(t.To, loc)
+		if err != nil {
+			return "", "", err
+		}
+		to = wallToUTC(nextBucket(truncateBucket(end, t.Unit), t.Unit), loc).Format(StatsBucketLayout)
+	}
+	return from, to, nil
+}
+
+// parseBucketTime 解析时间参数，返回其在分桶时区的本地时间 (以 UTC 表示)
+func parseBucketTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		wall := t.In(loc)
+		return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.UTC), nil
+	}
+	for _, layout := range []string{StatsBucketLayout, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间格式无效: %s", value)
+}
+
+// wallToUTC 将分桶时区的本地时间转换为 UTC
+func wallToUTC(wall time.Time, loc *time.Location) time.Time {
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc).UTC()
+}
+
+// truncateBucket 截断到所在桶的起始时间
+func truncateBucket(t time.Time, unit string) time.Time {
+	switch unit {
+	case BucketHour:
+		return t.Truncate(time.Hour)
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case BucketQuarter:
+		return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case BucketYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// nextBucket 返回下一个桶的起始时间
+func nextBucket(t time.Time, unit string) time.Time {
+	switch unit {
+	case BucketHour:
+		return t.Add(time.Hour)
+	case BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case BucketMonth:
+		return t.AddDate(0, 1, 0)
+	case BucketQuarter:
+		return t.AddDate(0, 3, 0)
+	case BucketYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// FinishStatsRows 对分组统计结果补齐空桶并计算滚动窗口，未使用时间分桶时原样返回
+// 补齐空桶时按桶的先后返回，同一桶内按分组首次出现的顺序排列
+func FinishStatsRows(req *StatsRequest, rows []map[string]any) ([]map[string]any, error) {
+	bucket := req.TimeBucket
+	if bucket == nil {
+		return rows, nil
+	}
+	column := bucket.column()
+
+	// 1. 统一分桶值并按其他分组字段划分序列
+	var keys []string
+	series := make(map[string][]map[string]any)
+	for _, row := range rows {
+		switch v := row[column].(type) {
+		case []byte:
+			row[column] = string(v)
+		case time.Time:
+			row[column] = v.Format(StatsBucketLayout)
+		}
+		var parts []string
+		for _, g := range req.GroupBy {
+			parts = append(parts, fmt.Sprintf("%v", row[g]))
+		}
+		key := strings.Join(parts, "\x00")
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], row)
+	}
+	if len(keys) == 0 && len(req.GroupBy) == 0 {
+		keys = append(keys, "")
+	}
+	for _, key := range keys {
+		slices.SortStableFunc(series[key], func(a, b map[string]any) int {
+			return strings.Compare(fmt.Sprintf("%v", a[column]), fmt.Sprintf("%v", b[column]))
+		})
+	}
+
+	// 2. 补齐空桶
+	if bucket.FillGaps {
+		buckets, err := bucketSequence(bucket, rows)
+		if err != nil {
+			return nil, err
+		}
+		filled := make([]map[string]any, 0, len(buckets)*max(len(keys), 1))
+		for _, key := range keys {
+			existing := make(map[string]map[string]any, len(series[key]))
+			for _, row := range series[key] {
+				existing[fmt.Sprintf("%v", row[column])] = row
+			}
+			var sample map[string]any
+			if len(series[key]) > 0 {
+				sample = series[key][0]
+			}
+			list := make([]map[string]any, 0, len(buckets))
+			for _, b := range buckets {
+				row, ok := existing[b]
+				if !ok {
+					row = emptyBucketRow(req, sample, column, b)
+				}
+				list = append(list, row)
+			}
+			series[key] = list
+		}
+		for i := range buckets {
+			for _, key := range keys {
+				filled = append(filled, series[key][i])
+			}
+		}
+		rows = filled
+	}
+
+	// 3. 滚动窗口
+	for _, key := range keys {
+		for _, w := range req.Windows {
+			applyWindow(series[key], w)
+		}
+	}
+	return rows, nil
+}
+
+// bucketSequence 返回补齐范围内的全部桶，未指定范围时取结果中最早和最晚的桶
+func bucketSequence(bucket *StatsTimeBucket, rows []map[string]any) ([]string, error) {
+	loc, err := bucket.location()
+	if err != nil {
+		return nil, err
+	}
+	var first, last time.Time
+	if bucket.From != "" {
+		if first, err = parseBucketTime(bucket.From, loc); err != nil {
+			return nil, err
+		}
+	}
+	if bucket.To != "" {
+		if last, err = parseBucketTime(bucket.To, loc); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range rows {
+		t, err := time.Parse(StatsBucketLayout, fmt.Sprintf("%v", row[bucket.column()]))
+		if err != nil {
+			continue
+		}
+		if bucket.From == "" && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+		if bucket.To == "" && (last.IsZero() || t.After(last)) {
+			last = t
+		}
+	}
+	if first.IsZero() || last.IsZero() {
+		return nil, nil
+	}
+
+	var buckets []string
+	for t := truncateBucket(first, bucket.Unit); !t.After(last); t = nextBucket(t, bucket.Unit) {
+		if len(buckets) >= MaxStatsLimit {
+			return nil, fmt.Errorf("补齐的时间桶超过 %d 个，请缩小时间范围或增大分桶粒度", MaxStatsLimit)
+		}
+		buckets = append(buckets, t.Format(StatsBucketLayout))
+	}
+	return buckets, nil
+}
+
+// emptyBucketRow 构建空桶记录，计数和求和为 0，其他聚合项为空
+func emptyBucketRow(req *StatsRequest, sample map[string]any, column, bucket string) map[string]any {
+	row := map[string]any{column: bucket}
+	for _, g := range req.GroupBy {
+		row[g] = sample[g]
+	}
+	for _, agg := range req.Aggregations {
+		var zero any
+		if agg.Func == StatsCount || agg.Func == StatsCountDistinct || agg.Func == StatsSum {
+			zero = int64(0)
+		}
+		row[agg.alias()] = zero
+	}
+	return row
+}
+
+// applyWindow 按顺序计算滚动窗口，空值不参与计算
+func applyWindow(rows []map[string]any, w StatsWindow) {
+	var sum float64
+	for i, row := range rows {
+		switch w.Func {
+		case WindowCumulativeSum:
+			v, _ := statsNumber(row[w.Field])
+			sum += v
+			row[w.alias()] = sum
+		case WindowMovingAvg:
+			var total float64
+			var n int
+			for _, r := range rows[max(i-w.Size+1, 0) : i+1] {
+				if v, ok := statsNumber(r[w.Field]); ok {
+					total += v
+					n++
+				}
+			}
+			if n == 0 {
+				row[w.alias()] = nil
+			} else {
+				row[w.alias()] = total / float64(n)
+			}
+		}
+	}
+}
+
+// alias 返回窗口列别名
+func (w StatsWindow) alias() string {
+	if w.Alias != "" {
+		return w.Alias
+	}
+	return w.Func + "_" + w.Field
+}
+
+// statsNumber 将聚合结果转换为浮点数
+func statsNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case []byte:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
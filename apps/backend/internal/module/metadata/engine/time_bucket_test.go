@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketExpression(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		dialect, unit string
+		loc           *time.Location
+		expected      string
+	}{
+		{"postgres", BucketWeek, time.UTC, `TO_CHAR(DATE_TRUNC('week', "t"), 'YYYY-MM-DD HH24:MI:SS')`},
+		{"oracle", BucketQuarter, shanghai, `TO_CHAR(TRUNC((FROM_TZ(CAST("t" AS TIMESTAMP), 'UTC') AT TIME ZONE 'Asia/Shanghai'), 'Q'), 'YYYY-MM-DD HH24:MI:SS')`},
+		{"mysql", BucketDay, shanghai, `DATE_FORMAT(CONVERT_TZ("t", '+00:00', 'Asia/Shanghai'), '%Y-%m-%d 00:00:00')`},
+		{"mysql", BucketHour, newYork, `DATE_FORMAT(CONVERT_TZ("t", '+00:00', 'America/New_York'), '%Y-%m-%d %H:00:00')`},
+		{"mysql", BucketWeek, time.UTC, `DATE_FORMAT(DATE_SUB("t", INTERVAL WEEKDAY("t") DAY), '%Y-%m-%d 00:00:00')`},
+		{"sqlite", BucketHour, shanghai, `strftime('%Y-%m-%d %H:00:00', datetime("t", '+480 minutes'))`},
+	} {
+		expr, err := bucketExpression(tc.dialect, `"t"`, tc.unit, tc.loc)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, expr, tc.dialect+" "+tc.unit)
+	}
+
+	_, err = bucketExpression("sqlserver", `"t"`, BucketDay, time.UTC)
+	assert.Error(t, err)
+
+	// SQLite 只能按固定偏移量换算，实行夏令时的时区直接拒绝
+	_, err = bucketExpression("sqlite", `"t"`, BucketDay, newYork)
+	assert.ErrorContains(t, err, "夏令时")
+}
+
+func TestFinishStatsRows(t *testing.T) {
+	req := &StatsRequest{
+		GroupBy:      []string{"region"},
+		TimeBucket:   &StatsTimeBucket{Field: "created_at", Unit: BucketDay, From: "2024-01-01", To: "2024-01-04", FillGaps: true},
+		Aggregations: []StatsAggregation{{Func: StatsSum, Field: "amount", Alias: "total"}, {Func: StatsAvg, Field: "amount"}},
+		Windows: []StatsWindow{
+			{Func: WindowCumulativeSum, Field: "total"},
+			{Func: WindowMovingAvg, Field: "total", Size: 2, Alias: "ma"},
+		},
+	}
+	rows, err := FinishStatsRows(req, []map[string]any{
+		{"created_at": "2024-01-03 00:00:00", "region": "east", "total": int64(30), "avg_amount": 30.0},
+		{"created_at": []byte("2024-01-01 00:00:00"), "region": "east", "total": int64(10), "avg_amount": 10.0},
+		{"created_at": "2024-01-02 00:00:00", "region": "west", "total": int64(5), "avg_amount": 5.0},
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 8)
+
+	// 按桶排列，同一桶内按分组首次出现的顺序
+	assert.Equal(t, "2024-01-01 00:00:00", rows[0]["created_at"])
+	assert.Equal(t, "east", rows[0]["region"])
+	assert.Equal(t, "west", rows[1]["region"])
+	assert.EqualValues(t, 0, rows[1]["total"])
+	assert.Nil(t, rows[1]["avg_amount"])
+
+	east := []map[string]any{rows[0], rows[2], rows[4], rows[6]}
+	var cumulative, moving []any
+	for _, row := range east {
+		cumulative = append(cumulative, row["cumulative_sum_total"])
+		moving = append(moving, row["ma"])
+	}
+	assert.Equal(t, []any{10.0, 10.0, 40.0, 40.0}, cumulative)
+	assert.Equal(t, []any{10.0, 5.0, 15.0, 15.0}, moving)
+
+	t.Run("Too many buckets", func(t *testing.T) {
+		req := &StatsRequest{TimeBucket: &StatsTimeBucket{Field: "created_at", Unit: BucketHour, From: "2000-01-01", To: "2024-01-01", FillGaps: true}}
+		_, err := FinishStatsRows(req, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,34 @@
+package engine
+
+import "strings"
+
+// UniqueKey 返回模型的唯一键字段，未配置唯一键时使用主键
+func (d *ModelData) UniqueKey() []string {
+	var keys []string
+	if d.Model != nil {
+		for _, k := range strings.Split(d.Model.UniqueKeyFields, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+	}
+	if len(keys) > 0 {
+		return keys
+	}
+	for _, f := range d.Fields {
+		if f.IsPrimaryKey {
+			keys = append(keys, f.ColumnName)
+		}
+	}
+	return keys
+}
+
+// PrimaryKey 返回模型主键列名，未配置主键时默认为 id
+func (d *ModelData) PrimaryKey() string {
+	for _, f := range d.Fields {
+		if f.IsPrimaryKey {
+			return f.ColumnName
+		}
+	}
+	return "id"
+}
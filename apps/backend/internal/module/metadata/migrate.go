@@ -38,6 +38,10 @@ func Migrate(db *gorm.DB) error {
 		&model.MdModelParam{},
 		&model.MdModelProcedure{},
 		&model.MdModelProcedureParam{},
+		&model.MdExportJob{},
+		&model.MdImportJob{},
+		&model.MdReEncryptJob{},
+		&model.MdAPIRevision{},
 	}
 
 	if err = helper.AutoMigrate(models...); err != nil {
@@ -68,6 +72,10 @@ func Migrate(db *gorm.DB) error {
 		"md_model_param":           "模型参数",
 		"md_model_procedure":       "模型存储过程/函数",
 		"md_model_procedure_param": "模型存储过程/函数参数",
+		"md_export_job":            "数据导出任务",
+		"md_import_job":            "数据导入任务",
+		"md_re_encrypt_job":        "加密字段重新加密任务",
+		"md_api_revision":          "动态接口版本",
 	}
 	helper.AddComments(comments)
 
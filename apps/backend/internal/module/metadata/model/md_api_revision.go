@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// APIRevisionID 动态接口版本记录的主键
+const APIRevisionID = "api"
+
+// MdAPIRevision 动态接口版本，接口变更时递增，各实例发现版本变化后重新加载动态接口
+type MdAPIRevision struct {
+	ID       string    `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
+	Revision int64     `json:"revision" form:"revision" gorm:"not null;default:0;comment:版本号"`
+	UpdateAt time.Time `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
+}
+
+// TableName 指定表名
+func (MdAPIRevision) TableName() string {
+	return "md_api_revision"
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// MdExportJob 导出任务模型，导出文件保存在文件存储中，过期后连同文件一并清理
+type MdExportJob struct {
+	ID           string     `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
+	TenantID     string     `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
+	ModelID      string     `json:"model_id" form:"model_id" gorm:"index;type:varchar(64);not null;default:'';comment:模型ID"`
+	Format       string     `json:"format" form:"format" gorm:"size:16;not null;default:'';comment:文件格式: json, ndjson, csv, xlsx, parquet"`
+	Encoding     string     `json:"encoding" form:"encoding" gorm:"size:16;default:'';comment:CSV 文件编码"`
+	Compression  string     `json:"compression" form:"compression" gorm:"size:16;default:'';comment:Parquet 压缩算法"`
+	RowGroupSize int        `json:"row_group_size" form:"row_group_size" gorm:"default:0;comment:Parquet 每个行组的行数"`
+	Params       string     `json:"params" form:"params" gorm:"type:text;comment:查询参数(JSON)"`
+	Status       string     `json:"status" form:"status" gorm:"size:16;not null;default:'';comment:状态: pending, running, succeeded, failed, canceled"`
+	Total        int64      `json:"total" form:"total" gorm:"default:0;comment:数据总数"`
+	Processed    int64      `json:"processed" form:"processed" gorm:"default:0;comment:已导出数"`
+	FileName     string     `json:"file_name" form:"file_name" gorm:"size:256;default:'';comment:存储中的文件名"`
+	FileSize     int64      `json:"file_size" form:"file_size" gorm:"default:0;comment:文件大小"`
+	Error        string     `json:"error" form:"error" gorm:"size:1024;default:'';comment:失败原因"`
+	ExpireAt     time.Time  `json:"expire_at" form:"expire_at" gorm:"index;comment:过期时间"`
+	FinishedAt   *time.Time `json:"finished_at" form:"finished_at" gorm:"comment:结束时间"`
+	CreateID     string     `json:"create_id" form:"create_id" gorm:"index;size:64;default:'';comment:创建人ID"`
+	CreateAt     time.Time  `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateAt     time.Time  `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
+}
+
+// TableName 指定表名
+func (MdExportJob) TableName() string {
+	return "md_export_job"
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// MdImportJob 导入任务模型，上传文件及完整错误列表保存在文件存储中，过期后连同文件一并清理
+type MdImportJob struct {
+	ID            string     `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
+	TenantID      string     `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
+	ModelID       string     `json:"model_id" form:"model_id" gorm:"index;type:varchar(64);not null;default:'';comment:模型ID"`
+	Options       string     `json:"options" form:"options" gorm:"type:text;comment:导入选项(JSON)"`
+	Status        string     `json:"status" form:"status" gorm:"size:16;not null;default:'';comment:状态: pending, running, succeeded, failed, canceled"`
+	Total         int        `json:"total" form:"total" gorm:"default:0;comment:数据行数"`
+	Processed     int        `json:"processed" form:"processed" gorm:"default:0;comment:已处理的行数"`
+	Inserted      int        `json:"inserted" form:"inserted" gorm:"default:0;comment:新增数"`
+	Updated       int        `json:"updated" form:"updated" gorm:"default:0;comment:更新数"`
+	Skipped       int        `json:"skipped" form:"skipped" gorm:"default:0;comment:跳过数"`
+	Failed        int        `json:"failed" form:"failed" gorm:"default:0;comment:失败的行数"`
+	ErrorCount    int        `json:"error_count" form:"error_count" gorm:"default:0;comment:错误总数"`
+	Errors        string     `json:"errors" form:"errors" gorm:"type:text;comment:前若干条错误(JSON)"`
+	FileName      string     `json:"file_name" form:"file_name" gorm:"size:256;default:'';comment:上传文件在存储中的文件名"`
+	ErrorFileName string     `json:"error_file_name" form:"error_file_name" gorm:"size:256;default:'';comment:完整错误列表在存储中的文件名"`
+	Error         string     `json:"error" form:"error" gorm:"size:1024;default:'';comment:失败原因"`
+	ExpireAt      time.Time  `json:"expire_at" form:"expire_at" gorm:"index;comment:过期时间"`
+	FinishedAt    *time.Time `json:"finished_at" form:"finished_at" gorm:"comment:结束时间"`
+	CreateID      string     `json:"create_id" form:"create_id" gorm:"index;size:64;default:'';comment:创建人ID"`
+	CreateAt      time.Time  `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateAt      time.Time  `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
+}
+
+// TableName 指定表名
+func (MdImportJob) TableName() string {
+	return "md_import_job"
+}
@@ -4,31 +4,42 @@ import "time"
 
 // MdModel 模型定义模型
 type MdModel struct {
-	ID              string    `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
-	TenantID        string    `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
-	ParentID        string    `json:"parent_id" form:"parent_id" gorm:"type:varchar(64);not null;default:'';comment:父ID"`
-	ConnID          string    `json:"conn_id" form:"conn_id" gorm:"type:varchar(64);not null;default:'';comment:连接ID"`
-	ConnName        string    `json:"conn_name" form:"conn_name" gorm:"size:256;default:'';comment:连接名称"`
-	ModelName       string    `json:"model_name" form:"model_name" gorm:"size:128;not null;default:'';comment:模型名称"`
-	ModelCode       string    `json:"model_code" form:"model_code" gorm:"size:128;not null;default:'';uniqueIndex:uix_md_model_title_creator;comment:模型编码"`
-	ModelVersion    string    `json:"model_version" form:"model_version" gorm:"size:64;not null;default:'1.0.0';comment:模型版本"`
-	ModelLogo       string    `json:"model_logo" form:"model_logo" gorm:"size:512;not null;default:'';comment:模型Logo"`
-	ModelKind       int       `json:"model_kind" form:"model_kind" gorm:"not null;default:0;comment:模型类型：1sql语句、2视图/表、3存储过程、4关联"`
-	IsPublic        bool      `json:"is_public" form:"is_public" gorm:"not null;default:false;comment:是否公开"`
-	IsLocked        bool      `json:"is_locked" form:"is_locked" gorm:"default:false;comment:是否锁定"`
-	IsTree          bool      `json:"is_tree" form:"is_tree" gorm:"default:false;comment:是否树形结构"`                          // 是否树形结构
-	TreeParentField string    `json:"tree_parent_field" form:"tree_parent_field" gorm:"size:64;default:'';comment:父节点字段名"` // 父节点字段名
-	TreePathField   string    `json:"tree_path_field" form:"tree_path_field" gorm:"size:64;default:'';comment:路径字段名"`      // 路径字段名
-	TreeLevelField  string    `json:"tree_level_field" form:"tree_level_field" gorm:"size:64;default:'';comment:层级字段名"`    // 层级字段名
-	Parameters      string    `json:"parameters" form:"parameters" gorm:"type:text;comment:模型参数(JSON)"`
-	Remark          string    `json:"remark" form:"remark" gorm:"size:1024;default:'';comment:备注"`
-	IsDeleted       bool      `json:"is_deleted" form:"is_deleted" gorm:"default:false;comment:是否删除"`
-	CreateID        string    `json:"create_id" form:"create_id" gorm:"size:64;default:'';comment:创建人ID"`
-	CreateBy        string    `json:"create_by" form:"create_by" gorm:"size:64;default:'';uniqueIndex:uix_md_model_title_creator;comment:创建人"`
-	CreateAt        time.Time `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
-	UpdateID        string    `json:"update_id" form:"update_id" gorm:"size:64;default:'';comment:更新人ID"`
-	UpdateBy        string    `json:"update_by" form:"update_by" gorm:"size:64;default:'';comment:更新人"`
-	UpdateAt        time.Time `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
+	ID                  string    `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
+	TenantID            string    `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
+	ParentID            string    `json:"parent_id" form:"parent_id" gorm:"type:varchar(64);not null;default:'';comment:父ID"`
+	ConnID              string    `json:"conn_id" form:"conn_id" gorm:"type:varchar(64);not null;default:'';comment:连接ID"`
+	ConnName            string    `json:"conn_name" form:"conn_name" gorm:"size:256;default:'';comment:连接名称"`
+	ModelName           string    `json:"model_name" form:"model_name" gorm:"size:128;not null;default:'';comment:模型名称"`
+	ModelCode           string    `json:"model_code" form:"model_code" gorm:"size:128;not null;default:'';uniqueIndex:uix_md_model_title_creator;comment:模型编码"`
+	ModelVersion        string    `json:"model_version" form:"model_version" gorm:"size:64;not null;default:'1.0.0';comment:模型版本"`
+	ModelLogo           string    `json:"model_logo" form:"model_logo" gorm:"size:512;not null;default:'';comment:模型Logo"`
+	ModelKind           int       `json:"model_kind" form:"model_kind" gorm:"not null;default:0;comment:模型类型：1sql语句、2视图/表、3存储过程、4关联"`
+	IsPublic            bool      `json:"is_public" form:"is_public" gorm:"not null;default:false;comment:是否公开"`
+	IsLocked            bool      `json:"is_locked" form:"is_locked" gorm:"default:false;comment:是否锁定"`
+	IsTree              bool      `json:"is_tree" form:"is_tree" gorm:"default:false;comment:是否树形结构"`                                        // 是否树形结构
+	TreeParentField     string    `json:"tree_parent_field" form:"tree_parent_field" gorm:"size:64;default:'';comment:父节点字段名"`               // 父节点字段名
+	TreePathField       string    `json:"tree_path_field" form:"tree_path_field" gorm:"size:64;default:'';comment:路径字段名"`                    // 路径字段名
+	TreeLevelField      string    `json:"tree_level_field" form:"tree_level_field" gorm:"size:64;default:'';comment:层级字段名"`                  // 层级字段名
+	TreeRootValue       string    `json:"tree_root_value" form:"tree_root_value" gorm:"size:64;default:'';comment:根节点的父节点值"`                 // 根节点的父节点值，为空时为 0，NULL 表示空值，EMPTY 表示空字符串
+	TreeSortField       string    `json:"tree_sort_field" form:"tree_sort_field" gorm:"size:256;default:'';comment:同级节点排序字段"`                // 同级节点排序字段，逗号分隔，可带 asc/desc
+	LogicDeleteField    string    `json:"logic_delete_field" form:"logic_delete_field" gorm:"size:64;default:'';comment:逻辑删除字段名"`            // 逻辑删除字段名
+	LogicDeleteValue    string    `json:"logic_delete_value" form:"logic_delete_value" gorm:"size:64;default:'1';comment:逻辑删除-已删除值"`         // 已删除值
+	LogicNotDeleteValue string    `json:"logic_not_delete_value" form:"logic_not_delete_value" gorm:"size:64;default:'0';comment:逻辑删除-未删除值"` // 未删除值
+	VersionField        string    `json:"version_field" form:"version_field" gorm:"size:64;default:'';comment:乐观锁版本字段名"`                     // 乐观锁版本字段名
+	UniqueKeyFields     string    `json:"unique_key_fields" form:"unique_key_fields" gorm:"size:256;default:'';comment:唯一键字段名"`              // 唯一键字段名，逗号分隔，未配置时使用主键
+	DataOrgField        string    `json:"data_org_field" form:"data_org_field" gorm:"size:64;default:'';comment:数据权限-组织字段名"`                 // 数据权限-组织字段名
+	DataOwnerField      string    `json:"data_owner_field" form:"data_owner_field" gorm:"size:64;default:'';comment:数据权限-所有人字段名"`            // 数据权限-所有人字段名
+	DataTenantField     string    `json:"data_tenant_field" form:"data_tenant_field" gorm:"size:64;default:'';comment:数据权限-租户字段名"`           // 数据权限-租户字段名
+	Parameters          string    `json:"parameters" form:"parameters" gorm:"type:text;comment:模型参数(JSON)"`
+	ProcID              string    `json:"proc_id" form:"proc_id" gorm:"type:varchar(64);default:'';comment:存储过程/函数ID(模型类型3)"`
+	Remark              string    `json:"remark" form:"remark" gorm:"size:1024;default:'';comment:备注"`
+	IsDeleted           bool      `json:"is_deleted" form:"is_deleted" gorm:"default:false;comment:是否删除"`
+	CreateID            string    `json:"create_id" form:"create_id" gorm:"size:64;default:'';comment:创建人ID"`
+	CreateBy            string    `json:"create_by" form:"create_by" gorm:"size:64;default:'';uniqueIndex:uix_md_model_title_creator;comment:创建人"`
+	CreateAt            time.Time `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateID            string    `json:"update_id" form:"update_id" gorm:"size:64;default:'';comment:更新人ID"`
+	UpdateBy            string    `json:"update_by" form:"update_by" gorm:"size:64;default:'';comment:更新人"`
+	UpdateAt            time.Time `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
 }
 
 // TableName 指定表名
@@ -29,6 +29,8 @@ type MdModelField struct {
 	MaxLength       int       `json:"max_length" form:"max_length" gorm:"default:0"`                     // 最大长度 (字符串)
 	ValidationRule  string    `json:"validation_rule" form:"validation_rule" gorm:"size:512;default:''"` // 正则校验
 	ValidationExpr  string    `json:"validation_expr" form:"validation_expr" gorm:"size:512;default:''"` // 表达式校验
+	EncryptAlgo     string    `json:"encrypt_algo" form:"encrypt_algo" gorm:"size:32;not null;default:'';comment:字段加密算法：sm4/aes-gcm"`
+	BlindIndexField string    `json:"blind_index_field" form:"blind_index_field" gorm:"size:256;not null;default:'';comment:盲索引列名 (密文等值查询)"`
 	ShowTitle       string    `json:"show_title" form:"show_title" gorm:"size:128;not null;default:'';comment:字段显示名称"`
 	ShowWidth       int       `json:"show_width" form:"show_width" gorm:"not null;default:100;comment:字段显示宽度"`
 	IsDeleted       bool      `json:"is_deleted" form:"is_deleted" gorm:"default:false;comment:是否删除"`
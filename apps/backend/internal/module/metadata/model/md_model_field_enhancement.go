@@ -8,16 +8,20 @@ type MdModelFieldEnhancement struct {
 	TenantID        string    `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
 	ModelID         string    `json:"model_id" form:"model_id" gorm:"index;type:varchar(64);not null;default:'';comment:模型ID"`
 	FieldID         string    `json:"field_id" form:"field_id" gorm:"index;type:varchar(64);not null;default:'';comment:字段ID"`
-	DisplayName     string    `json:"display_name" form:"display_name" gorm:"size:128;default:''"`     // 显示名称
-	DisplayOrder    int       `json:"display_order" form:"display_order" gorm:"not null;default:0"`    // 显示顺序
-	DisplayWidth    int       `json:"display_width" form:"display_width" gorm:"not null;default:100"`  // 显示宽度
-	IsSearchable    bool      `json:"is_searchable" form:"is_searchable" gorm:"not null;default:true"` // 可搜索
-	IsSortable      bool      `json:"is_sortable" form:"is_sortable" gorm:"not null;default:true"`     // 可排序
-	IsFilterable    bool      `json:"is_filterable" form:"is_filterable" gorm:"not null;default:true"` // 可筛选
-	Placeholder     string    `json:"placeholder" form:"placeholder" gorm:"size:256;default:''"`       // 占位符
-	HelpText        string    `json:"help_text" form:"help_text" gorm:"size:512;default:''"`           // 帮助文本
-	ComponentType   string    `json:"component_type" form:"component_type" gorm:"size:64;default:''"`  // 组件类型
-	ComponentConfig string    `json:"component_config" form:"component_config" gorm:"type:text"`       // 组件配置 JSON
+	DisplayName     string    `json:"display_name" form:"display_name" gorm:"size:128;default:''"`           // 显示名称
+	DisplayOrder    int       `json:"display_order" form:"display_order" gorm:"not null;default:0"`          // 显示顺序
+	DisplayWidth    int       `json:"display_width" form:"display_width" gorm:"not null;default:100"`        // 显示宽度
+	IsSearchable    bool      `json:"is_searchable" form:"is_searchable" gorm:"not null;default:true"`       // 可搜索
+	IsSortable      bool      `json:"is_sortable" form:"is_sortable" gorm:"not null;default:true"`           // 可排序
+	IsFilterable    bool      `json:"is_filterable" form:"is_filterable" gorm:"not null;default:true"`       // 可筛选
+	Placeholder     string    `json:"placeholder" form:"placeholder" gorm:"size:256;default:''"`             // 占位符
+	HelpText        string    `json:"help_text" form:"help_text" gorm:"size:512;default:''"`                 // 帮助文本
+	ComponentType   string    `json:"component_type" form:"component_type" gorm:"size:64;default:''"`        // 组件类型
+	ComponentConfig string    `json:"component_config" form:"component_config" gorm:"type:text"`             // 组件配置 JSON
+	MaskType        string    `json:"mask_type" form:"mask_type" gorm:"size:32;default:''"`                  // 脱敏方式：hide/partial/hash/format，为空不脱敏
+	MaskRule        string    `json:"mask_rule" form:"mask_rule" gorm:"size:256;default:''"`                 // 脱敏规则：partial/format 为"前保留位数,后保留位数"，hash 为盐值
+	MaskExemptRoles string    `json:"mask_exempt_roles" form:"mask_exempt_roles" gorm:"size:512;default:''"` // 脱敏豁免角色编码，逗号分隔
+	MaskExemptPerms string    `json:"mask_exempt_perms" form:"mask_exempt_perms" gorm:"size:512;default:''"` // 脱敏豁免权限(菜单)编码，逗号分隔
 	IsDeleted       bool      `json:"is_deleted" form:"is_deleted" gorm:"default:false;comment:是否删除"`
 	CreateID        string    `json:"create_id" form:"create_id" gorm:"size:64;default:'';comment:创建人ID"`
 	CreateBy        string    `json:"create_by" form:"create_by" gorm:"size:64;default:'';comment:创建人"`
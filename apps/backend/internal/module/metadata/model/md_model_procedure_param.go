@@ -11,7 +11,7 @@ type MdModelProcedureParam struct {
 	ProcName     string    `json:"proc_name" form:"proc_name" gorm:"size:256;default:'';comment:存储过程/函数名称"`
 	ParamName    string    `json:"param_name" form:"param_name" gorm:"size:256;default:'';comment:参数名称"`
 	ParamTitle   string    `json:"param_title" form:"param_title" gorm:"size:256;default:'';comment:参数标题"`
-	ParamMode    string    `json:"param_mode" form:"param_mode" gorm:"size:32;default:'';comment:参数模式: IN, OUT, INOUT"`
+	ParamMode    string    `json:"param_mode" form:"param_mode" gorm:"size:32;default:'';comment:参数模式: IN, OUT, INOUT, TABLE(表函数返回列)"`
 	ParamType    string    `json:"param_type" form:"param_type" gorm:"size:64;default:'';comment:参数数据类型"`
 	ParamLength  int       `json:"param_length" form:"param_length" gorm:"default:0;comment:参数长度"`
 	ParamComment string    `json:"param_comment" form:"param_comment" gorm:"size:256;default:'';comment:参数描述"`
@@ -9,6 +9,9 @@ type MdModelRelation struct {
 	DetailModelID string    `json:"detail_model_id" form:"detail_model_id" gorm:"type:varchar(64);not null;index"`
 	ForeignKey    string    `json:"foreign_key" form:"foreign_key" gorm:"size:64;not null"`      // 子表中指向主表ID的字段名
 	RelationType  string    `json:"relation_type" form:"relation_type" gorm:"size:32;default:'OneToMany'"` // OneToMany, OneToOne
+	PropertyName  string    `json:"property_name" form:"property_name" gorm:"size:128;default:'';comment:嵌套数据中子表数据的属性名，为空时使用子表模型ID"`
+	ReverseName   string    `json:"reverse_name" form:"reverse_name" gorm:"size:128;default:'';comment:子表记录展开主表记录时的属性名，为空时使用主表模型ID"`
+	DeleteRule    string    `json:"delete_rule" form:"delete_rule" gorm:"size:32;default:'cascade';comment:删除主表记录时的规则：cascade/restrict"`
 	Remark        string    `json:"remark" form:"remark" gorm:"size:1024;default:'';comment:备注"`
 	CreateBy      string    `json:"create_by" form:"create_by" gorm:"size:64;default:''"`
 	CreateAt      time.Time `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
@@ -18,3 +21,19 @@ type MdModelRelation struct {
 func (MdModelRelation) TableName() string {
 	return "md_model_relation"
 }
+
+// DetailProperty 主表记录中子表数据的属性名
+func (r *MdModelRelation) DetailProperty() string {
+	if r.PropertyName != "" {
+		return r.PropertyName
+	}
+	return r.DetailModelID
+}
+
+// MasterProperty 子表记录中主表记录的属性名
+func (r *MdModelRelation) MasterProperty() string {
+	if r.ReverseName != "" {
+		return r.ReverseName
+	}
+	return r.MasterModelID
+}
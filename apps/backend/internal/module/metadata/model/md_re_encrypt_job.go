@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// MdReEncryptJob 加密字段重新加密任务模型，密钥轮换后在后台按批执行
+type MdReEncryptJob struct {
+	ID         string     `json:"id" form:"id" gorm:"primary_key;type:varchar(64);comment:主键ID"`
+	TenantID   string     `json:"tenant_id" form:"tenant_id" gorm:"index;type:varchar(64);not null;default:'';comment:租户ID"`
+	ModelID    string     `json:"model_id" form:"model_id" gorm:"index;type:varchar(64);not null;default:'';comment:模型ID"`
+	BatchSize  int        `json:"batch_size" form:"batch_size" gorm:"default:0;comment:每批处理的记录数"`
+	Status     string     `json:"status" form:"status" gorm:"size:16;not null;default:'';comment:状态: pending, running, succeeded, failed, canceled"`
+	Processed  int64      `json:"processed" form:"processed" gorm:"default:0;comment:已重新加密的记录数"`
+	Error      string     `json:"error" form:"error" gorm:"size:1024;default:'';comment:失败原因"`
+	FinishedAt *time.Time `json:"finished_at" form:"finished_at" gorm:"comment:结束时间"`
+	CreateID   string     `json:"create_id" form:"create_id" gorm:"index;size:64;default:'';comment:创建人ID"`
+	CreateAt   time.Time  `json:"create_at" form:"create_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdateAt   time.Time  `json:"update_at" form:"update_at" gorm:"autoUpdateTime;comment:更新时间"`
+}
+
+// TableName 指定表名
+func (MdReEncryptJob) TableName() string {
+	return "md_re_encrypt_job"
+}
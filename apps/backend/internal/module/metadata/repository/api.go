@@ -14,6 +14,8 @@ type APIRepository interface {
 	UpdateAPI(api *model.API) error
 	DeleteAPI(id string) error
 	GetAllAPIs() ([]model.API, error)
+	GetRevision() (int64, error)
+	IncrRevision() error
 }
 
 // Repositories 元数据模块仓库集合
@@ -31,6 +33,9 @@ type Repositories struct {
 	ModelRelation    MdModelRelationRepository
 	ModelSql         MdModelSqlRepository
 	ModelParam       MdModelParamRepository
+	ExportJob        MdExportJobRepository
+	ImportJob        MdImportJobRepository
+	ReEncryptJob     MdReEncryptJobRepository
 }
 
 // NewRepositories 创建元数据模块仓库集合
@@ -49,6 +54,9 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		ModelRelation:    NewMdModelRelationRepository(db),
 		ModelSql:         NewMdModelSqlRepository(db),
 		ModelParam:       NewMdModelParamRepository(db),
+		ExportJob:        NewMdExportJobRepository(db),
+		ImportJob:        NewMdImportJobRepository(db),
+		ReEncryptJob:     NewMdReEncryptJobRepository(db),
 	}
 }
 
@@ -106,3 +114,22 @@ func (r *apiRepository) GetAllAPIs() ([]model.API, error) {
 	}
 	return apis, nil
 }
+
+// GetRevision 获取动态接口版本号，没有版本记录时返回 0
+func (r *apiRepository) GetRevision() (int64, error) {
+	var rev model.MdAPIRevision
+	result := r.db.Where("id = ?", model.APIRevisionID).Limit(1).Find(&rev)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return rev.Revision, nil
+}
+
+// IncrRevision 递增动态接口版本号
+func (r *apiRepository) IncrRevision() error {
+	rev := model.MdAPIRevision{ID: model.APIRevisionID}
+	if err := r.db.Where("id = ?", rev.ID).FirstOrCreate(&rev).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&rev).Update("revision", gorm.Expr("revision + ?", 1)).Error
+}
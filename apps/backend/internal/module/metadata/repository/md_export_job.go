@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"metadata-platform/internal/module/metadata/model"
+
+	"gorm.io/gorm"
+)
+
+// MdExportJobRepository 导出任务仓储接口
+type MdExportJobRepository interface {
+	CreateJob(job *model.MdExportJob) error
+	GetJobByID(id string) (*model.MdExportJob, error)
+	UpdateJob(id string, updates map[string]any) error
+	DeleteJob(id string) error
+	GetJobsByCreateID(createID string) ([]model.MdExportJob, error)
+	GetExpiredJobs(now time.Time) ([]model.MdExportJob, error)
+}
+
+type mdExportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewMdExportJobRepository 创建导出任务仓储实例
+func NewMdExportJobRepository(db *gorm.DB) MdExportJobRepository {
+	return &mdExportJobRepository{db: db}
+}
+
+func (r *mdExportJobRepository) CreateJob(job *model.MdExportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *mdExportJobRepository) GetJobByID(id string) (*model.MdExportJob, error) {
+	var job model.MdExportJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *mdExportJobRepository) UpdateJob(id string, updates map[string]any) error {
+	return r.db.Model(&model.MdExportJob{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *mdExportJobRepository) DeleteJob(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.MdExportJob{}).Error
+}
+
+// GetJobsByCreateID 按创建时间倒序返回用户的导出任务
+func (r *mdExportJobRepository) GetJobsByCreateID(createID string) ([]model.MdExportJob, error) {
+	var jobs []model.MdExportJob
+	err := r.db.Where("create_id = ?", createID).Order("create_at DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *mdExportJobRepository) GetExpiredJobs(now time.Time) ([]model.MdExportJob, error) {
+	var jobs []model.MdExportJob
+	err := r.db.Where("expire_at < ?", now).Find(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"metadata-platform/internal/module/metadata/model"
+
+	"gorm.io/gorm"
+)
+
+// MdImportJobRepository 导入任务仓储接口
+type MdImportJobRepository interface {
+	CreateJob(job *model.MdImportJob) error
+	GetJobByID(id string) (*model.MdImportJob, error)
+	UpdateJob(id string, updates map[string]any) error
+	DeleteJob(id string) error
+	GetExpiredJobs(now time.Time) ([]model.MdImportJob, error)
+}
+
+type mdImportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewMdImportJobRepository 创建导入任务仓储实例
+func NewMdImportJobRepository(db *gorm.DB) MdImportJobRepository {
+	return &mdImportJobRepository{db: db}
+}
+
+func (r *mdImportJobRepository) CreateJob(job *model.MdImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *mdImportJobRepository) GetJobByID(id string) (*model.MdImportJob, error) {
+	var job model.MdImportJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *mdImportJobRepository) UpdateJob(id string, updates map[string]any) error {
+	return r.db.Model(&model.MdImportJob{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *mdImportJobRepository) DeleteJob(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.MdImportJob{}).Error
+}
+
+func (r *mdImportJobRepository) GetExpiredJobs(now time.Time) ([]model.MdImportJob, error) {
+	var jobs []model.MdImportJob
+	err := r.db.Where("expire_at < ?", now).Find(&jobs).Error
+	return jobs, err
+}
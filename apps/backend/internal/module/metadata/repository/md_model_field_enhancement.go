@@ -14,6 +14,7 @@ type MdModelFieldEnhancementRepository interface {
 	UpdateEnhancement(enh *model.MdModelFieldEnhancement) error
 	DeleteEnhancement(id string) error
 	BatchUpdateEnhancements(enhancements []model.MdModelFieldEnhancement) error
+	GetMaskEnhancementsByTable(connID, schema, table string) (map[string][]model.MdModelFieldEnhancement, error)
 }
 
 type mdModelFieldEnhancementRepository struct {
@@ -65,3 +66,38 @@ func (r *mdModelFieldEnhancementRepository) BatchUpdateEnhancements(enhs []model
 		return nil
 	})
 }
+
+// GetMaskEnhancementsByTable 查询引用指定数据源表的模型字段上的脱敏配置，按列名分组
+func (r *mdModelFieldEnhancementRepository) GetMaskEnhancementsByTable(connID, schema, table string) (map[string][]model.MdModelFieldEnhancement, error) {
+	fieldQuery := r.db.Model(&model.MdModelField{}).
+		Where("table_name = ? AND is_deleted = ?", table, false).
+		Where("model_id IN (?)", r.db.Model(&model.MdModel{}).Select("id").Where("conn_id = ?", connID))
+	if schema != "" {
+		fieldQuery = fieldQuery.Where("(table_schema = ? OR table_schema = '')", schema)
+	}
+	var fields []model.MdModelField
+	if err := fieldQuery.Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	columnByField := make(map[string]string, len(fields))
+	fieldIDs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		columnByField[f.ID] = f.ColumnName
+		fieldIDs = append(fieldIDs, f.ID)
+	}
+
+	var enhs []model.MdModelFieldEnhancement
+	if err := r.db.Where("field_id IN ? AND mask_type <> '' AND is_deleted = ?", fieldIDs, false).Find(&enhs).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string][]model.MdModelFieldEnhancement)
+	for _, enh := range enhs {
+		column := columnByField[enh.FieldID]
+		result[column] = append(result[column], enh)
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"metadata-platform/internal/module/metadata/model"
+
+	"gorm.io/gorm"
+)
+
+// MdReEncryptJobRepository 重新加密任务仓储接口
+type MdReEncryptJobRepository interface {
+	CreateJob(job *model.MdReEncryptJob) error
+	GetJobByID(id string) (*model.MdReEncryptJob, error)
+	UpdateJob(id string, updates map[string]any) error
+	GetUnfinishedJobs(modelID string) ([]model.MdReEncryptJob, error)
+}
+
+type mdReEncryptJobRepository struct {
+	db *gorm.DB
+}
+
+// NewMdReEncryptJobRepository 创建重新加密任务仓储实例
+func NewMdReEncryptJobRepository(db *gorm.DB) MdReEncryptJobRepository {
+	return &mdReEncryptJobRepository{db: db}
+}
+
+func (r *mdReEncryptJobRepository) CreateJob(job *model.MdReEncryptJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *mdReEncryptJobRepository) GetJobByID(id string) (*model.MdReEncryptJob, error) {
+	var job model.MdReEncryptJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *mdReEncryptJobRepository) UpdateJob(id string, updates map[string]any) error {
+	return r.db.Model(&model.MdReEncryptJob{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// GetUnfinishedJobs 返回模型尚未结束的任务
+func (r *mdReEncryptJobRepository) GetUnfinishedJobs(modelID string) ([]model.MdReEncryptJob, error) {
+	var jobs []model.MdReEncryptJob
+	err := r.db.Where("model_id = ? AND finished_at IS NULL", modelID).Find(&jobs).Error
+	return jobs, err
+}
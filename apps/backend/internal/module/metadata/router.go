@@ -17,11 +17,15 @@ import (
 )
 
 // RegisterRoutes 注册元数据模块路由
-func RegisterRoutes(r *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
+func RegisterRoutes(r *server.Hertz, db *gorm.DB, userDB *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) {
 	fmt.Println(">>> Initializing Metadata Routes...")
 
-	// 注册全局 404 诊断处理器
+	// 注册全局 404 处理器：先查找动态接口分发表，未匹配时返回诊断信息
+	var dynamicRouter *api.DynamicRouter
 	r.NoRoute(func(c context.Context, ctx *app.RequestContext) {
+		if dynamicRouter != nil && dynamicRouter.Dispatch(c, ctx) {
+			return
+		}
 		path := string(ctx.Request.URI().Path())
 		method := string(ctx.Request.Method())
 		fmt.Printf("!!! Route Not Found: [%s] %s\n", method, path)
@@ -43,21 +47,23 @@ func RegisterRoutes(r *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *
 	repos := repository.NewRepositories(db)
 
 	// 初始化服务
-	services := service.NewServices(db, repos, auditDB, auditQueue)
+	services := service.NewServices(db, repos, userDB, auditDB, auditQueue)
 
 	// 初始化处理器
 	apiHandler := api.NewAPIHandler(services.API)
-	connHandler := api.NewMdConnHandler(services.Conn)
+	connHandler := api.NewMdConnHandler(services.Conn, services.FieldEnhancement, services.DataScope)
 	tableHandler := api.NewMdTableHandler(services.Table)
 	fieldHandler := api.NewMdTableFieldHandler(services.TableField)
 	modelHandler := api.NewMdModelHandler(services.Model)
 	procHandler := api.NewMdModelProcedureHandler(services.Procedure)
-	queryHandler := api.NewDataQueryHandler(services.CRUD, services.Model)
+	queryHandler := api.NewDataQueryHandler(services.CRUD, services.Model, services.DataScope)
 	templateHandler := api.NewQueryTemplateHandler(services.QueryTemplate)
 	enhancementHandler := api.NewFieldEnhancementHandler(services.FieldEnhancement)
-	treeHandler := api.NewTreeHandler(services.Tree)
-	masterDetailHandler := api.NewMasterDetailHandler(services.MasterDetail)
-	dataIOHandler := api.NewDataIOHandler(services.DataIO)
+	treeHandler := api.NewTreeHandler(services.Tree, services.DataScope)
+	masterDetailHandler := api.NewMasterDetailHandler(services.MasterDetail, services.DataScope)
+	dataIOHandler := api.NewDataIOHandler(services.DataIO, services.DataScope)
+	historyHandler := api.NewRecordHistoryHandler(services.RecordHistory, services.DataScope)
+	reEncryptHandler := api.NewReEncryptHandler(services.ReEncrypt, services.DataScope)
 
 	// 元数据模块路由组
 	metadataGroup := r.Group("/api/metadata")
@@ -135,6 +141,8 @@ func RegisterRoutes(r *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *
 		procGroup.GET("", procHandler.GetAllProcedures)
 		procGroup.GET("/conn/:conn_id", procHandler.GetProceduresByConnID)
 		procGroup.GET("/:id/params", procHandler.GetParamsByProcID)
+		procGroup.POST("/:id/execute", procHandler.ExecuteProcedure)
+		procGroup.POST("/:id/model", procHandler.BuildProcedureModel)
 	}
 
 	// 模型路由
@@ -162,6 +170,17 @@ func RegisterRoutes(r *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *
 		modelGroup.GET("/:id/params", modelHandler.GetModelParams)
 		modelGroup.GET("/:id/sql", modelHandler.GetSQLByModelID)
 
+		// 加密字段密钥轮换
+		modelGroup.POST("/:id/re-encrypt", reEncryptHandler.StartJob)
+		modelGroup.GET("/:id/re-encrypt/:job_id", reEncryptHandler.GetJob)
+		modelGroup.POST("/:id/re-encrypt/:job_id/cancel", reEncryptHandler.CancelJob)
+
+		// 记录历史路由
+		modelGroup.GET("/:id/records/:record_id/history", historyHandler.GetHistory)
+		modelGroup.GET("/:id/records/:record_id/as-of", historyHandler.GetAsOf)
+		modelGroup.POST("/:id/records/:record_id/restore", historyHandler.Restore)
+		modelGroup.POST("/:id/changes/:change_id/undo", historyHandler.UndoChange)
+
 		modelGroup.GET("", modelHandler.ListModels)
 		modelGroup.GET("/all", modelHandler.GetAllModels)
 		modelGroup.GET("/conn/:conn_id", modelHandler.GetModelsByConnID)
@@ -199,34 +218,59 @@ func RegisterRoutes(r *server.Hertz, db *gorm.DB, auditDB *gorm.DB, auditQueue *
 
 	// 树形结构 API
 	treeGroup := r.Group("/api/tree")
+	treeGroup.Use(globalMiddleware.TenantMiddleware())
+	treeGroup.Use(globalMiddleware.AuthMiddleware())
 	treeGroup.Use(globalMiddleware.AuditMiddleware(services.Audit, "metadata"))
 	{
 		treeGroup.GET("/:model_id", treeHandler.GetTree)
+		treeGroup.GET("/:model_id/search", treeHandler.SearchTree)
 		treeGroup.POST("/:model_id/node", treeHandler.AddNode)
 		treeGroup.PUT("/:model_id/node/:id/move", treeHandler.MoveNode) // 注意：TargetParentID 在 body 中
 		treeGroup.DELETE("/:model_id/node/:id", treeHandler.DeleteNode)
 		treeGroup.GET("/:model_id/node/:id/children", treeHandler.GetChildren)
 		treeGroup.GET("/:model_id/node/:id/path", treeHandler.GetPath)
+		treeGroup.GET("/:model_id/node/:id/descendants", treeHandler.GetDescendants)
 	}
 
 	// 主子表管理路由
 	mdGroup := r.Group("/api/master-detail")
+	mdGroup.Use(globalMiddleware.TenantMiddleware())
+	mdGroup.Use(globalMiddleware.AuthMiddleware())
 	mdGroup.Use(globalMiddleware.AuditMiddleware(services.Audit, "metadata"))
 	{
 		mdGroup.POST("/:master/:detail", masterDetailHandler.CreateMasterDetail)
+		mdGroup.GET("/:master/:detail/:id", masterDetailHandler.GetMasterDetail)
+		mdGroup.PUT("/:master/:detail/:id", masterDetailHandler.UpdateMasterDetail)
+		mdGroup.DELETE("/:master/:detail/:id", masterDetailHandler.DeleteMasterDetail)
 	}
 
 	// 数据导入导出路由
 	ioGroup := r.Group("/api/data")
+	ioGroup.Use(globalMiddleware.TenantMiddleware())
+	ioGroup.Use(globalMiddleware.AuthMiddleware())
 	ioGroup.Use(globalMiddleware.AuditMiddleware(services.Audit, "metadata"))
 	{
 		ioGroup.GET("/:model_id/export", dataIOHandler.ExportData)
+		ioGroup.POST("/:model_id/pivot/export", dataIOHandler.ExportPivot)
 		ioGroup.GET("/:model_id/import-template", dataIOHandler.ImportTemplate)
 		ioGroup.POST("/:model_id/import", dataIOHandler.ImportData)
+		ioGroup.POST("/:model_id/import-jobs", dataIOHandler.StartImportJob)
+		ioGroup.GET("/:model_id/import-jobs/:job_id", dataIOHandler.GetImportJob)
+		ioGroup.POST("/:model_id/import-jobs/:job_id/cancel", dataIOHandler.CancelImportJob)
+		ioGroup.GET("/:model_id/import-jobs/:job_id/errors", dataIOHandler.ImportErrorReport)
+		ioGroup.POST("/:model_id/export-jobs", dataIOHandler.StartExportJob)
+		ioGroup.GET("/exports", dataIOHandler.ListExportJobs)
+		ioGroup.GET("/exports/:job_id", dataIOHandler.GetExportJob)
+		ioGroup.DELETE("/exports/:job_id", dataIOHandler.DeleteExportJob)
 	}
 
-	// 注册动态路由
-	dynamicRouter := api.NewDynamicRouter(r, services)
+	// 导出文件下载凭签名链接访问，无需登录
+	downloadGroup := r.Group("/api/data/exports")
+	downloadGroup.Use(globalMiddleware.AuditMiddleware(services.Audit, "metadata"))
+	downloadGroup.GET("/:job_id/download", dataIOHandler.DownloadExport)
+
+	// 加载动态接口，接口变更后无需重启即可生效
+	dynamicRouter = api.NewDynamicRouter(r, services)
 	if err := dynamicRouter.LoadAndRegisterAll(); err != nil {
 		utils.SugarLogger.Errorf("Failed to register dynamic routes: %v", err)
 	}
@@ -16,7 +16,7 @@ func TestRouterRegistration(t *testing.T) {
 
 	// 模拟所需的参数 (即使为 nil，只要 RegisterRoutes 不在注册时立即调用它们即可)
 	// 由于我们的 RegisterRoutes 主要是闭包注册，所以只要不触发 Handler 逻辑，注入 nil 是安全的
-	RegisterRoutes(h, nil, nil, nil)
+	RegisterRoutes(h, nil, nil, nil, nil)
 
 	// 1. 验证探测接口 /api/metadata/ping
 	w1 := ut.PerformRequest(h.Engine, "GET", "/api/metadata/ping", nil)
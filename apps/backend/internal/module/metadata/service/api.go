@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"sync"
+
 	"gorm.io/gorm"
 
 	"metadata-platform/internal/module/audit/queue"
@@ -18,6 +21,11 @@ type APIService interface {
 	UpdateAPI(api *model.API) error
 	DeleteAPI(id string) error
 	GetAllAPIs() ([]model.API, error)
+	// NotifyChange 递增接口版本号并通知本实例的订阅者，其他实例发现版本号变化后重新加载
+	NotifyChange() error
+	GetRevision() (int64, error)
+	// OnChange 订阅本实例中的接口变更
+	OnChange(fn func())
 }
 
 // Services 元数据模块服务集合
@@ -36,11 +44,14 @@ type Services struct {
 	Tree             TreeService
 	MasterDetail     MasterDetailService
 	DataIO           DataIOService
+	DataScope        DataScopeService
+	RecordHistory    RecordHistoryService
+	ReEncrypt        ReEncryptService
 	Audit            auditService.AuditService
 }
 
 // NewServices 创建元数据模块服务集合
-func NewServices(db *gorm.DB, repos *repository.Repositories, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) *Services {
+func NewServices(db *gorm.DB, repos *repository.Repositories, userDB *gorm.DB, auditDB *gorm.DB, auditQueue *queue.AuditLogQueue) *Services {
 	connService := NewMdConnService(repos.Conn)
 
 	validator := NewDataValidator()
@@ -51,31 +62,39 @@ func NewServices(db *gorm.DB, repos *repository.Repositories, auditDB *gorm.DB,
 	sqlExecutor := engine.NewSQLExecutor(db, repos.Conn)
 	auditSvc := auditService.NewAuditService(auditDB, auditQueue)
 	crudSvc := NewCRUDService(sqlBuilder, sqlExecutor, validator, queryTemplateService, auditSvc)
-	treeSvc := NewTreeService(repos.Model, crudSvc, sqlExecutor)
-	masterDetailSvc := NewMasterDetailService(crudSvc, repos.ModelRelation, repos.Model, sqlExecutor)
-	dataIOSvc := NewDataIOService(crudSvc, repos.Model, repos.ModelField, validator)
+	treeSvc := NewTreeService(repos.Model, crudSvc, sqlBuilder, sqlExecutor)
+	masterDetailSvc := NewMasterDetailService(crudSvc, repos.ModelRelation, repos.Model, sqlBuilder, sqlExecutor)
+	dataIOSvc := NewDataIOService(crudSvc, repos.Model, repos.ModelField, repos.FieldEnhancement, repos.ExportJob, repos.ImportJob, validator)
+
+	apiSvc := NewAPIService(repos.API)
 
 	return &Services{
-		API:              NewAPIService(repos.API),
+		API:              apiSvc,
 		Conn:             connService,
 		Table:            NewMdTableService(repos.Table, repos.TableField),
 		TableField:       NewMdTableFieldService(repos.TableField),
 		Model:            NewMdModelService(repos.Model, repos.ModelField, repos.ModelSql, repos.ModelParam, connService),
-		Procedure:        NewMdModelProcedureService(repos.Procedure, repos.Conn),
+		Procedure:        NewMdModelProcedureService(repos.Procedure, repos.Model, connService),
 		FieldEnhancement: NewMdModelFieldEnhancementService(repos.FieldEnhancement),
 		CRUD:             crudSvc,
-		APIGenerator:     NewAPIGenerator(repos.Model, repos.API),
+		APIGenerator:     NewAPIGenerator(repos.Model, repos.API, apiSvc),
 		Validator:        validator,
 		QueryTemplate:    queryTemplateService,
 		Tree:             treeSvc,
 		MasterDetail:     masterDetailSvc,
 		DataIO:           dataIOSvc,
+		DataScope:        NewDataScopeService(userDB),
+		RecordHistory:    NewRecordHistoryService(sqlBuilder, crudSvc, auditSvc),
+		ReEncrypt:        NewReEncryptService(crudSvc, repos.ReEncryptJob),
 		Audit:            auditSvc,
 	}
 }
 
 type apiService struct {
 	repo repository.APIRepository
+
+	mu        sync.Mutex
+	listeners []func()
 }
 
 // NewAPIService 创建API服务实例
@@ -84,7 +103,10 @@ func NewAPIService(repo repository.APIRepository) APIService {
 }
 
 func (s *apiService) CreateAPI(api *model.API) error {
-	return s.repo.CreateAPI(api)
+	if err := s.repo.CreateAPI(api); err != nil {
+		return err
+	}
+	return s.NotifyChange()
 }
 
 func (s *apiService) GetAPIByID(id string) (*model.API, error) {
@@ -96,13 +118,43 @@ func (s *apiService) GetAPIByCode(code string) (*model.API, error) {
 }
 
 func (s *apiService) UpdateAPI(api *model.API) error {
-	return s.repo.UpdateAPI(api)
+	if err := s.repo.UpdateAPI(api); err != nil {
+		return err
+	}
+	return s.NotifyChange()
 }
 
 func (s *apiService) DeleteAPI(id string) error {
-	return s.repo.DeleteAPI(id)
+	if err := s.repo.DeleteAPI(id); err != nil {
+		return err
+	}
+	return s.NotifyChange()
 }
 
 func (s *apiService) GetAllAPIs() ([]model.API, error) {
 	return s.repo.GetAllAPIs()
 }
+
+// NotifyChange 递增接口版本号并通知本实例的订阅者
+func (s *apiService) NotifyChange() error {
+	if err := s.repo.IncrRevision(); err != nil {
+		return fmt.Errorf("更新接口版本失败: %w", err)
+	}
+	s.mu.Lock()
+	listeners := append([]func(){}, s.listeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+func (s *apiService) GetRevision() (int64, error) {
+	return s.repo.GetRevision()
+}
+
+func (s *apiService) OnChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
@@ -13,18 +13,29 @@ type APIGenerator interface {
 	BatchGenerate(modelID string, userID string, tenantID string) ([]*model.API, error)
 }
 
+// apiTemplate 接口生成模板
+type apiTemplate struct {
+	Name       string
+	Suffix     string
+	Method     string
+	CodeSuffix string // 可选，默认使用Method
+	Remark     string
+}
+
 type apiGenerator struct {
-	modelRepo repository.MdModelRepository
-	apiRepo   repository.APIRepository
-	snowflake *utils.Snowflake
+	modelRepo  repository.MdModelRepository
+	apiRepo    repository.APIRepository
+	apiService APIService
+	snowflake  *utils.Snowflake
 }
 
-// NewAPIGenerator 创建 API 生成器实例
-func NewAPIGenerator(modelRepo repository.MdModelRepository, apiRepo repository.APIRepository) APIGenerator {
+// NewAPIGenerator 创建 API 生成器实例，生成完成后通过 apiService 通知接口变更
+func NewAPIGenerator(modelRepo repository.MdModelRepository, apiRepo repository.APIRepository, apiService APIService) APIGenerator {
 	return &apiGenerator{
-		modelRepo: modelRepo,
-		apiRepo:   apiRepo,
-		snowflake: utils.NewSnowflake(1, 1),
+		modelRepo:  modelRepo,
+		apiRepo:    apiRepo,
+		apiService: apiService,
+		snowflake:  utils.NewSnowflake(1, 1),
 	}
 }
 
@@ -39,13 +50,7 @@ func (g *apiGenerator) BatchGenerate(modelID string, userID string, tenantID str
 	basePath := "/api/data/" + strings.ToLower(md.ModelCode)
 	
 	// 定义标准 CRUD 模板及扩展接口
-	templates := []struct {
-		Name       string
-		Suffix     string
-		Method     string
-		CodeSuffix string // 可选，默认使用Method
-		Remark     string
-	}{
+	templates := []apiTemplate{
 		{"创建" + md.ModelName, "", "POST", "", "自动生成的创建接口"},
 		{"查询" + md.ModelName + "列表", "", "GET", "", "自动生成的列表查询接口"},
 		{"获取" + md.ModelName + "详情", "/:id", "GET", "", "自动生成的单条查询接口"},
@@ -56,8 +61,29 @@ func (g *apiGenerator) BatchGenerate(modelID string, userID string, tenantID str
 		{"通用查询" + md.ModelName, "/query", "POST", "QUERY", "自动生成的通用查询接口"},
 		{"批量创建" + md.ModelName, "/batch-create", "POST", "BATCH_CREATE", "自动生成的批量创建接口"},
 		{"批量删除" + md.ModelName, "/batch-delete", "POST", "BATCH_DELETE", "自动生成的批量删除接口"},
+		{"批量更新" + md.ModelName, "/batch-update", "POST", "BATCH_UPDATE", "自动生成的批量更新接口"},
+		{"条件更新" + md.ModelName, "/update-where", "POST", "UPDATE_WHERE", "自动生成的按条件批量更新接口"},
+		{"条件删除" + md.ModelName, "/delete-where", "POST", "DELETE_WHERE", "自动生成的按条件批量删除接口"},
+		{"新增或更新" + md.ModelName, "/upsert", "POST", "UPSERT", "自动生成的按唯一键新增或更新接口"},
+		{"批量新增或更新" + md.ModelName, "/batch-upsert", "POST", "BATCH_UPSERT", "自动生成的按唯一键批量新增或更新接口"},
 		{"数据统计" + md.ModelName, "/statistics", "POST", "STATISTICS", "自动生成的数据统计接口"},
 		{"聚合查询" + md.ModelName, "/aggregate", "POST", "AGGREGATE", "自动生成的聚合查询接口"},
+		{"透视查询" + md.ModelName, "/pivot", "POST", "PIVOT", "自动生成的透视查询接口"},
+	}
+
+	// 配置了逻辑删除字段的模型额外生成恢复与清除接口
+	if md.LogicDeleteField != "" {
+		templates = append(templates,
+			apiTemplate{"恢复" + md.ModelName, "/:id/restore", "POST", "RESTORE", "自动生成的逻辑删除恢复接口"},
+			apiTemplate{"清除" + md.ModelName, "/:id/purge", "DELETE", "PURGE", "自动生成的物理清除接口"},
+		)
+	}
+
+	// 存储过程模型仅生成执行接口
+	if md.ModelKind == 3 {
+		templates = []apiTemplate{
+			{"执行" + md.ModelName, "/execute", "POST", "EXECUTE", "自动生成的存储过程执行接口"},
+		}
 	}
 
 	apis := make([]*model.API, 0)
@@ -85,5 +111,9 @@ func (g *apiGenerator) BatchGenerate(modelID string, userID string, tenantID str
 		apis = append(apis, api)
 	}
 
+	// 生成的接口立即生效
+	if err := g.apiService.NotifyChange(); err != nil {
+		return nil, err
+	}
 	return apis, nil
 }
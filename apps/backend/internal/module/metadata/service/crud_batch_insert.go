@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+)
+
+// 批量插入模式
+const (
+	BatchAllOrNothing = "all_or_nothing" // 任一行失败则全部回滚 (默认)
+	BatchBestEffort   = "best_effort"    // 跳过失败行，其余行正常写入
+)
+
+// DefaultBatchChunkSize 批量插入每条 INSERT 语句默认包含的行数
+const DefaultBatchChunkSize = 500
+
+// maxBatchParams 单条 INSERT 语句允许的最大参数个数，低于 MySQL/SQLite 的占位符上限
+const maxBatchParams = 30000
+
+// ErrBatchInsertFailed 批量插入失败
+var ErrBatchInsertFailed = errors.New("批量插入失败")
+
+// BatchInsertOptions 批量插入选项
+type BatchInsertOptions struct {
+	Mode      string `json:"mode"`       // all_or_nothing / best_effort
+	ChunkSize int    `json:"chunk_size"` // 每批行数，0 表示使用默认值
+}
+
+// RowError 行级错误
+type RowError struct {
+	Row   int    `json:"row"` // 从 0 开始的行号
+	Error string `json:"error"`
+}
+
+// BatchInsertResult 批量插入结果
+type BatchInsertResult struct {
+	Inserted int        `json:"inserted"`
+	IDs      []string   `json:"ids"` // 与输入行一一对应，失败或无法获取主键时为空
+	Errors   []RowError `json:"errors,omitempty"`
+}
+
+// pendingRow 待插入的行
+type pendingRow struct {
+	index int
+	data  map[string]any
+}
+
+// insertedRow 已插入的行
+type insertedRow struct {
+	index int
+	id    string
+	data  map[string]any
+}
+
+// BatchInsert 以多行 INSERT 分批写入，全部在同一事务中执行，每批使用保存点隔离
+func (s *crudService) BatchInsert(ctx context.Context, modelID string, dataList []map[string]any, opts BatchInsertOptions) (*BatchInsertResult, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	return s.batchInsert(ctx, md, dataList, opts)
+}
+
+// BatchCreate 批量创建，任一行失败则全部回滚，返回持久化后的记录 (含生成的主键)
+func (s *crudService) BatchCreate(ctx context.Context, modelID string, dataList []map[string]any) ([]map[string]any, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	result, err := s.batchInsert(ctx, md, dataList, BatchInsertOptions{Mode: BatchAllOrNothing})
+	if err != nil {
+		return nil, err
+	}
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return nil, err
+	}
+	return s.batchCreated(ctx, db, md, dataList, result)
+}
+
+// BatchCreateWithTx 在事务中批量创建，任一行失败返回错误，由调用方回滚
+func (s *crudService) BatchCreateWithTx(ctx context.Context, modelID string, dataList []map[string]any, tx *gorm.DB) ([]map[string]any, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	result, err := s.insertRows(ctx, md, dataList, BatchInsertOptions{Mode: BatchAllOrNothing}, tx)
+	if err != nil {
+		return nil, err
+	}
+	return s.batchCreated(ctx, tx, md, dataList, result)
+}
+
+// batchInsert 在新事务中执行批量插入，提交后再记录数据变更
+func (s *crudService) batchInsert(ctx context.Context, md *engine.ModelData, dataList []map[string]any, opts BatchInsertOptions) (*BatchInsertResult, error) {
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return nil, err
+	}
+
+	var result *BatchInsertResult
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = s.insertRows(ctx, md, dataList, opts, tx)
+		return err
+	}); err != nil {
+		return result, err
+	}
+	commit()
+	return result, nil
+}
+
+// batchCreated 读取批量创建后持久化的记录，无法获取主键的行返回输入数据
+func (s *crudService) batchCreated(ctx context.Context, db *gorm.DB, md *engine.ModelData, dataList []map[string]any, result *BatchInsertResult) ([]map[string]any, error) {
+	persisted, err := s.readPersisted(ctx, db, md, result.IDs)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]any, len(dataList))
+	for i, data := range dataList {
+		if row, ok := persisted[result.IDs[i]]; ok {
+			rows[i] = row
+			continue
+		}
+		row := make(map[string]any, len(data))
+		for k, v := range data {
+			row[k] = v
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// readPersisted 按主键分批读取刚写入的记录并解密、脱敏，返回主键到记录的映射
+func (s *crudService) readPersisted(ctx context.Context, db *gorm.DB, md *engine.ModelData, ids []string) (map[string]map[string]any, error) {
+	var keys []any
+	for _, id := range ids {
+		if id != "" {
+			keys = append(keys, id)
+		}
+	}
+	primaryKey := s.getPrimaryKey(md)
+	persisted := make(map[string]map[string]any, len(keys))
+	for start := 0; start < len(keys); start += DefaultBatchChunkSize {
+		part := keys[start:min(start+DefaultBatchChunkSize, len(keys))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(part)), ", ")
+		sql := fmt.Sprintf("SELECT * FROM %s WHERE `%s` IN (%s)", s.getMainTableName(md), primaryKey, placeholders)
+		rows, err := s.sqlExecutor.ExecuteWithTx(db, sql, part...)
+		if err != nil {
+			return nil, fmt.Errorf("读取写入后的记录失败: %w", err)
+		}
+		if err := md.DecryptRows(rows); err != nil {
+			return nil, err
+		}
+		engine.ApplyMasks(rows, md.Masks, engine.DataScopeFromContext(ctx))
+		for _, row := range rows {
+			persisted[fmt.Sprintf("%v", normalizeValue(row[primaryKey]))] = row
+		}
+	}
+	return persisted, nil
+}
+
+// insertRows 校验并分批插入数据，失败的批次回滚到保存点后逐行重试以定位错误行
+func (s *crudService) insertRows(ctx context.Context, md *engine.ModelData, dataList []map[string]any, opts BatchInsertOptions, tx *gorm.DB) (*BatchInsertResult, error) {
+	bestEffort := opts.Mode == BatchBestEffort
+	if opts.Mode != "" && opts.Mode != BatchAllOrNothing && !bestEffort {
+		return nil, fmt.Errorf("不支持的批量插入模式: %s", opts.Mode)
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBatchChunkSize
+	}
+
+	result := &BatchInsertResult{IDs: make([]string, len(dataList))}
+	fail := func() error {
+		e := result.Errors[0]
+		return fmt.Errorf("%w: 第 %d 条数据%s", ErrBatchInsertFailed, e.Row+1, e.Error)
+	}
+
+	// 1. 校验并加密，输入数据不被修改
+	pending := make([]pendingRow, 0, len(dataList))
+	for i, data := range dataList {
+		row := make(map[string]any, len(data))
+		for k, v := range data {
+			row[k] = v
+		}
+		if err := s.initDataScope(ctx, md, row); err != nil {
+			result.Errors = append(result.Errors, RowError{Row: i, Error: err.Error()})
+			continue
+		}
+		if err := s.validator.Validate(md.Model.ID, md.Fields, row); err != nil {
+			result.Errors = append(result.Errors, RowError{Row: i, Error: fmt.Sprintf("数据验证失败: %v", err)})
+			continue
+		}
+		s.initVersion(md, row)
+		if err := md.EncryptRow(row); err != nil {
+			result.Errors = append(result.Errors, RowError{Row: i, Error: err.Error()})
+			continue
+		}
+		pending = append(pending, pendingRow{index: i, data: row})
+	}
+	if len(result.Errors) > 0 && !bestEffort {
+		return result, fail()
+	}
+
+	// 2. 分批插入，成功后再记录数据变更，避免记录被回滚的数据
+	record := func(rows []insertedRow) {
+		for _, r := range rows {
+			result.IDs[r.index] = r.id
+			result.Inserted++
+			s.recordChange(ctx, md, ChangeCreate, r.id, nil, r.data)
+		}
+	}
+	for start := 0; start < len(pending); start += chunkSize {
+		chunk := pending[start:min(start+chunkSize, len(pending))]
+		savepoint := fmt.Sprintf("batch_insert_%d", start)
+		if err := tx.SavePoint(savepoint).Error; err != nil {
+			return result, fmt.Errorf("创建保存点失败: %w", err)
+		}
+		inserted, err := s.insertChunk(md, chunk, tx)
+		if err == nil {
+			record(inserted)
+			continue
+		}
+		if err := tx.RollbackTo(savepoint).Error; err != nil {
+			return result, fmt.Errorf("回滚保存点失败: %w", err)
+		}
+
+		for _, row := range chunk {
+			rowSavepoint := fmt.Sprintf("batch_row_%d", row.index)
+			if err := tx.SavePoint(rowSavepoint).Error; err != nil {
+				return result, fmt.Errorf("创建保存点失败: %w", err)
+			}
+			inserted, err := s.insertChunk(md, []pendingRow{row}, tx)
+			if err != nil {
+				if err := tx.RollbackTo(rowSavepoint).Error; err != nil {
+					return result, fmt.Errorf("回滚保存点失败: %w", err)
+				}
+				result.Errors = append(result.Errors, RowError{Row: row.index, Error: err.Error()})
+				continue
+			}
+			record(inserted)
+		}
+		if len(result.Errors) > 0 && !bestEffort {
+			return result, fail()
+		}
+	}
+	return result, nil
+}
+
+// insertChunk 以多行 INSERT 写入一批数据，列集合不同的行分别生成语句
+// 未提供主键的行由数据库生成主键，取回后回填到行数据中
+func (s *crudService) insertChunk(md *engine.ModelData, chunk []pendingRow, tx *gorm.DB) ([]insertedRow, error) {
+	primaryKey := s.getPrimaryKey(md)
+	keyField := md.GeneratedKeyField()
+
+	// 1. 主键使用序列时先取号，插入语句中显式写入主键
+	if keyField != nil {
+		if sequence := engine.KeySequence(keyField); sequence != "" {
+			var missing []pendingRow
+			for _, row := range chunk {
+				if row.data[primaryKey] == nil {
+					missing = append(missing, row)
+				}
+			}
+			if len(missing) > 0 {
+				values, err := s.sqlExecutor.NextSequenceValues(tx, sequence, len(missing))
+				if err != nil {
+					return nil, err
+				}
+				for i, row := range missing {
+					row.data[primaryKey] = values[i]
+				}
+			}
+		}
+	}
+
+	// 2. 按列集合分组并保持行顺序
+	var groups [][]pendingRow
+	var groupColumns [][]string
+	groupIndex := make(map[string]int)
+	for _, row := range chunk {
+		columns := s.insertColumns(md, row.data)
+		if len(columns) == 0 {
+			return nil, errors.New("no columns to insert")
+		}
+		key := strings.Join(columns, ",")
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, nil)
+			groupColumns = append(groupColumns, columns)
+		}
+		groups[i] = append(groups[i], row)
+	}
+
+	// 3. 每组按参数上限拆分为多条语句，整批成功后再回填生成的主键
+	inserted := make([]insertedRow, 0, len(chunk))
+	generatedKeys := make(map[int]any)
+	for i, rows := range groups {
+		columns := groupColumns[i]
+		generated := keyField != nil && !slices.Contains(columns, primaryKey)
+		step := max(1, maxBatchParams/len(columns))
+		// SQL Server 的 OUTPUT INSERTED 不保证返回顺序，需逐行插入
+		if generated && tx.Dialector.Name() == "sqlserver" {
+			step = 1
+		}
+		for start := 0; start < len(rows); start += step {
+			part := rows[start:min(start+step, len(rows))]
+			sql, args := s.buildBatchInsertSQL(tx.Dialector.Name(), md, columns, part)
+			if generated {
+				keys, err := s.sqlExecutor.InsertWithTx(tx, sql, primaryKey, len(part), args...)
+				if err != nil {
+					return nil, fmt.Errorf("执行插入失败: %w", err)
+				}
+				for j, row := range part {
+					generatedKeys[row.index] = keys[j]
+				}
+			} else if _, err := s.sqlExecutor.ExecWithTx(tx, sql, args...); err != nil {
+				return nil, fmt.Errorf("执行插入失败: %w", err)
+			}
+			for _, row := range part {
+				inserted = append(inserted, insertedRow{index: row.index, data: row.data})
+			}
+		}
+	}
+	for i, row := range inserted {
+		if key, ok := generatedKeys[row.index]; ok {
+			row.data[primaryKey] = key
+		}
+		if val := row.data[primaryKey]; val != nil {
+			inserted[i].id = fmt.Sprintf("%v", val)
+		}
+	}
+	return inserted, nil
+}
+
+// insertColumns 按模型字段顺序返回数据中包含的插入列
+func (s *crudService) insertColumns(md *engine.ModelData, data map[string]any) []string {
+	var columns []string
+	for _, field := range md.Fields {
+		if _, ok := data[field.ColumnName]; ok {
+			columns = append(columns, field.ColumnName)
+		}
+	}
+	for _, column := range md.BlindIndexColumns() {
+		if _, ok := data[column]; ok {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// buildBatchInsertSQL 按数据库方言的标识符引用构建多行 INSERT 语句
+func (s *crudService) buildBatchInsertSQL(dialect string, md *engine.ModelData, columns []string, rows []pendingRow) (string, []any) {
+	quote := dialectQuote(dialect)
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quote(c)
+		placeholders[i] = "?"
+	}
+	tuple := "(" + strings.Join(placeholders, ", ") + ")"
+
+	values := make([]string, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		values[i] = tuple
+		for _, c := range columns {
+			args = append(args, row.data[c])
+		}
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		s.quotedMainTable(quote, md),
+		strings.Join(quoted, ", "),
+		strings.Join(values, ", "))
+	return sql, args
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+)
+
+// DefaultBulkMaxRows 条件批量操作默认允许影响的最大行数
+const DefaultBulkMaxRows int64 = 1000
+
+// DefaultBulkMaxRowsLimit 客户端可指定的最大影响行数的默认上限
+const DefaultBulkMaxRowsLimit int64 = 10000
+
+var bulkMaxRowsLimit atomic.Int64
+
+func init() {
+	bulkMaxRowsLimit.Store(DefaultBulkMaxRowsLimit)
+}
+
+// InitBulkMaxRows 设置客户端可指定的最大影响行数上限，小于等于 0 时使用默认上限
+func InitBulkMaxRows(limit int64) {
+	if limit <= 0 {
+		limit = DefaultBulkMaxRowsLimit
+	}
+	bulkMaxRowsLimit.Store(limit)
+}
+
+// BulkOptions 条件批量更新/删除选项
+type BulkOptions struct {
+	DryRun        bool   `json:"dry_run"`        // 仅返回匹配行数，不执行
+	ExpectedCount *int64 `json:"expected_count"` // 预览得到的匹配行数，执行时必填且须与实际一致
+	MaxRows       int64  `json:"max_rows"`       // 最大影响行数，0 表示使用默认值，超过服务端上限时按上限处理
+}
+
+// BulkResult 条件批量更新/删除结果
+type BulkResult struct {
+	Count   int64 `json:"count"`
+	MaxRows int64 `json:"max_rows"`
+	DryRun  bool  `json:"dry_run"`
+}
+
+// UpdateWhere 按查询条件批量更新，条件与列表查询参数一致，全部在同一事务中执行
+func (s *crudService) UpdateWhere(ctx context.Context, modelID string, params, data map[string]any, opts BulkOptions) (*BulkResult, error) {
+	if len(data) == 0 {
+		return nil, errors.New("更新数据不能为空")
+	}
+	return s.bulkWhere(ctx, modelID, params, opts, func(md *engine.ModelData, id string, tx *gorm.DB) error {
+		row := make(map[string]any, len(data)+1)
+		for k, v := range data {
+			row[k] = v
+		}
+		delete(row, s.getPrimaryKey(md))
+		// 版本字段以事务内读取的当前值为准
+		if md.HasVersionField() {
+			current, err := s.readRow(tx, md, id)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				return fmt.Errorf("记录 %s 不存在", id)
+			}
+			row[md.Model.VersionField] = current[md.Model.VersionField]
+		}
+		return s.updateRow(ctx, md, id, row, tx)
+	})
+}
+
+// DeleteWhere 按查询条件批量删除，条件与列表查询参数一致，全部在同一事务中执行
+func (s *crudService) DeleteWhere(ctx context.Context, modelID string, params map[string]any, opts BulkOptions) (*BulkResult, error) {
+	return s.bulkWhere(ctx, modelID, params, opts, func(md *engine.ModelData, id string, tx *gorm.DB) error {
+		_, err := s.deleteRow(ctx, md, id, tx)
+		return err
+	})
+}
+
+// bulkWhere 在事务中查询匹配记录的主键并逐条执行 apply
+// 预览仅统计匹配行数；执行时匹配行数超过上限或与预览结果不一致则回滚
+func (s *crudService) bulkWhere(ctx context.Context, modelID string, params map[string]any, opts BulkOptions, apply func(md *engine.ModelData, id string, tx *gorm.DB) error) (*BulkResult, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	if md.Model.ModelKind == 1 || len(md.Groups) > 0 {
+		return nil, errors.New("SQL 模型和分组模型不支持条件批量操作")
+	}
+
+	sql, args, err := s.buildMatchSQL(ctx, md, params)
+	if err != nil {
+		return nil, fmt.Errorf("构建查询SQL失败: %w", err)
+	}
+
+	result := &BulkResult{MaxRows: opts.MaxRows, DryRun: opts.DryRun}
+	if result.MaxRows <= 0 {
+		result.MaxRows = DefaultBulkMaxRows
+	}
+	result.MaxRows = min(result.MaxRows, bulkMaxRowsLimit.Load())
+
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		if result.Count, err = s.countMatches(db, md, sql, args); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if opts.ExpectedCount == nil {
+		return nil, errors.New("执行前须先预览并提交 expected_count")
+	}
+
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		// 最多读取上限加一条主键，超过上限时无需读取全部匹配记录
+		ids, err := s.matchIDs(tx, md, sql, args, result.MaxRows+1)
+		if err != nil {
+			return err
+		}
+		result.Count = int64(len(ids))
+		if result.Count > result.MaxRows {
+			return fmt.Errorf("匹配记录数超过上限 %d", result.MaxRows)
+		}
+		if result.Count != *opts.ExpectedCount {
+			return fmt.Errorf("匹配记录数 %d 与预览结果 %d 不一致，请重新预览", result.Count, *opts.ExpectedCount)
+		}
+		for _, id := range ids {
+			if err := apply(md, id, tx); err != nil {
+				return fmt.Errorf("记录 %s 处理失败: %w", id, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	commit()
+	return result, nil
+}
+
+// matchIDs 执行匹配查询并返回去重后的主键 (关联查询可能产生重复行)，最多返回 limit 个
+func (s *crudService) matchIDs(db *gorm.DB, md *engine.ModelData, sql string, args []any, limit int64) ([]string, error) {
+	primaryKey := s.resultPrimaryKey(md)
+	idSQL := buildMatchIDsSQL(db.Dialector.Name(), primaryKey, sql, limit)
+	rows, err := s.sqlExecutor.ExecuteWithTx(db, idSQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询匹配记录失败: %w", err)
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, fmt.Sprintf("%v", normalizeValue(row[primaryKey])))
+	}
+	return ids, nil
+}
+
+// buildMatchIDsSQL 按数据库方言构建去重主键查询并限制行数
+// 子查询别名不加 AS，Oracle 不支持表别名使用 AS
+func buildMatchIDsSQL(dialect, primaryKey, sql string, limit int64) string {
+	column := dialectQuote(dialect)(primaryKey)
+	switch dialect {
+	case "sqlserver":
+		return fmt.Sprintf("SELECT DISTINCT TOP %d t.%s FROM (%s) t", limit, column, sql)
+	case "oracle", "dm":
+		return fmt.Sprintf("SELECT DISTINCT t.%s FROM (%s) t FETCH FIRST %d ROWS ONLY", column, sql, limit)
+	default:
+		return fmt.Sprintf("SELECT DISTINCT t.%s FROM (%s) t LIMIT %d", column, sql, limit)
+	}
+}
+
+// countMatches 统计匹配记录的去重主键数
+func (s *crudService) countMatches(db *gorm.DB, md *engine.ModelData, sql string, args []any) (int64, error) {
+	quote := dialectQuote(db.Dialector.Name())
+	countSQL := fmt.Sprintf("SELECT COUNT(DISTINCT t.%s) as count FROM (%s) t", quote(s.resultPrimaryKey(md)), sql)
+	rows, err := s.sqlExecutor.ExecuteWithTx(db, countSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("统计匹配记录失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return s.toInt64(rows[0]["count"]), nil
+}
+
+// buildMatchSQL 按列表查询参数构建匹配记录的查询，忽略分页
+func (s *crudService) buildMatchSQL(ctx context.Context, md *engine.ModelData, params map[string]any) (string, []any, error) {
+	scoped := s.scopedParams(ctx, params)
+	delete(scoped, "limit")
+	delete(scoped, "page")
+
+	unpaged := *md
+	unpaged.Limit = nil
+	return s.sqlBuilder.BuildFromMetadata(&unpaged, scoped)
+}
+
+// resultPrimaryKey 返回主键在查询结果中的列名
+func (s *crudService) resultPrimaryKey(md *engine.ModelData) string {
+	for _, f := range md.Fields {
+		if f.IsPrimaryKey {
+			return engine.ResultColumn(f)
+		}
+	}
+	return "id"
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// expandRows 按展开参数以 IN 查询批量加载关联记录并嵌入到记录中
+// 以主表展开子表数据时按关系类型嵌入列表或单条记录，以子表展开时嵌入引用的主表记录
+func (s *crudService) expandRows(ctx context.Context, md *engine.ModelData, rows []map[string]any, nodes []*engine.ExpandNode) error {
+	if len(nodes) == 0 || len(rows) == 0 {
+		return nil
+	}
+	relations, err := s.sqlBuilder.LoadRelations(md.Model.ID)
+	if err != nil {
+		return fmt.Errorf("加载模型关联失败: %w", err)
+	}
+
+	for _, node := range nodes {
+		relation, toDetail := findExpandRelation(md.Model.ID, relations, node.Name)
+		if relation == nil {
+			return fmt.Errorf("模型 %s 不存在关联 %s", md.Model.ID, node.Name)
+		}
+		targetID := relation.MasterModelID
+		if toDetail {
+			targetID = relation.DetailModelID
+		}
+		target, err := s.sqlBuilder.LoadModelData(targetID)
+		if err != nil {
+			return fmt.Errorf("加载关联模型失败: %w", err)
+		}
+
+		// 1. 收集关联键并批量查询，子表数据在查询中按关联键截取前 limit 条
+		ownColumn, targetColumn := relation.ForeignKey, target.PrimaryKey()
+		perKey := 0
+		if toDetail {
+			ownColumn, targetColumn = md.PrimaryKey(), relation.ForeignKey
+			perKey = node.Limit
+			if perKey <= 0 {
+				perKey = engine.DefaultExpandLimit
+			}
+			if relation.RelationType == RelationOneToOne {
+				perKey = 1
+			}
+		}
+		keys := make([]any, 0, len(rows))
+		for _, row := range rows {
+			if v := columnValue(md, row, ownColumn); v != nil {
+				keys = append(keys, v)
+			}
+		}
+		related, err := s.readWhereIn(ctx, target, targetColumn, keys, perKey)
+		if err != nil {
+			return err
+		}
+
+		// 2. 对截取后的记录递归展开下级关联，按关联键分组后按字段投影
+		if err := s.expandRows(ctx, target, related, node.Children); err != nil {
+			return err
+		}
+		relatedKeys := make([]string, len(related))
+		for i, row := range related {
+			relatedKeys[i] = expandKey(columnValue(target, row, targetColumn))
+		}
+		related, err = projectExpanded(target, node, related)
+		if err != nil {
+			return err
+		}
+
+		// 3. 按关联键嵌入
+		groups := make(map[string][]map[string]any)
+		for i, row := range related {
+			groups[relatedKeys[i]] = append(groups[relatedKeys[i]], row)
+		}
+		for _, row := range rows {
+			group := groups[expandKey(columnValue(md, row, ownColumn))]
+			switch {
+			case !toDetail:
+				row[relation.MasterProperty()] = firstOrNil(group)
+			case relation.RelationType == RelationOneToOne:
+				row[relation.DetailProperty()] = firstOrNil(group)
+			default:
+				if group == nil {
+					group = []map[string]any{}
+				}
+				row[relation.DetailProperty()] = group
+			}
+		}
+	}
+	return nil
+}
+
+// findExpandRelation 按属性名查找关联，toDetail 表示由主表展开子表数据
+func findExpandRelation(modelID string, relations []*model.MdModelRelation, name string) (*model.MdModelRelation, bool) {
+	for _, r := range relations {
+		if r.MasterModelID == modelID && r.DetailProperty() == name {
+			return r, true
+		}
+	}
+	for _, r := range relations {
+		if r.DetailModelID == modelID && r.MasterProperty() == name {
+			return r, false
+		}
+	}
+	return nil, false
+}
+
+// expandRowNumber 按关联键截取记录时窗口函数序号的列名
+const expandRowNumber = "__expand_rn"
+
+// readWhereIn 按列值分批以 IN 查询主表记录，过滤逻辑删除和数据权限，解密并脱敏
+// perKey 大于 0 时以窗口函数在查询中按列值分组截取前 perKey 条 (按主键排序)
+func (s *crudService) readWhereIn(ctx context.Context, md *engine.ModelData, column string, values []any, perKey int) ([]map[string]any, error) {
+	seen := make(map[string]bool, len(values))
+	unique := make([]any, 0, len(values))
+	for _, v := range values {
+		if key := expandKey(v); !seen[key] {
+			seen[key] = true
+			unique = append(unique, v)
+		}
+	}
+
+	connID, table := s.getConnID(md), s.getMainTableName(md)
+	columns := "*"
+	if perKey > 0 {
+		columns = fmt.Sprintf("%s.*, ROW_NUMBER() OVER (PARTITION BY `%s` ORDER BY `%s`) AS %s", table, column, s.getPrimaryKey(md), expandRowNumber)
+	}
+	var result []map[string]any
+	for start := 0; start < len(unique); start += DefaultBatchChunkSize {
+		part := unique[start:min(start+DefaultBatchChunkSize, len(unique))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(part)), ", ")
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE `%s` IN (%s)", columns, table, column, placeholders)
+		args := append([]any{}, part...)
+		if md.HasLogicDelete() {
+			sql += fmt.Sprintf(" AND `%s` = ?", md.Model.LogicDeleteField)
+			args = append(args, md.LogicActiveValue())
+		}
+		sql, args = s.appendDataScope(ctx, md, sql, args)
+		if perKey > 0 {
+			sql = fmt.Sprintf("SELECT * FROM (%s) AS t WHERE %s <= %d", sql, expandRowNumber, perKey)
+		}
+		sql += fmt.Sprintf(" ORDER BY `%s`", s.getPrimaryKey(md))
+
+		rows, err := s.sqlExecutor.Execute(connID, sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("查询关联数据失败: %w", err)
+		}
+		for _, row := range rows {
+			delete(row, expandRowNumber)
+		}
+		result = append(result, rows...)
+	}
+	if err := md.DecryptRows(result); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(result, md.Masks, engine.DataScopeFromContext(ctx))
+	return result, nil
+}
+
+// projectExpanded 按展开节点指定的字段投影关联记录，保留下级关联的属性
+func projectExpanded(md *engine.ModelData, node *engine.ExpandNode, rows []map[string]any) ([]map[string]any, error) {
+	if len(node.Fields) == 0 {
+		return rows, nil
+	}
+	keep := make([]string, 0, len(node.Fields)+len(node.Children))
+	for _, f := range node.Fields {
+		if !slices.ContainsFunc(md.Fields, func(field *model.MdModelField) bool { return field.ColumnName == f }) {
+			return nil, fmt.Errorf("关联 %s 不存在字段 %s", node.Name, f)
+		}
+		keep = append(keep, f)
+	}
+	for _, child := range node.Children {
+		keep = append(keep, child.Name)
+	}
+
+	projected := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		p := make(map[string]any, len(keep))
+		for _, k := range keep {
+			if v, ok := row[k]; ok {
+				p[k] = v
+			}
+		}
+		projected[i] = p
+	}
+	return projected, nil
+}
+
+// columnValue 读取记录中的列值，列表查询结果中的列可能使用别名
+func columnValue(md *engine.ModelData, row map[string]any, column string) any {
+	if v, ok := row[column]; ok {
+		return v
+	}
+	for _, f := range md.Fields {
+		if f.ColumnName == column {
+			return row[engine.ResultColumn(f)]
+		}
+	}
+	return nil
+}
+
+// expandKey 关联键的比较值
+func expandKey(v any) string {
+	return fmt.Sprintf("%v", normalizeValue(v))
+}
+
+// firstOrNil 返回第一条记录，没有时返回 nil
+func firstOrNil(rows []map[string]any) any {
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
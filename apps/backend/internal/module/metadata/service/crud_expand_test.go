@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+func TestCRUDService_Expand(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_customers (id INTEGER PRIMARY KEY, name TEXT)")
+	targetDB.Exec("CREATE TABLE test_orders (id INTEGER PRIMARY KEY, customer_id INTEGER, no TEXT)")
+	targetDB.Exec("CREATE TABLE test_order_lines (id INTEGER PRIMARY KEY, order_id INTEGER, sku TEXT)")
+	targetDB.Exec("INSERT INTO test_customers VALUES (1, 'acme'), (2, 'globex')")
+	targetDB.Exec("INSERT INTO test_orders VALUES (1, 1, 'SO-1'), (2, 1, 'SO-2'), (3, 2, 'SO-3')")
+	targetDB.Exec("INSERT INTO test_order_lines VALUES (1, 1, 'a'), (2, 1, 'b'), (3, 1, 'c'), (4, 2, 'd')")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_expand"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	svc := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+
+	migrateModelConfig(metaDB)
+	metaDB.AutoMigrate(&model.MdModelRelation{})
+	for modelID, def := range map[string][]string{
+		"m_customer": {"test_customers", "name"},
+		"m_order":    {"test_orders", "customer_id", "no"},
+		"m_line":     {"test_order_lines", "order_id", "sku"},
+	} {
+		metaDB.Create(&model.MdModelTable{ID: "t_" + modelID, ModelID: modelID, TableNameStr: def[0], IsMain: true, ConnID: connID})
+		metaDB.Create(&model.MdModelField{ID: "f_" + modelID, ModelID: modelID, ColumnName: "id", IsPrimaryKey: true})
+		for _, column := range def[1:] {
+			metaDB.Create(&model.MdModelField{ID: "f_" + modelID + "_" + column, ModelID: modelID, ColumnName: column})
+		}
+		modelRepo.On("GetModelByID", modelID).Return(&model.MdModel{ID: modelID, ConnID: connID}, nil)
+	}
+	metaDB.Create(&model.MdModelRelation{ID: "r1", MasterModelID: "m_customer", DetailModelID: "m_order", ForeignKey: "customer_id", PropertyName: "orders", ReverseName: "customer"})
+	metaDB.Create(&model.MdModelRelation{ID: "r2", MasterModelID: "m_order", DetailModelID: "m_line", ForeignKey: "order_id", PropertyName: "lines", ReverseName: "order"})
+
+	ctx := context.Background()
+
+	t.Run("Get expands details and master", func(t *testing.T) {
+		row, err := svc.GetWithParams(ctx, "m_order", "1", map[string]any{engine.ParamExpand: "lines:2(sku),customer"})
+		require.NoError(t, err)
+		lines := row["lines"].([]map[string]any)
+		require.Len(t, lines, 2, "per-relation limit")
+		assert.Equal(t, map[string]any{"sku": "a"}, lines[0])
+		assert.Equal(t, "acme", row["customer"].(map[string]any)["name"])
+	})
+
+	t.Run("List expands nested relations in batches", func(t *testing.T) {
+		rows, _, err := svc.List(ctx, "m_customer", map[string]any{engine.ParamExpand: "orders.lines(sku)"})
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		orders := rows[0]["orders"].([]map[string]any)
+		require.Len(t, orders, 2)
+		assert.Len(t, orders[0]["lines"], 3)
+		assert.Len(t, orders[1]["lines"], 1)
+		assert.Empty(t, rows[1]["orders"].([]map[string]any)[0]["lines"])
+	})
+
+	t.Run("Limit applies per key before nested expansion", func(t *testing.T) {
+		rows, _, err := svc.List(ctx, "m_customer", map[string]any{engine.ParamExpand: "orders:1.lines:2"})
+		require.NoError(t, err)
+		orders := rows[0]["orders"].([]map[string]any)
+		require.Len(t, orders, 1)
+		assert.Equal(t, "SO-1", orders[0]["no"])
+		assert.NotContains(t, orders[0], expandRowNumber)
+		lines := orders[0]["lines"].([]map[string]any)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "b", lines[1]["sku"])
+		assert.Len(t, rows[1]["orders"], 1)
+	})
+
+	t.Run("Unknown relation or field", func(t *testing.T) {
+		_, err := svc.GetWithParams(ctx, "m_order", "1", map[string]any{engine.ParamExpand: "nope"})
+		assert.Error(t, err)
+		_, err = svc.GetWithParams(ctx, "m_order", "1", map[string]any{engine.ParamExpand: "lines(price)"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"metadata-platform/internal/module/metadata/engine"
+)
+
+// PivotResult 透视结果，Data 中每行的 Cells 与 Columns 一一对应
+type PivotResult struct {
+	Rows       []string   `json:"rows"`        // 行维度
+	Column     string     `json:"column"`      // 列维度
+	Columns    []any      `json:"columns"`     // 列维度取值
+	Data       []PivotRow `json:"data"`        // 各行单元格及行合计
+	Totals     []any      `json:"totals"`      // 列合计
+	GrandTotal any        `json:"grand_total"` // 总计
+}
+
+// PivotRow 透视结果中的一行
+type PivotRow struct {
+	Keys  map[string]any `json:"keys"` // 行维度取值
+	Cells []any          `json:"cells"`
+	Total any            `json:"total"`
+}
+
+// Pivot 透视查询，列维度取值展开为条件聚合列，并返回行、列合计
+func (s *crudService) Pivot(ctx context.Context, modelID string, params map[string]any) (*PivotResult, error) {
+	// 1. 加载模型并解析透视请求
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	req, err := engine.ParsePivotRequest(params[engine.ParamPivot])
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, fmt.Errorf("缺少透视参数 %s", engine.ParamPivot)
+	}
+	connID := s.getConnID(md)
+	params = s.scopedParams(ctx, params)
+
+	// 2. 未指定列维度取值时取数据中的全部取值
+	values := req.Values
+	if len(values) == 0 {
+		sql, args, err := s.sqlBuilder.BuildPivotValuesSQL(modelID, params)
+		if err != nil {
+			return nil, fmt.Errorf("构建透视SQL失败: %w", err)
+		}
+		rows, err := s.sqlExecutor.Execute(connID, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > engine.MaxPivotColumns {
+			return nil, fmt.Errorf("列维度取值超过 %d 个，请指定固定取值或增加过滤条件", engine.MaxPivotColumns)
+		}
+		for _, row := range rows {
+			values = append(values, normalizeValue(row[req.Column]))
+		}
+	}
+
+	// 3. 按行维度分组的透视查询
+	sql, args, err := s.sqlBuilder.BuildPivotSQL(modelID, params, values, false)
+	if err != nil {
+		return nil, fmt.Errorf("构建透视SQL失败: %w", err)
+	}
+	rows, err := s.sqlExecutor.Execute(connID, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > engine.MaxPivotRows {
+		return nil, fmt.Errorf("透视结果超过 %d 行，请增加过滤条件", engine.MaxPivotRows)
+	}
+	if err := md.DecryptRows(rows); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(rows, md.Masks, engine.DataScopeFromContext(ctx))
+
+	// 4. 列合计，没有行维度时分组结果即为合计
+	totals := rows
+	if len(req.Rows) > 0 {
+		if sql, args, err = s.sqlBuilder.BuildPivotSQL(modelID, params, values, true); err != nil {
+			return nil, fmt.Errorf("构建透视SQL失败: %w", err)
+		}
+		if totals, err = s.sqlExecutor.Execute(connID, sql, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	// 5. 组装结果
+	result := &PivotResult{Rows: req.Rows, Column: req.Column, Columns: values, Data: make([]PivotRow, 0, len(rows))}
+	for _, row := range rows {
+		item := PivotRow{Keys: make(map[string]any, len(req.Rows)), Cells: pivotCells(row, len(values)), Total: normalizeValue(row[engine.PivotTotalAlias])}
+		for _, r := range req.Rows {
+			item.Keys[r] = normalizeValue(row[r])
+		}
+		result.Data = append(result.Data, item)
+	}
+	if len(totals) > 0 {
+		result.Totals = pivotCells(totals[0], len(values))
+		result.GrandTotal = normalizeValue(totals[0][engine.PivotTotalAlias])
+	}
+	return result, nil
+}
+
+// pivotCells 按列维度取值顺序取出单元格
+func pivotCells(row map[string]any, n int) []any {
+	cells := make([]any, n)
+	for i := range cells {
+		cells[i] = normalizeValue(row[engine.PivotColumnAlias(i)])
+	}
+	return cells
+}
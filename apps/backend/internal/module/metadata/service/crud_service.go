@@ -2,32 +2,77 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
 	"metadata-platform/internal/module/audit/service"
 	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
 )
 
 // CRUDService CRUD服务接口
 type CRUDService interface {
 	Create(ctx context.Context, modelID string, data map[string]any) (map[string]any, error)
 	CreateWithTx(ctx context.Context, modelID string, data map[string]any, tx *gorm.DB) (map[string]any, error)
-	Get(modelID, id string) (map[string]any, error)
+	Get(ctx context.Context, modelID, id string) (map[string]any, error)
+	GetWithParams(ctx context.Context, modelID, id string, params map[string]any) (map[string]any, error)
 	Update(ctx context.Context, modelID, id string, data map[string]any) error
+	UpdateWithTx(ctx context.Context, modelID, id string, data map[string]any, tx *gorm.DB) error
+	BatchUpdate(ctx context.Context, modelID string, dataList []map[string]any) error
 	Delete(ctx context.Context, modelID, id string) error
-	List(modelID string, params map[string]any) ([]map[string]any, int64, error)
-	BatchCreate(modelID string, dataList []map[string]any) ([]map[string]any, error)
+	DeleteWithTx(ctx context.Context, modelID, id string, tx *gorm.DB) error
+	Restore(ctx context.Context, modelID, id string) error
+	Purge(ctx context.Context, modelID, id string) error
+	Revert(ctx context.Context, modelID, id string, expected, target map[string]any) error
+	List(ctx context.Context, modelID string, params map[string]any) ([]map[string]any, int64, error)
+	Stream(ctx context.Context, modelID string, params map[string]any, batchSize int, fn func(total int64, rows []map[string]any) error) error
+	ListByFieldWithTx(ctx context.Context, modelID, field string, value any, tx *gorm.DB) ([]map[string]any, error)
+	ListWhereWithTx(ctx context.Context, modelID, where string, args []any, tx *gorm.DB) ([]map[string]any, error)
+	QueryWhereWithTx(ctx context.Context, modelID string, query WhereQuery, tx *gorm.DB) ([]map[string]any, int64, error)
+	CountByWhereWithTx(ctx context.Context, modelID, column, where string, args []any, tx *gorm.DB) (map[string]int64, error)
+	BatchCreate(ctx context.Context, modelID string, dataList []map[string]any) ([]map[string]any, error)
 	BatchCreateWithTx(ctx context.Context, modelID string, dataList []map[string]any, tx *gorm.DB) ([]map[string]any, error)
-	BatchDelete(modelID string, ids []string) error
-	Statistics(modelID string, queryParams map[string]any) (map[string]int64, error)
-	Aggregate(modelID string, queryParams map[string]any) ([]map[string]any, error)
+	BatchInsert(ctx context.Context, modelID string, dataList []map[string]any, opts BatchInsertOptions) (*BatchInsertResult, error)
+	BatchDelete(ctx context.Context, modelID string, ids []string) error
+	UpdateWhere(ctx context.Context, modelID string, params, data map[string]any, opts BulkOptions) (*BulkResult, error)
+	DeleteWhere(ctx context.Context, modelID string, params map[string]any, opts BulkOptions) (*BulkResult, error)
+	Upsert(ctx context.Context, modelID string, data map[string]any, opts UpsertOptions) (*UpsertResult, error)
+	BatchUpsert(ctx context.Context, modelID string, dataList []map[string]any, opts UpsertOptions) ([]*UpsertResult, error)
+	ExistingKeys(ctx context.Context, modelID string, keyFields []string, dataList []map[string]any) ([]bool, error)
+	Statistics(ctx context.Context, modelID string, queryParams map[string]any) (map[string]int64, error)
+	Aggregate(ctx context.Context, modelID string, queryParams map[string]any) ([]map[string]any, error)
+	Pivot(ctx context.Context, modelID string, params map[string]any) (*PivotResult, error)
+	ReEncrypt(ctx context.Context, modelID string, batchSize int, progress func(processed int64) error) (int64, error)
 	BuildSQLFromData(data *engine.ModelData, params map[string]any) (string, []any, error)
-	ExecuteModelData(data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error)
+	ExecuteModelData(ctx context.Context, data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error)
+}
+
+// ErrVersionConflict 乐观锁版本冲突
+var ErrVersionConflict = errors.New("数据已被其他用户修改，请刷新后重试")
+
+// VersionConflictError 乐观锁冲突错误，携带数据库中的当前记录
+type VersionConflictError struct {
+	Current map[string]any
+}
+
+// Error 实现error接口
+func (e *VersionConflictError) Error() string {
+	return ErrVersionConflict.Error()
+}
+
+// Unwrap 实现errors.Unwrap接口
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
 }
 
 // crudService CRUD服务实现
@@ -56,7 +101,7 @@ func NewCRUDService(
 	}
 }
 
-// Create 创建数据
+// Create 创建数据，主键由数据库生成时取回生成的主键，返回持久化后的记录
 func (s *crudService) Create(ctx context.Context, modelID string, data map[string]any) (map[string]any, error) {
 	// 1. 加载模型
 	md, err := s.sqlBuilder.LoadModelData(modelID)
@@ -64,57 +109,50 @@ func (s *crudService) Create(ctx context.Context, modelID string, data map[strin
 		return nil, fmt.Errorf("加载模型失败: %w", err)
 	}
 
-	// 2. 验证数据
-	if err := s.validator.Validate(modelID, md.Fields, data); err != nil {
-		return nil, fmt.Errorf("数据验证失败: %w", err)
-	}
-
-	// 3. 构建插入SQL
-	sql, args, err := s.buildInsertSQL(md, data)
-	if err != nil {
-		return nil, fmt.Errorf("构建插入SQL失败: %w", err)
-	}
-
-	// 4. 执行SQL
-	connID := s.getConnID(md)
-	result, err := s.sqlExecutor.Execute(connID, sql, args...)
+	// 2. 获取目标连接
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
 	if err != nil {
-		return nil, fmt.Errorf("执行插入失败: %w", err)
+		return nil, err
 	}
 
-	// 5. 获取插入后的ID
-	id := ""
-	if len(result) > 0 {
-		if idValue, ok := result[0]["id"]; ok {
-			id = fmt.Sprintf("%v", idValue)
-		}
-	}
-	if id == "" {
-		// 尝试从data中获取ID
-		if idValue, ok := data[s.getPrimaryKey(md)]; ok {
-			id = fmt.Sprintf("%v", idValue)
-		} else {
-			return nil, errors.New("无法获取插入后的ID")
-		}
+	// 3. 在事务中插入，提交后记录数据变更
+	var id string
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		id, err = s.createRow(ctx, md, data, tx)
+		return err
+	}); err != nil {
+		return nil, err
 	}
+	commit()
 
-	// 6. 查询插入后的数据
-	return s.Get(modelID, id)
+	// 4. 查询插入后的数据
+	return s.Get(ctx, modelID, id)
 }
 
 // Get 获取数据
-func (s *crudService) Get(modelID, id string) (map[string]any, error) {
+func (s *crudService) Get(ctx context.Context, modelID, id string) (map[string]any, error) {
+	return s.GetWithParams(ctx, modelID, id, nil)
+}
+
+// GetWithParams 获取数据，params 支持 expand 展开关联
+func (s *crudService) GetWithParams(ctx context.Context, modelID, id string, params map[string]any) (map[string]any, error) {
 	// 1. 加载模型
 	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
 		return nil, fmt.Errorf("加载模型失败: %w", err)
 	}
+	expand, err := engine.ParseExpand(params[engine.ParamExpand])
+	if err != nil {
+		return nil, err
+	}
 
 	// 2. 构建查询SQL
 	sql, args, err := s.buildGetSQL(md, id)
 	if err != nil {
 		return nil, fmt.Errorf("构建查询SQL失败: %w", err)
 	}
+	sql, args = s.appendDataScope(ctx, md, sql, args)
 
 	// 3. 执行SQL
 	connID := s.getConnID(md)
@@ -127,6 +165,13 @@ func (s *crudService) Get(modelID, id string) (map[string]any, error) {
 	if len(result) == 0 {
 		return nil, nil
 	}
+	if err := md.DecryptRows(result); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(result, md.Masks, engine.DataScopeFromContext(ctx))
+	if err := s.expandRows(ctx, md, result[:1], expand); err != nil {
+		return nil, err
+	}
 
 	return result[0], nil
 }
@@ -139,24 +184,127 @@ func (s *crudService) Update(ctx context.Context, modelID, id string, data map[s
 		return fmt.Errorf("加载模型失败: %w", err)
 	}
 
-	// 2. 验证数据
-	if err := s.validator.Validate(modelID, md.Fields, data); err != nil {
+	// 2. 获取目标连接
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	// 3. 在事务中读取变更前数据并更新
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return s.updateRow(ctx, md, id, data, tx)
+	}); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// UpdateWithTx 在事务中更新数据
+func (s *crudService) UpdateWithTx(ctx context.Context, modelID, id string, data map[string]any, tx *gorm.DB) error {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+
+	return s.updateRow(ctx, md, id, data, tx)
+}
+
+// BatchUpdate 批量更新，每条数据须包含主键，全部在同一事务中执行
+func (s *crudService) BatchUpdate(ctx context.Context, modelID string, dataList []map[string]any) error {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	primaryKey := s.getPrimaryKey(md)
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for i, data := range dataList {
+			idValue, ok := data[primaryKey]
+			if !ok || idValue == nil {
+				return fmt.Errorf("第 %d 条数据缺少主键 %s", i+1, primaryKey)
+			}
+			if err := s.updateRow(ctx, md, fmt.Sprintf("%v", idValue), data, tx); err != nil {
+				return fmt.Errorf("第 %d 条数据更新失败: %w", i+1, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// updateRow 执行单条更新，配置了版本字段时受影响行数为 0 视为版本冲突
+func (s *crudService) updateRow(ctx context.Context, md *engine.ModelData, id string, data map[string]any, db *gorm.DB) error {
+	// 1. 验证数据，当前用户不可见的脱敏列及数据权限字段不允许修改
+	data = s.stripMaskedColumns(ctx, md, data)
+	data, err := s.guardDataScope(ctx, md, data)
+	if err != nil {
+		return err
+	}
+	if err := s.validator.Validate(md.Model.ID, md.Fields, data); err != nil {
 		return fmt.Errorf("数据验证失败: %w", err)
 	}
+	if err := md.EncryptRow(data); err != nil {
+		return err
+	}
 
-	// 3. 构建更新SQL
+	// 2. 构建更新SQL
 	sql, args, err := s.buildUpdateSQL(md, id, data)
 	if err != nil {
 		return fmt.Errorf("构建更新SQL失败: %w", err)
 	}
+	sql, args = s.appendDataScope(ctx, md, sql, args)
 
-	// 4. 执行SQL
-	connID := s.getConnID(md)
-	_, err = s.sqlExecutor.Execute(connID, sql, args...)
+	// 3. 读取变更前数据并执行SQL
+	before, err := s.readRow(db, md, id)
+	if err != nil {
+		return err
+	}
+	affected, err := s.sqlExecutor.ExecWithTx(db, sql, args...)
 	if err != nil {
 		return fmt.Errorf("执行更新失败: %w", err)
 	}
 
+	// 4. 乐观锁校验
+	if affected == 0 && md.HasVersionField() {
+		getSQL, getArgs, err := s.buildGetSQL(md, id)
+		if err != nil {
+			return err
+		}
+		getSQL, getArgs = s.appendDataScope(ctx, md, getSQL, getArgs)
+		current, err := s.sqlExecutor.ExecuteWithTx(db, getSQL, getArgs...)
+		if err != nil {
+			return fmt.Errorf("查询当前记录失败: %w", err)
+		}
+		if len(current) == 0 {
+			return fmt.Errorf("记录 %s 不存在", id)
+		}
+		if err := md.DecryptRows(current); err != nil {
+			return err
+		}
+		engine.ApplyMasks(current, md.Masks, engine.DataScopeFromContext(ctx))
+		return &VersionConflictError{Current: current[0]}
+	}
+
+	// 5. 记录数据变更
+	if affected > 0 {
+		after, err := s.readRow(db, md, id)
+		if err != nil {
+			return err
+		}
+		s.recordChange(ctx, md, ChangeUpdate, id, before, after)
+	}
+
 	return nil
 }
 
@@ -168,32 +316,285 @@ func (s *crudService) Delete(ctx context.Context, modelID, id string) error {
 		return fmt.Errorf("加载模型失败: %w", err)
 	}
 
-	// 2. 构建删除SQL
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	// 2. 在事务中删除
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := s.deleteRow(ctx, md, id, tx)
+		return err
+	}); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// DeleteWithTx 在事务中删除数据
+func (s *crudService) DeleteWithTx(ctx context.Context, modelID, id string, tx *gorm.DB) error {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+
+	_, err = s.deleteRow(ctx, md, id, tx)
+	return err
+}
+
+// deleteRow 删除单条记录 (配置了逻辑删除字段时为 UPDATE)，返回受影响行数
+func (s *crudService) deleteRow(ctx context.Context, md *engine.ModelData, id string, tx *gorm.DB) (int64, error) {
+	var sql string
+	var args []any
+	var err error
+	if md.HasLogicDelete() {
+		sql, args, err = s.buildLogicDeleteSQL(md, id, md.LogicDeletedValue(), md.LogicActiveValue())
+	} else {
+		sql, args, err = s.buildDeleteSQL(md, id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("构建删除SQL失败: %w", err)
+	}
+	sql, args = s.appendDataScope(ctx, md, sql, args)
+
+	affected, err := s.changeRow(ctx, tx, md, id, ChangeDelete, sql, args, md.HasLogicDelete())
+	if err != nil {
+		return 0, fmt.Errorf("执行删除失败: %w", err)
+	}
+	return affected, nil
+}
+
+// Restore 恢复已逻辑删除的数据
+func (s *crudService) Restore(ctx context.Context, modelID, id string) error {
+	// 1. 加载模型
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+	if !md.HasLogicDelete() {
+		return errors.New("模型未配置逻辑删除字段")
+	}
+
+	// 2. 构建恢复SQL
+	sql, args, err := s.buildLogicDeleteSQL(md, id, md.LogicActiveValue(), md.LogicDeletedValue())
+	if err != nil {
+		return fmt.Errorf("构建恢复SQL失败: %w", err)
+	}
+	sql, args = s.appendDataScope(ctx, md, sql, args)
+
+	// 3. 执行SQL
+	if err := s.execRowChange(ctx, md, id, ChangeRestore, sql, args, true); err != nil {
+		return fmt.Errorf("执行恢复失败: %w", err)
+	}
+
+	return nil
+}
+
+// Purge 物理删除已逻辑删除的数据
+func (s *crudService) Purge(ctx context.Context, modelID, id string) error {
+	// 1. 加载模型
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+	if !md.HasLogicDelete() {
+		return errors.New("模型未配置逻辑删除字段")
+	}
+
+	// 2. 构建删除SQL，仅允许清除已处于删除状态的数据
 	sql, args, err := s.buildDeleteSQL(md, id)
 	if err != nil {
 		return fmt.Errorf("构建删除SQL失败: %w", err)
 	}
+	sql += fmt.Sprintf(" AND `%s` = ?", md.Model.LogicDeleteField)
+	args = append(args, md.LogicDeletedValue())
+	sql, args = s.appendDataScope(ctx, md, sql, args)
 
 	// 3. 执行SQL
-	connID := s.getConnID(md)
-	_, err = s.sqlExecutor.Execute(connID, sql, args...)
+	if err := s.execRowChange(ctx, md, id, ChangePurge, sql, args, false); err != nil {
+		return fmt.Errorf("执行清除失败: %w", err)
+	}
+
+	return nil
+}
+
+// Revert 将记录回滚为 target 镜像，用于按变更日志恢复数据
+// expected 为记录当前应有的字段值 (nil 表示记录应不存在)，与数据库不一致时返回 VersionConflictError；
+// target 为 nil 表示删除记录，记录不存在时按 target 重新插入
+func (s *crudService) Revert(ctx context.Context, modelID, id string, expected, target map[string]any) error {
+	// 1. 加载模型
+	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
-		return fmt.Errorf("执行删除失败: %w", err)
+		return fmt.Errorf("加载模型失败: %w", err)
 	}
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	// 2. 在事务中校验当前记录并回滚
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		current, err := s.readRow(tx, md, id)
+		if err != nil {
+			return err
+		}
+		if conflict := revertConflict(expected, current); conflict {
+			var visible map[string]any
+			if current != nil {
+				rows := []map[string]any{current}
+				if err := md.DecryptRows(rows); err != nil {
+					return err
+				}
+				engine.ApplyMasks(rows, md.Masks, engine.DataScopeFromContext(ctx))
+				visible = rows[0]
+			}
+			return &VersionConflictError{Current: visible}
+		}
 
+		var sql string
+		var args []any
+		switch {
+		case target == nil && current == nil:
+			return nil
+		case target == nil:
+			if md.HasLogicDelete() {
+				sql, args, err = s.buildLogicDeleteSQL(md, id, md.LogicDeletedValue(), md.LogicActiveValue())
+			} else {
+				sql, args, err = s.buildDeleteSQL(md, id)
+			}
+		case current == nil:
+			sql, args, err = s.buildInsertSQL(md, s.revertColumns(ctx, md, target, nil))
+		default:
+			sql, args, err = s.buildRevertSQL(md, id, s.revertColumns(ctx, md, target, current))
+		}
+		if err != nil {
+			return fmt.Errorf("构建回滚SQL失败: %w", err)
+		}
+		if sql == "" {
+			return nil
+		}
+		if current != nil {
+			sql, args = s.appendDataScope(ctx, md, sql, args)
+		}
+
+		affected, err := s.sqlExecutor.ExecWithTx(tx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("执行回滚失败: %w", err)
+		}
+		if affected == 0 {
+			return fmt.Errorf("记录 %s 不存在或无权限", id)
+		}
+		after, err := s.readRow(tx, md, id)
+		if err != nil {
+			return err
+		}
+		s.recordChange(ctx, md, ChangeRevert, id, current, after)
+		return nil
+	}); err != nil {
+		return err
+	}
+	commit()
 	return nil
 }
 
-// List 查询列表
-func (s *crudService) List(modelID string, params map[string]any) ([]map[string]any, int64, error) {
+// revertConflict 判断当前记录是否与预期不一致
+func revertConflict(expected, current map[string]any) bool {
+	if expected == nil || current == nil {
+		return (expected == nil) != (current == nil)
+	}
+	normalized := normalizeImage(current)
+	for k, v := range expected {
+		if len(DiffRecords(map[string]any{k: v}, map[string]any{k: normalized[k]})) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// revertColumns 从镜像中取出可回写的列：模型字段及盲索引列，当前用户不可见的脱敏列保持不变；
+// 镜像中的时间在 JSON 中为字符串，按当前记录的列类型还原
+func (s *crudService) revertColumns(ctx context.Context, md *engine.ModelData, image, current map[string]any) map[string]any {
+	masked := md.MaskedColumns(engine.DataScopeFromContext(ctx))
+	columns := make(map[string]any, len(image))
+	allowed := make(map[string]bool, len(md.Fields))
+	for _, f := range md.Fields {
+		allowed[f.ColumnName] = true
+	}
+	for _, c := range md.BlindIndexColumns() {
+		allowed[c] = true
+	}
+	for k, v := range image {
+		if !allowed[k] || masked[k] {
+			continue
+		}
+		if str, ok := v.(string); ok {
+			if _, isTime := current[k].(time.Time); isTime {
+				if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+					v = t
+				}
+			}
+		}
+		if n, ok := v.(json.Number); ok {
+			v = n.String()
+		}
+		columns[k] = v
+	}
+	return columns
+}
+
+// buildRevertSQL 构建回滚更新SQL，可回写逻辑删除字段，配置了版本字段时版本号继续递增
+func (s *crudService) buildRevertSQL(md *engine.ModelData, id string, data map[string]any) (string, []any, error) {
+	primaryKey := s.getPrimaryKey(md)
+	var setClauses []string
+	var args []any
+	columns := make([]string, 0, len(data))
+	for k := range data {
+		if k == primaryKey || (md.HasVersionField() && k == md.Model.VersionField) {
+			continue
+		}
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	for _, k := range columns {
+		setClauses = append(setClauses, fmt.Sprintf("`%s` = ?", k))
+		args = append(args, data[k])
+	}
+	if len(setClauses) == 0 {
+		return "", nil, nil
+	}
+
+	if md.HasVersionField() {
+		versionField := md.Model.VersionField
+		if md.IsTimestampVersion() {
+			setClauses = append(setClauses, fmt.Sprintf("`%s` = ?", versionField))
+			args = append(args, time.Now())
+		} else {
+			setClauses = append(setClauses, fmt.Sprintf("`%s` = `%s` + 1", versionField, versionField))
+		}
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE `%s` = ?", s.getMainTableName(md), strings.Join(setClauses, ", "), primaryKey)
+	return sql, append(args, id), nil
+}
+
+// List 查询列表，params 支持 expand 展开关联
+func (s *crudService) List(ctx context.Context, modelID string, params map[string]any) ([]map[string]any, int64, error) {
 	// 1. 加载模型
 	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("加载模型失败: %w", err)
 	}
 
+	expand, err := engine.ParseExpand(params[engine.ParamExpand])
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// 2. 构建查询SQL
-	sql, countSql, args, err := s.buildListSQL(md, params)
+	sql, countSql, args, err := s.buildListSQL(md, s.scopedParams(ctx, params))
 	if err != nil {
 		return nil, 0, fmt.Errorf("构建查询SQL失败: %w", err)
 	}
@@ -217,93 +618,167 @@ func (s *crudService) List(modelID string, params map[string]any) ([]map[string]
 	if err != nil {
 		return nil, 0, fmt.Errorf("执行列表查询失败: %w", err)
 	}
+	if err := md.DecryptRows(result); err != nil {
+		return nil, 0, err
+	}
+	engine.ApplyMasks(result, md.Masks, engine.DataScopeFromContext(ctx))
+	if err := s.expandRows(ctx, md, result, expand); err != nil {
+		return nil, 0, err
+	}
 
 	return result, total, nil
 }
 
-// CreateWithTx 在事务中创建数据
-func (s *crudService) CreateWithTx(ctx context.Context, modelID string, data map[string]any, tx *gorm.DB) (map[string]any, error) {
-	// 1. 加载模型
+// Stream 按列表查询条件流式读取全部数据，不分页，每批数据解密、脱敏后回调，批之间检查是否已取消
+func (s *crudService) Stream(ctx context.Context, modelID string, params map[string]any, batchSize int, fn func(total int64, rows []map[string]any) error) error {
 	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
-		return nil, fmt.Errorf("加载模型失败: %w", err)
+		return fmt.Errorf("加载模型失败: %w", err)
 	}
 
-	// 2. 验证数据
-	if err := s.validator.Validate(modelID, md.Fields, data); err != nil {
-		return nil, fmt.Errorf("数据验证失败: %w", err)
+	expand, err := engine.ParseExpand(params[engine.ParamExpand])
+	if err != nil {
+		return err
 	}
 
-	// 3. 构建插入SQL
-	sql, args, err := s.buildInsertSQL(md, data)
+	sql, args, err := s.buildMatchSQL(ctx, md, params)
 	if err != nil {
-		return nil, fmt.Errorf("构建插入SQL失败: %w", err)
+		return fmt.Errorf("构建查询SQL失败: %w", err)
 	}
+	countSql := fmt.Sprintf("SELECT COUNT(*) as count FROM (%s) AS t", sql)
 
-	// 4. 在事务中执行SQL
-	result, err := s.sqlExecutor.ExecuteWithTx(tx, sql, args...)
+	connID := s.getConnID(md)
+	countResult, err := s.sqlExecutor.Execute(connID, countSql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("执行插入失败: %w", err)
+		return fmt.Errorf("执行计数查询失败: %w", err)
+	}
+	var total int64
+	if len(countResult) > 0 {
+		total = s.toInt64(countResult[0]["count"])
 	}
 
-	// 5. 获取插入后的ID
-	id := ""
-	if len(result) > 0 {
-		if idValue, ok := result[0]["id"]; ok {
-			id = fmt.Sprintf("%v", idValue)
+	scope := engine.DataScopeFromContext(ctx)
+	return s.sqlExecutor.ExecuteStream(connID, sql, batchSize, func(rows []map[string]any) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-	}
-	if id == "" {
-		// 尝试从data中获取ID
-		if idValue, ok := data[s.getPrimaryKey(md)]; ok {
-			id = fmt.Sprintf("%v", idValue)
-		} else {
-			return nil, errors.New("无法获取插入后的ID")
+		if err := md.DecryptRows(rows); err != nil {
+			return err
 		}
+		engine.ApplyMasks(rows, md.Masks, scope)
+		if err := s.expandRows(ctx, md, rows, expand); err != nil {
+			return err
+		}
+		return fn(total, rows)
+	}, args...)
+}
+
+// ListByFieldWithTx 在事务中按字段值查询主表记录，过滤逻辑删除和数据权限，按主键排序
+func (s *crudService) ListByFieldWithTx(ctx context.Context, modelID, field string, value any, tx *gorm.DB) ([]map[string]any, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	if !slices.ContainsFunc(md.Fields, func(f *model.MdModelField) bool { return f.ColumnName == field }) {
+		return nil, fmt.Errorf("模型 %s 不存在字段 %s", modelID, field)
 	}
 
-	// 6. 查询插入后的数据
-	return s.Get(modelID, id)
+	rows, _, err := s.queryWhere(ctx, md, WhereQuery{Where: fmt.Sprintf("`%s` = ?", field), Args: []any{value}}, tx)
+	return rows, err
 }
 
-// BatchCreate 批量创建
-func (s *crudService) BatchCreate(modelID string, dataList []map[string]any) ([]map[string]any, error) {
-	results := make([]map[string]any, 0, len(dataList))
-	for _, data := range dataList {
-		result, err := s.Create(context.Background(), modelID, data)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, result)
+// ListWhereWithTx 在事务中按条件查询主表记录，条件由调用方构造 (列名须已校验)，过滤逻辑删除和数据权限，按主键排序
+func (s *crudService) ListWhereWithTx(ctx context.Context, modelID, where string, args []any, tx *gorm.DB) ([]map[string]any, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
 	}
-	return results, nil
+
+	rows, _, err := s.queryWhere(ctx, md, WhereQuery{Where: where, Args: args}, tx)
+	return rows, err
 }
 
-// BatchCreateWithTx 在事务中批量创建
-func (s *crudService) BatchCreateWithTx(ctx context.Context, modelID string, dataList []map[string]any, tx *gorm.DB) ([]map[string]any, error) {
-	results := make([]map[string]any, 0, len(dataList))
-	for _, data := range dataList {
-		result, err := s.CreateWithTx(ctx, modelID, data, tx)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, result)
+// CreateWithTx 在事务中创建数据，返回事务内读取的持久化记录
+func (s *crudService) CreateWithTx(ctx context.Context, modelID string, data map[string]any, tx *gorm.DB) (map[string]any, error) {
+	// 1. 加载模型
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
 	}
-	return results, nil
+
+	// 2. 插入数据 (事务由调用方控制，调用方可通过 beginChanges 延迟到提交后记录数据变更)
+	id, err := s.createRow(ctx, md, data, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 在事务中查询插入后的数据
+	persisted, err := s.readPersisted(ctx, tx, md, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	return persisted[id], nil
 }
 
-// BatchDelete 批量删除
-func (s *crudService) BatchDelete(modelID string, ids []string) error {
-	for _, id := range ids {
-		if err := s.Delete(context.Background(), modelID, id); err != nil {
-			return err
+// createRow 校验并插入单条数据、记录数据变更，返回主键 (含数据库生成的主键)
+func (s *crudService) createRow(ctx context.Context, md *engine.ModelData, data map[string]any, tx *gorm.DB) (string, error) {
+	// 1. 验证数据
+	if err := s.initDataScope(ctx, md, data); err != nil {
+		return "", err
+	}
+	if err := s.validator.Validate(md.Model.ID, md.Fields, data); err != nil {
+		return "", fmt.Errorf("数据验证失败: %w", err)
+	}
+	s.initVersion(md, data)
+	if err := md.EncryptRow(data); err != nil {
+		return "", err
+	}
+
+	// 2. 插入并获取主键
+	inserted, err := s.insertChunk(md, []pendingRow{{data: data}}, tx)
+	if err != nil {
+		return "", err
+	}
+	id := inserted[0].id
+	if id == "" {
+		return "", errors.New("无法获取插入后的ID")
+	}
+
+	// 3. 记录数据变更
+	after, _ := s.readRow(tx, md, id)
+	s.recordChange(ctx, md, ChangeCreate, id, nil, after)
+	return id, nil
+}
+
+// BatchDelete 批量删除，全部在同一事务中执行
+func (s *crudService) BatchDelete(ctx context.Context, modelID string, ids []string) error {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return fmt.Errorf("加载模型失败: %w", err)
+	}
+
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			if _, err := s.deleteRow(ctx, md, id, tx); err != nil {
+				return err
+			}
 		}
+		return nil
+	}); err != nil {
+		return err
 	}
+	commit()
 	return nil
 }
 
 // Statistics 统计查询
-func (s *crudService) Statistics(modelID string, queryParams map[string]any) (map[string]int64, error) {
+func (s *crudService) Statistics(ctx context.Context, modelID string, queryParams map[string]any) (map[string]int64, error) {
 	// 1. 加载模型
 	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
@@ -313,7 +788,7 @@ func (s *crudService) Statistics(modelID string, queryParams map[string]any) (ma
 	connID := s.getConnID(md)
 
 	// 2. 构建基础SQL
-	sql, _, err := s.sqlBuilder.BuildSQL(modelID, queryParams)
+	sql, args, err := s.sqlBuilder.BuildSQL(modelID, s.scopedParams(ctx, queryParams))
 	if err != nil {
 		return nil, fmt.Errorf("构建SQL失败: %w", err)
 	}
@@ -323,7 +798,7 @@ func (s *crudService) Statistics(modelID string, queryParams map[string]any) (ma
 
 	// 总记录数
 	countSQL := fmt.Sprintf("SELECT COUNT(*) as count FROM (%s) AS t", sql)
-	countResult, err := s.sqlExecutor.Execute(connID, countSQL)
+	countResult, err := s.sqlExecutor.Execute(connID, countSQL, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -335,7 +810,7 @@ func (s *crudService) Statistics(modelID string, queryParams map[string]any) (ma
 }
 
 // Aggregate 聚合查询
-func (s *crudService) Aggregate(modelID string, queryParams map[string]any) ([]map[string]any, error) {
+func (s *crudService) Aggregate(ctx context.Context, modelID string, queryParams map[string]any) ([]map[string]any, error) {
 	// 1. 加载模型
 	md, err := s.sqlBuilder.LoadModelData(modelID)
 	if err != nil {
@@ -344,14 +819,125 @@ func (s *crudService) Aggregate(modelID string, queryParams map[string]any) ([]m
 
 	connID := s.getConnID(md)
 
-	// 2. 构建基础SQL
-	sql, args, err := s.sqlBuilder.BuildSQL(modelID, queryParams)
-	if err != nil {
+	// 2. 构建基础SQL，提供统计参数时编译为模型 SQL 外层的分组统计
+	var sql string
+	var args []any
+	if _, ok := queryParams[engine.ParamStats]; ok {
+		db, err := s.sqlExecutor.GetConnection(connID)
+		if err != nil {
+			return nil, err
+		}
+		sql, args, err = s.sqlBuilder.BuildStatsSQL(modelID, s.scopedParams(ctx, queryParams), db.Dialector.Name())
+		if err != nil {
+			return nil, fmt.Errorf("构建统计SQL失败: %w", err)
+		}
+	} else if sql, args, err = s.sqlBuilder.BuildSQL(modelID, s.scopedParams(ctx, queryParams)); err != nil {
 		return nil, fmt.Errorf("构建SQL失败: %w", err)
 	}
 
 	// 3. 执行查询
-	return s.sqlExecutor.Execute(connID, sql, args...)
+	result, err := s.sqlExecutor.Execute(connID, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := md.DecryptRows(result); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(result, md.Masks, engine.DataScopeFromContext(ctx))
+
+	// 4. 时间分桶的空桶补齐和滚动窗口
+	if req, _ := engine.ParseStatsRequest(queryParams[engine.ParamStats]); req != nil {
+		return engine.FinishStatsRows(req, result)
+	}
+	return result, nil
+}
+
+// ReEncrypt 使用当前版本密钥重新加密模型的加密字段，返回处理的记录数
+// 按主键分批扫描非当前密钥版本的密文 (含加密前写入的明文)，逐行以原值为条件更新，避免覆盖并发修改
+// 每批结束后调用 progress，返回错误时终止
+func (s *crudService) ReEncrypt(ctx context.Context, modelID string, batchSize int, progress func(processed int64) error) (int64, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return 0, fmt.Errorf("加载模型失败: %w", err)
+	}
+	fields := md.EncryptedFields()
+	if len(fields) == 0 {
+		return 0, errors.New("模型未配置加密字段")
+	}
+	ring := utils.GetFieldKeyRing()
+	if ring == nil {
+		return 0, engine.ErrFieldKeyRingMissing
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	connID := s.getConnID(md)
+	tableName := s.getMainTableName(md)
+	primaryKey := s.getPrimaryKey(md)
+	var total int64
+	for _, f := range fields {
+		prefix := utils.FieldCiphertextPrefix(f.EncryptAlgo, ring.CurrentVersion())
+		var lastID any
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+			sql := fmt.Sprintf("SELECT `%s`, `%s` FROM %s WHERE `%s` NOT LIKE ?", primaryKey, f.ColumnName, tableName, f.ColumnName)
+			args := []any{prefix + "%"}
+			if lastID != nil {
+				sql += fmt.Sprintf(" AND `%s` > ?", primaryKey)
+				args = append(args, lastID)
+			}
+			sql += fmt.Sprintf(" ORDER BY `%s` LIMIT %d", primaryKey, batchSize)
+			rows, err := s.sqlExecutor.Execute(connID, sql, args...)
+			if err != nil {
+				return total, fmt.Errorf("查询待加密数据失败: %w", err)
+			}
+
+			for _, row := range rows {
+				lastID = row[primaryKey]
+				old := fmt.Sprintf("%v", row[f.ColumnName])
+				if b, ok := row[f.ColumnName].([]byte); ok {
+					old = string(b)
+				}
+				plain := old
+				if utils.IsFieldCiphertext(old) {
+					if plain, _, err = ring.Decrypt(old); err != nil {
+						return total, fmt.Errorf("记录 %v 字段 %s 解密失败: %w", lastID, f.ColumnName, err)
+					}
+				}
+				cipherText, err := ring.Encrypt(f.EncryptAlgo, plain)
+				if err != nil {
+					return total, fmt.Errorf("加密字段 %s 失败: %w", f.ColumnName, err)
+				}
+
+				setClause := fmt.Sprintf("`%s` = ?", f.ColumnName)
+				setArgs := []any{cipherText}
+				if f.BlindIndexField != "" {
+					setClause += fmt.Sprintf(", `%s` = ?", f.BlindIndexField)
+					setArgs = append(setArgs, ring.BlindIndex(plain))
+				}
+				updateSQL := fmt.Sprintf("UPDATE %s SET %s WHERE `%s` = ? AND `%s` = ?", tableName, setClause, primaryKey, f.ColumnName)
+				affected, err := s.sqlExecutor.Exec(connID, updateSQL, append(setArgs, lastID, old)...)
+				if err != nil {
+					return total, fmt.Errorf("更新记录 %v 失败: %w", lastID, err)
+				}
+				total += affected
+			}
+			if progress != nil {
+				if err := progress(total); err != nil {
+					return total, err
+				}
+			}
+
+			if len(rows) < batchSize {
+				break
+			}
+		}
+	}
+
+	return total, nil
 }
 
 // BuildSQLFromData 从ModelData构建SQL
@@ -359,12 +945,16 @@ func (s *crudService) BuildSQLFromData(data *engine.ModelData, params map[string
 	return s.sqlBuilder.BuildSQL(data.Model.ID, params)
 }
 
-// ExecuteModelData 执行ModelData查询
-func (s *crudService) ExecuteModelData(data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error) {
-	connID := s.getConnID(data)
+// ExecuteModelData 执行ModelData查询，按已保存的模型构建、解密和脱敏，不使用请求中的字段定义
+func (s *crudService) ExecuteModelData(ctx context.Context, data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error) {
+	md, err := s.sqlBuilder.LoadModelData(data.Model.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("加载模型失败: %w", err)
+	}
+	connID := s.getConnID(md)
 
 	// 1. 构建SQL
-	sql, args, err := s.sqlBuilder.BuildSQL(data.Model.ID, params)
+	sql, args, err := s.sqlBuilder.BuildSQL(md.Model.ID, s.scopedParams(ctx, params))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -385,6 +975,10 @@ func (s *crudService) ExecuteModelData(data *engine.ModelData, params map[string
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := md.DecryptRows(results); err != nil {
+		return nil, 0, err
+	}
+	engine.ApplyMasks(results, md.Masks, engine.DataScopeFromContext(ctx))
 
 	return results, total, nil
 }
@@ -405,12 +999,7 @@ func (s *crudService) getConnID(md *engine.ModelData) string {
 }
 
 func (s *crudService) getPrimaryKey(md *engine.ModelData) string {
-	for _, f := range md.Fields {
-		if f.IsPrimaryKey {
-			return f.ColumnName
-		}
-	}
-	return "id"
+	return md.PrimaryKey()
 }
 
 func (s *crudService) toInt64(v any) int64 {
@@ -445,6 +1034,13 @@ func (s *crudService) buildInsertSQL(md *engine.ModelData, data map[string]any)
 			args = append(args, val)
 		}
 	}
+	for _, column := range md.BlindIndexColumns() {
+		if val, ok := data[column]; ok {
+			columns = append(columns, "`"+column+"`")
+			placeholders = append(placeholders, "?")
+			args = append(args, val)
+		}
+	}
 
 	if len(columns) == 0 {
 		return "", nil, errors.New("no columns to insert")
@@ -465,7 +1061,12 @@ func (s *crudService) buildGetSQL(md *engine.ModelData, id string) (string, []an
 	primaryKey := s.getPrimaryKey(md)
 
 	sql := fmt.Sprintf("SELECT * FROM %s WHERE `%s` = ?", tableName, primaryKey)
-	return sql, []any{id}, nil
+	args := []any{id}
+	if md.HasLogicDelete() {
+		sql += fmt.Sprintf(" AND `%s` = ?", md.Model.LogicDeleteField)
+		args = append(args, md.LogicActiveValue())
+	}
+	return sql, args, nil
 }
 
 func (s *crudService) buildUpdateSQL(md *engine.ModelData, id string, data map[string]any) (string, []any, error) {
@@ -474,20 +1075,49 @@ func (s *crudService) buildUpdateSQL(md *engine.ModelData, id string, data map[s
 
 	primaryKey := s.getPrimaryKey(md)
 
+	// 乐观锁：客户端必须提交最后读取到的版本值
+	var expectedVersion any
+	if md.HasVersionField() {
+		val, ok := data[md.Model.VersionField]
+		if !ok || val == nil {
+			return "", nil, fmt.Errorf("缺少版本字段 %s", md.Model.VersionField)
+		}
+		expectedVersion = val
+	}
+
 	for _, field := range md.Fields {
-		if field.ColumnName == primaryKey {
-			continue // 跳过主键
+		if field.ColumnName == primaryKey || (md.HasLogicDelete() && field.ColumnName == md.Model.LogicDeleteField) {
+			continue // 跳过主键和逻辑删除字段
+		}
+		if md.HasVersionField() && field.ColumnName == md.Model.VersionField {
+			continue // 版本字段由系统维护
 		}
 		if val, ok := data[field.ColumnName]; ok {
 			setClauses = append(setClauses, fmt.Sprintf("`%s` = ?", field.ColumnName))
 			args = append(args, val)
 		}
 	}
+	for _, column := range md.BlindIndexColumns() {
+		if val, ok := data[column]; ok {
+			setClauses = append(setClauses, fmt.Sprintf("`%s` = ?", column))
+			args = append(args, val)
+		}
+	}
 
 	if len(setClauses) == 0 {
 		return "", nil, errors.New("no columns to update")
 	}
 
+	if md.HasVersionField() {
+		versionField := md.Model.VersionField
+		if md.IsTimestampVersion() {
+			setClauses = append(setClauses, fmt.Sprintf("`%s` = ?", versionField))
+			args = append(args, time.Now())
+		} else {
+			setClauses = append(setClauses, fmt.Sprintf("`%s` = `%s` + 1", versionField, versionField))
+		}
+	}
+
 	tableName := s.getMainTableName(md)
 	sql := fmt.Sprintf("UPDATE %s SET %s WHERE `%s` = ?",
 		tableName,
@@ -495,9 +1125,204 @@ func (s *crudService) buildUpdateSQL(md *engine.ModelData, id string, data map[s
 		primaryKey)
 	args = append(args, id)
 
+	// 已逻辑删除的数据不允许更新
+	if md.HasLogicDelete() {
+		sql += fmt.Sprintf(" AND `%s` = ?", md.Model.LogicDeleteField)
+		args = append(args, md.LogicActiveValue())
+	}
+
+	if md.HasVersionField() {
+		sql += fmt.Sprintf(" AND `%s` = ?", md.Model.VersionField)
+		args = append(args, expectedVersion)
+	}
+
 	return sql, args, nil
 }
 
+// initVersion 新增数据未提供版本值时初始化版本字段
+func (s *crudService) initVersion(md *engine.ModelData, data map[string]any) {
+	if !md.HasVersionField() {
+		return
+	}
+	if val, ok := data[md.Model.VersionField]; ok && val != nil {
+		return
+	}
+	if md.IsTimestampVersion() {
+		data[md.Model.VersionField] = time.Now()
+	} else {
+		data[md.Model.VersionField] = 1
+	}
+}
+
+// scopedParams 复制查询参数并写入当前请求的数据权限，丢弃客户端伪造的同名参数
+func (s *crudService) scopedParams(ctx context.Context, params map[string]any) map[string]any {
+	scoped := make(map[string]any, len(params)+1)
+	for k, v := range params {
+		scoped[k] = v
+	}
+	delete(scoped, engine.ParamDataScope)
+	if scope := engine.DataScopeFromContext(ctx); scope != nil {
+		scoped[engine.ParamDataScope] = scope
+	}
+	return scoped
+}
+
+// appendDataScope 为按主键操作的单表语句追加数据权限条件
+func (s *crudService) appendDataScope(ctx context.Context, md *engine.ModelData, sql string, args []any) (string, []any) {
+	cond, condArgs := md.DataScopeCondition(engine.DataScopeFromContext(ctx), func(name string) string {
+		return "`" + name + "`"
+	})
+	if cond == "" {
+		return sql, args
+	}
+	return sql + " AND " + cond, append(args, condArgs...)
+}
+
+// stripMaskedColumns 去除对当前用户生效的脱敏列，避免将脱敏后的值写回
+func (s *crudService) stripMaskedColumns(ctx context.Context, md *engine.ModelData, data map[string]any) map[string]any {
+	masked := md.MaskedColumns(engine.DataScopeFromContext(ctx))
+	if len(masked) == 0 {
+		return data
+	}
+	stripped := make(map[string]any, len(data))
+	for k, v := range data {
+		if !masked[k] {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
+
+// initDataScope 按当前用户写入新增数据的数据权限字段
+// 管理员仅填充未提供的所有人/租户；其他用户的所有人/租户固定为当前用户，组织须在可访问范围内，未提供时取首个可访问的组织
+func (s *crudService) initDataScope(ctx context.Context, md *engine.ModelData, data map[string]any) error {
+	scope := engine.DataScopeFromContext(ctx)
+	if scope == nil {
+		return nil
+	}
+	if scope.Deny {
+		return errors.New("未登录，无法写入数据")
+	}
+	set := func(field, value string) {
+		if field == "" || value == "" {
+			return
+		}
+		if val, ok := data[field]; scope.Admin && ok && val != nil && val != "" {
+			return
+		}
+		data[field] = value
+	}
+	set(md.Model.DataOwnerField, scope.UserID)
+	set(md.Model.DataTenantField, scope.TenantID)
+
+	if field := md.Model.DataOrgField; field != "" && !scope.All {
+		if val, ok := data[field]; ok && val != nil && val != "" {
+			return checkDataOrg(scope, val)
+		}
+		if len(scope.OrgIDs) > 0 {
+			data[field] = scope.OrgIDs[0]
+		}
+	}
+	return nil
+}
+
+// guardDataScope 更新时非管理员不能修改所有人/租户字段，修改组织时须在可访问范围内，返回去除这些字段后的数据
+func (s *crudService) guardDataScope(ctx context.Context, md *engine.ModelData, data map[string]any) (map[string]any, error) {
+	scope := engine.DataScopeFromContext(ctx)
+	if scope == nil || scope.Admin {
+		return data, nil
+	}
+	if scope.Deny {
+		return nil, errors.New("未登录，无法修改数据")
+	}
+	if field := md.Model.DataOrgField; field != "" && !scope.All {
+		if val, ok := data[field]; ok {
+			if err := checkDataOrg(scope, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var guarded map[string]any
+	for _, field := range []string{md.Model.DataOwnerField, md.Model.DataTenantField} {
+		if _, ok := data[field]; field == "" || !ok {
+			continue
+		}
+		if guarded == nil {
+			guarded = maps.Clone(data)
+		}
+		delete(guarded, field)
+	}
+	if guarded == nil {
+		return data, nil
+	}
+	return guarded, nil
+}
+
+// checkDataOrg 校验组织是否在当前用户可访问的范围内
+func checkDataOrg(scope *engine.DataScope, val any) error {
+	if org := fmt.Sprintf("%v", val); val == nil || !slices.Contains(scope.OrgIDs, org) {
+		return fmt.Errorf("无权写入组织 %v 的数据", val)
+	}
+	return nil
+}
+
+// readRow 按主键读取原始记录 (不解密、不脱敏、不过滤逻辑删除)，用于记录变更前后镜像
+func (s *crudService) readRow(db *gorm.DB, md *engine.ModelData, id string) (map[string]any, error) {
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE `%s` = ?", s.getMainTableName(md), s.getPrimaryKey(md))
+	rows, err := s.sqlExecutor.ExecuteWithTx(db, sql, id)
+	if err != nil {
+		return nil, fmt.Errorf("读取记录 %s 失败: %w", id, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0], nil
+}
+
+// execRowChange 在事务中读取变更前数据、执行单条记录的变更语句并记录数据变更，未影响任何记录时返回错误
+func (s *crudService) execRowChange(ctx context.Context, md *engine.ModelData, id, action, sql string, args []any, withAfter bool) error {
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return err
+	}
+
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		affected, err := s.changeRow(ctx, tx, md, id, action, sql, args, withAfter)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("记录 %s 不存在或无权限", id)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// changeRow 读取变更前数据、执行单条记录的变更语句并记录数据变更，返回受影响行数
+func (s *crudService) changeRow(ctx context.Context, tx *gorm.DB, md *engine.ModelData, id, action, sql string, args []any, withAfter bool) (int64, error) {
+	before, err := s.readRow(tx, md, id)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := s.sqlExecutor.ExecWithTx(tx, sql, args...)
+	if err != nil || affected == 0 {
+		return affected, err
+	}
+	var after map[string]any
+	if withAfter {
+		if after, err = s.readRow(tx, md, id); err != nil {
+			return affected, err
+		}
+	}
+	s.recordChange(ctx, md, action, id, before, after)
+	return affected, nil
+}
+
 func (s *crudService) buildDeleteSQL(md *engine.ModelData, id string) (string, []any, error) {
 	tableName := s.getMainTableName(md)
 	primaryKey := s.getPrimaryKey(md)
@@ -506,6 +1331,16 @@ func (s *crudService) buildDeleteSQL(md *engine.ModelData, id string) (string, [
 	return sql, []any{id}, nil
 }
 
+// buildLogicDeleteSQL 构建逻辑删除/恢复SQL，将逻辑删除字段从 from 状态切换为 to 状态
+func (s *crudService) buildLogicDeleteSQL(md *engine.ModelData, id string, to, from string) (string, []any, error) {
+	tableName := s.getMainTableName(md)
+	primaryKey := s.getPrimaryKey(md)
+	field := md.Model.LogicDeleteField
+
+	sql := fmt.Sprintf("UPDATE %s SET `%s` = ? WHERE `%s` = ? AND `%s` = ?", tableName, field, primaryKey, field)
+	return sql, []any{to, id, from}, nil
+}
+
 func (s *crudService) buildListSQL(md *engine.ModelData, params map[string]any) (string, string, []any, error) {
 	// 使用 SQLBuilder 构建查询
 	sql, args, err := s.sqlBuilder.BuildSQL(md.Model.ID, params)
@@ -520,20 +1355,25 @@ func (s *crudService) buildListSQL(md *engine.ModelData, params map[string]any)
 }
 
 func (s *crudService) getMainTableName(md *engine.ModelData) string {
+	t := s.mainTable(md)
+	if t == nil {
+		return ""
+	}
+	if t.TableSchema != "" {
+		return fmt.Sprintf("`%s`.`%s`", t.TableSchema, t.TableNameStr)
+	}
+	return "`" + t.TableNameStr + "`"
+}
+
+// mainTable 返回模型主表，未标记主表时取第一张表
+func (s *crudService) mainTable(md *engine.ModelData) *model.MdModelTable {
 	for _, t := range md.Tables {
 		if t.IsMain {
-			if t.TableSchema != "" {
-				return fmt.Sprintf("`%s`.`%s`", t.TableSchema, t.TableNameStr)
-			}
-			return "`" + t.TableNameStr + "`"
+			return t
 		}
 	}
 	if len(md.Tables) > 0 {
-		t := md.Tables[0]
-		if t.TableSchema != "" {
-			return fmt.Sprintf("`%s`.`%s`", t.TableSchema, t.TableNameStr)
-		}
-		return "`" + t.TableNameStr + "`"
+		return md.Tables[0]
 	}
-	return ""
+	return nil
 }
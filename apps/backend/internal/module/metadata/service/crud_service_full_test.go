@@ -94,7 +94,7 @@ func (m *MockCRUDService) BuildSQLFromData(data *engine.ModelData, params map[st
 	return "", nil, nil
 }
 
-func (m *MockCRUDService) ExecuteModelData(data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error) {
+func (m *MockCRUDService) ExecuteModelData(ctx context.Context, data *engine.ModelData, params map[string]any) ([]map[string]any, int64, error) {
 	if m.ExecuteModelDataFunc != nil {
 		return m.ExecuteModelDataFunc(data, params)
 	}
@@ -262,7 +262,7 @@ func TestCRUDService_ExecuteModelData(t *testing.T) {
 	data := &engine.ModelData{
 		Model: &model.MdModel{ID: "123"},
 	}
-	results, count, err := mock.ExecuteModelData(data, nil)
+	results, count, err := mock.ExecuteModelData(context.Background(), data, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), count)
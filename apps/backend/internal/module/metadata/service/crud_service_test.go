@@ -1,17 +1,24 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"metadata-platform/internal/module/audit"
 	auditService "metadata-platform/internal/module/audit/service"
 	"metadata-platform/internal/module/metadata/engine"
 	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/repository"
+	"metadata-platform/internal/utils"
+	"strings"
 	"testing"
 
 	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -106,7 +113,7 @@ func TestCRUDService_Lifecycle(t *testing.T) {
 
 	// 5. Test Get
 	t.Run("Get", func(t *testing.T) {
-		res, err := svc.Get(modelID, "1")
+		res, err := svc.Get(context.Background(), modelID, "1")
 		assert.NoError(t, err)
 		assert.Equal(t, "Alice", res["name"])
 		// SQLite might return int64 for INTEGER
@@ -118,7 +125,7 @@ func TestCRUDService_Lifecycle(t *testing.T) {
 		err := svc.Update(context.Background(), modelID, "1", map[string]any{"name": "Alice Smith"})
 		assert.NoError(t, err)
 
-		res, _ := svc.Get(modelID, "1")
+		res, _ := svc.Get(context.Background(), modelID, "1")
 		assert.Equal(t, "Alice Smith", res["name"])
 	})
 
@@ -127,7 +134,557 @@ func TestCRUDService_Lifecycle(t *testing.T) {
 		err := svc.Delete(context.Background(), modelID, "1")
 		assert.NoError(t, err)
 
-		res, _ := svc.Get(modelID, "1")
+		res, _ := svc.Get(context.Background(), modelID, "1")
 		assert.Nil(t, res)
 	})
 }
+
+func TestCRUDService_OptimisticLock(t *testing.T) {
+	modelID := "m_lock"
+	svc := newTestCRUD(t, "CREATE TABLE test_orders (id INTEGER PRIMARY KEY, amount INTEGER, version INTEGER)",
+		append(testFields("id", "amount"), model.MdModelField{ColumnName: "version", FieldType: "integer"}),
+		&model.MdModel{ID: modelID, VersionField: "version"}).svc
+
+	created, err := svc.Create(context.Background(), modelID, map[string]any{"id": 1, "amount": 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), created["version"])
+
+	t.Run("Missing version is rejected", func(t *testing.T) {
+		err := svc.Update(context.Background(), modelID, "1", map[string]any{"amount": 20})
+		assert.Error(t, err)
+	})
+
+	t.Run("Matching version increments", func(t *testing.T) {
+		err := svc.Update(context.Background(), modelID, "1", map[string]any{"amount": 20, "version": 1})
+		assert.NoError(t, err)
+		res, _ := svc.Get(context.Background(), modelID, "1")
+		assert.Equal(t, int64(2), res["version"])
+	})
+
+	t.Run("Stale version conflicts with current record", func(t *testing.T) {
+		err := svc.Update(context.Background(), modelID, "1", map[string]any{"amount": 30, "version": 1})
+		var conflict *VersionConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, int64(20), conflict.Current["amount"])
+	})
+
+	t.Run("Batch update rolls back on conflict", func(t *testing.T) {
+		err := svc.BatchUpdate(context.Background(), modelID, []map[string]any{
+			{"id": 1, "amount": 40, "version": 2},
+			{"id": 1, "amount": 50, "version": 2},
+		})
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		res, _ := svc.Get(context.Background(), modelID, "1")
+		assert.Equal(t, int64(20), res["amount"])
+	})
+}
+
+func TestCRUDService_WhereBulk(t *testing.T) {
+	modelID := "m_bulk"
+	f := newTestCRUD(t, "CREATE TABLE test_tasks (id INTEGER PRIMARY KEY, status TEXT, priority INTEGER, version INTEGER, deleted INTEGER DEFAULT 0)",
+		append(testFields("id", "status", "priority"), model.MdModelField{ColumnName: "version", FieldType: "integer"}),
+		&model.MdModel{ID: modelID, VersionField: "version", LogicDeleteField: "deleted"})
+	f.metaDB.Create(&model.MdModelWhere{ID: "w1", ModelID: modelID, TableNameStr: "test_tasks", ColumnName: "status", Operator2: "=", ParamKey: "status"})
+	f.metaDB.Create(&model.MdModelLimit{ID: "l1", ModelID: modelID, Limit: 1})
+	svc, targetDB := f.svc, f.targetDB
+
+	for i, status := range []string{"open", "open", "open", "done"} {
+		_, err := svc.Create(context.Background(), modelID, map[string]any{"id": i + 1, "status": status, "priority": 1})
+		assert.NoError(t, err)
+	}
+	filter := map[string]any{"status": "open"}
+	count := func(where string) int64 {
+		var n int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_tasks WHERE " + where).Scan(&n)
+		return n
+	}
+
+	t.Run("Dry run ignores pagination and changes nothing", func(t *testing.T) {
+		res, err := svc.UpdateWhere(context.Background(), modelID, filter, map[string]any{"priority": 9}, BulkOptions{DryRun: true})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), res.Count)
+		assert.Zero(t, count("priority = 9"))
+	})
+
+	t.Run("Execution requires expected count", func(t *testing.T) {
+		_, err := svc.UpdateWhere(context.Background(), modelID, filter, map[string]any{"priority": 9}, BulkOptions{})
+		assert.Error(t, err)
+
+		stale := int64(2)
+		_, err = svc.UpdateWhere(context.Background(), modelID, filter, map[string]any{"priority": 9}, BulkOptions{ExpectedCount: &stale})
+		assert.Error(t, err)
+		assert.Zero(t, count("priority = 9"))
+	})
+
+	t.Run("Max rows guard", func(t *testing.T) {
+		expected := int64(3)
+		_, err := svc.DeleteWhere(context.Background(), modelID, filter, BulkOptions{ExpectedCount: &expected, MaxRows: 2})
+		assert.Error(t, err)
+		assert.Zero(t, count("deleted = 1"))
+
+		// 客户端指定的上限不能超过服务端上限
+		InitBulkMaxRows(2)
+		defer InitBulkMaxRows(0)
+		res, err := svc.DeleteWhere(context.Background(), modelID, filter, BulkOptions{DryRun: true, MaxRows: 100})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), res.MaxRows)
+		_, err = svc.DeleteWhere(context.Background(), modelID, filter, BulkOptions{ExpectedCount: &expected, MaxRows: 100})
+		assert.Error(t, err)
+		assert.Zero(t, count("deleted = 1"))
+	})
+
+	t.Run("Update where bumps versions", func(t *testing.T) {
+		expected := int64(3)
+		res, err := svc.UpdateWhere(context.Background(), modelID, filter, map[string]any{"priority": 9}, BulkOptions{ExpectedCount: &expected})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), res.Count)
+		assert.Equal(t, int64(3), count("priority = 9 AND version = 2"))
+		assert.Equal(t, int64(1), count("priority = 1"))
+	})
+
+	t.Run("Delete where", func(t *testing.T) {
+		expected := int64(3)
+		_, err := svc.DeleteWhere(context.Background(), modelID, filter, BulkOptions{ExpectedCount: &expected})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count("deleted = 1"))
+	})
+
+	t.Run("Dialect row caps", func(t *testing.T) {
+		inner := "SELECT id FROM test_tasks"
+		assert.Equal(t, "SELECT DISTINCT t.`id` FROM (SELECT id FROM test_tasks) t LIMIT 3", buildMatchIDsSQL("mysql", "id", inner, 3))
+		assert.Equal(t, `SELECT DISTINCT TOP 3 t."id" FROM (SELECT id FROM test_tasks) t`, buildMatchIDsSQL("sqlserver", "id", inner, 3))
+		assert.Equal(t, `SELECT DISTINCT t."id" FROM (SELECT id FROM test_tasks) t FETCH FIRST 3 ROWS ONLY`, buildMatchIDsSQL("oracle", "id", inner, 3))
+		assert.Equal(t, `SELECT DISTINCT t."id" FROM (SELECT id FROM test_tasks) t FETCH FIRST 3 ROWS ONLY`, buildMatchIDsSQL("dm", "id", inner, 3))
+	})
+}
+
+func TestCRUDService_RestorePurge(t *testing.T) {
+	modelID := "m_trash"
+	f := newTestCRUD(t, "CREATE TABLE test_notes (id INTEGER PRIMARY KEY, title TEXT, deleted INTEGER DEFAULT 0)",
+		testFields("id", "title"), &model.MdModel{ID: modelID, LogicDeleteField: "deleted"})
+	svc, ctx := f.svc, context.Background()
+	_, err := svc.Create(ctx, modelID, map[string]any{"id": 1, "title": "a"})
+	require.NoError(t, err)
+
+	// 未删除的记录不能恢复或清除，不存在的记录同样返回错误
+	assert.Error(t, svc.Restore(ctx, modelID, "1"))
+	assert.Error(t, svc.Purge(ctx, modelID, "1"))
+	assert.Error(t, svc.Restore(ctx, modelID, "404"))
+
+	require.NoError(t, svc.Delete(ctx, modelID, "1"))
+	require.NoError(t, svc.Restore(ctx, modelID, "1"))
+	require.NoError(t, svc.Delete(ctx, modelID, "1"))
+	require.NoError(t, svc.Purge(ctx, modelID, "1"))
+	assert.Error(t, svc.Purge(ctx, modelID, "1"))
+}
+
+func TestCRUDService_StreamIgnoresModelLimit(t *testing.T) {
+	modelID := "m_stream"
+	f := newTestCRUD(t, "CREATE TABLE test_logs (id INTEGER PRIMARY KEY, title TEXT)",
+		testFields("id", "title"), &model.MdModel{ID: modelID})
+	f.metaDB.Create(&model.MdModelLimit{ID: "l1", ModelID: modelID, Limit: 2})
+	svc, ctx := f.svc, context.Background()
+	for i := 1; i <= 5; i++ {
+		_, err := svc.Create(ctx, modelID, map[string]any{"id": i, "title": fmt.Sprintf("t%d", i)})
+		require.NoError(t, err)
+	}
+
+	var streamed int
+	var reported int64
+	err := svc.Stream(ctx, modelID, map[string]any{}, 2, func(total int64, rows []map[string]any) error {
+		reported = total
+		streamed += len(rows)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, streamed, "导出不受模型分页配置限制")
+	assert.Equal(t, int64(5), reported)
+}
+
+func TestCRUDService_ExecuteModelDataScopeAndMask(t *testing.T) {
+	modelID := "m_preview"
+	f := newTestCRUD(t, "CREATE TABLE test_contacts (id INTEGER PRIMARY KEY, phone TEXT, owner_id TEXT)",
+		testFields("id", "phone", "owner_id"), &model.MdModel{ID: modelID, DataOwnerField: "owner_id"})
+	f.metaDB.Create(&model.MdModelFieldEnhancement{ID: "e1", ModelID: modelID, FieldID: "f02", MaskType: "hide"})
+	f.targetDB.Exec("INSERT INTO test_contacts VALUES (1, '13800000000', 'u1'), (2, '13900000000', 'u2')")
+	user := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", Self: true})
+
+	// 请求中的字段定义不影响解密和脱敏
+	data := &engine.ModelData{Model: &model.MdModel{ID: modelID}, Fields: []*model.MdModelField{{ColumnName: "phone"}}}
+	rows, total, err := f.svc.ExecuteModelData(user, data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, rows, 1)
+	assert.NotEqual(t, "13800000000", rows[0]["phone"])
+}
+
+func TestCRUDService_Upsert(t *testing.T) {
+	modelID := "m_upsert"
+	f := newTestCRUD(t, "CREATE TABLE test_products (id INTEGER PRIMARY KEY AUTOINCREMENT, code TEXT UNIQUE, name TEXT, price INTEGER, note TEXT, version INTEGER)",
+		append(testFields("id", "code", "name", "price", "note"), model.MdModelField{ColumnName: "version", FieldType: "integer"}),
+		&model.MdModel{ID: modelID, UniqueKeyFields: "code", VersionField: "version"})
+	svc, targetDB, builder := f.svc, f.targetDB, f.builder
+
+	ctx := context.Background()
+	opts := UpsertOptions{Policies: map[string]string{"name": UpsertKeep, "note": UpsertCoalesce}}
+
+	first, err := svc.Upsert(ctx, modelID, map[string]any{"code": "P1", "name": "Pen", "price": 10, "note": "blue"}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, UpsertInserted, first.Action)
+	assert.Equal(t, int64(1), first.Data["version"])
+
+	results, err := svc.BatchUpsert(ctx, modelID, []map[string]any{
+		{"code": "P1", "name": "Pencil", "price": 12, "note": nil},
+		{"code": "P2", "name": "Ink", "price": 5},
+	}, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, UpsertUpdated, results[0].Action)
+		assert.Equal(t, first.ID, results[0].ID)
+		assert.Equal(t, "Pen", results[0].Data["name"], "keep policy")
+		assert.Equal(t, int64(12), results[0].Data["price"], "overwrite policy")
+		assert.Equal(t, "blue", results[0].Data["note"], "coalesce policy")
+		assert.Equal(t, int64(2), results[0].Data["version"])
+		assert.Equal(t, UpsertInserted, results[1].Action)
+	}
+
+	t.Run("Missing key is rejected and batch rolls back", func(t *testing.T) {
+		_, err := svc.BatchUpsert(ctx, modelID, []map[string]any{
+			{"code": "P3", "price": 1},
+			{"price": 2},
+		}, UpsertOptions{})
+		assert.Error(t, err)
+		var n int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_products").Scan(&n)
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("Dialect statements", func(t *testing.T) {
+		md, err := builder.LoadModelData(modelID)
+		assert.NoError(t, err)
+		data := map[string]any{"code": "P1", "price": 1, "note": "x", "version": 1}
+		impl := svc.(*crudService)
+
+		sql, _, err := impl.buildUpsertSQL("mysql", md, []string{"code"}, data, opts.Policies)
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT INTO `test_products` (`code`, `price`, `note`, `version`) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE `price` = VALUES(`price`), `note` = COALESCE(VALUES(`note`), `note`), `version` = `version` + 1", sql)
+
+		sql, _, err = impl.buildUpsertSQL("postgres", md, []string{"code"}, data, opts.Policies)
+		assert.NoError(t, err)
+		assert.Equal(t, `INSERT INTO "test_products" ("code", "price", "note", "version") VALUES (?, ?, ?, ?) ON CONFLICT ("code") DO UPDATE SET "price" = excluded."price", "note" = COALESCE(excluded."note", "test_products"."note"), "version" = "test_products"."version" + 1`, sql)
+
+		sql, _, err = impl.buildUpsertSQL("sqlserver", md, []string{"code"}, data, opts.Policies)
+		assert.NoError(t, err)
+		assert.Equal(t, `MERGE INTO "test_products" tgt USING (SELECT ? AS "code", ? AS "price", ? AS "note", ? AS "version") src ON (tgt."code" = src."code") WHEN MATCHED THEN UPDATE SET tgt."price" = src."price", tgt."note" = COALESCE(src."note", tgt."note"), tgt."version" = tgt."version" + 1 WHEN NOT MATCHED THEN INSERT ("code", "price", "note", "version") VALUES (src."code", src."price", src."note", src."version");`, sql)
+	})
+}
+
+func TestCRUDService_BatchInsert(t *testing.T) {
+	modelID := "m_batch"
+	f := newTestCRUD(t, "CREATE TABLE test_skus (id INTEGER PRIMARY KEY, code TEXT UNIQUE, qty INTEGER DEFAULT 7)",
+		testFields("id", "code", "qty"), &model.MdModel{ID: modelID})
+	svc, targetDB := f.svc, f.targetDB
+
+	ctx := context.Background()
+	count := func() int64 {
+		var n int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_skus").Scan(&n)
+		return n
+	}
+	rows := []map[string]any{
+		{"id": 1, "code": "A", "qty": 1},
+		{"id": 2, "code": "B"},
+		{"id": 3, "code": "A", "qty": 3}, // 唯一键冲突
+		{"id": 4, "code": "D", "qty": 4},
+		{"id": 5, "code": "E", "qty": 5},
+	}
+
+	t.Run("All or nothing reports the failing row", func(t *testing.T) {
+		result, err := svc.BatchInsert(ctx, modelID, rows, BatchInsertOptions{ChunkSize: 2})
+		assert.ErrorIs(t, err, ErrBatchInsertFailed)
+		if assert.NotNil(t, result) && assert.Len(t, result.Errors, 1) {
+			assert.Equal(t, 2, result.Errors[0].Row)
+		}
+		assert.Zero(t, count())
+	})
+
+	t.Run("Best effort skips the failing row", func(t *testing.T) {
+		result, err := svc.BatchInsert(ctx, modelID, rows, BatchInsertOptions{Mode: BatchBestEffort, ChunkSize: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.Inserted)
+		assert.Equal(t, []string{"1", "2", "", "4", "5"}, result.IDs)
+		if assert.Len(t, result.Errors, 1) {
+			assert.Equal(t, 2, result.Errors[0].Row)
+		}
+		assert.Equal(t, int64(4), count())
+
+		var qty int64
+		targetDB.Raw("SELECT qty FROM test_skus WHERE id = 2").Scan(&qty)
+		assert.Equal(t, int64(7), qty, "missing columns keep database defaults")
+	})
+
+	t.Run("Batch create returns keys", func(t *testing.T) {
+		created, err := svc.BatchCreate(ctx, modelID, []map[string]any{{"id": 10, "code": "X"}, {"id": 11, "code": "Y"}})
+		assert.NoError(t, err)
+		if assert.Len(t, created, 2) {
+			assert.EqualValues(t, 11, created[1]["id"])
+		}
+		assert.Equal(t, int64(6), count())
+	})
+	t.Run("Dialect statements", func(t *testing.T) {
+		md, err := f.builder.LoadModelData(modelID)
+		require.NoError(t, err)
+		impl := svc.(*crudService)
+		rows := []pendingRow{{data: map[string]any{"code": "P", "qty": 1}}, {data: map[string]any{"code": "Q", "qty": 2}}}
+
+		sql, args := impl.buildBatchInsertSQL("mysql", md, []string{"code", "qty"}, rows)
+		assert.Equal(t, "INSERT INTO `test_skus` (`code`, `qty`) VALUES (?, ?), (?, ?)", sql)
+		assert.Equal(t, []any{"P", 1, "Q", 2}, args)
+
+		sql, _ = impl.buildBatchInsertSQL("postgres", md, []string{"code", "qty"}, rows)
+		assert.Equal(t, `INSERT INTO "test_skus" ("code", "qty") VALUES (?, ?), (?, ?)`, sql)
+
+		sql, _ = impl.buildBatchInsertSQL("sqlserver", md, []string{"code", "qty"}, rows[:1])
+		assert.Equal(t, `INSERT INTO "test_skus" ("code", "qty") VALUES (?, ?)`, sql)
+	})
+}
+
+// migrateModelConfig 创建 SQLBuilder.LoadModelData 所需的全部模型配置表
+func migrateModelConfig(db *gorm.DB) {
+	db.AutoMigrate(
+		&model.MdModelTable{}, &model.MdModelField{}, &model.MdModelJoin{}, &model.MdModelJoinField{},
+		&model.MdModelWhere{}, &model.MdModelGroup{}, &model.MdModelHaving{}, &model.MdModelOrder{},
+		&model.MdModelLimit{}, &model.MdModelSql{}, &model.MdModelFieldEnhancement{},
+	)
+}
+
+// crudFixture 基于内存数据库的 CRUD 服务测试环境
+type crudFixture struct {
+	svc      CRUDService
+	builder  *engine.SQLBuilder
+	metaDB   *gorm.DB
+	targetDB *gorm.DB
+}
+
+// newTestCRUD 创建 CRUD 服务：ddl 在目标库中建表，fields 为主表字段，m 为模型 (连接ID为空时按模型ID生成)
+func newTestCRUD(t *testing.T, ddl string, fields []model.MdModelField, m *model.MdModel) *crudFixture {
+	t.Helper()
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	targetDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, targetDB.Exec(ddl).Error)
+
+	if m.ConnID == "" {
+		m.ConnID = "c_" + m.ID
+	}
+	modelRepo := new(MockMdModelRepo)
+	modelRepo.On("GetModelByID", m.ID).Return(m, nil)
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, new(MockMdConnRepo))
+	executor.SetCustomConnection(m.ConnID, targetDB)
+
+	// 表名取自建表语句 CREATE TABLE <name> (...)
+	tableName := strings.Fields(ddl)[2]
+	migrateModelConfig(metaDB)
+	require.NoError(t, metaDB.Create(&model.MdModelTable{ID: "t_" + m.ID, ModelID: m.ID, TableNameStr: tableName, IsMain: true, ConnID: m.ConnID}).Error)
+	for i, field := range fields {
+		field.ModelID = m.ID
+		if field.ID == "" {
+			// 字段按ID排序加载，保持声明顺序
+			field.ID = fmt.Sprintf("f%02d", i+1)
+		}
+		require.NoError(t, metaDB.Create(&field).Error)
+	}
+
+	return &crudFixture{
+		svc:      NewCRUDService(builder, executor, NewDataValidator(), nil, nil),
+		builder:  builder,
+		metaDB:   metaDB,
+		targetDB: targetDB,
+	}
+}
+
+// testFields 按列名生成模型字段，第一列为主键
+func testFields(columns ...string) []model.MdModelField {
+	fields := make([]model.MdModelField, len(columns))
+	for i, column := range columns {
+		fields[i] = model.MdModelField{ColumnName: column, IsPrimaryKey: i == 0}
+	}
+	return fields
+}
+
+func TestCRUDService_AggregateStats(t *testing.T) {
+	f := newTestCRUD(t, "CREATE TABLE test_sales (id INTEGER PRIMARY KEY, status TEXT, region TEXT, amount INTEGER)",
+		testFields("id", "status", "region", "amount"), &model.MdModel{ID: "m_sale"})
+	f.targetDB.Exec("INSERT INTO test_sales VALUES (1, 'paid', 'east', 10), (2, 'paid', 'east', 30), (3, 'paid', 'west', 5), (4, 'new', 'east', 7)")
+	f.metaDB.Create(&model.MdModelLimit{ID: "l1", ModelID: "m_sale", Limit: 1})
+	svc := f.svc
+
+	rows, err := svc.Aggregate(context.Background(), "m_sale", map[string]any{
+		engine.ParamStats: map[string]any{
+			"group_by": []any{"status", "region"},
+			"aggregations": []any{
+				map[string]any{"func": "count", "alias": "cnt"},
+				map[string]any{"func": "sum", "field": "amount", "alias": "total"},
+			},
+			"having":   []any{map[string]any{"alias": "total", "operator": ">=", "value": 7}},
+			"order_by": []any{map[string]any{"field": "total", "desc": true}},
+		},
+	})
+	require.NoError(t, err)
+	// 模型配置的分页不影响统计
+	require.Len(t, rows, 2)
+	assert.Equal(t, "east", rows[0]["region"])
+	assert.EqualValues(t, 2, rows[0]["cnt"])
+	assert.EqualValues(t, 40, rows[0]["total"])
+	assert.Equal(t, "new", rows[1]["status"])
+}
+
+func TestCRUDService_AggregateTimeBucket(t *testing.T) {
+	f := newTestCRUD(t, "CREATE TABLE test_visits (id INTEGER PRIMARY KEY, created_at TEXT, amount INTEGER)",
+		testFields("id", "created_at", "amount"), &model.MdModel{ID: "m_visit"})
+	// UTC 时间，按上海时区 2024-01-01 (周一) 00:30 与 2024-01-07 (周日) 23:30 分别落在第一周
+	f.targetDB.Exec("INSERT INTO test_visits VALUES (1, '2023-12-31 16:30:00', 1), (2, '2024-01-07 15:30:00', 2), (3, '2024-01-22 02:00:00', 4), (4, '2024-04-02 00:00:00', 8)")
+	svc := f.svc
+	ctx := context.Background()
+
+	t.Run("Weekly buckets with gaps and cumulative sum", func(t *testing.T) {
+		rows, err := svc.Aggregate(ctx, "m_visit", map[string]any{
+			engine.ParamStats: map[string]any{
+				"time_bucket":  map[string]any{"field": "created_at", "unit": "week", "alias": "week", "time_zone": "Asia/Shanghai", "to": "2024-01-31", "fill_gaps": true},
+				"aggregations": []any{map[string]any{"func": "sum", "field": "amount", "alias": "total"}},
+				"windows":      []any{map[string]any{"func": "cumulative_sum", "field": "total", "alias": "running"}},
+			},
+		})
+		require.NoError(t, err)
+		var weeks, totals, running []any
+		for _, row := range rows {
+			weeks = append(weeks, row["week"])
+			totals = append(totals, row["total"])
+			running = append(running, row["running"])
+		}
+		assert.Equal(t, []any{"2024-01-01 00:00:00", "2024-01-08 00:00:00", "2024-01-15 00:00:00", "2024-01-22 00:00:00", "2024-01-29 00:00:00"}, weeks)
+		assert.Equal(t, []any{int64(3), int64(0), int64(0), int64(4), int64(0)}, totals)
+		assert.Equal(t, []any{3.0, 3.0, 3.0, 7.0, 7.0}, running)
+	})
+
+	t.Run("Quarter buckets", func(t *testing.T) {
+		rows, err := svc.Aggregate(ctx, "m_visit", map[string]any{
+			engine.ParamStats: map[string]any{
+				"time_bucket":  map[string]any{"field": "created_at", "unit": "quarter"},
+				"aggregations": []any{map[string]any{"func": "count", "alias": "cnt"}},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, "2023-10-01 00:00:00", rows[0]["created_at"])
+		assert.Equal(t, "2024-04-01 00:00:00", rows[2]["created_at"])
+	})
+}
+
+func TestCRUDService_Pivot(t *testing.T) {
+	f := newTestCRUD(t, "CREATE TABLE test_costs (id INTEGER PRIMARY KEY, dept TEXT, month TEXT, amount INTEGER)",
+		testFields("id", "dept", "month", "amount"), &model.MdModel{ID: "m_cost"})
+	f.targetDB.Exec("INSERT INTO test_costs VALUES (1, 'hr', '01', 10), (2, 'hr', '02', 20), (3, 'it', '01', 5), (4, 'it', '01', 7), (5, 'it', NULL, 1)")
+	f.metaDB.Create(&model.MdModelLimit{ID: "l1", ModelID: "m_cost", Limit: 1})
+	svc := f.svc
+	ioSvc := NewDataIOService(svc, nil, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	t.Run("Column values from data with totals", func(t *testing.T) {
+		result, err := svc.Pivot(ctx, "m_cost", map[string]any{
+			engine.ParamPivot: map[string]any{"rows": []any{"dept"}, "column": "month", "measure": "amount"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []any{nil, "01", "02"}, result.Columns)
+		require.Len(t, result.Data, 2)
+		assert.Equal(t, "hr", result.Data[0].Keys["dept"])
+		assert.Equal(t, []any{nil, int64(10), int64(20)}, result.Data[0].Cells)
+		assert.EqualValues(t, 30, result.Data[0].Total)
+		assert.Equal(t, []any{int64(1), int64(12), nil}, result.Data[1].Cells)
+		assert.Equal(t, []any{int64(1), int64(22), int64(20)}, result.Totals)
+		assert.EqualValues(t, 43, result.GrandTotal)
+	})
+
+	t.Run("Fixed column values with count", func(t *testing.T) {
+		result, err := svc.Pivot(ctx, "m_cost", map[string]any{
+			engine.ParamPivot: map[string]any{"rows": []any{"dept"}, "column": "month", "values": []any{"01", "03"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []any{int64(1), int64(0)}, result.Data[0].Cells)
+		assert.Equal(t, []any{int64(2), int64(0)}, result.Data[1].Cells)
+		assert.Equal(t, []any{int64(3), int64(0)}, result.Totals)
+		assert.EqualValues(t, 5, result.GrandTotal)
+	})
+
+	t.Run("Export as CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := ioSvc.ExportPivot(ctx, "m_cost", map[string]any{
+			engine.ParamPivot: map[string]any{"rows": []any{"dept"}, "column": "month", "values": []any{"01", "02"}, "measure": "amount"},
+		}, PivotExportCSV, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, "dept,01,02,合计\nhr,10,20,30\nit,12,,13\n合计,22,20,43\n", buf.String())
+
+		assert.Error(t, ioSvc.ExportPivot(ctx, "m_cost", map[string]any{}, PivotExportCSV, &buf))
+		assert.Error(t, ioSvc.ExportPivot(ctx, "m_cost", map[string]any{engine.ParamPivot: map[string]any{"column": "month"}}, "pdf", &buf))
+	})
+}
+
+func TestCRUDService_DataScopeWrite(t *testing.T) {
+	modelID := "m_scope"
+	f := newTestCRUD(t, "CREATE TABLE test_docs (id INTEGER PRIMARY KEY, title TEXT, owner_id TEXT, tenant_id TEXT, org_id TEXT)",
+		testFields("id", "title", "owner_id", "tenant_id", "org_id"),
+		&model.MdModel{ID: modelID, DataOwnerField: "owner_id", DataTenantField: "tenant_id", DataOrgField: "org_id"})
+	svc, targetDB := f.svc, f.targetDB
+	user := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", TenantID: "t1", OrgIDs: []string{"o1", "o2"}, Self: true})
+	row := func(id int) map[string]any {
+		var data map[string]any
+		targetDB.Raw("SELECT owner_id, tenant_id, org_id FROM test_docs WHERE id = ?", id).Scan(&data)
+		return data
+	}
+
+	t.Run("Create pins owner and tenant", func(t *testing.T) {
+		_, err := svc.Create(user, modelID, map[string]any{"id": 1, "title": "a", "owner_id": "u2", "tenant_id": "t2"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"owner_id": "u1", "tenant_id": "t1", "org_id": "o1"}, row(1))
+
+		_, err = svc.Create(user, modelID, map[string]any{"id": 2, "title": "b", "org_id": "o9"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Upsert pins owner and tenant", func(t *testing.T) {
+		_, err := svc.Upsert(user, modelID, map[string]any{"id": 3, "title": "c", "owner_id": "u2", "tenant_id": "t2", "org_id": "o2"}, UpsertOptions{KeyFields: []string{"id"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"owner_id": "u1", "tenant_id": "t1", "org_id": "o2"}, row(3))
+	})
+
+	t.Run("Update cannot move owner, tenant or foreign org", func(t *testing.T) {
+		require.NoError(t, svc.Update(user, modelID, "1", map[string]any{"owner_id": "u2", "tenant_id": "t2", "org_id": "o2"}))
+		assert.Equal(t, map[string]any{"owner_id": "u1", "tenant_id": "t1", "org_id": "o2"}, row(1))
+
+		assert.Error(t, svc.Update(user, modelID, "1", map[string]any{"org_id": "o9"}))
+		assert.Error(t, svc.BatchUpdate(user, modelID, []map[string]any{{"id": 1, "org_id": "o9"}}))
+		_, err := svc.UpdateWhere(user, modelID, map[string]any{}, map[string]any{"org_id": "o9"}, BulkOptions{})
+		assert.Error(t, err)
+		assert.Equal(t, "o2", row(1)["org_id"])
+	})
+
+	t.Run("Admin may set owner", func(t *testing.T) {
+		admin := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "root", TenantID: "t1", All: true, Admin: true})
+		_, err := svc.Create(admin, modelID, map[string]any{"id": 4, "title": "d", "owner_id": "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"owner_id": "u2", "tenant_id": "t1", "org_id": nil}, row(4))
+	})
+
+	t.Run("Unauthenticated writes are denied", func(t *testing.T) {
+		anonymous := engine.WithDataScope(context.Background(), &engine.DataScope{Deny: true})
+		_, err := svc.Create(anonymous, modelID, map[string]any{"id": 5, "title": "e"})
+		assert.Error(t, err)
+		assert.Error(t, svc.Update(anonymous, modelID, "1", map[string]any{"title": "x"}))
+	})
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+)
+
+// 唯一键冲突时的字段更新策略
+const (
+	UpsertOverwrite = "overwrite" // 以新值覆盖 (默认)
+	UpsertKeep      = "keep"      // 保留原值
+	UpsertCoalesce  = "coalesce"  // 新值为 NULL 时保留原值
+)
+
+// 写入结果
+const (
+	UpsertInserted = "inserted"
+	UpsertUpdated  = "updated"
+)
+
+// UpsertOptions 新增或更新选项
+type UpsertOptions struct {
+	KeyFields []string          `json:"key_fields"` // 冲突判定字段，默认为模型唯一键
+	Policies  map[string]string `json:"policies"`   // 字段更新策略，未配置的字段为 overwrite
+}
+
+// UpsertResult 单条新增或更新结果
+type UpsertResult struct {
+	ID     string         `json:"id"`
+	Action string         `json:"action"`
+	Data   map[string]any `json:"data"`
+}
+
+// Upsert 按唯一键新增或更新数据，由数据库原生语句保证原子性
+func (s *crudService) Upsert(ctx context.Context, modelID string, data map[string]any, opts UpsertOptions) (*UpsertResult, error) {
+	results, err := s.BatchUpsert(ctx, modelID, []map[string]any{data}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// BatchUpsert 批量新增或更新，全部在同一事务中执行，按顺序返回每条数据的写入结果
+func (s *crudService) BatchUpsert(ctx context.Context, modelID string, dataList []map[string]any, opts UpsertOptions) ([]*UpsertResult, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	keys := opts.KeyFields
+	if len(keys) == 0 {
+		keys = md.UniqueKey()
+	}
+	if err := s.validateUpsert(md, keys, opts.Policies); err != nil {
+		return nil, err
+	}
+
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*UpsertResult, 0, len(dataList))
+	ctx, commit := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for i, data := range dataList {
+			result, err := s.upsertRow(ctx, md, keys, data, opts.Policies, tx)
+			if err != nil {
+				if len(dataList) == 1 {
+					return err
+				}
+				return fmt.Errorf("第 %d 条数据写入失败: %w", i+1, err)
+			}
+			results = append(results, result)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	commit()
+	return results, nil
+}
+
+// validateUpsert 校验唯一键与字段更新策略
+func (s *crudService) validateUpsert(md *engine.ModelData, keys []string, policies map[string]string) error {
+	if len(keys) == 0 {
+		return errors.New("模型未配置主键或唯一键")
+	}
+	fields := make(map[string]bool, len(md.Fields))
+	for _, f := range md.Fields {
+		fields[f.ColumnName] = true
+	}
+	for _, k := range keys {
+		if !fields[k] {
+			return fmt.Errorf("唯一键字段 %s 不存在", k)
+		}
+		for _, f := range md.EncryptedFields() {
+			if f.ColumnName == k {
+				return fmt.Errorf("加密字段 %s 不能作为唯一键", k)
+			}
+		}
+	}
+	for field, policy := range policies {
+		switch policy {
+		case UpsertOverwrite, UpsertKeep, UpsertCoalesce:
+		default:
+			return fmt.Errorf("字段 %s 的更新策略 %s 无效", field, policy)
+		}
+	}
+	return nil
+}
+
+// upsertRow 写入单条数据，写入前后按唯一键读取记录以判断新增或更新并记录数据变更
+func (s *crudService) upsertRow(ctx context.Context, md *engine.ModelData, keys []string, data map[string]any, policies map[string]string, tx *gorm.DB) (*UpsertResult, error) {
+	// 1. 验证数据
+	data = s.stripMaskedColumns(ctx, md, data)
+	for _, k := range keys {
+		if val, ok := data[k]; !ok || val == nil {
+			return nil, fmt.Errorf("缺少唯一键字段 %s", k)
+		}
+	}
+	if err := s.initDataScope(ctx, md, data); err != nil {
+		return nil, err
+	}
+	if err := s.validator.Validate(md.Model.ID, md.Fields, data); err != nil {
+		return nil, fmt.Errorf("数据验证失败: %w", err)
+	}
+
+	// 2. 已存在的记录须在当前用户数据权限内
+	before, err := s.readRowByKey(tx, md, keys, data)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil && !md.DataScopeAllows(engine.DataScopeFromContext(ctx), before) {
+		return nil, errors.New("无权修改已存在的记录")
+	}
+
+	// 3. 版本字段由系统维护，已逻辑删除的记录重新写入时恢复
+	if md.HasVersionField() {
+		delete(data, md.Model.VersionField)
+		s.initVersion(md, data)
+	}
+	if md.HasLogicDelete() {
+		data[md.Model.LogicDeleteField] = md.LogicActiveValue()
+	}
+	if err := md.EncryptRow(data); err != nil {
+		return nil, err
+	}
+
+	// 4. 执行数据库原生的新增或更新语句
+	sql, args, err := s.buildUpsertSQL(tx.Dialector.Name(), md, keys, data, policies)
+	if err != nil {
+		return nil, fmt.Errorf("构建写入SQL失败: %w", err)
+	}
+	if _, err := s.sqlExecutor.ExecWithTx(tx, sql, args...); err != nil {
+		return nil, fmt.Errorf("执行写入失败: %w", err)
+	}
+
+	// 5. 读取写入后数据并记录变更
+	after, err := s.readRowByKey(tx, md, keys, data)
+	if err != nil {
+		return nil, err
+	}
+	if after == nil {
+		return nil, errors.New("无法读取写入后的记录")
+	}
+	result := &UpsertResult{
+		ID:     fmt.Sprintf("%v", normalizeValue(after[s.getPrimaryKey(md)])),
+		Action: UpsertInserted,
+	}
+	if before != nil {
+		result.Action = UpsertUpdated
+		s.recordChange(ctx, md, ChangeUpdate, result.ID, before, after)
+	} else {
+		s.recordChange(ctx, md, ChangeCreate, result.ID, nil, after)
+	}
+
+	row := make(map[string]any, len(after))
+	for k, v := range after {
+		row[k] = normalizeValue(v)
+	}
+	rows := []map[string]any{row}
+	if err := md.DecryptRows(rows); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(rows, md.Masks, engine.DataScopeFromContext(ctx))
+	result.Data = row
+	return result, nil
+}
+
+// readRowByKey 按唯一键读取原始记录 (不解密、不脱敏、不过滤逻辑删除)
+func (s *crudService) readRowByKey(db *gorm.DB, md *engine.ModelData, keys []string, data map[string]any) (map[string]any, error) {
+	conds := make([]string, len(keys))
+	args := make([]any, len(keys))
+	for i, k := range keys {
+		conds[i] = fmt.Sprintf("`%s` = ?", k)
+		args[i] = data[k]
+	}
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE %s", s.getMainTableName(md), strings.Join(conds, " AND "))
+	rows, err := s.sqlExecutor.ExecuteWithTx(db, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("按唯一键读取记录失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0], nil
+}
+
+// existingKeysChunk 按唯一键判断是否存在时每次查询的数据条数
+const existingKeysChunk = 500
+
+// ExistingKeys 按唯一键判断每条数据对应的记录是否已存在 (不过滤逻辑删除)，缺少唯一键字段的数据视为不存在
+func (s *crudService) ExistingKeys(ctx context.Context, modelID string, keyFields []string, dataList []map[string]any) ([]bool, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	keys := keyFields
+	if len(keys) == 0 {
+		keys = md.UniqueKey()
+	}
+	if err := s.validateUpsert(md, keys, nil); err != nil {
+		return nil, err
+	}
+	exists := make([]bool, len(dataList))
+	if len(dataList) == 0 {
+		return exists, nil
+	}
+	db, err := s.sqlExecutor.GetConnection(s.getConnID(md))
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(keys))
+	for i, k := range keys {
+		columns[i] = fmt.Sprintf("`%s`", k)
+	}
+	keyOf := func(row map[string]any) (string, bool) {
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			val, ok := row[k]
+			if !ok || val == nil {
+				return "", false
+			}
+			parts[i] = expandKey(val)
+		}
+		return strings.Join(parts, "\x00"), true
+	}
+
+	for start := 0; start < len(dataList); start += existingKeysChunk {
+		end := min(start+existingKeysChunk, len(dataList))
+		index := make(map[string][]int)
+		var conds []string
+		var args []any
+		for i := start; i < end; i++ {
+			key, ok := keyOf(dataList[i])
+			if !ok {
+				continue
+			}
+			if _, seen := index[key]; !seen {
+				parts := make([]string, len(keys))
+				for j, k := range keys {
+					parts[j] = columns[j] + " = ?"
+					args = append(args, dataList[i][k])
+				}
+				conds = append(conds, "("+strings.Join(parts, " AND ")+")")
+			}
+			index[key] = append(index[key], i)
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), s.getMainTableName(md), strings.Join(conds, " OR "))
+		rows, err := s.sqlExecutor.ExecuteWithTx(db, sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("按唯一键查询记录失败: %w", err)
+		}
+		for _, row := range rows {
+			key, _ := keyOf(row)
+			for _, i := range index[key] {
+				exists[i] = true
+			}
+		}
+	}
+	return exists, nil
+}
+
+// upsertColumn 新增或更新语句中的一列
+type upsertColumn struct {
+	name   string
+	value  any
+	policy string // 冲突时的更新策略，唯一键与主键为 keep
+}
+
+// dialectQuote 返回数据库方言的标识符引用函数，MySQL 使用反引号，其余使用双引号
+func dialectQuote(dialect string) func(name string) string {
+	if dialect == "mysql" {
+		return func(name string) string { return "`" + name + "`" }
+	}
+	return func(name string) string { return `"` + name + `"` }
+}
+
+// buildUpsertSQL 按数据库方言构建新增或更新语句：
+// MySQL 使用 ON DUPLICATE KEY UPDATE，PostgreSQL/SQLite 使用 ON CONFLICT DO UPDATE，其余使用 MERGE
+func (s *crudService) buildUpsertSQL(dialect string, md *engine.ModelData, keys []string, data map[string]any, policies map[string]string) (string, []any, error) {
+	columns := s.upsertColumns(md, keys, data, policies)
+	if len(columns) == 0 {
+		return "", nil, errors.New("no columns to insert")
+	}
+
+	quote := dialectQuote(dialect)
+	table := s.quotedMainTable(quote, md)
+
+	names := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	var args []any
+	for i, c := range columns {
+		names[i] = quote(c.name)
+		placeholders[i] = "?"
+		args = append(args, c.value)
+	}
+
+	// setClauses 以 newValue 引用新值、oldValue 引用原值生成更新子句
+	var versionArgs []any
+	setClauses := func(target func(string) string, newValue func(string) string, oldValue func(string) string) []string {
+		var sets []string
+		versionArgs = nil
+		for _, c := range columns {
+			col := target(c.name)
+			switch {
+			case md.HasVersionField() && c.name == md.Model.VersionField:
+				if md.IsTimestampVersion() {
+					sets = append(sets, col+" = ?")
+					versionArgs = append(versionArgs, time.Now())
+				} else {
+					sets = append(sets, fmt.Sprintf("%s = %s + 1", col, oldValue(c.name)))
+				}
+			case c.policy == UpsertOverwrite:
+				sets = append(sets, fmt.Sprintf("%s = %s", col, newValue(c.name)))
+			case c.policy == UpsertCoalesce:
+				sets = append(sets, fmt.Sprintf("%s = COALESCE(%s, %s)", col, newValue(c.name), oldValue(c.name)))
+			}
+		}
+		return sets
+	}
+
+	switch dialect {
+	case "mysql":
+		sets := setClauses(quote, func(n string) string { return "VALUES(" + quote(n) + ")" }, quote)
+		if len(sets) == 0 {
+			sets = []string{fmt.Sprintf("%s = %s", quote(keys[0]), quote(keys[0]))}
+		}
+		sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+			table, strings.Join(names, ", "), strings.Join(placeholders, ", "), strings.Join(sets, ", "))
+		return sql, append(args, versionArgs...), nil
+
+	case "postgres", "sqlite":
+		quotedKeys := make([]string, len(keys))
+		for i, k := range keys {
+			quotedKeys[i] = quote(k)
+		}
+		tableRef := quote(s.mainTableNameStr(md))
+		sets := setClauses(quote,
+			func(n string) string { return "excluded." + quote(n) },
+			func(n string) string { return tableRef + "." + quote(n) })
+		action := "DO NOTHING"
+		if len(sets) > 0 {
+			action = "DO UPDATE SET " + strings.Join(sets, ", ")
+		}
+		sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+			table, strings.Join(names, ", "), strings.Join(placeholders, ", "), strings.Join(quotedKeys, ", "), action)
+		return sql, append(args, versionArgs...), nil
+
+	default:
+		source := make([]string, len(columns))
+		sourceRefs := make([]string, len(columns))
+		for i, c := range columns {
+			source[i] = "? AS " + quote(c.name)
+			sourceRefs[i] = "src." + quote(c.name)
+		}
+		from := ""
+		if dialect == "oracle" || dialect == "dm" {
+			from = " FROM DUAL"
+		}
+		on := make([]string, len(keys))
+		for i, k := range keys {
+			on[i] = fmt.Sprintf("tgt.%s = src.%s", quote(k), quote(k))
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "MERGE INTO %s tgt USING (SELECT %s%s) src ON (%s)",
+			table, strings.Join(source, ", "), from, strings.Join(on, " AND "))
+		sets := setClauses(
+			func(n string) string { return "tgt." + quote(n) },
+			func(n string) string { return "src." + quote(n) },
+			func(n string) string { return "tgt." + quote(n) })
+		if len(sets) > 0 {
+			sb.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", "))
+		}
+		fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(names, ", "), strings.Join(sourceRefs, ", "))
+		if dialect == "sqlserver" {
+			sb.WriteString(";")
+		}
+		return sb.String(), append(args, versionArgs...), nil
+	}
+}
+
+// upsertColumns 按模型字段顺序收集写入列及其冲突更新策略
+// 唯一键、主键和数据权限的所有人/租户字段在冲突时保留原值，盲索引列跟随所属加密字段的策略
+func (s *crudService) upsertColumns(md *engine.ModelData, keys []string, data map[string]any, policies map[string]string) []upsertColumn {
+	keep := map[string]bool{s.getPrimaryKey(md): true}
+	for _, k := range keys {
+		keep[k] = true
+	}
+	if md.Model.DataOwnerField != "" {
+		keep[md.Model.DataOwnerField] = true
+	}
+	if md.Model.DataTenantField != "" {
+		keep[md.Model.DataTenantField] = true
+	}
+	policyOf := func(column string) string {
+		if keep[column] {
+			return UpsertKeep
+		}
+		if p := policies[column]; p != "" {
+			return p
+		}
+		return UpsertOverwrite
+	}
+
+	var columns []upsertColumn
+	for _, f := range md.Fields {
+		if val, ok := data[f.ColumnName]; ok {
+			columns = append(columns, upsertColumn{name: f.ColumnName, value: val, policy: policyOf(f.ColumnName)})
+		}
+	}
+	blindSource := make(map[string]string)
+	for _, f := range md.EncryptedFields() {
+		if f.BlindIndexField != "" {
+			blindSource[f.BlindIndexField] = f.ColumnName
+		}
+	}
+	for _, column := range md.BlindIndexColumns() {
+		if val, ok := data[column]; ok {
+			columns = append(columns, upsertColumn{name: column, value: val, policy: policyOf(blindSource[column])})
+		}
+	}
+	return columns
+}
+
+// mainTableNameStr 返回主表名 (不含模式、不加引号)
+func (s *crudService) mainTableNameStr(md *engine.ModelData) string {
+	if t := s.mainTable(md); t != nil {
+		return t.TableNameStr
+	}
+	return ""
+}
+
+// quotedMainTable 返回以 quote 引用的主表名，包含模式
+func (s *crudService) quotedMainTable(quote func(string) string, md *engine.ModelData) string {
+	table := quote(s.mainTableNameStr(md))
+	if schema := s.mainTableSchema(md); schema != "" {
+		table = quote(schema) + "." + table
+	}
+	return table
+}
+
+// mainTableSchema 返回主表模式
+func (s *crudService) mainTableSchema(md *engine.ModelData) string {
+	if t := s.mainTable(md); t != nil {
+		return t.TableSchema
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// WhereQuery 按内部构造的条件查询主表记录，条件中的列名须由调用方校验
+type WhereQuery struct {
+	Where   string
+	Args    []any
+	OrderBy []string // 排序列，可带 asc/desc，为空时按主键排序
+	Limit   int      // 大于 0 时限制返回条数
+	Offset  int
+	Count   bool // 是否统计满足条件的总数，否则总数为返回条数
+}
+
+// QueryWhereWithTx 在事务中按条件分页查询主表记录，过滤逻辑删除和数据权限
+func (s *crudService) QueryWhereWithTx(ctx context.Context, modelID string, query WhereQuery, tx *gorm.DB) ([]map[string]any, int64, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("加载模型失败: %w", err)
+	}
+
+	return s.queryWhere(ctx, md, query, tx)
+}
+
+// CountByWhereWithTx 在事务中按列分组统计满足条件的记录数，以列值的字符串形式为键
+func (s *crudService) CountByWhereWithTx(ctx context.Context, modelID, column, where string, args []any, tx *gorm.DB) (map[string]int64, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	if !hasColumn(md, column) {
+		return nil, fmt.Errorf("模型 %s 不存在字段 %s", modelID, column)
+	}
+
+	from, fromArgs := s.whereClause(ctx, md, where, args)
+	sql := fmt.Sprintf("SELECT `%s` AS `key`, COUNT(*) AS `count` %s GROUP BY `%s`", column, from, column)
+	rows, err := s.sqlExecutor.ExecuteWithTx(tx, sql, fromArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("执行统计查询失败: %w", err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[expandKey(row["key"])] = s.toInt64(row["count"])
+	}
+	return counts, nil
+}
+
+// queryWhere 按条件查询主表记录，解密并脱敏
+func (s *crudService) queryWhere(ctx context.Context, md *engine.ModelData, query WhereQuery, tx *gorm.DB) ([]map[string]any, int64, error) {
+	orderBy, err := whereOrderBy(md, query.OrderBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 1. 构建查询SQL
+	from, args := s.whereClause(ctx, md, query.Where, query.Args)
+	sql := "SELECT * " + from + " ORDER BY " + orderBy
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d OFFSET %d", query.Limit, max(query.Offset, 0))
+	}
+
+	// 2. 执行SQL
+	result, err := s.sqlExecutor.ExecuteWithTx(tx, sql, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("执行查询失败: %w", err)
+	}
+	total := int64(len(result))
+	if query.Count {
+		if total, err = s.sqlExecutor.ExecuteCountWithTx(tx, "SELECT 1 "+from, args...); err != nil {
+			return nil, 0, fmt.Errorf("执行计数查询失败: %w", err)
+		}
+	}
+	if err := md.DecryptRows(result); err != nil {
+		return nil, 0, err
+	}
+	engine.ApplyMasks(result, md.Masks, engine.DataScopeFromContext(ctx))
+	return result, total, nil
+}
+
+// whereClause 构建 FROM 和 WHERE 子句，追加逻辑删除和数据权限条件
+func (s *crudService) whereClause(ctx context.Context, md *engine.ModelData, where string, args []any) (string, []any) {
+	sql := fmt.Sprintf("FROM %s WHERE (%s)", s.getMainTableName(md), where)
+	args = append([]any{}, args...)
+	if md.HasLogicDelete() {
+		sql += fmt.Sprintf(" AND `%s` = ?", md.Model.LogicDeleteField)
+		args = append(args, md.LogicActiveValue())
+	}
+	return s.appendDataScope(ctx, md, sql, args)
+}
+
+// whereOrderBy 校验排序列并生成 ORDER BY 表达式，主键始终作为最后的排序列以保证分页稳定
+func whereOrderBy(md *engine.ModelData, orderBy []string) (string, error) {
+	primaryKey := md.PrimaryKey()
+	orders := make([]string, 0, len(orderBy)+1)
+	for _, item := range orderBy {
+		column, direction, _ := strings.Cut(strings.TrimSpace(item), " ")
+		direction = strings.ToUpper(strings.TrimSpace(direction))
+		if direction == "" {
+			direction = "ASC"
+		}
+		if !hasColumn(md, column) || (direction != "ASC" && direction != "DESC") {
+			return "", fmt.Errorf("排序字段无效: %s", item)
+		}
+		orders = append(orders, fmt.Sprintf("`%s` %s", column, direction))
+		if column == primaryKey {
+			return strings.Join(orders, ", "), nil
+		}
+	}
+	return strings.Join(append(orders, fmt.Sprintf("`%s`", primaryKey)), ", "), nil
+}
+
+// hasColumn 模型是否包含该列
+func hasColumn(md *engine.ModelData, column string) bool {
+	return slices.ContainsFunc(md.Fields, func(f *model.MdModelField) bool { return f.ColumnName == column })
+}
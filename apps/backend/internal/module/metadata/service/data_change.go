@@ -0,0 +1,197 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	auditModel "metadata-platform/internal/module/audit/model"
+	auditService "metadata-platform/internal/module/audit/service"
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/utils"
+)
+
+// 数据变更操作类型
+const (
+	ChangeCreate  = "CREATE"
+	ChangeUpdate  = "UPDATE"
+	ChangeDelete  = "DELETE"
+	ChangeRestore = "RESTORE"
+	ChangePurge   = "PURGE"
+	ChangeRevert  = "REVERT"
+)
+
+// changeSource 数据变更日志来源模块
+const changeSource = "metadata_crud"
+
+// ChangeOperator 数据变更操作人，TraceID 用于关联操作日志
+type ChangeOperator struct {
+	TraceID     string
+	UserID      string
+	UserAccount string
+}
+
+type changeOperatorKey struct{}
+
+// WithChangeOperator 返回携带操作人信息的 context
+func WithChangeOperator(ctx context.Context, op *ChangeOperator) context.Context {
+	return context.WithValue(ctx, changeOperatorKey{}, op)
+}
+
+// ChangeOperatorFromContext 获取 context 中的操作人信息，未设置时返回 nil
+func ChangeOperatorFromContext(ctx context.Context) *ChangeOperator {
+	if ctx == nil {
+		return nil
+	}
+	op, _ := ctx.Value(changeOperatorKey{}).(*ChangeOperator)
+	return op
+}
+
+// FieldChange 字段级差异
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// DiffRecords 比较变更前后的记录，返回按字段名排序的差异
+func DiffRecords(before, after map[string]any) []FieldChange {
+	keys := make(map[string]struct{}, len(before)+len(after))
+	for k := range before {
+		keys[k] = struct{}{}
+	}
+	for k := range after {
+		keys[k] = struct{}{}
+	}
+	fields := make([]string, 0, len(keys))
+	for k := range keys {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	var changes []FieldChange
+	for _, f := range fields {
+		b, a := normalizeValue(before[f]), normalizeValue(after[f])
+		if reflect.DeepEqual(b, a) || (b != nil && a != nil && fmt.Sprintf("%v", b) == fmt.Sprintf("%v", a)) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: f, Before: b, After: a})
+	}
+	return changes
+}
+
+func normalizeValue(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// decodeImage 解析变更日志中的记录镜像，数值保留为 json.Number 以免精度丢失
+func decodeImage(data string) (map[string]any, error) {
+	if data == "" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.UseNumber()
+	var image map[string]any
+	if err := dec.Decode(&image); err != nil {
+		return nil, fmt.Errorf("解析记录镜像失败: %w", err)
+	}
+	return image, nil
+}
+
+// normalizeImage 将数据库读出的记录转换为与变更日志镜像一致的表示，便于比较
+func normalizeImage(row map[string]any) map[string]any {
+	if row == nil {
+		return nil
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return row
+	}
+	image, err := decodeImage(string(data))
+	if err != nil {
+		return row
+	}
+	return image
+}
+
+type changeBufferKey struct{}
+
+// changeBuffer 暂存事务内产生的数据变更日志，事务提交后再写入审计
+type changeBuffer struct {
+	mu    sync.Mutex
+	audit auditService.AuditService
+	logs  []*auditModel.SysDataChangeLog
+}
+
+// beginChanges 开始收集数据变更日志，返回的 commit 须在事务提交成功后调用
+// 外层已开始收集时复用外层缓冲，commit 为空操作
+func beginChanges(ctx context.Context) (context.Context, func()) {
+	if _, ok := ctx.Value(changeBufferKey{}).(*changeBuffer); ok {
+		return ctx, func() {}
+	}
+	buf := &changeBuffer{}
+	commit := func() {
+		buf.mu.Lock()
+		defer buf.mu.Unlock()
+		for _, log := range buf.logs {
+			buf.audit.RecordDataChange(ctx, log)
+		}
+		buf.logs = nil
+	}
+	return context.WithValue(ctx, changeBufferKey{}, buf), commit
+}
+
+// recordChange 记录一条数据变更；处于 beginChanges 范围内时延迟到提交后记录
+func (s *crudService) recordChange(ctx context.Context, md *engine.ModelData, action, recordID string, before, after map[string]any) {
+	if s.auditSvc == nil {
+		return
+	}
+	diff := DiffRecords(before, after)
+	if action == ChangeUpdate && len(diff) == 0 {
+		return
+	}
+
+	log := &auditModel.SysDataChangeLog{
+		ID:       utils.GetSnowflake().GenerateIDString(),
+		ModelID:  md.Model.ID,
+		RecordID: recordID,
+		Action:   action,
+		Source:   changeSource,
+		Remark:   md.Model.ModelName,
+	}
+	if before != nil {
+		data, _ := json.Marshal(before)
+		log.BeforeData = string(data)
+	}
+	if after != nil {
+		data, _ := json.Marshal(after)
+		log.AfterData = string(data)
+	}
+	if len(diff) > 0 {
+		data, _ := json.Marshal(diff)
+		log.DiffData = string(data)
+	}
+	if op := ChangeOperatorFromContext(ctx); op != nil {
+		log.TraceID = op.TraceID
+		log.CreateBy = op.UserAccount
+		if log.CreateBy == "" {
+			log.CreateBy = op.UserID
+		}
+	}
+
+	if buf, ok := ctx.Value(changeBufferKey{}).(*changeBuffer); ok {
+		buf.mu.Lock()
+		buf.audit = s.auditSvc
+		buf.logs = append(buf.logs, log)
+		buf.mu.Unlock()
+		return
+	}
+	s.auditSvc.RecordDataChange(ctx, log)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	auditModel "metadata-platform/internal/module/audit/model"
+	auditService "metadata-platform/internal/module/audit/service"
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+// recordingAudit 记录数据变更日志的审计服务桩
+type recordingAudit struct {
+	auditService.AuditService
+	changes []*auditModel.SysDataChangeLog
+}
+
+func (a *recordingAudit) RecordDataChange(_ context.Context, log *auditModel.SysDataChangeLog) {
+	// 模拟入库时间，保证时间线有序
+	log.CreateAt = time.Date(2024, 1, 1, 0, 0, len(a.changes), 0, time.UTC)
+	a.changes = append(a.changes, log)
+}
+
+func (a *recordingAudit) GetDataChangeLogByID(id string) (*auditModel.SysDataChangeLog, error) {
+	for _, c := range a.changes {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (a *recordingAudit) GetRecordChanges(modelID, recordID string) ([]auditModel.SysDataChangeLog, error) {
+	var logs []auditModel.SysDataChangeLog
+	for _, c := range a.changes {
+		if c.ModelID == modelID && c.RecordID == recordID {
+			logs = append(logs, *c)
+		}
+	}
+	return logs, nil
+}
+
+func TestDiffRecords(t *testing.T) {
+	diff := DiffRecords(
+		map[string]any{"id": int64(1), "name": []byte("a"), "amount": int64(10)},
+		map[string]any{"id": int64(1), "name": "b", "amount": 10, "remark": "x"},
+	)
+	assert.Equal(t, []FieldChange{
+		{Field: "name", Before: "a", After: "b"},
+		{Field: "remark", Before: nil, After: "x"},
+	}, diff)
+}
+
+func TestCRUDService_DataChangeLog(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_items (id INTEGER PRIMARY KEY, name TEXT, deleted INTEGER DEFAULT 0)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	modelID := "m_change"
+	connID := "c_change"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	audit := &recordingAudit{}
+	svc := NewCRUDService(builder, executor, NewDataValidator(), nil, audit)
+
+	migrateModelConfig(metaDB)
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: modelID, TableNameStr: "test_items", IsMain: true, ConnID: connID})
+	metaDB.Create(&model.MdModelField{ID: "f1", ModelID: modelID, ColumnName: "id", IsPrimaryKey: true})
+	metaDB.Create(&model.MdModelField{ID: "f2", ModelID: modelID, ColumnName: "name"})
+	modelRepo.On("GetModelByID", modelID).Return(&model.MdModel{ID: modelID, ConnID: connID, LogicDeleteField: "deleted"}, nil)
+
+	ctx := WithChangeOperator(context.Background(), &ChangeOperator{TraceID: "trace-1", UserID: "u1", UserAccount: "alice"})
+
+	_, err := svc.Create(ctx, modelID, map[string]any{"id": 1, "name": "a"})
+	require.NoError(t, err)
+	require.NoError(t, svc.Update(ctx, modelID, "1", map[string]any{"name": "b"}))
+	require.NoError(t, svc.Update(ctx, modelID, "1", map[string]any{"name": "b"}))
+	require.NoError(t, svc.Delete(ctx, modelID, "1"))
+
+	require.Len(t, audit.changes, 3, "unchanged update is not logged")
+	for _, c := range audit.changes {
+		assert.Equal(t, "trace-1", c.TraceID)
+		assert.Equal(t, "alice", c.CreateBy)
+		assert.Equal(t, modelID, c.ModelID)
+		assert.Equal(t, "1", c.RecordID)
+	}
+
+	create, update, del := audit.changes[0], audit.changes[1], audit.changes[2]
+	assert.Equal(t, ChangeCreate, create.Action)
+	assert.Empty(t, create.BeforeData)
+	assert.Contains(t, create.AfterData, `"name":"a"`)
+
+	assert.Equal(t, ChangeUpdate, update.Action)
+	assert.Contains(t, update.BeforeData, `"name":"a"`)
+	assert.Contains(t, update.AfterData, `"name":"b"`)
+	var diff []FieldChange
+	require.NoError(t, json.Unmarshal([]byte(update.DiffData), &diff))
+	assert.Equal(t, []FieldChange{{Field: "name", Before: "a", After: "b"}}, diff)
+
+	assert.Equal(t, ChangeDelete, del.Action)
+	assert.Contains(t, del.DiffData, `"field":"deleted"`)
+
+	t.Run("Buffered changes are recorded after commit", func(t *testing.T) {
+		audit.changes = nil
+		bufCtx, commit := beginChanges(ctx)
+		require.NoError(t, svc.Restore(bufCtx, modelID, "1"))
+		assert.Empty(t, audit.changes)
+		commit()
+		require.Len(t, audit.changes, 1)
+		assert.Equal(t, ChangeRestore, audit.changes[0].Action)
+	})
+}
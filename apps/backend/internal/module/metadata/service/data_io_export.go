@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+// 导出文件格式
+const (
+	ExportFormatJSON    = "json"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatCSV     = "csv"
+	ExportFormatExcel   = "xlsx"
+	ExportFormatParquet = "parquet"
+)
+
+// 导出任务状态
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportSucceeded = "succeeded"
+	ExportFailed    = "failed"
+	ExportCanceled  = "canceled"
+)
+
+const (
+	ExportFileRetention = 24 * time.Hour   // 导出文件的保留时长，过期后删除任务及文件
+	ExportLinkTTL       = 10 * time.Minute // 下载链接的有效期
+)
+
+// ExportDownloadPath 导出文件的下载路径，参数为任务ID
+const ExportDownloadPath = "/api/data/exports/%s/download"
+
+// ExportJob 导出任务，导出成功后附带签名的下载链接
+type ExportJob struct {
+	model.MdExportJob
+	DownloadURL string     `json:"download_url,omitempty"`
+	URLExpireAt *time.Time `json:"url_expire_at,omitempty"`
+}
+
+// ExportOptions 导出任务选项
+type ExportOptions struct {
+	Format       string // 文件格式，默认 xlsx
+	Encoding     string // CSV 文件编码
+	Compression  string // Parquet 压缩算法，默认 snappy
+	RowGroupSize int    // Parquet 每个行组的行数
+}
+
+// StartExport 创建后台导出任务，查询参数与列表查询一致，文件写入文件存储
+func (s *dataIOService) StartExport(ctx context.Context, modelID string, params map[string]any, opts ExportOptions) (*ExportJob, error) {
+	if !utils.URLSignerReady() {
+		return nil, errors.New("未配置下载链接签名密钥 (EXPORT_SIGN_KEY)，无法创建导出任务")
+	}
+	scope := engine.DataScopeFromContext(ctx)
+	if !jobAccessible(scope, "") {
+		return nil, errors.New("未登录，无法创建导出任务")
+	}
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = ExportFormatExcel
+	}
+	switch format {
+	case ExportFormatJSON, ExportFormatNDJSON, ExportFormatExcel:
+	case ExportFormatCSV:
+		if _, err := csvWriter(io.Discard, opts.Encoding); err != nil {
+			return nil, err
+		}
+	case ExportFormatParquet:
+		if _, err := utils.NewParquetWriter(io.Discard, []utils.ParquetColumn{{Name: "_"}}, utils.ParquetOptions{Compression: opts.Compression, RowGroupSize: opts.RowGroupSize}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的导出文件格式: %s", format)
+	}
+	if _, err := s.modelRepo.GetModelByID(modelID); err != nil {
+		return nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	params = maps.Clone(params)
+	delete(params, "page")
+	delete(params, "page_size")
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("查询参数格式错误: %w", err)
+	}
+	s.purgeExports()
+
+	// 1. 记录任务，未结束的任务同样在保留时长后清理
+	now := time.Now()
+	job := &model.MdExportJob{
+		ID:           utils.GetSnowflake().GenerateIDString(),
+		ModelID:      modelID,
+		Format:       format,
+		Encoding:     opts.Encoding,
+		Compression:  strings.ToLower(opts.Compression),
+		RowGroupSize: opts.RowGroupSize,
+		Params:       string(raw),
+		Status:       ExportPending,
+		ExpireAt:     now.Add(ExportFileRetention),
+		TenantID:     scope.TenantID,
+		CreateID:     scope.UserID,
+	}
+	if err := s.exportJobRepo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("创建导出任务失败: %w", err)
+	}
+
+	// 2. 后台执行，保留请求中的数据权限
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.jobsMu.Lock()
+	s.exports[job.ID] = cancel
+	s.jobsMu.Unlock()
+	go func() {
+		defer func() {
+			cancel()
+			s.jobsMu.Lock()
+			delete(s.exports, job.ID)
+			s.jobsMu.Unlock()
+		}()
+		s.runExportJob(runCtx, *job, params)
+	}()
+	return &ExportJob{MdExportJob: *job}, nil
+}
+
+// ListExportJobs 返回当前用户的导出任务，已过期的任务先行清理
+func (s *dataIOService) ListExportJobs(ctx context.Context) ([]*ExportJob, error) {
+	s.purgeExports()
+	scope := engine.DataScopeFromContext(ctx)
+	if !jobAccessible(scope, "") {
+		return nil, errors.New("未登录，无法查询导出任务")
+	}
+	jobs, err := s.exportJobRepo.GetJobsByCreateID(scope.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("查询导出任务失败: %w", err)
+	}
+	result := make([]*ExportJob, len(jobs))
+	for i := range jobs {
+		result[i] = exportJobWithURL(jobs[i])
+	}
+	return result, nil
+}
+
+// GetExportJob 查询导出任务进度
+func (s *dataIOService) GetExportJob(ctx context.Context, jobID string) (*ExportJob, error) {
+	job, err := s.exportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return exportJobWithURL(*job), nil
+}
+
+// DeleteExportJob 删除导出任务及文件，运行中的任务先取消
+func (s *dataIOService) DeleteExportJob(ctx context.Context, jobID string) error {
+	job, err := s.exportJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	s.removeExport(job)
+	return nil
+}
+
+// OpenExportFile 校验下载链接签名后打开导出文件
+func (s *dataIOService) OpenExportFile(jobID, expires, signature string) (*model.MdExportJob, io.ReadCloser, error) {
+	if err := utils.VerifyURL(fmt.Sprintf(ExportDownloadPath, jobID), expires, signature); err != nil {
+		return nil, nil, err
+	}
+	job, err := s.exportJobRepo.GetJobByID(jobID)
+	if err != nil {
+		return nil, nil, errors.New("导出任务不存在")
+	}
+	if job.Status != ExportSucceeded || time.Now().After(job.ExpireAt) {
+		return nil, nil, errors.New("导出文件不存在或已过期")
+	}
+	file, err := utils.GetFileStore().Open(job.FileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开导出文件失败: %w", err)
+	}
+	return job, file, nil
+}
+
+// exportJob 查找导出任务，仅创建者和管理员可以访问
+func (s *dataIOService) exportJob(ctx context.Context, jobID string) (*model.MdExportJob, error) {
+	job, err := s.exportJobRepo.GetJobByID(jobID)
+	if err != nil {
+		return nil, errors.New("导出任务不存在")
+	}
+	if !jobAccessible(engine.DataScopeFromContext(ctx), job.CreateID) {
+		return nil, errors.New("无权访问该导出任务")
+	}
+	return job, nil
+}
+
+// jobAccessible 后台任务仅创建者和管理员可以访问，未登录时拒绝；owner 为空时只校验是否登录
+func jobAccessible(scope *engine.DataScope, owner string) bool {
+	if scope == nil || scope.Deny || scope.UserID == "" {
+		return false
+	}
+	return owner == "" || scope.Admin || scope.UserID == owner
+}
+
+// exportJobWithURL 为导出成功且未过期的任务生成签名的下载链接
+func exportJobWithURL(job model.MdExportJob) *ExportJob {
+	result := &ExportJob{MdExportJob: job}
+	if job.Status != ExportSucceeded || time.Now().After(job.ExpireAt) {
+		return result
+	}
+	expires := time.Now().Add(ExportLinkTTL)
+	if expires.After(job.ExpireAt) {
+		expires = job.ExpireAt
+	}
+	path := fmt.Sprintf(ExportDownloadPath, job.ID)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", utils.SignURL(path, expires))
+	result.DownloadURL = path + "?" + query.Encode()
+	result.URLExpireAt = &expires
+	return result
+}
+
+// purgeExports 删除已过期的导出任务及文件
+func (s *dataIOService) purgeExports() {
+	jobs, err := s.exportJobRepo.GetExpiredJobs(time.Now())
+	if err != nil {
+		utils.SugarLogger.Errorf("Failed to load expired export jobs: %v", err)
+		return
+	}
+	for i := range jobs {
+		s.removeExport(&jobs[i])
+	}
+}
+
+// removeExport 取消运行中的任务并删除文件和任务记录
+func (s *dataIOService) removeExport(job *model.MdExportJob) {
+	s.jobsMu.Lock()
+	if cancel := s.exports[job.ID]; cancel != nil {
+		cancel()
+	}
+	s.jobsMu.Unlock()
+	if job.FileName != "" {
+		if err := utils.GetFileStore().Delete(job.FileName); err != nil {
+			utils.SugarLogger.Errorf("Failed to delete export file %s: %v", job.FileName, err)
+		}
+	}
+	if err := s.exportJobRepo.DeleteJob(job.ID); err != nil {
+		utils.SugarLogger.Errorf("Failed to delete export job %s: %v", job.ID, err)
+	}
+}
+
+// runExportJob 执行导出任务，逐页更新进度，失败或取消时删除已写入的文件
+func (s *dataIOService) runExportJob(ctx context.Context, job model.MdExportJob, params map[string]any) {
+	update := func(updates map[string]any) {
+		if err := s.exportJobRepo.UpdateJob(job.ID, updates); err != nil {
+			utils.SugarLogger.Errorf("Failed to update export job %s: %v", job.ID, err)
+		}
+	}
+	finish := func(status string, err error, updates map[string]any) {
+		now := time.Now()
+		if updates == nil {
+			updates = make(map[string]any)
+		}
+		updates["status"] = status
+		updates["finished_at"] = &now
+		if err != nil {
+			updates["error"] = err.Error()
+		}
+		update(updates)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			utils.SugarLogger.Errorf("Export job %s panic: %v", job.ID, r)
+			finish(ExportFailed, fmt.Errorf("导出异常: %v", r), nil)
+		}
+	}()
+	update(map[string]any{"status": ExportRunning})
+
+	// 1. 写入文件存储
+	store := utils.GetFileStore()
+	name := job.ID + "." + job.Format
+	size, err := s.writeExportFile(ctx, store, name, job, params, func(total, processed int64) {
+		update(map[string]any{"total": total, "processed": processed})
+	})
+
+	// 2. 记录结果，文件自导出结束起保留
+	switch {
+	case err == nil:
+		finish(ExportSucceeded, nil, map[string]any{"file_name": name, "file_size": size, "expire_at": time.Now().Add(ExportFileRetention)})
+		return
+	case ctx.Err() != nil:
+		finish(ExportCanceled, nil, nil)
+	default:
+		finish(ExportFailed, err, nil)
+	}
+	if err := store.Delete(name); err != nil {
+		utils.SugarLogger.Errorf("Failed to delete export file %s: %v", name, err)
+	}
+}
+
+// writeExportFile 按格式写入导出文件，返回文件大小
+func (s *dataIOService) writeExportFile(ctx context.Context, store utils.FileStore, name string, job model.MdExportJob, params map[string]any, progress exportProgress) (int64, error) {
+	file, err := store.Create(name)
+	if err != nil {
+		return 0, fmt.Errorf("创建导出文件失败: %w", err)
+	}
+	counter := &countingWriter{w: file}
+	w := bufio.NewWriter(counter)
+
+	switch job.Format {
+	case ExportFormatExcel:
+		err = s.writeExcel(ctx, job.ModelID, params, w, progress)
+	case ExportFormatCSV:
+		err = s.writeCSV(ctx, job.ModelID, params, job.Encoding, w, progress)
+	case ExportFormatParquet:
+		err = s.writeParquet(ctx, job.ModelID, params, utils.ParquetOptions{Compression: job.Compression, RowGroupSize: job.RowGroupSize}, w, progress)
+	default:
+		err = s.writeJSON(ctx, job.ModelID, params, job.Format == ExportFormatNDJSON, w, progress)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入导出文件失败: %w", closeErr)
+	}
+	return counter.n, err
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,871 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+// 导入任务状态
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+	ImportCanceled  = "canceled"
+)
+
+// 导入策略
+const (
+	ImportInsert       = "insert"        // 直接新增，冲突的行记为失败
+	ImportUpsert       = "upsert"        // 按唯一键新增或更新
+	ImportSkipExisting = "skip_existing" // 按唯一键跳过已存在的记录
+)
+
+// 导入文件格式
+const (
+	ImportFormatJSON  = "json"
+	ImportFormatCSV   = "csv"
+	ImportFormatExcel = "xlsx"
+)
+
+const (
+	MaxImportJobErrors   = 100            // 任务详情中返回的错误条数，完整错误见错误报告
+	MaxRunningImportJobs = 4              // 每个实例同时运行的导入任务数
+	ImportJobRetention   = 24 * time.Hour // 导入任务及上传文件的保留时长，过期后删除
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Format    string   `json:"format"`     // 文件格式：json、csv、xlsx
+	Encoding  string   `json:"encoding"`   // CSV 文件编码，为空时自动识别
+	Strategy  string   `json:"strategy"`   // 导入策略，默认为 insert
+	KeyFields []string `json:"key_fields"` // upsert、skip_existing 的唯一键字段，默认为模型唯一键
+	DryRun    bool     `json:"dry_run"`    // 只校验不写入，新增、更新、跳过数为预估值
+}
+
+// ImportRowError 导入的行错误，Column 为空时为整行错误
+type ImportRowError struct {
+	Sheet  string `json:"sheet,omitempty"`
+	Row    int    `json:"row"` // 文件中的行号，表头为第 1 行；JSON 为数组中的序号
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+
+	cell int // 单元格在行中的序号，-1 表示没有对应的单元格
+}
+
+// String 返回错误描述，如 "Sheet1 Row 3 [状态]: 字段不能为空"
+func (e ImportRowError) String() string {
+	var sb strings.Builder
+	if e.Sheet != "" {
+		sb.WriteString(e.Sheet + " ")
+	}
+	fmt.Fprintf(&sb, "Row %d", e.Row)
+	if e.Column != "" {
+		sb.WriteString(" [" + e.Column + "]")
+	}
+	sb.WriteString(": " + e.Error)
+	return sb.String()
+}
+
+// ImportJob 导入任务
+type ImportJob struct {
+	ID         string           `json:"id"`
+	ModelID    string           `json:"model_id"`
+	Options    ImportOptions    `json:"options"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`     // 数据行数
+	Processed  int              `json:"processed"` // 已处理的行数
+	Inserted   int              `json:"inserted"`
+	Updated    int              `json:"updated"`
+	Skipped    int              `json:"skipped"`
+	Failed     int              `json:"failed"`      // 失败的行数
+	ErrorCount int              `json:"error_count"` // 错误总数，含表头错误
+	Errors     []ImportRowError `json:"errors"`      // 前 MaxImportJobErrors 条错误
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// importJob 导入任务的运行状态，progress 不为空时每批结束后保存进度
+type importJob struct {
+	mu       sync.Mutex
+	job      ImportJob
+	errors   []ImportRowError
+	data     []byte
+	progress func(j *importJob) error // 返回错误时终止导入
+}
+
+// storedRowError 错误列表文件中的行错误，保留单元格序号用于标注 Excel 错误报告
+type storedRowError struct {
+	ImportRowError
+	Cell int `json:"cell"`
+}
+
+// errImportCanceled 任务已被取消，可能由其他实例取消
+var errImportCanceled = errors.New("导入任务已取消")
+
+// snapshot 返回任务当前状态的副本
+func (j *importJob) snapshot() *ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.ErrorCount = len(j.errors)
+	job.Errors = append([]ImportRowError{}, j.errors[:min(len(j.errors), MaxImportJobErrors)]...)
+	return &job
+}
+
+// update 在锁内更新任务状态
+func (j *importJob) update(fn func(job *ImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.job)
+}
+
+// addErrors 记录错误
+func (j *importJob) addErrors(errs ...ImportRowError) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.errors = append(j.errors, errs...)
+}
+
+// StartImport 创建后台导入任务，上传文件保存到文件存储，任务结束后用于生成错误报告
+func (s *dataIOService) StartImport(ctx context.Context, modelID string, data []byte, opts ImportOptions) (*ImportJob, error) {
+	scope := engine.DataScopeFromContext(ctx)
+	if !jobAccessible(scope, "") {
+		return nil, errors.New("未登录，无法创建导入任务")
+	}
+	opts, err := s.checkImportOptions(ctx, modelID, opts)
+	if err != nil {
+		return nil, err
+	}
+	rawOpts, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("导入选项格式错误: %w", err)
+	}
+	s.purgeImports()
+
+	// 1. 限制本实例同时运行的任务数，上传文件在任务运行期间驻留内存
+	j := newImportJob(modelID, data, opts)
+	s.jobsMu.Lock()
+	if len(s.imports) >= MaxRunningImportJobs {
+		s.jobsMu.Unlock()
+		return nil, errors.New("运行中的导入任务过多，请稍后再试")
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.imports[j.job.ID] = cancel
+	s.jobsMu.Unlock()
+	release := func() {
+		cancel()
+		s.jobsMu.Lock()
+		delete(s.imports, j.job.ID)
+		s.jobsMu.Unlock()
+	}
+
+	// 2. 保存上传文件并记录任务
+	record := &model.MdImportJob{
+		ID:       j.job.ID,
+		TenantID: scope.TenantID,
+		ModelID:  modelID,
+		Options:  string(rawOpts),
+		Status:   ImportPending,
+		FileName: j.job.ID + "_upload." + opts.Format,
+		ExpireAt: time.Now().Add(ImportJobRetention),
+		CreateID: scope.UserID,
+	}
+	if err := writeStoreFile(record.FileName, data); err != nil {
+		release()
+		return nil, fmt.Errorf("保存导入文件失败: %w", err)
+	}
+	if err := s.importJobRepo.CreateJob(record); err != nil {
+		release()
+		if err := utils.GetFileStore().Delete(record.FileName); err != nil {
+			utils.SugarLogger.Errorf("Failed to delete import file %s: %v", record.FileName, err)
+		}
+		return nil, fmt.Errorf("创建导入任务失败: %w", err)
+	}
+
+	// 3. 后台执行，保留请求中的数据权限
+	j.job.CreatedAt = record.CreateAt
+	j.progress = s.saveImportProgress
+	snapshot := j.snapshot()
+	go func() {
+		defer release()
+		s.runImportJob(runCtx, j)
+	}()
+	return snapshot, nil
+}
+
+// GetImportJob 查询导入任务
+func (s *dataIOService) GetImportJob(ctx context.Context, modelID, jobID string) (*ImportJob, error) {
+	record, err := s.importJob(ctx, modelID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return importJobFromModel(record)
+}
+
+// CancelImportJob 取消导入任务，已写入的批次不回滚；其他实例中运行的任务在下一批开始前结束
+func (s *dataIOService) CancelImportJob(ctx context.Context, modelID, jobID string) error {
+	record, err := s.importJob(ctx, modelID, jobID)
+	if err != nil {
+		return err
+	}
+	if record.FinishedAt != nil {
+		return errors.New("导入任务已结束")
+	}
+	if err := s.importJobRepo.UpdateJob(record.ID, map[string]any{"status": ImportCanceled}); err != nil {
+		return fmt.Errorf("取消导入任务失败: %w", err)
+	}
+	s.jobsMu.Lock()
+	if cancel := s.imports[record.ID]; cancel != nil {
+		cancel()
+	}
+	s.jobsMu.Unlock()
+	return nil
+}
+
+// ImportErrorReport 生成错误报告，返回报告的文件格式：
+// Excel 为原文件标红出错单元格并附批注，CSV 为出错行附加错误信息列，JSON 为出错行的数据与错误列表
+func (s *dataIOService) ImportErrorReport(ctx context.Context, modelID, jobID string, writer io.Writer) (string, error) {
+	record, err := s.importJob(ctx, modelID, jobID)
+	if err != nil {
+		return "", err
+	}
+	if record.FinishedAt == nil {
+		return "", errors.New("导入任务尚未结束")
+	}
+	var opts ImportOptions
+	if err := json.Unmarshal([]byte(record.Options), &opts); err != nil {
+		return "", fmt.Errorf("解析导入选项失败: %w", err)
+	}
+	data, err := readStoreFile(record.FileName)
+	if err != nil {
+		return "", fmt.Errorf("读取导入文件失败: %w", err)
+	}
+	var errs []ImportRowError
+	if record.ErrorFileName != "" {
+		raw, err := readStoreFile(record.ErrorFileName)
+		if err != nil {
+			return "", fmt.Errorf("读取错误列表失败: %w", err)
+		}
+		var stored []storedRowError
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			return "", fmt.Errorf("解析错误列表失败: %w", err)
+		}
+		errs = make([]ImportRowError, len(stored))
+		for i, e := range stored {
+			errs[i] = e.ImportRowError
+			errs[i].cell = e.Cell
+		}
+	}
+
+	switch opts.Format {
+	case ImportFormatExcel:
+		err = excelErrorReport(data, errs, writer)
+	case ImportFormatCSV:
+		err = csvErrorReport(data, opts.Encoding, errs, writer)
+	default:
+		err = jsonErrorReport(data, errs, writer)
+	}
+	if err != nil {
+		return "", fmt.Errorf("生成错误报告失败: %w", err)
+	}
+	return opts.Format, nil
+}
+
+// importJob 查找导入任务，仅创建者和管理员可以访问
+func (s *dataIOService) importJob(ctx context.Context, modelID, jobID string) (*model.MdImportJob, error) {
+	record, err := s.importJobRepo.GetJobByID(jobID)
+	if err != nil || record.ModelID != modelID {
+		return nil, errors.New("导入任务不存在")
+	}
+	if !jobAccessible(engine.DataScopeFromContext(ctx), record.CreateID) {
+		return nil, errors.New("无权访问该导入任务")
+	}
+	return record, nil
+}
+
+// importJobFromModel 由任务记录还原任务详情
+func importJobFromModel(record *model.MdImportJob) (*ImportJob, error) {
+	job := &ImportJob{
+		ID:         record.ID,
+		ModelID:    record.ModelID,
+		Status:     record.Status,
+		Total:      record.Total,
+		Processed:  record.Processed,
+		Inserted:   record.Inserted,
+		Updated:    record.Updated,
+		Skipped:    record.Skipped,
+		Failed:     record.Failed,
+		ErrorCount: record.ErrorCount,
+		Errors:     []ImportRowError{},
+		Error:      record.Error,
+		CreatedAt:  record.CreateAt,
+		FinishedAt: record.FinishedAt,
+	}
+	if err := json.Unmarshal([]byte(record.Options), &job.Options); err != nil {
+		return nil, fmt.Errorf("解析导入选项失败: %w", err)
+	}
+	if record.Errors != "" {
+		if err := json.Unmarshal([]byte(record.Errors), &job.Errors); err != nil {
+			return nil, fmt.Errorf("解析导入错误失败: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// importJobUpdates 任务进度对应的记录字段
+func importJobUpdates(job *ImportJob) map[string]any {
+	errs, _ := json.Marshal(job.Errors)
+	return map[string]any{
+		"status":      job.Status,
+		"total":       job.Total,
+		"processed":   job.Processed,
+		"inserted":    job.Inserted,
+		"updated":     job.Updated,
+		"skipped":     job.Skipped,
+		"failed":      job.Failed,
+		"error_count": job.ErrorCount,
+		"errors":      string(errs),
+	}
+}
+
+// saveImportProgress 保存导入进度，任务已被取消时返回 errImportCanceled
+func (s *dataIOService) saveImportProgress(j *importJob) error {
+	snapshot := j.snapshot()
+	record, err := s.importJobRepo.GetJobByID(snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("读取导入任务失败: %w", err)
+	}
+	if record.Status == ImportCanceled {
+		return errImportCanceled
+	}
+	if err := s.importJobRepo.UpdateJob(snapshot.ID, importJobUpdates(snapshot)); err != nil {
+		return fmt.Errorf("保存导入进度失败: %w", err)
+	}
+	return nil
+}
+
+// finishImportJob 结束任务，保存完整的错误列表并释放上传文件的内容
+func (s *dataIOService) finishImportJob(j *importJob, status string, cause error) {
+	now := time.Now()
+	j.mu.Lock()
+	j.job.Status = status
+	j.job.FinishedAt = &now
+	if cause != nil {
+		j.job.Error = cause.Error()
+	}
+	stored := make([]storedRowError, len(j.errors))
+	for i, e := range j.errors {
+		stored[i] = storedRowError{ImportRowError: e, Cell: e.cell}
+	}
+	j.data = nil
+	j.mu.Unlock()
+
+	snapshot := j.snapshot()
+	updates := importJobUpdates(snapshot)
+	updates["error"] = snapshot.Error
+	updates["finished_at"] = &now
+	if len(stored) > 0 {
+		name := snapshot.ID + "_errors.json"
+		raw, err := json.Marshal(stored)
+		if err == nil {
+			err = writeStoreFile(name, raw)
+		}
+		if err != nil {
+			utils.SugarLogger.Errorf("Failed to save import errors of job %s: %v", snapshot.ID, err)
+		} else {
+			updates["error_file_name"] = name
+		}
+	}
+	if err := s.importJobRepo.UpdateJob(snapshot.ID, updates); err != nil {
+		utils.SugarLogger.Errorf("Failed to update import job %s: %v", snapshot.ID, err)
+	}
+}
+
+// purgeImports 删除已过期的导入任务及文件，运行中的任务先取消
+func (s *dataIOService) purgeImports() {
+	jobs, err := s.importJobRepo.GetExpiredJobs(time.Now())
+	if err != nil {
+		utils.SugarLogger.Errorf("Failed to load expired import jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		s.jobsMu.Lock()
+		if cancel := s.imports[job.ID]; cancel != nil {
+			cancel()
+		}
+		s.jobsMu.Unlock()
+		for _, name := range []string{job.FileName, job.ErrorFileName} {
+			if name == "" {
+				continue
+			}
+			if err := utils.GetFileStore().Delete(name); err != nil {
+				utils.SugarLogger.Errorf("Failed to delete import file %s: %v", name, err)
+			}
+		}
+		if err := s.importJobRepo.DeleteJob(job.ID); err != nil {
+			utils.SugarLogger.Errorf("Failed to delete import job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// writeStoreFile 将内容写入文件存储
+func writeStoreFile(name string, data []byte) error {
+	w, err := utils.GetFileStore().Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// readStoreFile 读取文件存储中的文件
+func readStoreFile(name string) ([]byte, error) {
+	r, err := utils.GetFileStore().Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// checkImportOptions 校验导入选项并填充默认值
+func (s *dataIOService) checkImportOptions(ctx context.Context, modelID string, opts ImportOptions) (ImportOptions, error) {
+	opts.Format = strings.ToLower(opts.Format)
+	switch opts.Format {
+	case ImportFormatJSON, ImportFormatCSV, ImportFormatExcel:
+	default:
+		return opts, fmt.Errorf("不支持的导入文件格式: %s", opts.Format)
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = ImportInsert
+	case ImportInsert:
+	case ImportUpsert, ImportSkipExisting:
+		// 提前校验唯一键
+		if _, err := s.crudSvc.ExistingKeys(ctx, modelID, opts.KeyFields, nil); err != nil {
+			return opts, err
+		}
+	default:
+		return opts, fmt.Errorf("不支持的导入策略: %s", opts.Strategy)
+	}
+	return opts, nil
+}
+
+// newImportJob 创建导入任务的运行状态
+func newImportJob(modelID string, data []byte, opts ImportOptions) *importJob {
+	return &importJob{
+		job: ImportJob{
+			ID:        utils.GetSnowflake().GenerateIDString(),
+			ModelID:   modelID,
+			Options:   opts,
+			Status:    ImportPending,
+			CreatedAt: time.Now(),
+		},
+		data: data,
+	}
+}
+
+// runImportJob 执行导入任务并记录结束状态
+func (s *dataIOService) runImportJob(ctx context.Context, j *importJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.SugarLogger.Errorf("Import job %s panic: %v", j.job.ID, r)
+			s.finishImportJob(j, ImportFailed, fmt.Errorf("导入异常: %v", r))
+		}
+	}()
+	j.update(func(job *ImportJob) { job.Status = ImportRunning })
+
+	err := s.saveImportProgress(j)
+	if err == nil {
+		err = s.runImport(ctx, j)
+	}
+	switch {
+	case ctx.Err() != nil || errors.Is(err, errImportCanceled):
+		s.finishImportJob(j, ImportCanceled, nil)
+	case err != nil:
+		s.finishImportJob(j, ImportFailed, err)
+	default:
+		s.finishImportJob(j, ImportSucceeded, nil)
+	}
+}
+
+// importNow 在当前请求中同步导入，返回新增和更新的行数及错误描述
+func (s *dataIOService) importNow(ctx context.Context, modelID string, reader io.Reader, opts ImportOptions) (int, []string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("读取导入文件失败: %w", err)
+	}
+	if opts, err = s.checkImportOptions(ctx, modelID, opts); err != nil {
+		return 0, nil, err
+	}
+	j := newImportJob(modelID, data, opts)
+	if err := s.runImport(ctx, j); err != nil {
+		return 0, nil, err
+	}
+	messages := make([]string, len(j.errors))
+	for i, e := range j.errors {
+		messages[i] = e.String()
+	}
+	return j.job.Inserted + j.job.Updated, messages, nil
+}
+
+// runImport 读取文件并分批校验、写入，每批结束后更新进度并检查是否已取消
+func (s *dataIOService) runImport(ctx context.Context, j *importJob) error {
+	opts := j.job.Options
+	md, columns, err := s.loadColumns(j.job.ModelID, false)
+	if err != nil {
+		return err
+	}
+
+	var records []*importRecord
+	var headerErrors []ImportRowError
+	switch opts.Format {
+	case ImportFormatExcel:
+		records, headerErrors, err = readExcelRecords(j.data, columns)
+	case ImportFormatCSV:
+		records, headerErrors, err = readCSVRecords(j.data, opts.Encoding, columns)
+	default:
+		records, err = readJSONRecords(j.data)
+	}
+	if err != nil {
+		return err
+	}
+	j.addErrors(headerErrors...)
+	j.update(func(job *ImportJob) { job.Total = len(records) })
+
+	im := &recordImporter{s: s, md: md, columns: columns, opts: opts, job: j}
+	for start := 0; start < len(records); start += importBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := im.importChunk(ctx, records[start:min(start+importBatchSize, len(records))]); err != nil {
+			return err
+		}
+		if j.progress != nil {
+			if err := j.progress(j); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordImporter 按导入策略校验并写入数据行
+type recordImporter struct {
+	s       *dataIOService
+	md      *model.MdModel
+	columns []*ioColumn
+	opts    ImportOptions
+	job     *importJob
+}
+
+// importChunk 校验并写入一批数据行，行错误记入任务，只有无法继续时返回错误
+func (im *recordImporter) importChunk(ctx context.Context, chunk []*importRecord) error {
+	// 1. 逐个单元格校验
+	var rows []*importRecord
+	var failed []ImportRowError
+	failedRows := 0
+	for _, r := range chunk {
+		if errs := im.validate(r); len(errs) > 0 {
+			failed = append(failed, errs...)
+			failedRows++
+			continue
+		}
+		rows = append(rows, r)
+	}
+	dataList := make([]map[string]any, len(rows))
+	for i, r := range rows {
+		dataList[i] = r.data
+	}
+
+	// 2. 按唯一键判断记录是否已存在
+	var exists []bool
+	if im.opts.Strategy != ImportInsert && len(rows) > 0 {
+		var err error
+		if exists, err = im.s.crudSvc.ExistingKeys(ctx, im.md.ID, im.opts.KeyFields, dataList); err != nil {
+			return err
+		}
+	}
+
+	// 3. 按策略写入
+	var inserted, updated, skipped int
+	fail := func(i int, err error) {
+		failed = append(failed, ImportRowError{Sheet: rows[i].sheet, Row: rows[i].row, Error: err.Error(), cell: -1})
+		failedRows++
+	}
+	switch {
+	case im.opts.DryRun:
+		for i := range rows {
+			switch {
+			case exists == nil || !exists[i]:
+				inserted++
+			case im.opts.Strategy == ImportSkipExisting:
+				skipped++
+			default:
+				updated++
+			}
+		}
+
+	case im.opts.Strategy == ImportUpsert:
+		results, err := im.s.crudSvc.BatchUpsert(ctx, im.md.ID, dataList, UpsertOptions{KeyFields: im.opts.KeyFields})
+		if err != nil {
+			// 批量写入在同一事务中，失败时逐行写入以定位出错的行
+			results = make([]*UpsertResult, len(dataList))
+			for i, data := range dataList {
+				if results[i], err = im.s.crudSvc.Upsert(ctx, im.md.ID, data, UpsertOptions{KeyFields: im.opts.KeyFields}); err != nil {
+					fail(i, err)
+				}
+			}
+		}
+		for _, result := range results {
+			switch {
+			case result == nil:
+			case result.Action == UpsertUpdated:
+				updated++
+			default:
+				inserted++
+			}
+		}
+
+	default:
+		var index []int
+		var insertList []map[string]any
+		for i, data := range dataList {
+			if exists != nil && exists[i] {
+				skipped++
+				continue
+			}
+			index = append(index, i)
+			insertList = append(insertList, data)
+		}
+		if len(insertList) == 0 {
+			break
+		}
+		result, err := im.s.crudSvc.BatchInsert(ctx, im.md.ID, insertList, BatchInsertOptions{Mode: BatchBestEffort})
+		if err != nil {
+			for _, i := range index {
+				fail(i, err)
+			}
+			break
+		}
+		inserted = result.Inserted
+		for _, e := range result.Errors {
+			fail(index[e.Row], errors.New(e.Error))
+		}
+	}
+
+	// 4. 更新进度
+	im.job.addErrors(failed...)
+	im.job.update(func(job *ImportJob) {
+		job.Processed += len(chunk)
+		job.Inserted += inserted
+		job.Updated += updated
+		job.Skipped += skipped
+		job.Failed += failedRows
+	})
+	return nil
+}
+
+// validate 逐列校验一行数据，错误定位到单元格
+func (im *recordImporter) validate(r *importRecord) []ImportRowError {
+	if r.err != "" {
+		return []ImportRowError{{Sheet: r.sheet, Row: r.row, Error: r.err, cell: -1}}
+	}
+	cellError := func(column, msg string) ImportRowError {
+		e := ImportRowError{Sheet: r.sheet, Row: r.row, Column: column, Error: msg, cell: -1}
+		if c, ok := r.cells[column]; ok {
+			e.Column, e.cell = c.header, c.index
+		}
+		return e
+	}
+
+	var errs []ImportRowError
+	known := make(map[string]bool, len(im.columns))
+	for _, c := range im.columns {
+		known[c.field.ColumnName] = true
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.data)) {
+		if !known[key] && key != "id" {
+			errs = append(errs, cellError(key, "字段未在模型中定义"))
+		}
+	}
+	for _, c := range im.columns {
+		name := c.field.ColumnName
+		data := make(map[string]any, 1)
+		if val, ok := r.data[name]; ok {
+			data[name] = val
+		}
+		if err := im.s.validator.Validate(im.md.ID, []*model.MdModelField{c.field}, data); err != nil {
+			errs = append(errs, cellError(name, err.Error()))
+		}
+	}
+	return errs
+}
+
+// importRowKey 错误报告中的行
+type importRowKey struct {
+	sheet string
+	row   int
+}
+
+// groupRowErrors 按行归集错误
+func groupRowErrors(errs []ImportRowError) map[importRowKey][]ImportRowError {
+	rows := make(map[importRowKey][]ImportRowError)
+	for _, e := range errs {
+		key := importRowKey{e.Sheet, e.Row}
+		rows[key] = append(rows[key], e)
+	}
+	return rows
+}
+
+// rowErrorText 合并一行的错误描述
+func rowErrorText(errs []ImportRowError) string {
+	texts := make([]string, len(errs))
+	for i, e := range errs {
+		texts[i] = e.Error
+		if e.Column != "" {
+			texts[i] = "[" + e.Column + "] " + e.Error
+		}
+	}
+	return strings.Join(texts, "; ")
+}
+
+// excelErrorReport 在原文件中标红出错单元格并添加批注，出错的工作表末尾追加错误信息列
+func excelErrorReport(data []byte, errs []ImportRowError, writer io.Writer) error {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	style, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}})
+	if err != nil {
+		return err
+	}
+
+	rowErrors := groupRowErrors(errs)
+	sheets := make(map[string][]int)
+	for key := range rowErrors {
+		sheets[key.sheet] = append(sheets[key.sheet], key.row)
+	}
+	for sheet, lines := range sheets {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return err
+		}
+		width := 0
+		for _, row := range rows {
+			width = max(width, len(row))
+		}
+		if err := f.SetCellValue(sheet, cellName(width, 1), "错误信息"); err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			errs := rowErrors[importRowKey{sheet, line}]
+			if line > 1 {
+				if err := f.SetCellValue(sheet, cellName(width, line), rowErrorText(errs)); err != nil {
+					return err
+				}
+			}
+			comments := make(map[int][]string)
+			for _, e := range errs {
+				if e.cell >= 0 {
+					comments[e.cell] = append(comments[e.cell], e.Error)
+				}
+			}
+			for index, texts := range comments {
+				cell := cellName(index, line)
+				if err := f.SetCellStyle(sheet, cell, cell, style); err != nil {
+					return err
+				}
+				comment := excelize.Comment{Author: "导入", Cell: cell, Paragraph: []excelize.RichTextRun{{Text: strings.Join(texts, "\n")}}}
+				if err := f.AddComment(sheet, comment); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return f.Write(writer)
+}
+
+// cellName 返回第 index 列 (从 0 开始) 第 line 行的单元格名称
+func cellName(index, line int) string {
+	name, _ := excelize.CoordinatesToCellName(index+1, line)
+	return name
+}
+
+// csvErrorReport 输出表头及出错的数据行，末尾追加错误信息列，表头错误见任务详情
+func csvErrorReport(data []byte, encoding string, errs []ImportRowError, writer io.Writer) error {
+	rows, err := readCSVRows(data, encoding)
+	if err != nil {
+		return err
+	}
+	w, err := csvWriter(writer, encoding)
+	if err != nil {
+		return err
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	rowErrors := groupRowErrors(errs)
+	for i, row := range rows {
+		line := i + 1
+		if line > 1 && rowErrors[importRowKey{"", line}] == nil {
+			continue
+		}
+		record := make([]string, width+1)
+		copy(record, row)
+		record[width] = "错误信息"
+		if line > 1 {
+			record[width] = rowErrorText(rowErrors[importRowKey{"", line}])
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// jsonErrorReport 输出出错行的原始数据与错误列表
+func jsonErrorReport(data []byte, errs []ImportRowError, writer io.Writer) error {
+	records, err := readJSONRecords(data)
+	if err != nil {
+		return err
+	}
+	type reportRow struct {
+		Row    int              `json:"row"`
+		Data   map[string]any   `json:"data"`
+		Errors []ImportRowError `json:"errors"`
+	}
+	rowErrors := groupRowErrors(errs)
+	report := []reportRow{}
+	for _, r := range records {
+		if errs := rowErrors[importRowKey{"", r.row}]; errs != nil {
+			report = append(report, reportRow{Row: r.row, Data: r.data, Errors: errs})
+		}
+	}
+	return json.NewEncoder(writer).Encode(report)
+}
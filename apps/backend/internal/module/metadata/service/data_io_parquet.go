@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"metadata-platform/internal/utils"
+)
+
+// ExportToParquet 导出 Parquet，列名为查询结果列名，类型由字段类型推导，字典值保持原值
+func (s *dataIOService) ExportToParquet(ctx context.Context, modelID string, queryParams map[string]any, opts utils.ParquetOptions, writer io.Writer) error {
+	return s.writeParquet(ctx, modelID, queryParams, opts, writer, nil)
+}
+
+// writeParquet 流式读取数据并按行组写入 Parquet，内存中仅保留当前批次和行组
+func (s *dataIOService) writeParquet(ctx context.Context, modelID string, queryParams map[string]any, opts utils.ParquetOptions, writer io.Writer, progress exportProgress) error {
+	_, columns, err := s.loadColumns(modelID, false)
+	if err != nil {
+		return err
+	}
+	schema := make([]utils.ParquetColumn, len(columns))
+	for i, c := range columns {
+		schema[i] = utils.ParquetColumn{Name: c.key, Type: parquetType(c)}
+	}
+	w, err := utils.NewParquetWriter(writer, schema, opts)
+	if err != nil {
+		return err
+	}
+
+	row := make([]any, len(columns))
+	err = s.eachBatch(ctx, modelID, queryParams, progress, func(list []map[string]any) error {
+		for _, item := range list {
+			for i, c := range columns {
+				row[i] = item[c.key]
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// parquetType 由字段逻辑类型推导 Parquet 列类型，未设置逻辑类型时按列类型映射；
+// 加密或脱敏字段的值为字符串，统一按字符串导出
+func parquetType(c *ioColumn) utils.ParquetType {
+	f := c.field
+	if c.masked || f.EncryptAlgo != "" {
+		return utils.ParquetString
+	}
+	columnType := strings.ToLower(strings.TrimSpace(f.ColumnType))
+	baseType, _, _ := strings.Cut(columnType, "(")
+	baseType, _, _ = strings.Cut(baseType, " ")
+	kind := strings.ToLower(f.FieldType)
+	if kind == "" {
+		kind = mapDataType(baseType)
+	}
+
+	switch kind {
+	case "integer", "int":
+		if isInt32Column(baseType, columnType) {
+			return utils.ParquetInt32
+		}
+		return utils.ParquetInt64
+	case "long", "bigint":
+		return utils.ParquetInt64
+	case "decimal", "number", "float", "double":
+		return utils.ParquetDouble
+	case "boolean", "bool":
+		return utils.ParquetBoolean
+	case "date":
+		return utils.ParquetDate
+	case "datetime", "timestamp":
+		return utils.ParquetTimestamp
+	default:
+		return utils.ParquetString
+	}
+}
+
+// isInt32Column 列类型的取值范围是否在 INT32 内，无符号 int 及未知列类型按 INT64 处理
+func isInt32Column(baseType, columnType string) bool {
+	switch baseType {
+	case "tinyint", "smallint", "mediumint":
+		return true
+	case "int":
+		return !strings.Contains(columnType, "unsigned")
+	}
+	return false
+}
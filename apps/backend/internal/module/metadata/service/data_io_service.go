@@ -1,29 +1,56 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
+
 	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/repository"
-	// "github.com/xuri/excelize/v2" // Uncomment when available
+	"metadata-platform/internal/utils"
 )
 
 // DataIOService 数据导入导出服务接口
 type DataIOService interface {
-	ExportToExcel(modelID string, queryParams map[string]any, writer io.Writer) error
-	ExportToJSON(modelID string, queryParams map[string]any, writer io.Writer) error
+	ExportToExcel(ctx context.Context, modelID string, queryParams map[string]any, writer io.Writer) error
+	ExportToCSV(ctx context.Context, modelID string, queryParams map[string]any, encoding string, writer io.Writer) error
+	ExportToJSON(ctx context.Context, modelID string, queryParams map[string]any, writer io.Writer) error
+	ExportToParquet(ctx context.Context, modelID string, queryParams map[string]any, opts utils.ParquetOptions, writer io.Writer) error
+	ExportPivot(ctx context.Context, modelID string, params map[string]any, format string, writer io.Writer) error
 	GenerateExcelTemplate(modelID string, writer io.Writer) error
-	ImportFromExcel(modelID string, reader io.Reader) (int, []string, error)
-	ImportFromJSON(modelID string, reader io.Reader) (int, []string, error)
+	GenerateCSVTemplate(modelID string, encoding string, writer io.Writer) error
+	ImportFromExcel(ctx context.Context, modelID string, reader io.Reader) (int, []string, error)
+	ImportFromCSV(ctx context.Context, modelID string, encoding string, reader io.Reader) (int, []string, error)
+	ImportFromJSON(ctx context.Context, modelID string, reader io.Reader) (int, []string, error)
+	StartImport(ctx context.Context, modelID string, data []byte, opts ImportOptions) (*ImportJob, error)
+	GetImportJob(ctx context.Context, modelID, jobID string) (*ImportJob, error)
+	CancelImportJob(ctx context.Context, modelID, jobID string) error
+	ImportErrorReport(ctx context.Context, modelID, jobID string, writer io.Writer) (string, error)
+	StartExport(ctx context.Context, modelID string, params map[string]any, opts ExportOptions) (*ExportJob, error)
+	ListExportJobs(ctx context.Context) ([]*ExportJob, error)
+	GetExportJob(ctx context.Context, jobID string) (*ExportJob, error)
+	DeleteExportJob(ctx context.Context, jobID string) error
+	OpenExportFile(jobID, expires, signature string) (*model.MdExportJob, io.ReadCloser, error)
 }
 
 type dataIOService struct {
-	crudSvc        CRUDService
-	modelRepo      repository.MdModelRepository
-	modelFieldRepo repository.MdModelFieldRepository
-	validator      DataValidator
+	crudSvc         CRUDService
+	modelRepo       repository.MdModelRepository
+	modelFieldRepo  repository.MdModelFieldRepository
+	enhancementRepo repository.MdModelFieldEnhancementRepository
+	exportJobRepo   repository.MdExportJobRepository
+	importJobRepo   repository.MdImportJobRepository
+	validator       DataValidator
+	sheetRows       int // 单个工作表最多的数据行数
+
+	jobsMu  sync.Mutex
+	imports map[string]context.CancelFunc // 本实例中运行的导入任务
+	exports map[string]context.CancelFunc // 本实例中运行的导出任务
 }
 
 // NewDataIOService 创建数据导入导出服务实例
@@ -31,94 +58,45 @@ func NewDataIOService(
 	crudSvc CRUDService,
 	modelRepo repository.MdModelRepository,
 	modelFieldRepo repository.MdModelFieldRepository,
+	enhancementRepo repository.MdModelFieldEnhancementRepository,
+	exportJobRepo repository.MdExportJobRepository,
+	importJobRepo repository.MdImportJobRepository,
 	validator DataValidator,
 ) DataIOService {
 	return &dataIOService{
-		crudSvc:        crudSvc,
-		modelRepo:      modelRepo,
-		modelFieldRepo: modelFieldRepo,
-		validator:      validator,
+		crudSvc:         crudSvc,
+		modelRepo:       modelRepo,
+		modelFieldRepo:  modelFieldRepo,
+		enhancementRepo: enhancementRepo,
+		exportJobRepo:   exportJobRepo,
+		importJobRepo:   importJobRepo,
+		validator:       validator,
+		sheetRows:       MaxExcelSheetRows,
+		imports:         make(map[string]context.CancelFunc),
+		exports:         make(map[string]context.CancelFunc),
 	}
 }
 
-// ExportToExcel 导出 Excel
-func (s *dataIOService) ExportToExcel(modelID string, queryParams map[string]any, writer io.Writer) error {
-	return errors.New("Excel support is currently disabled due to missing dependency (excelize)")
-
-	/* Uncomment when excelize is installed
-	modelData, err := s.crudSvc.(*crudService).builder.LoadModelData(modelID)
-	if err != nil {
-		return err
-	}
-
-	// Stream query results
-	queryParams["page_size"] = 10000 // Large batch
-	list, _, err := s.crudSvc.List(modelID, queryParams)
-	if err != nil {
-		return err
-	}
-
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			utils.SugarLogger.Error(err)
-		}
-	}()
-
-	// Create header
-	headers := []string{}
-	for _, field := range modelData.Fields {
-		headers = append(headers, field.Title)
-	}
-	f.SetSheetRow("Sheet1", "A1", &headers)
-
-	// Write data
-	for i, row := range list {
-		values := []interface{}{}
-		for _, field := range modelData.Fields {
-			val := row[field.ColumnName]
-			values = append(values, val)
-		}
-		cell, _ := excelize.CoordinatesToCellName(1, i+2)
-		f.SetSheetRow("Sheet1", cell, &values)
-	}
-
-	return f.Write(writer)
-	*/
+// ExportToJSON 导出 JSON (Streaming)
+func (s *dataIOService) ExportToJSON(ctx context.Context, modelID string, queryParams map[string]any, writer io.Writer) error {
+	return s.writeJSON(ctx, modelID, queryParams, false, writer, nil)
 }
 
-// ExportToJSON 导出 JSON (Streaming)
-func (s *dataIOService) ExportToJSON(modelID string, queryParams map[string]any, writer io.Writer) error {
-	// 获取数据
-	// 注意：对于大数据量，CRUDService.List 可能会撑爆内存
-	// 理想情况下应该让 CRUDService 返回一个 Iterator 或者 Channel
-	// 这里暂时使用分页循环获取
-	
+// writeJSON 流式读取并导出 JSON 数组，ndjson 为 true 时每行一条记录
+func (s *dataIOService) writeJSON(ctx context.Context, modelID string, queryParams map[string]any, ndjson bool, writer io.Writer, progress exportProgress) error {
 	encoder := json.NewEncoder(writer)
-	
-	// 写入数组开始符
-	if _, err := writer.Write([]byte("[")); err != nil {
-		return err
-	}
 
-	page := 1
-	pageSize := 100
-	first := true
-
-	for {
-		queryParams["page"] = page
-		queryParams["page_size"] = pageSize
-		list, _, err := s.crudSvc.List(modelID, queryParams)
-		if err != nil {
+	// 写入数组开始符
+	if !ndjson {
+		if _, err := writer.Write([]byte("[")); err != nil {
 			return err
 		}
+	}
 
-		if len(list) == 0 {
-			break
-		}
-
+	first := true
+	err := s.eachBatch(ctx, modelID, queryParams, progress, func(list []map[string]any) error {
 		for _, item := range list {
-			if !first {
+			if !first && !ndjson {
 				if _, err := writer.Write([]byte(",")); err != nil {
 					return err
 				}
@@ -128,124 +106,109 @@ func (s *dataIOService) ExportToJSON(modelID string, queryParams map[string]any,
 			}
 			first = false
 		}
-
-		if len(list) < pageSize {
-			break
-		}
-		page++
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// 写入数组结束符
-	if _, err := writer.Write([]byte("]")); err != nil {
-		return err
+	if !ndjson {
+		if _, err := writer.Write([]byte("]")); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
-// GenerateExcelTemplate 生成 Excel 模板
-func (s *dataIOService) GenerateExcelTemplate(modelID string, writer io.Writer) error {
-	return errors.New("Excel support is currently disabled due to missing dependency (excelize)")
-	/*
-	md, err := s.modelRepo.GetModelByID(modelID)
-	if err != nil {
-		return err
+// 透视结果导出格式
+const (
+	PivotExportCSV  = "csv"
+	PivotExportJSON = "json"
+)
+
+// ExportPivot 导出透视结果，CSV 首行为表头，末行为列合计
+func (s *dataIOService) ExportPivot(ctx context.Context, modelID string, params map[string]any, format string, writer io.Writer) error {
+	if format == "" {
+		format = PivotExportCSV
+	}
+	if format != PivotExportCSV && format != PivotExportJSON {
+		return fmt.Errorf("不支持的透视导出格式: %s", format)
 	}
-	
-	fields, err := s.modelRepo.GetModelFields(modelID)
+	result, err := s.crudSvc.Pivot(ctx, modelID, params)
 	if err != nil {
 		return err
 	}
-
-	f := excelize.NewFile()
-	headers := []string{}
-	for _, field := range fields {
-		if field.IsPrimaryKey { continue } // Skip ID for template?
-		headers = append(headers, field.Title)
+	if format == PivotExportJSON {
+		return json.NewEncoder(writer).Encode(result)
 	}
-	f.SetSheetRow("Sheet1", "A1", &headers)
-	return f.Write(writer)
-	*/
-}
 
-// ImportFromExcel 导入 Excel
-func (s *dataIOService) ImportFromExcel(modelID string, reader io.Reader) (int, []string, error) {
-	return 0, nil, errors.New("Excel support is currently disabled due to missing dependency (excelize)")
-}
-
-// ImportFromJSON 导入 JSON
-func (s *dataIOService) ImportFromJSON(modelID string, reader io.Reader) (int, []string, error) {
-	md, err := s.modelRepo.GetModelByID(modelID)
-	if err != nil {
-		return 0, nil, err
-	}
-	fields, err := s.modelFieldRepo.GetFieldsByModelID(modelID)
-	if err != nil {
-		return 0, nil, err
+	// 行维度占据前几列，没有行维度时保留一列放置合计标题
+	w := csv.NewWriter(writer)
+	width := max(len(result.Rows), 1)
+	header := make([]string, width)
+	copy(header, result.Rows)
+	for _, v := range result.Columns {
+		header = append(header, pivotText(v))
 	}
-
-	decoder := json.NewDecoder(reader)
-	
-	// Expect array
-	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
-		return 0, nil, errors.New("expected JSON array start")
-	}
-
-	successCount := 0
-	errorReport := []string{}
-	rowIndex := 0
-
-	batchSize := 100
-	batchData := []map[string]any{}
-
-	processBatch := func() error {
-		if len(batchData) == 0 { return nil }
-		_, err := s.crudSvc.BatchCreate(modelID, batchData)
-		if err != nil {
-			// 如果批量失败，整个批次都标记失败，或者尝试逐条插入？
-			// 简单策略：整个批次失败
-			errMsg := fmt.Sprintf("Batch error (rows %d-%d): %v", rowIndex-len(batchData)+1, rowIndex, err)
-			errorReport = append(errorReport, errMsg)
-		} else {
-			successCount += len(batchData)
-		}
-		batchData = []map[string]any{} // clear
-		return nil
+	if err := w.Write(append(header, "合计")); err != nil {
+		return err
 	}
-
-	for decoder.More() {
-		rowIndex++
-		var data map[string]any
-		if err := decoder.Decode(&data); err != nil {
-			errorReport = append(errorReport, fmt.Sprintf("Row %d: JSON decode error: %v", rowIndex, err))
-			continue
+	for _, row := range result.Data {
+		record := make([]string, width)
+		for i, r := range result.Rows {
+			record[i] = pivotText(row.Keys[r])
 		}
-
-		// Convert fields to ptr slice for validator
-		fieldPtrs := make([]*model.MdModelField, len(fields))
-		for i := range fields {
-			fieldPtrs[i] = &fields[i]
+		for _, v := range append(row.Cells, row.Total) {
+			record = append(record, pivotText(v))
 		}
-		
-		// Validate
-		if err := s.validator.Validate(md.ID, fieldPtrs, data); err != nil {
-			errorReport = append(errorReport, fmt.Sprintf("Row %d: Validation error: %v", rowIndex, err))
-			continue
+		if err := w.Write(record); err != nil {
+			return err
 		}
+	}
+	totals := make([]string, width)
+	totals[0] = "合计"
+	for _, v := range append(result.Totals, result.GrandTotal) {
+		totals = append(totals, pivotText(v))
+	}
+	if err := w.Write(totals); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
 
-		batchData = append(batchData, data)
-
-		if len(batchData) >= batchSize {
-			_ = processBatch()
-		}
+// pivotText 将透视结果中的值转换为 CSV 文本，空值输出为空字符串
+func pivotText(v any) string {
+	if v == nil {
+		return ""
 	}
+	return fmt.Sprintf("%v", v)
+}
 
-	// Process remaining
-	_ = processBatch()
+// ImportFromJSON 导入 JSON 数组
+func (s *dataIOService) ImportFromJSON(ctx context.Context, modelID string, reader io.Reader) (int, []string, error) {
+	return s.importNow(ctx, modelID, reader, ImportOptions{Format: ImportFormatJSON})
+}
 
-	if _, err := decoder.Token(); err != nil {
-		// End of array
+// readJSONRecords 读取 JSON 数组中的对象，非对象元素记为行错误
+func readJSONRecords(data []byte) ([]*importRecord, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("JSON 文件须为数组")
 	}
 
-	return successCount, errorReport, nil
+	var records []*importRecord
+	for row := 1; decoder.More(); row++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("解析 JSON 文件失败: %w", err)
+		}
+		r := &importRecord{row: row}
+		if err := json.Unmarshal(raw, &r.data); err != nil || r.data == nil {
+			r.err = "数据须为 JSON 对象"
+		}
+		records = append(records, r)
+	}
+	return records, nil
 }
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/repository"
+	"metadata-platform/internal/utils"
+)
+
+// newTestDataIOService 创建基于内存数据库的导入导出服务，test_staff 的 name 唯一，status 为带字典选项的整数
+func newTestDataIOService(t *testing.T) (*dataIOService, *gorm.DB) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	// 元数据库使用文件，后台任务与测试通过不同连接访问
+	metaDB, _ := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "meta.db")+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_staff (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE, status INTEGER, dept TEXT)")
+	targetDB.Exec("INSERT INTO test_staff (name, status, dept) VALUES ('张三', 1, 'hr'), ('李四', 0, 'it'), ('王五', 1, 'it')")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_io"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+	svc := NewDataIOService(crud, modelRepo, repository.NewMdModelFieldRepository(metaDB),
+		repository.NewMdModelFieldEnhancementRepository(metaDB), repository.NewMdExportJobRepository(metaDB), repository.NewMdImportJobRepository(metaDB), NewDataValidator()).(*dataIOService)
+
+	migrateModelConfig(metaDB)
+	metaDB.AutoMigrate(&model.MdExportJob{}, &model.MdImportJob{})
+	utils.InitFileStore(utils.NewLocalFileStore(t.TempDir()))
+	t.Cleanup(func() { utils.InitFileStore(utils.NewLocalFileStore(utils.DefaultFileStoreDir)) })
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: "m_staff", TableNameStr: "test_staff", IsMain: true, ConnID: connID})
+	for i, column := range []string{"id", "name", "status", "dept"} {
+		fieldType := "string"
+		if column == "id" || column == "status" {
+			fieldType = "integer"
+		}
+		metaDB.Create(&model.MdModelField{ID: "f_" + column, ModelID: "m_staff", ColumnName: column, FieldType: fieldType, IsPrimaryKey: i == 0, IsAutoIncrement: i == 0, IsNullable: true})
+	}
+	metaDB.Create(&model.MdModelFieldEnhancement{ID: "e1", ModelID: "m_staff", FieldID: "f_name", DisplayName: "姓名"})
+	metaDB.Create(&model.MdModelFieldEnhancement{ID: "e2", ModelID: "m_staff", FieldID: "f_status", DisplayName: "状态",
+		ComponentConfig: `{"options":[{"label":"启用","value":1},{"label":"停用","value":0}]}`})
+	modelRepo.On("GetModelByID", "m_staff").Return(&model.MdModel{ID: "m_staff", ConnID: connID}, nil)
+	return svc, targetDB
+}
+
+func TestDataIOService_Tabular(t *testing.T) {
+	svc, targetDB := newTestDataIOService(t)
+	ctx := context.Background()
+
+	t.Run("Export CSV with BOM or GBK", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, svc.ExportToCSV(ctx, "m_staff", nil, "", &buf))
+		assert.Equal(t, "\xEF\xBB\xBFid,姓名,状态,dept\n1,张三,启用,hr\n2,李四,停用,it\n3,王五,启用,it\n", buf.String())
+
+		buf.Reset()
+		require.NoError(t, svc.ExportToCSV(ctx, "m_staff", nil, CSVEncodingGBK, &buf))
+		decoded, err := simplifiedchinese.GBK.NewDecoder().String(buf.String())
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(decoded, "id,姓名,状态,dept\n1,张三,启用,hr\n"))
+
+		assert.Error(t, svc.ExportToCSV(ctx, "m_staff", nil, "latin1", &buf))
+	})
+
+	t.Run("Export Parquet with typed schema", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, svc.ExportToParquet(ctx, "m_staff", nil, utils.ParquetOptions{Compression: utils.ParquetGzip, RowGroupSize: 2}, &buf))
+		content := buf.Bytes()
+		assert.Equal(t, "PAR1", string(content[:4]))
+		assert.Equal(t, "PAR1", string(content[len(content)-4:]))
+		footer := content[len(content)-8-int(binary.LittleEndian.Uint32(content[len(content)-8:])) : len(content)-8]
+		for _, column := range []string{"id", "name", "status", "dept"} {
+			assert.True(t, bytes.Contains(footer, []byte(column)), column)
+		}
+
+		_, columns, err := svc.loadColumns("m_staff", false)
+		require.NoError(t, err)
+		types := make([]utils.ParquetType, len(columns))
+		for i, c := range columns {
+			types[i] = parquetType(c)
+		}
+		assert.Equal(t, []utils.ParquetType{utils.ParquetInt64, utils.ParquetString, utils.ParquetInt64, utils.ParquetString}, types)
+
+		assert.Error(t, svc.ExportToParquet(ctx, "m_staff", nil, utils.ParquetOptions{Compression: "lz4"}, &buf))
+	})
+
+	t.Run("Export Excel across sheets", func(t *testing.T) {
+		svc.sheetRows = 2
+		defer func() { svc.sheetRows = MaxExcelSheetRows }()
+
+		var buf bytes.Buffer
+		require.NoError(t, svc.ExportToExcel(ctx, "m_staff", map[string]any{}, &buf))
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		defer f.Close()
+		assert.Equal(t, []string{"Sheet1", "Sheet2"}, f.GetSheetList())
+		rows, err := f.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"id", "姓名", "状态", "dept"}, {"1", "张三", "启用", "hr"}, {"2", "李四", "停用", "it"}}, rows)
+		rows, err = f.GetRows("Sheet2")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"id", "姓名", "状态", "dept"}, {"3", "王五", "启用", "it"}}, rows)
+	})
+
+	t.Run("Excel template with dropdowns round-trips through import", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, svc.GenerateExcelTemplate("m_staff", &buf))
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		defer f.Close()
+		headers, err := f.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"姓名", "状态", "dept"}}, headers)
+		validations, err := f.GetDataValidations("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, validations, 1)
+		assert.Equal(t, `"启用,停用"`, validations[0].Formula1)
+
+		require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]any{"赵六", "停用", "ops"}))
+		require.NoError(t, f.SetSheetRow("Sheet1", "A4", &[]any{"钱七", "启用"}))
+		var upload bytes.Buffer
+		require.NoError(t, f.Write(&upload))
+
+		success, errs, err := svc.ImportFromExcel(ctx, "m_staff", &upload)
+		require.NoError(t, err)
+		assert.Equal(t, 2, success)
+		assert.Empty(t, errs)
+		var status int
+		targetDB.Raw("SELECT status FROM test_staff WHERE name = '赵六'").Scan(&status)
+		assert.Equal(t, 0, status)
+	})
+
+	t.Run("Import GBK CSV with detection", func(t *testing.T) {
+		content, err := simplifiedchinese.GBK.NewEncoder().String("姓名,状态,备注\n孙八,启用,x\n")
+		require.NoError(t, err)
+
+		success, errs, err := svc.ImportFromCSV(ctx, "m_staff", "", strings.NewReader(content))
+		require.NoError(t, err)
+		assert.Equal(t, 1, success)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0], "备注")
+
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_staff WHERE name = '孙八' AND status = 1").Scan(&count)
+		assert.EqualValues(t, 1, count)
+	})
+}
+
+func TestDataIOService_ImportJob(t *testing.T) {
+	svc, targetDB := newTestDataIOService(t)
+	ctx := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", All: true})
+
+	wait := func(t *testing.T, id string) *ImportJob {
+		var job *ImportJob
+		require.Eventually(t, func() bool {
+			var err error
+			job, err = svc.GetImportJob(ctx, "m_staff", id)
+			require.NoError(t, err)
+			return job.FinishedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+
+	t.Run("Dry run reports cell errors without writing", func(t *testing.T) {
+		content := "姓名,状态\n张三,停用\n赵六,abc\n钱七,启用\n"
+		job, err := svc.StartImport(ctx, "m_staff", []byte(content), ImportOptions{Format: ImportFormatCSV, Strategy: ImportUpsert, KeyFields: []string{"name"}, DryRun: true})
+		require.NoError(t, err)
+		job = wait(t, job.ID)
+
+		assert.Equal(t, ImportSucceeded, job.Status)
+		assert.Equal(t, 3, job.Total)
+		assert.Equal(t, 3, job.Processed)
+		assert.Equal(t, 1, job.Inserted)
+		assert.Equal(t, 1, job.Updated)
+		assert.Equal(t, 1, job.Failed)
+		require.Len(t, job.Errors, 1)
+		assert.Equal(t, 3, job.Errors[0].Row)
+		assert.Equal(t, "状态", job.Errors[0].Column)
+
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_staff WHERE name IN ('赵六', '钱七') OR status = 0 AND name = '张三'").Scan(&count)
+		assert.EqualValues(t, 0, count)
+
+		var report bytes.Buffer
+		format, err := svc.ImportErrorReport(ctx, "m_staff", job.ID, &report)
+		require.NoError(t, err)
+		assert.Equal(t, ImportFormatCSV, format)
+		assert.Equal(t, "\xEF\xBB\xBF姓名,状态,错误信息\n赵六,abc,[状态] 字段 'status' 格式不正确，应为数值\n", report.String())
+	})
+
+	t.Run("Excel upsert with annotated error report", func(t *testing.T) {
+		f := excelize.NewFile()
+		require.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]any{"姓名", "状态", "dept"}))
+		require.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]any{"张三", "停用", "ops"}))
+		require.NoError(t, f.SetSheetRow("Sheet1", "A3", &[]any{"周九", "启用"}))
+		require.NoError(t, f.SetSheetRow("Sheet1", "A4", &[]any{"吴十", "未知"}))
+		var upload bytes.Buffer
+		require.NoError(t, f.Write(&upload))
+		f.Close()
+
+		job, err := svc.StartImport(ctx, "m_staff", upload.Bytes(), ImportOptions{Format: ImportFormatExcel, Strategy: ImportUpsert, KeyFields: []string{"name"}})
+		require.NoError(t, err)
+		job = wait(t, job.ID)
+		assert.Equal(t, ImportSucceeded, job.Status)
+		assert.Equal(t, []int{1, 1, 1}, []int{job.Inserted, job.Updated, job.Failed})
+
+		var row struct {
+			Status int
+			Dept   string
+		}
+		targetDB.Raw("SELECT status, dept FROM test_staff WHERE name = '张三'").Scan(&row)
+		assert.Equal(t, 0, row.Status)
+		assert.Equal(t, "ops", row.Dept)
+
+		var report bytes.Buffer
+		_, err = svc.ImportErrorReport(ctx, "m_staff", job.ID, &report)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(&report)
+		require.NoError(t, err)
+		defer out.Close()
+		header, _ := out.GetCellValue("Sheet1", "D1")
+		assert.Equal(t, "错误信息", header)
+		message, _ := out.GetCellValue("Sheet1", "D4")
+		assert.Contains(t, message, "[状态]")
+		comments, err := out.GetComments("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.Equal(t, "B4", comments[0].Cell)
+	})
+
+	t.Run("JSON skip existing and job access", func(t *testing.T) {
+		content := `[{"name": "李四", "status": 1}, {"name": "郑十一"}, 5]`
+		job, err := svc.StartImport(ctx, "m_staff", []byte(content), ImportOptions{Format: ImportFormatJSON, Strategy: ImportSkipExisting, KeyFields: []string{"name"}})
+		require.NoError(t, err)
+		job = wait(t, job.ID)
+		assert.Equal(t, []int{1, 1, 1}, []int{job.Inserted, job.Skipped, job.Failed})
+
+		var status int
+		targetDB.Raw("SELECT status FROM test_staff WHERE name = '李四'").Scan(&status)
+		assert.Equal(t, 0, status)
+
+		var report bytes.Buffer
+		_, err = svc.ImportErrorReport(ctx, "m_staff", job.ID, &report)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"row": 3, "data": null, "errors": [{"row": 3, "error": "数据须为 JSON 对象"}]}]`, report.String())
+
+		assert.Error(t, svc.CancelImportJob(ctx, "m_staff", job.ID))
+		other := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u2", All: true})
+		_, err = svc.GetImportJob(other, "m_staff", job.ID)
+		assert.Error(t, err)
+		_, err = svc.GetImportJob(ctx, "m_other", job.ID)
+		assert.Error(t, err)
+
+		// 未登录时不能访问任何导入任务
+		for _, anonymous := range []context.Context{context.Background(), engine.WithDataScope(context.Background(), &engine.DataScope{Deny: true})} {
+			_, err = svc.GetImportJob(anonymous, "m_staff", job.ID)
+			assert.Error(t, err)
+			assert.Error(t, svc.CancelImportJob(anonymous, "m_staff", job.ID))
+			_, err = svc.ImportErrorReport(anonymous, "m_staff", job.ID, io.Discard)
+			assert.Error(t, err)
+			_, err = svc.StartImport(anonymous, "m_staff", []byte(content), ImportOptions{Format: ImportFormatJSON})
+			assert.Error(t, err)
+		}
+
+		_, err = svc.StartImport(ctx, "m_staff", []byte(content), ImportOptions{Format: ImportFormatJSON, Strategy: ImportUpsert, KeyFields: []string{"missing"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Jobs are persisted and visible to other instances", func(t *testing.T) {
+		content := "姓名,状态\n冯十二,未知\n"
+		job, err := svc.StartImport(ctx, "m_staff", []byte(content), ImportOptions{Format: ImportFormatCSV})
+		require.NoError(t, err)
+		job = wait(t, job.ID)
+
+		instance := NewDataIOService(svc.crudSvc, svc.modelRepo, svc.modelFieldRepo, svc.enhancementRepo, svc.exportJobRepo, svc.importJobRepo, svc.validator)
+		loaded, err := instance.GetImportJob(ctx, "m_staff", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, job.Failed, loaded.Failed)
+		assert.Equal(t, job.Errors, loaded.Errors)
+		var report bytes.Buffer
+		_, err = instance.ImportErrorReport(ctx, "m_staff", job.ID, &report)
+		require.NoError(t, err)
+		assert.Contains(t, report.String(), "冯十二,未知,[状态]")
+
+		// 其他实例取消的任务在保存进度时结束
+		j := newImportJob("m_staff", nil, ImportOptions{Format: ImportFormatJSON})
+		require.NoError(t, svc.importJobRepo.CreateJob(&model.MdImportJob{ID: j.job.ID, ModelID: "m_staff", Status: ImportCanceled, CreateID: "u1"}))
+		assert.ErrorIs(t, svc.saveImportProgress(j), errImportCanceled)
+	})
+}
+
+func TestDataIOService_ExportJob(t *testing.T) {
+	svc, _ := newTestDataIOService(t)
+	utils.InitFileStore(utils.NewLocalFileStore(t.TempDir()))
+	utils.InitURLSigner("export-test-key")
+	defer func() {
+		utils.InitFileStore(utils.NewLocalFileStore(utils.DefaultFileStoreDir))
+		utils.InitURLSigner("")
+	}()
+	ctx := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", All: true})
+	other := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u2", All: true})
+
+	wait := func(t *testing.T, id string) *ExportJob {
+		var job *ExportJob
+		require.Eventually(t, func() bool {
+			var err error
+			job, err = svc.GetExportJob(ctx, id)
+			require.NoError(t, err)
+			return job.FinishedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+	download := func(t *testing.T, job *ExportJob) string {
+		link, err := url.Parse(job.DownloadURL)
+		require.NoError(t, err)
+		_, file, err := svc.OpenExportFile(job.ID, link.Query().Get("expires"), link.Query().Get("signature"))
+		require.NoError(t, err)
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("Export NDJSON with progress and signed link", func(t *testing.T) {
+		job, err := svc.StartExport(ctx, "m_staff", map[string]any{"page": 2}, ExportOptions{Format: "NDJSON"})
+		require.NoError(t, err)
+		assert.Equal(t, ExportPending, job.Status)
+		assert.Empty(t, job.DownloadURL)
+
+		job = wait(t, job.ID)
+		assert.Equal(t, ExportSucceeded, job.Status)
+		assert.EqualValues(t, 3, job.Total)
+		assert.EqualValues(t, 3, job.Processed)
+		assert.Equal(t, "{}", job.Params)
+		assert.True(t, strings.HasPrefix(job.DownloadURL, "/api/data/exports/"+job.ID+"/download?"))
+
+		content := download(t, job)
+		assert.EqualValues(t, len(content), job.FileSize)
+		lines := strings.Split(strings.TrimSpace(content), "\n")
+		require.Len(t, lines, 3)
+		assert.Contains(t, lines[0], `"name":"张三"`)
+
+		link, _ := url.Parse(job.DownloadURL)
+		_, _, err = svc.OpenExportFile(job.ID, link.Query().Get("expires"), "bad")
+		assert.Error(t, err)
+		_, err = svc.GetExportJob(other, job.ID)
+		assert.Error(t, err)
+
+		// 未登录时不能访问任何导出任务
+		for _, anonymous := range []context.Context{context.Background(), engine.WithDataScope(context.Background(), &engine.DataScope{Deny: true})} {
+			_, err = svc.GetExportJob(anonymous, job.ID)
+			assert.Error(t, err)
+			assert.Error(t, svc.DeleteExportJob(anonymous, job.ID))
+			_, err = svc.ListExportJobs(anonymous)
+			assert.Error(t, err)
+			_, err = svc.StartExport(anonymous, "m_staff", nil, ExportOptions{Format: ExportFormatCSV})
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("Export CSV listed per user and expired", func(t *testing.T) {
+		job, err := svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: ExportFormatCSV, Encoding: CSVEncodingUTF8})
+		require.NoError(t, err)
+		job = wait(t, job.ID)
+		assert.True(t, strings.HasPrefix(download(t, job), "\xEF\xBB\xBFid,姓名,状态,dept\n"))
+
+		jobs, err := svc.ListExportJobs(ctx)
+		require.NoError(t, err)
+		assert.Len(t, jobs, 2)
+		jobs, err = svc.ListExportJobs(other)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		// 过期后任务与文件一并清理
+		require.NoError(t, svc.exportJobRepo.UpdateJob(job.ID, map[string]any{"expire_at": time.Now().Add(-time.Minute)}))
+		jobs, err = svc.ListExportJobs(ctx)
+		require.NoError(t, err)
+		assert.Len(t, jobs, 1)
+		_, err = utils.GetFileStore().Open(job.FileName)
+		assert.Error(t, err)
+
+		require.NoError(t, svc.DeleteExportJob(ctx, jobs[0].ID))
+		jobs, err = svc.ListExportJobs(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("Export Parquet job", func(t *testing.T) {
+		job, err := svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: ExportFormatParquet, Compression: "ZSTD", RowGroupSize: 1})
+		require.NoError(t, err)
+		assert.Equal(t, utils.ParquetZstd, job.Compression)
+		job = wait(t, job.ID)
+		assert.Equal(t, ExportSucceeded, job.Status)
+		assert.EqualValues(t, 3, job.Processed)
+		assert.True(t, strings.HasPrefix(download(t, job), "PAR1"))
+	})
+
+	t.Run("Reject invalid options", func(t *testing.T) {
+		_, err := svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: "pdf"})
+		assert.Error(t, err)
+		_, err = svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: ExportFormatCSV, Encoding: "latin1"})
+		assert.Error(t, err)
+		_, err = svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: ExportFormatParquet, Compression: "lz4"})
+		assert.Error(t, err)
+
+		// 未配置签名密钥时拒绝创建导出任务
+		utils.InitURLSigner("")
+		defer utils.InitURLSigner("export-test-key")
+		_, err = svc.StartExport(ctx, "m_staff", nil, ExportOptions{Format: ExportFormatCSV})
+		assert.ErrorContains(t, err, "EXPORT_SIGN_KEY")
+	})
+}
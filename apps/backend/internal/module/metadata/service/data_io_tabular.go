@@ -0,0 +1,506 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+)
+
+// CSV 文件编码
+const (
+	CSVEncodingUTF8 = "utf-8" // 导出时带 BOM，便于 Excel 识别
+	CSVEncodingGBK  = "gbk"
+)
+
+// 导入导出规模
+const (
+	MaxExcelSheetRows = 1000000 // 单个工作表最多的数据行数，超出后写入新的工作表
+	exportBatchSize   = 1000
+	importBatchSize   = 1000
+)
+
+// ioOptionSheet 模板中存放较长下拉选项的隐藏工作表
+const ioOptionSheet = "_options"
+
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// ioColumn 导入导出的列
+type ioColumn struct {
+	field   *model.MdModelField
+	key     string     // 查询结果中的列名
+	title   string     // 表头，依次取增强配置的显示名称、字段显示名称、列标题、列名
+	options []ioOption // 字典选项，导出时值转为标签，导入时标签转为值
+	masked  bool       // 配置了脱敏策略
+}
+
+// ioOption 字典选项，来自字段增强配置 component_config 中的 options
+type ioOption struct {
+	Label string `json:"label"`
+	Value any    `json:"value"`
+}
+
+// loadColumns 加载模型的导入导出列，writable 为 true 时排除自增和计算字段
+func (s *dataIOService) loadColumns(modelID string, writable bool) (*model.MdModel, []*ioColumn, error) {
+	md, err := s.modelRepo.GetModelByID(modelID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	fields, err := s.modelFieldRepo.GetFieldsByModelID(modelID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载模型字段失败: %w", err)
+	}
+	enhancements := make(map[string]model.MdModelFieldEnhancement)
+	if s.enhancementRepo != nil {
+		list, err := s.enhancementRepo.GetEnhancementsByModelID(modelID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载字段增强配置失败: %w", err)
+		}
+		for _, e := range list {
+			if !e.IsDeleted {
+				enhancements[e.FieldID] = e
+			}
+		}
+	}
+
+	var columns []*ioColumn
+	for i := range fields {
+		f := &fields[i]
+		if writable && (f.IsAutoIncrement || f.Func != "" || f.AggFunc != "") {
+			continue
+		}
+		column := &ioColumn{field: f, key: engine.ResultColumn(f)}
+		enh := enhancements[f.ID]
+		column.masked = enh.MaskType != ""
+		for _, title := range []string{enh.DisplayName, f.ShowTitle, f.ColumnTitle, f.ColumnName} {
+			if title != "" {
+				column.title = title
+				break
+			}
+		}
+		if enh.ComponentConfig != "" {
+			var config struct {
+				Options []ioOption `json:"options"`
+			}
+			if err := json.Unmarshal([]byte(enh.ComponentConfig), &config); err == nil {
+				column.options = config.Options
+			}
+		}
+		columns = append(columns, column)
+	}
+	return md, columns, nil
+}
+
+// ioHeaders 返回表头
+func ioHeaders(columns []*ioColumn) []string {
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.title
+	}
+	return headers
+}
+
+// exportValue 转换导出的单元格值，字典值转为标签
+func (c *ioColumn) exportValue(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		v = string(val)
+	case time.Time:
+		return val.Format(time.DateTime)
+	}
+	for _, o := range c.options {
+		if fmt.Sprintf("%v", o.Value) == fmt.Sprintf("%v", v) {
+			return o.Label
+		}
+	}
+	return v
+}
+
+// importValue 转换导入的单元格值，字典标签转为值
+func (c *ioColumn) importValue(text string) any {
+	for _, o := range c.options {
+		if o.Label == text {
+			return o.Value
+		}
+	}
+	return text
+}
+
+// exportProgress 导出进度回调，total 为数据总数，processed 为已读取的条数
+type exportProgress func(total, processed int64)
+
+// eachBatch 流式读取模型数据，每批之间检查是否已取消
+func (s *dataIOService) eachBatch(ctx context.Context, modelID string, queryParams map[string]any, progress exportProgress, fn func([]map[string]any) error) error {
+	var processed int64
+	return s.crudSvc.Stream(ctx, modelID, queryParams, exportBatchSize, func(total int64, list []map[string]any) error {
+		if err := fn(list); err != nil {
+			return err
+		}
+		processed += int64(len(list))
+		if progress != nil {
+			progress(total, processed)
+		}
+		return nil
+	})
+}
+
+// ExportToExcel 导出 Excel，数据超过单个工作表的行数上限时写入多个工作表
+func (s *dataIOService) ExportToExcel(ctx context.Context, modelID string, queryParams map[string]any, writer io.Writer) error {
+	return s.writeExcel(ctx, modelID, queryParams, writer, nil)
+}
+
+// writeExcel 导出 Excel 并报告进度
+func (s *dataIOService) writeExcel(ctx context.Context, modelID string, queryParams map[string]any, writer io.Writer, progress exportProgress) error {
+	_, columns, err := s.loadColumns(modelID, false)
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	headers := make([]any, len(columns))
+	for i, c := range columns {
+		headers[i] = c.title
+	}
+
+	var sw *excelize.StreamWriter
+	sheets, row := 0, 0
+	nextSheet := func() error {
+		if sw != nil {
+			if err := sw.Flush(); err != nil {
+				return err
+			}
+		}
+		sheets++
+		name := fmt.Sprintf("Sheet%d", sheets)
+		if sheets > 1 {
+			if _, err := f.NewSheet(name); err != nil {
+				return err
+			}
+		}
+		if sw, err = f.NewStreamWriter(name); err != nil {
+			return err
+		}
+		row = 1
+		return sw.SetRow("A1", headers, excelize.RowOpts{StyleID: headerStyle})
+	}
+	if err := nextSheet(); err != nil {
+		return err
+	}
+
+	err = s.eachBatch(ctx, modelID, queryParams, progress, func(list []map[string]any) error {
+		for _, item := range list {
+			if row > s.sheetRows {
+				if err := nextSheet(); err != nil {
+					return err
+				}
+			}
+			row++
+			values := make([]any, len(columns))
+			for i, c := range columns {
+				values[i] = c.exportValue(item[c.key])
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, row)
+			if err := sw.SetRow(cell, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	return f.Write(writer)
+}
+
+// ExportToCSV 导出 CSV，UTF-8 编码时写入 BOM
+func (s *dataIOService) ExportToCSV(ctx context.Context, modelID string, queryParams map[string]any, encoding string, writer io.Writer) error {
+	return s.writeCSV(ctx, modelID, queryParams, encoding, writer, nil)
+}
+
+// writeCSV 导出 CSV 并报告进度
+func (s *dataIOService) writeCSV(ctx context.Context, modelID string, queryParams map[string]any, encoding string, writer io.Writer, progress exportProgress) error {
+	_, columns, err := s.loadColumns(modelID, false)
+	if err != nil {
+		return err
+	}
+	w, err := csvWriter(writer, encoding)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(ioHeaders(columns)); err != nil {
+		return err
+	}
+
+	err = s.eachBatch(ctx, modelID, queryParams, progress, func(list []map[string]any) error {
+		record := make([]string, len(columns))
+		for _, item := range list {
+			for i, c := range columns {
+				record[i] = fmt.Sprintf("%v", c.exportValue(item[c.key]))
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvWriter 按编码创建 CSV 写入器
+func csvWriter(writer io.Writer, encoding string) (*csv.Writer, error) {
+	switch strings.ToLower(encoding) {
+	case "", CSVEncodingUTF8:
+		if _, err := writer.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+		return csv.NewWriter(writer), nil
+	case CSVEncodingGBK:
+		return csv.NewWriter(simplifiedchinese.GBK.NewEncoder().Writer(writer)), nil
+	default:
+		return nil, fmt.Errorf("不支持的文件编码: %s", encoding)
+	}
+}
+
+// GenerateExcelTemplate 生成 Excel 导入模板，字典字段提供下拉选项
+func (s *dataIOService) GenerateExcelTemplate(modelID string, writer io.Writer) error {
+	_, columns, err := s.loadColumns(modelID, true)
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	const sheet = "Sheet1"
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	optionColumns := 0
+	for i, c := range columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		if err := f.SetCellValue(sheet, cell, c.title); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, cell, cell, headerStyle); err != nil {
+			return err
+		}
+		if len(c.options) == 0 {
+			continue
+		}
+
+		// 下拉选项总长度超过 Excel 限制时引用隐藏工作表中的选项
+		labels := make([]string, len(c.options))
+		for j, o := range c.options {
+			labels[j] = o.Label
+		}
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		dv := excelize.NewDataValidation(true)
+		dv.SetSqref(fmt.Sprintf("%s2:%s%d", name, name, excelize.TotalRows))
+		if err := dv.SetDropList(labels); err != nil {
+			if optionColumns == 0 {
+				if _, err := f.NewSheet(ioOptionSheet); err != nil {
+					return err
+				}
+				if err := f.SetSheetVisible(ioOptionSheet, false); err != nil {
+					return err
+				}
+			}
+			optionColumns++
+			optionName, _ := excelize.ColumnNumberToName(optionColumns)
+			for j, label := range labels {
+				if err := f.SetCellValue(ioOptionSheet, fmt.Sprintf("%s%d", optionName, j+1), label); err != nil {
+					return err
+				}
+			}
+			dv.SetSqrefDropList(fmt.Sprintf("'%s'!$%s$1:$%s$%d", ioOptionSheet, optionName, optionName, len(labels)))
+		}
+		if err := f.AddDataValidation(sheet, dv); err != nil {
+			return err
+		}
+	}
+	return f.Write(writer)
+}
+
+// GenerateCSVTemplate 生成 CSV 导入模板
+func (s *dataIOService) GenerateCSVTemplate(modelID string, encoding string, writer io.Writer) error {
+	_, columns, err := s.loadColumns(modelID, true)
+	if err != nil {
+		return err
+	}
+	w, err := csvWriter(writer, encoding)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(ioHeaders(columns)); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ImportFromExcel 导入 Excel，读取全部可见工作表，每个工作表首行为表头
+func (s *dataIOService) ImportFromExcel(ctx context.Context, modelID string, reader io.Reader) (int, []string, error) {
+	return s.importNow(ctx, modelID, reader, ImportOptions{Format: ImportFormatExcel})
+}
+
+// ImportFromCSV 导入 CSV，未指定编码时去除 UTF-8 BOM，内容不是合法 UTF-8 时按 GBK 解码
+func (s *dataIOService) ImportFromCSV(ctx context.Context, modelID string, encoding string, reader io.Reader) (int, []string, error) {
+	return s.importNow(ctx, modelID, reader, ImportOptions{Format: ImportFormatCSV, Encoding: encoding})
+}
+
+// importRecord 导入文件中的一行
+type importRecord struct {
+	sheet string
+	row   int                   // 文件中的行号，JSON 为数组中的序号，均从 1 开始
+	data  map[string]any        // 按列名转换后的数据
+	cells map[string]importCell // 列名对应的单元格，JSON 为空
+	err   string                // 行解析错误
+}
+
+// importCell 数据列在文件中的位置
+type importCell struct {
+	index  int
+	header string
+}
+
+// readExcelRecords 读取 Excel 全部可见工作表的数据行
+func readExcelRecords(data []byte, columns []*ioColumn) ([]*importRecord, []ImportRowError, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 Excel 文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var records []*importRecord
+	var headerErrors []ImportRowError
+	for _, sheet := range f.GetSheetList() {
+		if visible, _ := f.GetSheetVisible(sheet); !visible {
+			continue
+		}
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取工作表 %s 失败: %w", sheet, err)
+		}
+		list, errs := tabularRecords(sheet, rows, columns)
+		records = append(records, list...)
+		headerErrors = append(headerErrors, errs...)
+	}
+	return records, headerErrors, nil
+}
+
+// readCSVRecords 按编码解码后读取 CSV 的数据行
+func readCSVRecords(data []byte, encoding string, columns []*ioColumn) ([]*importRecord, []ImportRowError, error) {
+	rows, err := readCSVRows(data, encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, headerErrors := tabularRecords("", rows, columns)
+	return records, headerErrors, nil
+}
+
+// readCSVRows 解码并解析 CSV，未指定编码时去除 UTF-8 BOM，内容不是合法 UTF-8 时按 GBK 解码
+func readCSVRows(data []byte, encoding string) ([][]string, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	var err error
+	switch strings.ToLower(encoding) {
+	case "":
+		if utf8.Valid(data) {
+			break
+		}
+		fallthrough
+	case CSVEncodingGBK:
+		if data, err = simplifiedchinese.GBK.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("CSV 文件解码失败: %w", err)
+		}
+	case CSVEncodingUTF8:
+	default:
+		return nil, fmt.Errorf("不支持的文件编码: %s", encoding)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 CSV 文件失败: %w", err)
+		}
+		rows = append(rows, record)
+	}
+}
+
+// tabularRecords 按首行表头匹配列并转换数据行，空行跳过，空单元格不写入以便使用默认值
+func tabularRecords(sheet string, rows [][]string, columns []*ioColumn) ([]*importRecord, []ImportRowError) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	// 1. 表头可使用显示名称、字段显示名称、列标题或列名，未识别的列忽略并记录
+	headers := rows[0]
+	mapping := make([]*ioColumn, len(headers))
+	var headerErrors []ImportRowError
+	for i, h := range headers {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		for _, c := range columns {
+			if h == c.title || h == c.field.ShowTitle || h == c.field.ColumnTitle || h == c.field.ColumnName {
+				mapping[i] = c
+				break
+			}
+		}
+		if mapping[i] == nil {
+			headerErrors = append(headerErrors, ImportRowError{Sheet: sheet, Row: 1, Column: h, Error: "未识别的列，已忽略", cell: i})
+		}
+	}
+
+	// 2. 转换数据行
+	var records []*importRecord
+	for line, record := range rows[1:] {
+		r := &importRecord{sheet: sheet, row: line + 2, data: make(map[string]any), cells: make(map[string]importCell)}
+		for i, c := range mapping {
+			if c == nil {
+				continue
+			}
+			r.cells[c.field.ColumnName] = importCell{index: i, header: strings.TrimSpace(headers[i])}
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				r.data[c.field.ColumnName] = c.importValue(record[i])
+			}
+		}
+		if len(r.data) > 0 {
+			records = append(records, r)
+		}
+	}
+	return records, headerErrors
+}
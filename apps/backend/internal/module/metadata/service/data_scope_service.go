@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	userModel "metadata-platform/internal/module/user/model"
+)
+
+// DataScopeService 数据权限解析服务接口
+type DataScopeService interface {
+	// Resolve 解析用户在指定接口上的数据权限及角色/权限编码，菜单上配置的数据范围优先于角色/岗位
+	// 租户取自用户记录，tenantID 为请求指定的租户，与用户所属租户不一致时返回错误
+	Resolve(userID, tenantID, path, method string) (*engine.DataScope, error)
+}
+
+type dataScopeService struct {
+	db *gorm.DB
+}
+
+// NewDataScopeService 创建数据权限解析服务实例，db 为用户库连接
+func NewDataScopeService(db *gorm.DB) DataScopeService {
+	return &dataScopeService{db: db}
+}
+
+// dataRange 数据范围配置
+type dataRange struct {
+	Range string
+	Scope string
+}
+
+// Resolve 解析数据权限
+func (s *dataScopeService) Resolve(userID, tenantID, path, method string) (*engine.DataScope, error) {
+	scope := &engine.DataScope{UserID: userID, TenantID: tenantID}
+	if s.db == nil {
+		scope.All = true
+		return scope, nil
+	}
+
+	// 1. 用户所属租户
+	var user userModel.SsoUser
+	if err := s.db.Select("id", "tenant_id").Where("id = ? AND is_deleted = ?", userID, false).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if tenantID != "" && tenantID != user.TenantID {
+		return nil, fmt.Errorf("用户 %s 不属于租户 %s", userID, tenantID)
+	}
+	scope.TenantID = user.TenantID
+
+	// 2. 用户角色 (直接授予 + 岗位授予)
+	roleIDs, posRanges, err := s.userRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 数据范围：接口对应菜单上的配置优先
+	menus, err := s.roleMenus(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	var ranges []dataRange
+	for _, m := range menus {
+		scope.Perms = append(scope.Perms, m.MenuCode)
+		if path != "" && m.URL == path && (m.Method == "" || strings.EqualFold(m.Method, method)) {
+			ranges = append(ranges, dataRange{Range: m.DataRange, Scope: m.DataScope})
+		}
+	}
+	roles, err := s.activeRoles(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		scope.Roles = append(scope.Roles, r.RoleCode)
+	}
+	if len(ranges) == 0 {
+		for _, r := range roles {
+			ranges = append(ranges, dataRange{Range: r.DataRange, Scope: r.DataScope})
+		}
+		ranges = append(ranges, posRanges...)
+	}
+
+	// 4. 未分配任何数据范围时仅可访问本人数据
+	if len(ranges) == 0 {
+		scope.Self = true
+		return scope, nil
+	}
+
+	// 5. 合并各数据范围
+	var userOrgs []string
+	orgSet := make(map[string]struct{})
+	addOrgs := func(ids []string) {
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			if _, ok := orgSet[id]; !ok {
+				orgSet[id] = struct{}{}
+				scope.OrgIDs = append(scope.OrgIDs, id)
+			}
+		}
+	}
+	for _, r := range ranges {
+		switch r.Range {
+		case engine.DataRangeAll:
+			scope.All = true
+		case engine.DataRangeCustom:
+			addOrgs(strings.Split(r.Scope, ","))
+		case engine.DataRangeOrg, engine.DataRangeOrgAndSub:
+			if userOrgs == nil {
+				if userOrgs, err = s.userOrgs(userID); err != nil {
+					return nil, err
+				}
+			}
+			if r.Range == engine.DataRangeOrg {
+				addOrgs(userOrgs)
+			} else {
+				subOrgs, err := s.descendantOrgs(userOrgs)
+				if err != nil {
+					return nil, err
+				}
+				addOrgs(subOrgs)
+			}
+		case engine.DataRangeSelf:
+			scope.Self = true
+		}
+	}
+
+	return scope, nil
+}
+
+// userRoles 查询用户的有效角色ID及所在岗位的数据范围
+func (s *dataScopeService) userRoles(userID string) ([]string, []dataRange, error) {
+	var roleIDs []string
+	if err := s.db.Model(&userModel.SsoUserRole{}).
+		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询用户角色失败: %w", err)
+	}
+
+	var posIDs []string
+	if err := s.db.Model(&userModel.SsoUserPos{}).
+		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Pluck("pos_id", &posIDs).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询用户岗位失败: %w", err)
+	}
+	if len(posIDs) == 0 {
+		return roleIDs, nil, nil
+	}
+
+	var positions []userModel.SsoPos
+	if err := s.db.Where("id IN ? AND status = ? AND is_deleted = ?", posIDs, 1, false).
+		Find(&positions).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询岗位失败: %w", err)
+	}
+	ranges := make([]dataRange, 0, len(positions))
+	activePosIDs := make([]string, 0, len(positions))
+	for _, p := range positions {
+		ranges = append(ranges, dataRange{Range: p.DataRange, Scope: p.DataScope})
+		activePosIDs = append(activePosIDs, p.ID)
+	}
+
+	if len(activePosIDs) > 0 {
+		var posRoleIDs []string
+		if err := s.db.Model(&userModel.SsoPosRole{}).
+			Where("pos_id IN ? AND is_deleted = ?", activePosIDs, false).
+			Pluck("role_id", &posRoleIDs).Error; err != nil {
+			return nil, nil, fmt.Errorf("查询岗位角色失败: %w", err)
+		}
+		roleIDs = append(roleIDs, posRoleIDs...)
+	}
+
+	return roleIDs, ranges, nil
+}
+
+// activeRoles 查询有效角色
+func (s *dataScopeService) activeRoles(roleIDs []string) ([]userModel.SsoRole, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	var roles []userModel.SsoRole
+	if err := s.db.Where("id IN ? AND status = ? AND is_deleted = ?", roleIDs, 1, false).
+		Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("查询角色失败: %w", err)
+	}
+	return roles, nil
+}
+
+// roleMenus 查询角色已授权的有效菜单
+func (s *dataScopeService) roleMenus(roleIDs []string) ([]userModel.SsoMenu, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	var menuIDs []string
+	if err := s.db.Model(&userModel.SsoRoleMenu{}).
+		Where("role_id IN ? AND is_deleted = ?", roleIDs, false).
+		Pluck("menu_id", &menuIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询角色菜单失败: %w", err)
+	}
+	if len(menuIDs) == 0 {
+		return nil, nil
+	}
+	var menus []userModel.SsoMenu
+	if err := s.db.Where("id IN ? AND status = ? AND is_deleted = ?", menuIDs, 1, false).
+		Find(&menus).Error; err != nil {
+		return nil, fmt.Errorf("查询菜单失败: %w", err)
+	}
+	return menus, nil
+}
+
+// userOrgs 查询用户所属组织 (主组织 + 兼职组织)
+func (s *dataScopeService) userOrgs(userID string) ([]string, error) {
+	orgIDs := make([]string, 0)
+	if err := s.db.Model(&userModel.SsoOrgUser{}).
+		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Pluck("org_id", &orgIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户组织失败: %w", err)
+	}
+	var user userModel.SsoUser
+	if err := s.db.Select("id", "org_id").Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if user.OrgID != "" {
+		orgIDs = append(orgIDs, user.OrgID)
+	}
+	return orgIDs, nil
+}
+
+// descendantOrgs 返回指定组织及其全部下级组织
+func (s *dataScopeService) descendantOrgs(rootIDs []string) ([]string, error) {
+	if len(rootIDs) == 0 {
+		return nil, nil
+	}
+	var orgs []userModel.SsoOrg
+	if err := s.db.Select("id", "parent_id").Where("is_deleted = ?", false).Find(&orgs).Error; err != nil {
+		return nil, fmt.Errorf("查询组织失败: %w", err)
+	}
+	children := make(map[string][]string)
+	for _, o := range orgs {
+		children[o.ParentID] = append(children[o.ParentID], o.ID)
+	}
+
+	visited := make(map[string]struct{})
+	result := make([]string, 0, len(rootIDs))
+	queue := append([]string(nil), rootIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+		result = append(result, id)
+		queue = append(queue, children[id]...)
+	}
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	userModel "metadata-platform/internal/module/user/model"
+)
+
+func TestDataScopeService_ResolveTenant(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&userModel.SsoUser{}, &userModel.SsoUserRole{}, &userModel.SsoUserPos{}))
+	require.NoError(t, db.Create(&userModel.SsoUser{ID: "u1", Account: "u1", TenantID: "t1"}).Error)
+	svc := NewDataScopeService(db)
+
+	// 未指定租户时取用户所属租户
+	scope, err := svc.Resolve("u1", "", "/api/data/m1", "GET")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", scope.TenantID)
+	assert.True(t, scope.Self)
+
+	scope, err = svc.Resolve("u1", "t1", "/api/data/m1", "GET")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", scope.TenantID)
+
+	// 不能通过请求头切换到其他租户
+	_, err = svc.Resolve("u1", "t2", "/api/data/m1", "GET")
+	assert.Error(t, err)
+	_, err = svc.Resolve("u404", "", "/api/data/m1", "GET")
+	assert.Error(t, err)
+}
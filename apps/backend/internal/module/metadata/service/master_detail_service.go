@@ -5,142 +5,471 @@ import (
 	"errors"
 	"fmt"
 
+	"gorm.io/gorm"
+
 	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/repository"
 	"metadata-platform/internal/utils"
 )
 
+// 主子表关系类型
+const (
+	RelationOneToMany = "OneToMany"
+	RelationOneToOne  = "OneToOne"
+)
+
+// 删除主表记录时的子表规则
+const (
+	DeleteCascade  = "cascade"  // 级联删除子表数据 (默认)
+	DeleteRestrict = "restrict" // 存在子表数据时拒绝删除
+)
+
+// 主子表错误
+var (
+	ErrMasterNotFound   = errors.New("主表记录不存在")
+	ErrDetailRestricted = errors.New("存在关联的子表数据，不允许删除")
+)
+
 // MasterDetailService 主子表服务接口
 type MasterDetailService interface {
-	CreateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, payload map[string]any) error
+	CreateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, payload map[string]any) (map[string]any, error)
+	GetMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string) (map[string]any, error)
+	UpdateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string, payload map[string]any) (map[string]any, error)
+	DeleteMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string) error
 }
 
 type masterDetailService struct {
 	crudSvc      CRUDService
 	relationRepo repository.MdModelRelationRepository
 	modelRepo    repository.MdModelRepository
+	sqlBuilder   *engine.SQLBuilder
 	executor     *engine.SQLExecutor
 }
 
+// relationNode 关系树节点，children 为子表模型作为主表的下级关系
+type relationNode struct {
+	relation model.MdModelRelation
+	md       *engine.ModelData
+	children []*relationNode
+}
+
 // NewMasterDetailService 创建主子表服务实例
 func NewMasterDetailService(
 	crudSvc CRUDService,
 	relationRepo repository.MdModelRelationRepository,
 	modelRepo repository.MdModelRepository,
+	sqlBuilder *engine.SQLBuilder,
 	executor *engine.SQLExecutor,
 ) MasterDetailService {
 	return &masterDetailService{
 		crudSvc:      crudSvc,
 		relationRepo: relationRepo,
 		modelRepo:    modelRepo,
+		sqlBuilder:   sqlBuilder,
 		executor:     executor,
 	}
 }
 
-// CreateMasterDetail 创建主子表数据
-func (s *masterDetailService) CreateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, payload map[string]any) error {
+// CreateMasterDetail 创建主子表数据，子表行可按下级关系的属性名嵌套数据，返回持久化后的主表和子表记录
+func (s *masterDetailService) CreateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, payload map[string]any) (map[string]any, error) {
 	// 1. 获取模型和关系定义
-	relation, err := s.relationRepo.GetRelation(masterModelID, detailModelID)
+	masterMD, node, db, err := s.load(masterModelID, detailModelID)
 	if err != nil {
-		return fmt.Errorf("failed to get relation: %v", err)
-	}
-	if relation == nil {
-		return fmt.Errorf("relation between %s and %s not found", masterModelID, detailModelID)
+		return nil, err
 	}
 
 	masterData, ok := payload["master"].(map[string]any)
 	if !ok {
-		return errors.New("invalid master data format")
+		return nil, errors.New("invalid master data format")
 	}
 
-	var detailList []map[string]any
-	if dList, ok := payload["details"].([]any); ok {
-		for _, item := range dList {
-			if d, ok := item.(map[string]any); ok {
-				detailList = append(detailList, d)
+	// 2. 在事务中创建主表和各级子表数据，数据变更日志在提交后记录
+	var result map[string]any
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		createdMaster, err := s.crudSvc.CreateWithTx(ctx, masterModelID, masterData, tx)
+		if err != nil {
+			return fmt.Errorf("failed to create master record: %w", err)
+		}
+		// 主键可能由数据库生成 (自增或序列)
+		masterID := createdMaster[masterMD.PrimaryKey()]
+		if utils.ToString(masterID) == "" {
+			return errors.New("failed to retrieve master ID")
+		}
+
+		details, err := s.createDetails(ctx, tx, node, masterID, payload["details"])
+		if err != nil {
+			return fmt.Errorf("failed to create detail records: %w", err)
+		}
+		result = map[string]any{"master": createdMaster, "details": details}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	commitChanges()
+
+	return result, nil
+}
+
+// GetMasterDetail 获取主表记录及嵌套的各级子表数据
+func (s *masterDetailService) GetMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string) (map[string]any, error) {
+	masterMD, node, db, err := s.load(masterModelID, detailModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := s.getMaster(ctx, db, masterMD, id)
+	if err != nil {
+		return nil, err
+	}
+	details, err := s.readDetails(ctx, db, node, master[masterMD.PrimaryKey()])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"master": master, "details": details}, nil
+}
+
+// UpdateMasterDetail 更新主表记录并在同一事务中将子表列表差异化同步为新增、更新和删除
+// 未提交 details (或子表行未提交下级属性) 时对应的子表数据保持不变
+func (s *masterDetailService) UpdateMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string, payload map[string]any) (map[string]any, error) {
+	masterMD, node, db, err := s.load(masterModelID, detailModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]any
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		// 1. 更新主表
+		if masterData, ok := payload["master"].(map[string]any); ok && len(masterData) > 0 {
+			if err := s.crudSvc.UpdateWithTx(ctx, masterModelID, id, masterData, tx); err != nil {
+				return fmt.Errorf("更新主表记录失败: %w", err)
 			}
 		}
-	} else if dList, ok := payload["details"].([]map[string]any); ok {
-		detailList = dList
+		master, err := s.getMaster(ctx, tx, masterMD, id)
+		if err != nil {
+			return err
+		}
+		masterID := master[masterMD.PrimaryKey()]
+
+		// 2. 同步子表
+		var details any
+		if value, ok := payload["details"]; ok {
+			details, err = s.syncDetails(ctx, tx, node, masterID, value)
+		} else {
+			details, err = s.readDetails(ctx, tx, node, masterID)
+		}
+		if err != nil {
+			return err
+		}
+		result = map[string]any{"master": master, "details": details}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	commitChanges()
+
+	return result, nil
+}
+
+// DeleteMasterDetail 删除主表记录，主表的所有子表关系按各自的删除规则级联删除或拒绝删除
+func (s *masterDetailService) DeleteMasterDetail(ctx context.Context, masterModelID string, detailModelID string, id string) error {
+	masterMD, _, db, err := s.load(masterModelID, detailModelID)
+	if err != nil {
+		return err
+	}
+	nodes, err := s.loadRelations(masterModelID, map[string]bool{masterModelID: true})
+	if err != nil {
+		return err
 	}
 
-	if len(detailList) == 0 {
-		// 允许只创建主表? 暂时允许
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.getMaster(ctx, tx, masterMD, id); err != nil {
+			return err
+		}
+		return s.deleteRecord(ctx, tx, masterModelID, nodes, id)
+	}); err != nil {
+		return err
+	}
+	commitChanges()
+	return nil
+}
+
+// load 加载主表模型、以指定子表为根的关系树和主表连接，各级子表须与主表位于同一连接
+func (s *masterDetailService) load(masterModelID, detailModelID string) (*engine.ModelData, *relationNode, *gorm.DB, error) {
+	relation, err := s.relationRepo.GetRelation(masterModelID, detailModelID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get relation: %w", err)
+	}
+	if relation == nil {
+		return nil, nil, nil, fmt.Errorf("relation between %s and %s not found", masterModelID, detailModelID)
 	}
 
-	// 2. 获取主表数据库连接信息
 	masterModel, err := s.modelRepo.GetModelByID(masterModelID)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
+	}
+	masterMD, err := s.sqlBuilder.LoadModelData(masterModelID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	node, err := s.loadNode(*relation, map[string]bool{masterModelID: true})
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	db, err := s.executor.GetConnection(masterModel.ConnID)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
+	}
+	return masterMD, node, db, nil
+}
+
+// loadNode 加载关系节点及其下级关系，path 为当前路径上的模型，用于检测循环关系
+func (s *masterDetailService) loadNode(relation model.MdModelRelation, path map[string]bool) (*relationNode, error) {
+	if path[relation.DetailModelID] {
+		return nil, fmt.Errorf("模型 %s 的主子表关系存在循环", relation.DetailModelID)
+	}
+	md, err := s.sqlBuilder.LoadModelData(relation.DetailModelID)
+	if err != nil {
+		return nil, fmt.Errorf("加载子表模型失败: %w", err)
 	}
 
-	// 3. 开启事务
-	tx := db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	path[relation.DetailModelID] = true
+	defer delete(path, relation.DetailModelID)
+	children, err := s.loadRelations(relation.DetailModelID, path)
+	if err != nil {
+		return nil, err
+	}
+	return &relationNode{relation: relation, md: md, children: children}, nil
+}
+
+// loadRelations 加载模型作为主表的全部下级关系
+func (s *masterDetailService) loadRelations(modelID string, path map[string]bool) ([]*relationNode, error) {
+	relations, err := s.relationRepo.GetRelationByMasterID(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relations: %w", err)
+	}
+	nodes := make([]*relationNode, 0, len(relations))
+	for _, relation := range relations {
+		node, err := s.loadNode(relation, path)
+		if err != nil {
+			return nil, err
 		}
-	}()
-	if tx.Error != nil {
-		return tx.Error
+		nodes = append(nodes, node)
 	}
+	return nodes, nil
+}
 
-	// 4. 创建主表数据
-	createdMaster, err := s.crudSvc.CreateWithTx(ctx, masterModelID, masterData, tx)
+// getMaster 在事务中读取主表记录，不存在或无权访问时返回错误
+func (s *masterDetailService) getMaster(ctx context.Context, tx *gorm.DB, md *engine.ModelData, id string) (map[string]any, error) {
+	rows, err := s.crudSvc.ListByFieldWithTx(ctx, md.Model.ID, md.PrimaryKey(), id, tx)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create master record: %v", err)
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMasterNotFound, id)
 	}
+	return rows[0], nil
+}
 
-	// 5. 获取主键值
-	// 假设 dataValidator 或者 builder 能够识别主键，但是 Create 返回的是 data map。
-	// 如果是自增ID，CreateWithTx 目前的实现可能没有把 ID 回填到 map 中（如果是 executor 执行的 insert）。
-	// 目前的 SQLBuilder 生成的 insert 语句 和 SQLExecutor 的 execution 并没有自动获取 LastInsertId 并回填。
+// readDetails 读取父记录下的子表数据并递归读取下级关系，一对一关系返回单条记录
+func (s *masterDetailService) readDetails(ctx context.Context, tx *gorm.DB, node *relationNode, parentID any) (any, error) {
+	rows, err := s.crudSvc.ListByFieldWithTx(ctx, node.relation.DetailModelID, node.relation.ForeignKey, parentID, tx)
+	if err != nil {
+		return nil, fmt.Errorf("读取子表 %s 数据失败: %w", node.relation.DetailModelID, err)
+	}
+	for _, row := range rows {
+		for _, child := range node.children {
+			if row[child.property()], err = s.readDetails(ctx, tx, child, row[node.md.PrimaryKey()]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node.shape(rows), nil
+}
 
-	// 这里是一个潜在的坑: 如果主键是自增的，我们需要获取它。
-	// 如果主键是 UUID (客户端生成或者 validator 生成)，那么 map 里已经有了。
+// createDetails 批量创建父记录下的子表数据并递归创建嵌套的下级数据
+func (s *masterDetailService) createDetails(ctx context.Context, tx *gorm.DB, node *relationNode, parentID any, value any) (any, error) {
+	rows, err := node.rows(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return node.shape(nil), nil
+	}
 
-	// 为了演示完整性，我们假设主键在 masterData 中已经存在（例如 UUID），或者底层支持回填。
-	// 如果需要支持自增，Metadata模块应该配置为 "服务端生成UUID" 或 "客户端提供"。
-	// 暂时假设: 主键字段名为 'id' 且已在 createdMaster 中 (如果 Create 只有 data copy, 那么必须在 data 中)
+	dataList := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		dataList[i] = node.columns(row, parentID)
+	}
+	created, err := s.crudSvc.BatchCreateWithTx(ctx, node.relation.DetailModelID, dataList, tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range created {
+		for _, child := range node.children {
+			if row[child.property()], err = s.createDetails(ctx, tx, child, row[node.md.PrimaryKey()], rows[i][child.property()]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return node.shape(created), nil
+}
 
-	// 在实际生产中，Executor 应该 Scan 插入后的 ID，或者使用 RETURNING (Postgres) / LastInsertId (MySQL)。
-	// 目前的 Execute 方法没有返回 ID。
-	// 这是一个已知限制，我们在 Phase 3 应该考虑增强 SQLExecutor 或者是要求 UUID。
+// syncDetails 将提交的子表数据与现有数据比对：未提交的现有行删除，带主键的行更新，不带主键的行新增
+func (s *masterDetailService) syncDetails(ctx context.Context, tx *gorm.DB, node *relationNode, parentID any, value any) (any, error) {
+	rows, err := node.rows(value)
+	if err != nil {
+		return nil, err
+	}
+	detailModelID := node.relation.DetailModelID
+	primaryKey := node.md.PrimaryKey()
 
-	var masterID string
-	if id, ok := createdMaster["id"]; ok {
-		masterID = utils.ToString(id)
-	} else {
-		// 尝试查找任意 ID 字段?
-		// 暂时无法获取自增ID，回滚
-		tx.Rollback()
-		return errors.New("failed to retrieve master ID (auto-increment not supported in current master-detail impl, please use UUID)")
+	existing, err := s.crudSvc.ListByFieldWithTx(ctx, detailModelID, node.relation.ForeignKey, parentID, tx)
+	if err != nil {
+		return nil, fmt.Errorf("读取子表 %s 数据失败: %w", detailModelID, err)
+	}
+	current := make(map[string]bool, len(existing))
+	for _, row := range existing {
+		current[utils.ToString(normalizeValue(row[primaryKey]))] = true
 	}
 
-	// 6. 创建子表数据
-	if len(detailList) > 0 {
-		for i := range detailList {
-			// 设置外键
-			detailList[i][relation.ForeignKey] = masterID
+	kept := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if id := utils.ToString(row[primaryKey]); id != "" {
+			if !current[id] {
+				return nil, fmt.Errorf("子表记录 %s 不属于当前主表记录", id)
+			}
+			kept[id] = true
 		}
+	}
 
-		_, err := s.crudSvc.BatchCreateWithTx(ctx, detailModelID, detailList, tx)
-		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to create detail records: %v", err)
+	// 1. 先删除未提交的现有行，其下级数据按删除规则处理
+	for _, row := range existing {
+		id := utils.ToString(normalizeValue(row[primaryKey]))
+		if !kept[id] {
+			if err := s.deleteRecord(ctx, tx, detailModelID, node.children, id); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	// 7. 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("transaction commit failed: %v", err)
+	// 2. 新增不带主键的行，更新带主键的行并递归同步其提交的下级数据
+	for i, row := range rows {
+		id := utils.ToString(row[primaryKey])
+		if id == "" {
+			if _, err := s.createDetails(ctx, tx, node, parentID, row); err != nil {
+				return nil, fmt.Errorf("第 %d 条子表数据新增失败: %w", i+1, err)
+			}
+			continue
+		}
+
+		data := node.columns(row, parentID)
+		delete(data, primaryKey)
+		if err := s.crudSvc.UpdateWithTx(ctx, detailModelID, id, data, tx); err != nil {
+			return nil, fmt.Errorf("子表记录 %s 更新失败: %w", id, err)
+		}
+		for _, child := range node.children {
+			if nested, ok := row[child.property()]; ok {
+				if _, err := s.syncDetails(ctx, tx, child, row[primaryKey], nested); err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
+	return s.readDetails(ctx, tx, node, parentID)
+}
+
+// deleteRecord 按下级关系的删除规则处理子表数据后删除记录
+// 数据权限只作用于当前记录，子表数据不论是否可见均参与限制删除的检查和级联删除
+func (s *masterDetailService) deleteRecord(ctx context.Context, tx *gorm.DB, modelID string, children []*relationNode, id string) error {
+	unscoped := engine.WithoutDataScope(ctx)
+	for _, child := range children {
+		rows, err := s.crudSvc.ListByFieldWithTx(unscoped, child.relation.DetailModelID, child.relation.ForeignKey, id, tx)
+		if err != nil {
+			return fmt.Errorf("读取子表 %s 数据失败: %w", child.relation.DetailModelID, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if child.relation.DeleteRule == DeleteRestrict {
+			return fmt.Errorf("%w: 子表 %s 存在 %d 条数据", ErrDetailRestricted, child.relation.DetailModelID, len(rows))
+		}
+		for _, row := range rows {
+			if err := s.deleteRecord(unscoped, tx, child.relation.DetailModelID, child.children, utils.ToString(normalizeValue(row[child.md.PrimaryKey()]))); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.crudSvc.DeleteWithTx(ctx, modelID, id, tx); err != nil {
+		return fmt.Errorf("删除记录 %s 失败: %w", id, err)
+	}
 	return nil
 }
+
+// property 返回嵌套数据中子表数据的属性名
+func (n *relationNode) property() string {
+	return n.relation.DetailProperty()
+}
+
+// oneToOne 是否为一对一关系
+func (n *relationNode) oneToOne() bool {
+	return n.relation.RelationType == RelationOneToOne
+}
+
+// rows 解析提交的子表数据，一对多为数组，一对一为对象或 null
+func (n *relationNode) rows(value any) ([]map[string]any, error) {
+	var rows []map[string]any
+	switch v := value.(type) {
+	case nil:
+	case map[string]any:
+		rows = []map[string]any{v}
+	case []map[string]any:
+		rows = v
+	case []any:
+		for _, item := range v {
+			row, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("子表 %s 数据格式错误", n.relation.DetailModelID)
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("子表 %s 数据格式错误", n.relation.DetailModelID)
+	}
+	if n.oneToOne() && len(rows) > 1 {
+		return nil, fmt.Errorf("一对一子表 %s 只能包含一条数据", n.relation.DetailModelID)
+	}
+	return rows, nil
+}
+
+// shape 按关系类型返回子表数据，一对一返回单条记录或 nil
+func (n *relationNode) shape(rows []map[string]any) any {
+	if n.oneToOne() {
+		if len(rows) == 0 {
+			return nil
+		}
+		return rows[0]
+	}
+	if rows == nil {
+		return []map[string]any{}
+	}
+	return rows
+}
+
+// columns 去除下级关系的嵌套属性并设置外键，返回待写入的列数据
+func (n *relationNode) columns(row map[string]any, parentID any) map[string]any {
+	data := make(map[string]any, len(row)+1)
+	for k, v := range row {
+		data[k] = v
+	}
+	for _, child := range n.children {
+		delete(data, child.property())
+	}
+	data[n.relation.ForeignKey] = parentID
+	return data
+}
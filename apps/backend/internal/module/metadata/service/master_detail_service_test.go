@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/repository"
+	"metadata-platform/internal/utils"
+)
+
+func TestMasterDetailService_GeneratedKeys(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_orders (id INTEGER PRIMARY KEY AUTOINCREMENT, no TEXT, status TEXT DEFAULT 'new')")
+	targetDB.Exec("CREATE TABLE test_order_lines (id INTEGER PRIMARY KEY AUTOINCREMENT, order_id INTEGER, sku TEXT)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_md"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+
+	migrateModelConfig(metaDB)
+	metaDB.AutoMigrate(&model.MdModelRelation{})
+	relationRepo := repository.NewMdModelRelationRepository(metaDB)
+	svc := NewMasterDetailService(crud, relationRepo, modelRepo, builder, executor)
+
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: "m_order", TableNameStr: "test_orders", IsMain: true, ConnID: connID})
+	metaDB.Create(&model.MdModelField{ID: "f1", ModelID: "m_order", ColumnName: "id", IsPrimaryKey: true, IsAutoIncrement: true})
+	metaDB.Create(&model.MdModelField{ID: "f2", ModelID: "m_order", ColumnName: "no"})
+	metaDB.Create(&model.MdModelField{ID: "f3", ModelID: "m_order", ColumnName: "status"})
+	metaDB.Create(&model.MdModelTable{ID: "t2", ModelID: "m_line", TableNameStr: "test_order_lines", IsMain: true, ConnID: connID})
+	metaDB.Create(&model.MdModelField{ID: "f4", ModelID: "m_line", ColumnName: "id", IsPrimaryKey: true, IsAutoIncrement: true})
+	metaDB.Create(&model.MdModelField{ID: "f5", ModelID: "m_line", ColumnName: "order_id"})
+	metaDB.Create(&model.MdModelField{ID: "f6", ModelID: "m_line", ColumnName: "sku"})
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r1", MasterModelID: "m_order", DetailModelID: "m_line", ForeignKey: "order_id"}))
+	modelRepo.On("GetModelByID", "m_order").Return(&model.MdModel{ID: "m_order", ConnID: connID}, nil)
+	modelRepo.On("GetModelByID", "m_line").Return(&model.MdModel{ID: "m_line", ConnID: connID}, nil)
+
+	ctx := context.Background()
+
+	t.Run("Create returns the generated key", func(t *testing.T) {
+		created, err := crud.Create(ctx, "m_order", map[string]any{"no": "A"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, created["id"])
+		assert.Equal(t, "new", created["status"])
+	})
+
+	t.Run("Batch insert maps generated keys to input rows", func(t *testing.T) {
+		result, err := crud.BatchInsert(ctx, "m_order", []map[string]any{
+			{"no": "B"}, {"id": 10, "no": "C"}, {"no": "D"},
+		}, BatchInsertOptions{})
+		require.NoError(t, err)
+		// 列集合相同的行合并为一条语句，B 和 D 先于 C 写入
+		assert.Equal(t, []string{"2", "10", "3"}, result.IDs)
+
+		var no string
+		targetDB.Raw("SELECT no FROM test_orders WHERE id = 3").Scan(&no)
+		assert.Equal(t, "D", no)
+	})
+
+	t.Run("Batch create returns persisted records", func(t *testing.T) {
+		created, err := crud.BatchCreate(ctx, "m_order", []map[string]any{{"no": "E"}, {"no": "F"}})
+		require.NoError(t, err)
+		require.Len(t, created, 2)
+		assert.EqualValues(t, 12, created[1]["id"])
+		assert.Equal(t, "new", created[1]["status"])
+	})
+
+	t.Run("Master detail with auto-increment master key", func(t *testing.T) {
+		result, err := svc.CreateMasterDetail(ctx, "m_order", "m_line", map[string]any{
+			"master":  map[string]any{"no": "G"},
+			"details": []any{map[string]any{"sku": "x"}, map[string]any{"sku": "y"}},
+		})
+		require.NoError(t, err)
+		master := result["master"].(map[string]any)
+		details := result["details"].([]map[string]any)
+		assert.EqualValues(t, 13, master["id"])
+		require.Len(t, details, 2)
+		assert.EqualValues(t, 13, details[0]["order_id"])
+		assert.EqualValues(t, 2, details[1]["id"])
+	})
+}
+
+func TestMasterDetailService_Lifecycle(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_orders (id INTEGER PRIMARY KEY AUTOINCREMENT, no TEXT)")
+	targetDB.Exec("CREATE TABLE test_order_lines (id INTEGER PRIMARY KEY AUTOINCREMENT, order_id INTEGER, sku TEXT)")
+	targetDB.Exec("CREATE TABLE test_line_notes (id INTEGER PRIMARY KEY AUTOINCREMENT, line_id INTEGER, text TEXT)")
+	targetDB.Exec("CREATE TABLE test_invoices (id INTEGER PRIMARY KEY AUTOINCREMENT, order_id INTEGER, title TEXT)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_md"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+
+	migrateModelConfig(metaDB)
+	metaDB.AutoMigrate(&model.MdModelRelation{})
+	relationRepo := repository.NewMdModelRelationRepository(metaDB)
+	svc := NewMasterDetailService(crud, relationRepo, modelRepo, builder, executor)
+
+	for modelID, def := range map[string][]string{
+		"m_order":   {"test_orders", "no"},
+		"m_line":    {"test_order_lines", "order_id", "sku"},
+		"m_note":    {"test_line_notes", "line_id", "text"},
+		"m_invoice": {"test_invoices", "order_id", "title"},
+	} {
+		metaDB.Create(&model.MdModelTable{ID: "t_" + modelID, ModelID: modelID, TableNameStr: def[0], IsMain: true, ConnID: connID})
+		metaDB.Create(&model.MdModelField{ID: "f_" + modelID, ModelID: modelID, ColumnName: "id", IsPrimaryKey: true, IsAutoIncrement: true})
+		for _, column := range def[1:] {
+			metaDB.Create(&model.MdModelField{ID: "f_" + modelID + "_" + column, ModelID: modelID, ColumnName: column})
+		}
+		modelRepo.On("GetModelByID", modelID).Return(&model.MdModel{ID: modelID, ConnID: connID}, nil)
+	}
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r1", MasterModelID: "m_order", DetailModelID: "m_line", ForeignKey: "order_id", RelationType: RelationOneToMany, DeleteRule: DeleteCascade}))
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r2", MasterModelID: "m_line", DetailModelID: "m_note", ForeignKey: "line_id", RelationType: RelationOneToMany, PropertyName: "notes", DeleteRule: DeleteCascade}))
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r3", MasterModelID: "m_order", DetailModelID: "m_invoice", ForeignKey: "order_id", RelationType: RelationOneToOne, DeleteRule: DeleteRestrict}))
+
+	ctx := context.Background()
+	count := func(table string) int64 {
+		var n int64
+		targetDB.Raw("SELECT COUNT(*) FROM " + table).Scan(&n)
+		return n
+	}
+
+	created, err := svc.CreateMasterDetail(ctx, "m_order", "m_line", map[string]any{
+		"master": map[string]any{"no": "SO-1"},
+		"details": []any{
+			map[string]any{"sku": "a", "notes": []any{map[string]any{"text": "a1"}, map[string]any{"text": "a2"}}},
+			map[string]any{"sku": "b", "notes": []any{map[string]any{"text": "b1"}}},
+		},
+	})
+	require.NoError(t, err)
+	orderID := utils.ToString(created["master"].(map[string]any)["id"])
+	assert.Equal(t, int64(3), count("test_line_notes"))
+
+	t.Run("Get returns nested details", func(t *testing.T) {
+		result, err := svc.GetMasterDetail(ctx, "m_order", "m_line", orderID)
+		require.NoError(t, err)
+		lines := result["details"].([]map[string]any)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "a", lines[0]["sku"])
+		assert.Len(t, lines[0]["notes"], 2)
+		assert.Len(t, lines[1]["notes"], 1)
+
+		_, err = svc.GetMasterDetail(ctx, "m_order", "m_line", "404")
+		assert.ErrorIs(t, err, ErrMasterNotFound)
+	})
+
+	t.Run("Update diffs the detail list", func(t *testing.T) {
+		result, err := svc.UpdateMasterDetail(ctx, "m_order", "m_line", orderID, map[string]any{
+			"master": map[string]any{"no": "SO-1b"},
+			"details": []any{
+				// 更新 a 并替换其备注，删除 b (级联删除其备注)，新增 c
+				map[string]any{"id": 1, "sku": "a2", "notes": []any{map[string]any{"id": 1, "text": "a1!"}}},
+				map[string]any{"sku": "c"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SO-1b", result["master"].(map[string]any)["no"])
+		lines := result["details"].([]map[string]any)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "a2", lines[0]["sku"])
+		assert.Equal(t, "c", lines[1]["sku"])
+		notes := lines[0]["notes"].([]map[string]any)
+		require.Len(t, notes, 1)
+		assert.Equal(t, "a1!", notes[0]["text"])
+		assert.Equal(t, int64(1), count("test_line_notes"))
+	})
+
+	t.Run("Update rejects rows of another master", func(t *testing.T) {
+		_, err := svc.UpdateMasterDetail(ctx, "m_order", "m_line", orderID, map[string]any{
+			"details": []any{map[string]any{"id": 99, "sku": "x"}},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, int64(2), count("test_order_lines"))
+	})
+
+	t.Run("One-to-one detail", func(t *testing.T) {
+		result, err := svc.UpdateMasterDetail(ctx, "m_order", "m_invoice", orderID, map[string]any{
+			"details": map[string]any{"title": "ACME"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ACME", result["details"].(map[string]any)["title"])
+
+		_, err = svc.UpdateMasterDetail(ctx, "m_order", "m_invoice", orderID, map[string]any{
+			"details": []any{map[string]any{"title": "x"}, map[string]any{"title": "y"}},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Delete is restricted then cascades", func(t *testing.T) {
+		err := svc.DeleteMasterDetail(ctx, "m_order", "m_line", orderID)
+		assert.ErrorIs(t, err, ErrDetailRestricted)
+		assert.Equal(t, int64(1), count("test_orders"))
+
+		_, err = svc.UpdateMasterDetail(ctx, "m_order", "m_invoice", orderID, map[string]any{"details": nil})
+		require.NoError(t, err)
+		require.NoError(t, svc.DeleteMasterDetail(ctx, "m_order", "m_line", orderID))
+		assert.Zero(t, count("test_orders"))
+		assert.Zero(t, count("test_order_lines"))
+		assert.Zero(t, count("test_line_notes"))
+	})
+
+	t.Run("Cyclic relations are rejected", func(t *testing.T) {
+		require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r4", MasterModelID: "m_note", DetailModelID: "m_order", ForeignKey: "no"}))
+		_, err := svc.GetMasterDetail(ctx, "m_order", "m_line", orderID)
+		assert.ErrorContains(t, err, "循环")
+	})
+}
+
+func TestMasterDetailService_DeleteIgnoresDetailScope(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_docs (id INTEGER PRIMARY KEY, owner_id TEXT)")
+	targetDB.Exec("CREATE TABLE test_doc_items (id INTEGER PRIMARY KEY, doc_id INTEGER, owner_id TEXT)")
+	targetDB.Exec("CREATE TABLE test_doc_links (id INTEGER PRIMARY KEY, doc_id INTEGER, owner_id TEXT)")
+	// 子表数据均属于其他用户，对当前用户不可见
+	targetDB.Exec("INSERT INTO test_docs VALUES (1, 'u1')")
+	targetDB.Exec("INSERT INTO test_doc_items VALUES (1, 1, 'u2'), (2, 1, 'u2')")
+	targetDB.Exec("INSERT INTO test_doc_links VALUES (1, 1, 'u2')")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_md_scope"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+
+	migrateModelConfig(metaDB)
+	metaDB.AutoMigrate(&model.MdModelRelation{})
+	relationRepo := repository.NewMdModelRelationRepository(metaDB)
+	svc := NewMasterDetailService(crud, relationRepo, modelRepo, builder, executor)
+
+	for modelID, def := range map[string][]string{
+		"m_doc":  {"test_docs", "owner_id"},
+		"m_item": {"test_doc_items", "doc_id", "owner_id"},
+		"m_link": {"test_doc_links", "doc_id", "owner_id"},
+	} {
+		metaDB.Create(&model.MdModelTable{ID: "t_" + modelID, ModelID: modelID, TableNameStr: def[0], IsMain: true, ConnID: connID})
+		metaDB.Create(&model.MdModelField{ID: "f_" + modelID, ModelID: modelID, ColumnName: "id", IsPrimaryKey: true})
+		for _, column := range def[1:] {
+			metaDB.Create(&model.MdModelField{ID: "f_" + modelID + "_" + column, ModelID: modelID, ColumnName: column})
+		}
+		modelRepo.On("GetModelByID", modelID).Return(&model.MdModel{ID: modelID, ConnID: connID, DataOwnerField: "owner_id"}, nil)
+	}
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r1", MasterModelID: "m_doc", DetailModelID: "m_item", ForeignKey: "doc_id", RelationType: RelationOneToMany, DeleteRule: DeleteCascade}))
+	require.NoError(t, relationRepo.CreateRelation(&model.MdModelRelation{ID: "r2", MasterModelID: "m_doc", DetailModelID: "m_link", ForeignKey: "doc_id", RelationType: RelationOneToMany, PropertyName: "links", DeleteRule: DeleteRestrict}))
+
+	owner := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", Self: true})
+	other := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u3", Self: true})
+	count := func(table string) int64 {
+		var n int64
+		targetDB.Raw("SELECT COUNT(*) FROM " + table).Scan(&n)
+		return n
+	}
+
+	assert.ErrorIs(t, svc.DeleteMasterDetail(other, "m_doc", "m_item", "1"), ErrMasterNotFound)
+	assert.ErrorIs(t, svc.DeleteMasterDetail(owner, "m_doc", "m_item", "1"), ErrDetailRestricted)
+	assert.Equal(t, int64(1), count("test_docs"))
+
+	targetDB.Exec("DELETE FROM test_doc_links")
+	require.NoError(t, svc.DeleteMasterDetail(owner, "m_doc", "m_item", "1"))
+	assert.Zero(t, count("test_docs"))
+	assert.Zero(t, count("test_doc_items"))
+}
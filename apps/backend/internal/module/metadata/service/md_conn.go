@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"metadata-platform/internal/module/metadata/adapter"
@@ -28,6 +29,7 @@ type MdConnService interface {
 	ExecuteSQLForColumns(conn *model.MdConn, query string, params map[string]interface{}) ([]adapter.ColumnInfo, error)
 	GetProcedures(conn *model.MdConn, schema string) ([]adapter.ProcedureInfo, error)
 	GetFunctions(conn *model.MdConn, schema string) ([]adapter.ProcedureInfo, error)
+	CallProcedure(ctx context.Context, conn *model.MdConn, call adapter.ProcedureCall) (*adapter.ProcedureResult, error)
 }
 
 // mdConnService 数据连接服务实现
@@ -346,3 +348,21 @@ func (s *mdConnService) GetFunctions(conn *model.MdConn, schema string) ([]adapt
 	}
 	return extractor.GetFunctions(schema)
 }
+
+// CallProcedure 调用存储过程/函数
+func (s *mdConnService) CallProcedure(ctx context.Context, conn *model.MdConn, call adapter.ProcedureCall) (*adapter.ProcedureResult, error) {
+	extractor, err := s.getExtractor(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer extractor.Close()
+
+	caller, ok := extractor.(adapter.ProcedureCaller)
+	if !ok {
+		return nil, fmt.Errorf("数据源 %s 不支持调用存储过程", conn.ConnKind)
+	}
+	if call.Schema == "" {
+		call.Schema = conn.ConnDatabase
+	}
+	return caller.CallProcedure(ctx, call)
+}
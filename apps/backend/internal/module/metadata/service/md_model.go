@@ -279,14 +279,29 @@ func (s *mdModelService) GetFieldsByModelID(modelID string) ([]model.MdModelFiel
 
 // CreateField 创建模型字段
 func (s *mdModelService) CreateField(field *model.MdModelField) error {
+	if err := validateEncryptAlgo(field.EncryptAlgo); err != nil {
+		return err
+	}
 	return s.fieldRepo.CreateField(field)
 }
 
 // UpdateField 更新模型字段
 func (s *mdModelService) UpdateField(field *model.MdModelField) error {
+	if err := validateEncryptAlgo(field.EncryptAlgo); err != nil {
+		return err
+	}
 	return s.fieldRepo.UpdateField(field)
 }
 
+// validateEncryptAlgo 校验字段加密算法
+func validateEncryptAlgo(algo string) error {
+	switch algo {
+	case "", utils.FieldCipherSM4, utils.FieldCipherAES:
+		return nil
+	}
+	return fmt.Errorf("不支持的字段加密算法: %s", algo)
+}
+
 // DeleteField 删除模型字段
 func (s *mdModelService) DeleteField(id string) error {
 	return s.fieldRepo.DeleteField(id)
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+
+	"metadata-platform/internal/module/metadata/engine"
 	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/repository"
 )
@@ -13,6 +16,7 @@ type MdModelFieldEnhancementService interface {
 	UpdateEnhancement(enh *model.MdModelFieldEnhancement) error
 	DeleteEnhancement(id string) error
 	BatchUpdateEnhancements(enhancements []model.MdModelFieldEnhancement) error
+	GetTableMaskPolicies(connID, schema, table string) ([]*engine.MaskPolicy, error)
 }
 
 type mdModelFieldEnhancementService struct {
@@ -25,6 +29,9 @@ func NewMdModelFieldEnhancementService(enhRepo repository.MdModelFieldEnhancemen
 }
 
 func (s *mdModelFieldEnhancementService) CreateEnhancement(enh *model.MdModelFieldEnhancement) error {
+	if err := validateMaskType(enh); err != nil {
+		return err
+	}
 	return s.enhRepo.CreateEnhancement(enh)
 }
 
@@ -37,6 +44,9 @@ func (s *mdModelFieldEnhancementService) GetEnhancementsByModelID(modelID string
 }
 
 func (s *mdModelFieldEnhancementService) UpdateEnhancement(enh *model.MdModelFieldEnhancement) error {
+	if err := validateMaskType(enh); err != nil {
+		return err
+	}
 	return s.enhRepo.UpdateEnhancement(enh)
 }
 
@@ -45,5 +55,50 @@ func (s *mdModelFieldEnhancementService) DeleteEnhancement(id string) error {
 }
 
 func (s *mdModelFieldEnhancementService) BatchUpdateEnhancements(enhs []model.MdModelFieldEnhancement) error {
+	for i := range enhs {
+		if err := validateMaskType(&enhs[i]); err != nil {
+			return err
+		}
+	}
 	return s.enhRepo.BatchUpdateEnhancements(enhs)
 }
+
+// validateMaskType 校验脱敏方式
+func validateMaskType(enh *model.MdModelFieldEnhancement) error {
+	switch enh.MaskType {
+	case "", engine.MaskHide, engine.MaskPartial, engine.MaskHash, engine.MaskFormat:
+		return nil
+	}
+	return fmt.Errorf("不支持的脱敏方式: %s", enh.MaskType)
+}
+
+// GetTableMaskPolicies 获取数据源表上的脱敏策略，同一列被多个模型配置时取最严格的一项
+func (s *mdModelFieldEnhancementService) GetTableMaskPolicies(connID, schema, table string) ([]*engine.MaskPolicy, error) {
+	byColumn, err := s.enhRepo.GetMaskEnhancementsByTable(connID, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*engine.MaskPolicy, 0, len(byColumn))
+	for column, enhs := range byColumn {
+		var strictest *engine.MaskPolicy
+		for i := range enhs {
+			p := engine.NewMaskPolicy(column, column, &enhs[i])
+			if p == nil {
+				continue
+			}
+			if strictest == nil || maskStrictness(p) > maskStrictness(strictest) {
+				strictest = p
+			}
+		}
+		if strictest != nil {
+			policies = append(policies, strictest)
+		}
+	}
+	return policies, nil
+}
+
+// maskStrictness 脱敏方式的严格程度，豁免条件越多越宽松
+func maskStrictness(p *engine.MaskPolicy) int {
+	level := map[string]int{engine.MaskHide: 40, engine.MaskHash: 30, engine.MaskPartial: 20, engine.MaskFormat: 10}[p.Type]
+	return level - len(p.ExemptRoles) - len(p.ExemptPerms)
+}
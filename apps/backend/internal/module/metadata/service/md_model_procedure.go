@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"metadata-platform/internal/module/metadata/adapter"
 	"metadata-platform/internal/module/metadata/model"
@@ -20,22 +24,37 @@ type MdModelProcedureService interface {
 	GetAllProcedures(tenantID string) ([]model.MdModelProcedure, error)
 	SaveSelectedProcedures(connID, tenantID string, procSchema string, procedures []adapter.ProcedureInfo, connName string) error
 	GetParamsByProcID(procID string) ([]model.MdModelProcedureParam, error)
+	Execute(ctx context.Context, procID string, values map[string]any) (*adapter.ProcedureResult, error)
+	BuildModel(req *BuildFromProcedureRequest) (*model.MdModel, error)
+	ExecuteModel(ctx context.Context, modelID string, values map[string]any) (*adapter.ProcedureResult, error)
+}
+
+// BuildFromProcedureRequest 由存储过程/函数创建模型请求，名称和编码为空时使用存储过程名称
+type BuildFromProcedureRequest struct {
+	ProcID    string
+	ModelName string
+	ModelCode string
+	TenantID  string
+	UserID    string
+	Username  string
 }
 
 // mdProcedureService 存储过程/函数服务实现
 type mdModelProcedureService struct {
-	procRepo  repository.MdModelProcedureRepository
-	connRepo  repository.MdConnRepository
-	snowflake *utils.Snowflake
+	procRepo    repository.MdModelProcedureRepository
+	modelRepo   repository.MdModelRepository
+	connService MdConnService
+	snowflake   *utils.Snowflake
 }
 
 // NewMdModelProcedureService 创建存储过程/函数服务实例
-func NewMdModelProcedureService(procRepo repository.MdModelProcedureRepository, connRepo repository.MdConnRepository) MdModelProcedureService {
+func NewMdModelProcedureService(procRepo repository.MdModelProcedureRepository, modelRepo repository.MdModelRepository, connService MdConnService) MdModelProcedureService {
 	snowflake := utils.NewSnowflake(1, 1)
 	return &mdModelProcedureService{
-		procRepo:  procRepo,
-		connRepo:  connRepo,
-		snowflake: snowflake,
+		procRepo:    procRepo,
+		modelRepo:   modelRepo,
+		connService: connService,
+		snowflake:   snowflake,
 	}
 }
 
@@ -103,7 +122,7 @@ func (s *mdModelProcedureService) GetAllProcedures(tenantID string) ([]model.MdM
 	return s.procRepo.GetAllProcedures(tenantID)
 }
 
-// SaveSelectedProcedures 保存选中的存储过程/函数
+// SaveSelectedProcedures 保存选中的存储过程/函数及其参数，表函数的返回列以 TABLE 模式保存
 func (s *mdModelProcedureService) SaveSelectedProcedures(connID, tenantID string, procSchema string, procedures []adapter.ProcedureInfo, connName string) error {
 	for _, procInfo := range procedures {
 		existingProc, err := s.procRepo.GetProcedureByName(connID, procSchema, procInfo.Name)
@@ -117,6 +136,9 @@ func (s *mdModelProcedureService) SaveSelectedProcedures(connID, tenantID string
 			if err != nil {
 				return err
 			}
+			if err := s.saveParams(existingProc, procInfo); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -138,6 +160,50 @@ func (s *mdModelProcedureService) SaveSelectedProcedures(connID, tenantID string
 		if err != nil {
 			return err
 		}
+		if err := s.saveParams(proc, procInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveParams 以提取的参数和返回列替换存储过程的参数，同名参数保留手工维护的标题和描述
+func (s *mdModelProcedureService) saveParams(proc *model.MdModelProcedure, procInfo adapter.ProcedureInfo) error {
+	existing, err := s.procRepo.GetParamsByProcID(proc.ID)
+	if err != nil {
+		return fmt.Errorf("获取参数列表失败: %w", err)
+	}
+	previous := make(map[string]model.MdModelProcedureParam, len(existing))
+	for _, p := range existing {
+		previous[strings.ToUpper(p.ParamMode)+":"+p.ParamName] = p
+	}
+	if err := s.procRepo.DeleteParamsByProcID(proc.ID); err != nil {
+		return err
+	}
+
+	all := append(append([]adapter.ProcedureParam{}, procInfo.Parameters...), procInfo.Columns...)
+	for i, p := range all {
+		param := &model.MdModelProcedureParam{
+			ID:           s.snowflake.GenerateIDString(),
+			TenantID:     proc.TenantID,
+			ConnID:       proc.ConnID,
+			ProcID:       proc.ID,
+			ProcName:     proc.ProcName,
+			ParamName:    p.Name,
+			ParamTitle:   p.Name,
+			ParamMode:    p.Mode,
+			ParamType:    p.DataType,
+			ParamLength:  p.Length,
+			DefaultValue: p.DefaultValue,
+			Sort:         i + 1,
+		}
+		if old, ok := previous[strings.ToUpper(p.Mode)+":"+p.Name]; ok && old.ParamTitle != "" {
+			param.ParamTitle = old.ParamTitle
+			param.ParamComment = old.ParamComment
+		}
+		if err := s.procRepo.CreateProcedureParam(param); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -146,3 +212,149 @@ func (s *mdModelProcedureService) SaveSelectedProcedures(connID, tenantID string
 func (s *mdModelProcedureService) GetParamsByProcID(procID string) ([]model.MdModelProcedureParam, error) {
 	return s.procRepo.GetParamsByProcID(procID)
 }
+
+// Execute 调用存储过程/函数，按参数定义绑定 IN/INOUT 参数值，未传入时使用默认值
+func (s *mdModelProcedureService) Execute(ctx context.Context, procID string, values map[string]any) (*adapter.ProcedureResult, error) {
+	proc, err := s.procRepo.GetProcedureByID(procID)
+	if err != nil {
+		return nil, errors.New("存储过程/函数不存在")
+	}
+	params, err := s.procRepo.GetParamsByProcID(procID)
+	if err != nil {
+		return nil, fmt.Errorf("获取参数列表失败: %w", err)
+	}
+	conn, err := s.connService.GetConnByID(proc.ConnID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据连接失败: %w", err)
+	}
+
+	call := adapter.ProcedureCall{
+		Schema:     proc.ProcSchema,
+		Name:       proc.ProcName,
+		Type:       proc.ProcType,
+		ReturnType: proc.ReturnType,
+	}
+	known := make(map[string]bool)
+	for _, p := range params {
+		mode := procedureParamMode(p.ParamMode)
+		if p.IsDeleted || p.ParamName == "" || mode == "" {
+			continue
+		}
+		arg := adapter.ProcedureArg{Name: procedureParamKey(p.ParamName), Mode: mode, Type: p.ParamType}
+		if arg.IsIn() {
+			key := arg.Name
+			known[key] = true
+			if v, ok := values[key]; ok {
+				arg.Value = v
+			} else if p.DefaultValue != "" {
+				arg.Value = p.DefaultValue
+			}
+		}
+		call.Args = append(call.Args, arg)
+	}
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("未知参数: %s", key)
+		}
+	}
+
+	result, err := s.connService.CallProcedure(ctx, conn, call)
+	if err != nil {
+		return nil, fmt.Errorf("调用存储过程/函数失败: %w", err)
+	}
+	return result, nil
+}
+
+// BuildModel 由存储过程/函数创建模型 (模型类型 3)，IN/INOUT 参数作为模型参数
+func (s *mdModelProcedureService) BuildModel(req *BuildFromProcedureRequest) (*model.MdModel, error) {
+	proc, err := s.procRepo.GetProcedureByID(req.ProcID)
+	if err != nil {
+		return nil, errors.New("存储过程/函数不存在")
+	}
+	params, err := s.procRepo.GetParamsByProcID(proc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取参数列表失败: %w", err)
+	}
+
+	if req.ModelName == "" {
+		req.ModelName = proc.ProcTitle
+		if req.ModelName == "" {
+			req.ModelName = proc.ProcName
+		}
+	}
+	if req.ModelCode == "" {
+		req.ModelCode = proc.ProcName
+	}
+	existingModel, err := s.modelRepo.GetModelByCode(req.ModelCode)
+	if err == nil && existingModel != nil {
+		return nil, errors.New("模型编码已存在")
+	}
+
+	modelParams := make([]SQLParameter, 0, len(params))
+	for _, p := range params {
+		mode := procedureParamMode(p.ParamMode)
+		if p.IsDeleted || p.ParamName == "" || mode == "" || mode == adapter.ParamModeOut {
+			continue
+		}
+		modelParams = append(modelParams, SQLParameter{
+			Name:    procedureParamKey(p.ParamName),
+			Type:    p.ParamType,
+			Default: p.DefaultValue,
+		})
+	}
+	paramsJson, _ := json.Marshal(modelParams)
+
+	mdModel := &model.MdModel{
+		ID:           s.snowflake.GenerateIDString(),
+		TenantID:     req.TenantID,
+		ParentID:     "0",
+		ConnID:       proc.ConnID,
+		ConnName:     proc.ConnName,
+		ModelName:    req.ModelName,
+		ModelCode:    req.ModelCode,
+		ModelVersion: "1.0.0",
+		ModelKind:    3,
+		ProcID:       proc.ID,
+		Parameters:   string(paramsJson),
+		Remark:       proc.ProcComment,
+		CreateID:     req.UserID,
+		CreateBy:     req.Username,
+		UpdateID:     req.UserID,
+		UpdateBy:     req.Username,
+	}
+	if err := s.modelRepo.CreateModel(mdModel); err != nil {
+		return nil, err
+	}
+	return mdModel, nil
+}
+
+// ExecuteModel 调用存储过程模型关联的存储过程/函数
+func (s *mdModelProcedureService) ExecuteModel(ctx context.Context, modelID string, values map[string]any) (*adapter.ProcedureResult, error) {
+	md, err := s.modelRepo.GetModelByID(modelID)
+	if err != nil {
+		return nil, errors.New("模型不存在")
+	}
+	if md.ModelKind != 3 || md.ProcID == "" {
+		return nil, errors.New("模型不是存储过程模型")
+	}
+	return s.Execute(ctx, md.ProcID, values)
+}
+
+// procedureParamMode 规范化参数模式，返回空字符串表示不参与调用 (如表函数的返回列)；
+// 没有名称的参数 (如 MySQL 函数的返回值) 同样不参与调用
+func procedureParamMode(mode string) string {
+	switch strings.ToUpper(strings.Join(strings.Fields(mode), "")) {
+	case "", adapter.ParamModeIn:
+		return adapter.ParamModeIn
+	case adapter.ParamModeOut:
+		return adapter.ParamModeOut
+	case adapter.ParamModeInOut:
+		return adapter.ParamModeInOut
+	}
+	return ""
+}
+
+// procedureParamKey 参数值的键，去掉 SQL Server 参数名的 @ 前缀
+func procedureParamKey(name string) string {
+	return strings.TrimPrefix(name, "@")
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/adapter"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/repository"
+)
+
+func TestMdModelProcedureService_Execute(t *testing.T) {
+	metaDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, metaDB.AutoMigrate(&model.MdModel{}, &model.MdModelProcedure{}, &model.MdModelProcedureParam{}))
+
+	procRepo := repository.NewMdModelProcedureRepository(metaDB)
+	connSvc := new(MockConnService)
+	svc := NewMdModelProcedureService(procRepo, repository.NewMdModelRepository(metaDB), connSvc)
+
+	conn := &model.MdConn{ID: "c1", ConnKind: "MySQL", ConnDatabase: "shop"}
+	connSvc.On("GetConnByID", "c1").Return(conn, nil)
+
+	proc := &model.MdModelProcedure{ID: "p1", ConnID: "c1", ConnName: "shop", ProcSchema: "shop", ProcName: "calc_order", ProcType: "PROCEDURE", ProcComment: "计算订单"}
+	require.NoError(t, procRepo.CreateProcedure(proc))
+	for _, p := range []model.MdModelProcedureParam{
+		{ID: "a1", ProcID: "p1", ParamName: "order_id", ParamMode: "IN", ParamType: "bigint", Sort: 1},
+		{ID: "a2", ProcID: "p1", ParamName: "discount", ParamMode: "", ParamType: "decimal", DefaultValue: "0.5", Sort: 2},
+		{ID: "a3", ProcID: "p1", ParamName: "total", ParamMode: "in out", ParamType: "decimal", Sort: 3},
+		{ID: "a4", ProcID: "p1", ParamName: "message", ParamMode: "OUT", ParamType: "varchar", Sort: 4},
+		{ID: "a5", ProcID: "p1", ParamName: "removed", ParamMode: "IN", IsDeleted: true, Sort: 5},
+	} {
+		require.NoError(t, procRepo.CreateProcedureParam(&p))
+	}
+
+	t.Run("Execute binds parameters", func(t *testing.T) {
+		want := &adapter.ProcedureResult{Outputs: map[string]any{"total": 9.5, "message": "ok"}}
+		connSvc.On("CallProcedure", mock.Anything, conn, mock.Anything).Return(want, nil).Once()
+
+		result, err := svc.Execute(context.Background(), "p1", map[string]any{"order_id": 7, "total": 10})
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+
+		call := connSvc.Calls[len(connSvc.Calls)-1].Arguments.Get(2).(adapter.ProcedureCall)
+		assert.Equal(t, "shop", call.Schema)
+		assert.Equal(t, "calc_order", call.Name)
+		assert.Equal(t, []adapter.ProcedureArg{
+			{Name: "order_id", Mode: adapter.ParamModeIn, Type: "bigint", Value: 7},
+			{Name: "discount", Mode: adapter.ParamModeIn, Type: "decimal", Value: "0.5"},
+			{Name: "total", Mode: adapter.ParamModeInOut, Type: "decimal", Value: 10},
+			{Name: "message", Mode: adapter.ParamModeOut, Type: "varchar"},
+		}, call.Args)
+	})
+
+	t.Run("Execute rejects unknown parameters", func(t *testing.T) {
+		_, err := svc.Execute(context.Background(), "p1", map[string]any{"message": "x"})
+		assert.ErrorContains(t, err, "未知参数")
+		_, err = svc.Execute(context.Background(), "missing", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Build and execute procedure model", func(t *testing.T) {
+		md, err := svc.BuildModel(&BuildFromProcedureRequest{ProcID: "p1", TenantID: "1"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, md.ModelKind)
+		assert.Equal(t, "p1", md.ProcID)
+		assert.Equal(t, "calc_order", md.ModelCode)
+
+		var params []SQLParameter
+		require.NoError(t, json.Unmarshal([]byte(md.Parameters), &params))
+		assert.Equal(t, []SQLParameter{
+			{Name: "order_id", Type: "bigint"},
+			{Name: "discount", Type: "decimal", Default: "0.5"},
+			{Name: "total", Type: "decimal"},
+		}, params)
+
+		_, err = svc.BuildModel(&BuildFromProcedureRequest{ProcID: "p1"})
+		assert.ErrorContains(t, err, "模型编码已存在")
+
+		connSvc.On("CallProcedure", mock.Anything, conn, mock.Anything).Return(&adapter.ProcedureResult{}, nil).Once()
+		_, err = svc.ExecuteModel(context.Background(), md.ID, map[string]any{"order_id": 1})
+		require.NoError(t, err)
+	})
+}
+
+func TestMdModelProcedureService_SaveSelectedProcedures(t *testing.T) {
+	metaDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, metaDB.AutoMigrate(&model.MdModel{}, &model.MdModelProcedure{}, &model.MdModelProcedureParam{}))
+
+	procRepo := repository.NewMdModelProcedureRepository(metaDB)
+	svc := NewMdModelProcedureService(procRepo, repository.NewMdModelRepository(metaDB), new(MockConnService))
+
+	info := adapter.ProcedureInfo{
+		Name:       "top_orders",
+		Type:       "FUNCTION",
+		ReturnType: "TABLE",
+		Parameters: []adapter.ProcedureParam{
+			{Name: "customer_id", Mode: "IN", DataType: "bigint", Position: 1},
+			{Name: "limit_count", Mode: "IN", DataType: "integer", DefaultValue: "10", Position: 2},
+		},
+		Columns: []adapter.ProcedureParam{
+			{Name: "order_no", Mode: adapter.ParamModeTable, DataType: "varchar", Length: 32, Position: 1},
+			{Name: "amount", Mode: adapter.ParamModeTable, DataType: "numeric", Position: 2},
+		},
+	}
+	require.NoError(t, svc.SaveSelectedProcedures("c1", "1", "public", []adapter.ProcedureInfo{info}, "shop"))
+
+	proc, err := procRepo.GetProcedureByName("c1", "public", "top_orders")
+	require.NoError(t, err)
+	params, err := svc.GetParamsByProcID(proc.ID)
+	require.NoError(t, err)
+	require.Len(t, params, 4)
+	assert.Equal(t, "customer_id", params[0].ParamName)
+	assert.Equal(t, "10", params[1].DefaultValue)
+	assert.Equal(t, adapter.ParamModeTable, params[2].ParamMode)
+	assert.Equal(t, 32, params[2].ParamLength)
+	assert.Equal(t, 4, params[3].Sort)
+
+	// 再次保存时替换参数，保留手工维护的标题
+	params[0].ParamTitle = "客户"
+	require.NoError(t, metaDB.Save(&params[0]).Error)
+	info.Parameters = info.Parameters[:1]
+	require.NoError(t, svc.SaveSelectedProcedures("c1", "1", "public", []adapter.ProcedureInfo{info}, "shop"))
+
+	params, err = svc.GetParamsByProcID(proc.ID)
+	require.NoError(t, err)
+	require.Len(t, params, 3)
+	assert.Equal(t, "客户", params[0].ParamTitle)
+	assert.Equal(t, "order_no", params[1].ParamName)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -176,6 +177,14 @@ func (m *MockConnService) GetFunctions(conn *model.MdConn, schema string) ([]ada
 	return m.Called(conn, schema).Get(0).([]adapter.ProcedureInfo), m.Called(conn, schema).Error(1)
 }
 
+func (m *MockConnService) CallProcedure(ctx context.Context, conn *model.MdConn, call adapter.ProcedureCall) (*adapter.ProcedureResult, error) {
+	args := m.Called(ctx, conn, call)
+	if r, ok := args.Get(0).(*adapter.ProcedureResult); ok {
+		return r, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestMdModelService_BuildFromTable(t *testing.T) {
 	mockModelRepo := new(MockModelRepo)
 	mockFieldRepo := new(MockFieldRepo)
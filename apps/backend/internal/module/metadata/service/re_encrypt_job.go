@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/repository"
+	"metadata-platform/internal/utils"
+)
+
+// 重新加密任务状态
+const (
+	ReEncryptPending   = "pending"
+	ReEncryptRunning   = "running"
+	ReEncryptSucceeded = "succeeded"
+	ReEncryptFailed    = "failed"
+	ReEncryptCanceled  = "canceled"
+)
+
+// ReEncryptJobStale 未结束的任务超过该时长未更新进度时视为已中断 (如实例重启)，允许重新创建
+const ReEncryptJobStale = 10 * time.Minute
+
+// errReEncryptCanceled 任务已被取消，可能由其他实例取消
+var errReEncryptCanceled = errors.New("重新加密任务已取消")
+
+// ReEncryptService 加密字段重新加密服务，密钥轮换后以后台任务按批执行
+type ReEncryptService interface {
+	StartReEncrypt(ctx context.Context, modelID string, batchSize int) (*model.MdReEncryptJob, error)
+	GetReEncryptJob(ctx context.Context, modelID, jobID string) (*model.MdReEncryptJob, error)
+	CancelReEncryptJob(ctx context.Context, modelID, jobID string) error
+}
+
+type reEncryptService struct {
+	crudSvc CRUDService
+	jobRepo repository.MdReEncryptJobRepository
+
+	jobsMu sync.Mutex
+	jobs   map[string]context.CancelFunc // 本实例中运行的任务
+}
+
+// NewReEncryptService 创建重新加密服务实例
+func NewReEncryptService(crudSvc CRUDService, jobRepo repository.MdReEncryptJobRepository) ReEncryptService {
+	return &reEncryptService{
+		crudSvc: crudSvc,
+		jobRepo: jobRepo,
+		jobs:    make(map[string]context.CancelFunc),
+	}
+}
+
+// StartReEncrypt 创建后台重新加密任务，同一模型同时只运行一个任务
+// 已处理的批次不会回滚，任务中断后重新创建即从未轮换的记录继续
+func (s *reEncryptService) StartReEncrypt(ctx context.Context, modelID string, batchSize int) (*model.MdReEncryptJob, error) {
+	scope := engine.DataScopeFromContext(ctx)
+	if !jobAccessible(scope, "") {
+		return nil, errors.New("未登录，无法创建重新加密任务")
+	}
+	if utils.GetFieldKeyRing() == nil {
+		return nil, engine.ErrFieldKeyRingMissing
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	// 1. 检查是否有运行中的任务，长时间未更新的任务标记为失败
+	unfinished, err := s.jobRepo.GetUnfinishedJobs(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("查询重新加密任务失败: %w", err)
+	}
+	for _, job := range unfinished {
+		if time.Since(job.UpdateAt) < ReEncryptJobStale {
+			return nil, fmt.Errorf("模型已有运行中的重新加密任务: %s", job.ID)
+		}
+		now := time.Now()
+		if err := s.jobRepo.UpdateJob(job.ID, map[string]any{"status": ReEncryptFailed, "error": "任务已中断", "finished_at": &now}); err != nil {
+			return nil, fmt.Errorf("更新重新加密任务失败: %w", err)
+		}
+	}
+
+	// 2. 记录任务
+	job := &model.MdReEncryptJob{
+		ID:        utils.GetSnowflake().GenerateIDString(),
+		TenantID:  scope.TenantID,
+		ModelID:   modelID,
+		BatchSize: batchSize,
+		Status:    ReEncryptPending,
+		CreateID:  scope.UserID,
+	}
+	if err := s.jobRepo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("创建重新加密任务失败: %w", err)
+	}
+
+	// 3. 后台执行，不随请求取消；重新加密针对整张表，不做数据权限过滤
+	runCtx, cancel := context.WithCancel(engine.WithoutDataScope(context.WithoutCancel(ctx)))
+	s.jobsMu.Lock()
+	s.jobs[job.ID] = cancel
+	s.jobsMu.Unlock()
+	go func() {
+		defer func() {
+			cancel()
+			s.jobsMu.Lock()
+			delete(s.jobs, job.ID)
+			s.jobsMu.Unlock()
+		}()
+		s.runReEncryptJob(runCtx, *job)
+	}()
+	return job, nil
+}
+
+// GetReEncryptJob 查询重新加密任务进度
+func (s *reEncryptService) GetReEncryptJob(ctx context.Context, modelID, jobID string) (*model.MdReEncryptJob, error) {
+	return s.reEncryptJob(ctx, modelID, jobID)
+}
+
+// CancelReEncryptJob 取消重新加密任务，已处理的批次不回滚；其他实例中运行的任务在下一批开始前结束
+func (s *reEncryptService) CancelReEncryptJob(ctx context.Context, modelID, jobID string) error {
+	job, err := s.reEncryptJob(ctx, modelID, jobID)
+	if err != nil {
+		return err
+	}
+	if job.FinishedAt != nil {
+		return errors.New("重新加密任务已结束")
+	}
+	if err := s.jobRepo.UpdateJob(job.ID, map[string]any{"status": ReEncryptCanceled}); err != nil {
+		return fmt.Errorf("取消重新加密任务失败: %w", err)
+	}
+	s.jobsMu.Lock()
+	if cancel := s.jobs[job.ID]; cancel != nil {
+		cancel()
+	}
+	s.jobsMu.Unlock()
+	return nil
+}
+
+// reEncryptJob 查找重新加密任务，仅创建者和管理员可以访问
+func (s *reEncryptService) reEncryptJob(ctx context.Context, modelID, jobID string) (*model.MdReEncryptJob, error) {
+	job, err := s.jobRepo.GetJobByID(jobID)
+	if err != nil || job.ModelID != modelID {
+		return nil, errors.New("重新加密任务不存在")
+	}
+	if !jobAccessible(engine.DataScopeFromContext(ctx), job.CreateID) {
+		return nil, errors.New("无权访问该重新加密任务")
+	}
+	return job, nil
+}
+
+// runReEncryptJob 执行重新加密任务，每批结束后保存进度并检查是否已取消
+func (s *reEncryptService) runReEncryptJob(ctx context.Context, job model.MdReEncryptJob) {
+	update := func(updates map[string]any) {
+		if err := s.jobRepo.UpdateJob(job.ID, updates); err != nil {
+			utils.SugarLogger.Errorf("Failed to update re-encrypt job %s: %v", job.ID, err)
+		}
+	}
+	finish := func(status string, err error, updates map[string]any) {
+		now := time.Now()
+		if updates == nil {
+			updates = make(map[string]any)
+		}
+		updates["status"] = status
+		updates["finished_at"] = &now
+		if err != nil {
+			updates["error"] = err.Error()
+		}
+		update(updates)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			utils.SugarLogger.Errorf("Re-encrypt job %s panic: %v", job.ID, r)
+			finish(ReEncryptFailed, fmt.Errorf("重新加密异常: %v", r), nil)
+		}
+	}()
+	update(map[string]any{"status": ReEncryptRunning})
+
+	processed, err := s.crudSvc.ReEncrypt(ctx, job.ModelID, job.BatchSize, func(processed int64) error {
+		record, err := s.jobRepo.GetJobByID(job.ID)
+		if err != nil {
+			return fmt.Errorf("读取重新加密任务失败: %w", err)
+		}
+		if record.Status == ReEncryptCanceled {
+			return errReEncryptCanceled
+		}
+		if err := s.jobRepo.UpdateJob(job.ID, map[string]any{"processed": processed}); err != nil {
+			return fmt.Errorf("保存重新加密进度失败: %w", err)
+		}
+		return nil
+	})
+
+	result := map[string]any{"processed": processed}
+	switch {
+	case ctx.Err() != nil || errors.Is(err, errReEncryptCanceled):
+		finish(ReEncryptCanceled, nil, result)
+	case err != nil:
+		utils.SugarLogger.Errorf("模型 %s 重新加密失败 (已处理 %d 条): %v", job.ModelID, processed, err)
+		finish(ReEncryptFailed, err, result)
+	default:
+		utils.SugarLogger.Infof("模型 %s 重新加密完成，共处理 %d 条", job.ModelID, processed)
+		finish(ReEncryptSucceeded, nil, result)
+	}
+}
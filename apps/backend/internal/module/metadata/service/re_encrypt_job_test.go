@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/module/metadata/repository"
+	"metadata-platform/internal/utils"
+)
+
+func TestReEncryptService_Job(t *testing.T) {
+	require.NoError(t, utils.InitFieldKeyRing("1:old-key,2:new-key", 1, "index-key"))
+	fields := testFields("id", "phone")
+	fields[1].EncryptAlgo = utils.FieldCipherAES
+	f := newTestCRUD(t, "CREATE TABLE test_secrets (id INTEGER PRIMARY KEY, phone TEXT)", fields, &model.MdModel{ID: "m_secret"})
+	ctx := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", All: true})
+	for i := 1; i <= 5; i++ {
+		_, err := f.svc.Create(ctx, "m_secret", map[string]any{"id": i, "phone": fmt.Sprintf("1380000000%d", i)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, utils.InitFieldKeyRing("1:old-key,2:new-key", 2, "index-key"))
+
+	// 任务表使用文件，后台任务与测试通过不同连接访问
+	jobDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "jobs.db")+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, jobDB.AutoMigrate(&model.MdReEncryptJob{}))
+	svc := NewReEncryptService(f.svc, repository.NewMdReEncryptJobRepository(jobDB))
+
+	t.Run("Runs in the background and records progress", func(t *testing.T) {
+		job, err := svc.StartReEncrypt(ctx, "m_secret", 2)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			job, err = svc.GetReEncryptJob(ctx, "m_secret", job.ID)
+			require.NoError(t, err)
+			return job.FinishedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, ReEncryptSucceeded, job.Status, job.Error)
+		assert.EqualValues(t, 5, job.Processed)
+		var phones []string
+		f.targetDB.Raw("SELECT phone FROM test_secrets").Scan(&phones)
+		for _, phone := range phones {
+			assert.True(t, strings.HasPrefix(phone, utils.FieldCiphertextPrefix(utils.FieldCipherAES, 2)))
+		}
+		row, err := f.svc.Get(ctx, "m_secret", "1")
+		require.NoError(t, err)
+		assert.Equal(t, "13800000001", row["phone"])
+
+		assert.Error(t, svc.CancelReEncryptJob(ctx, "m_secret", job.ID), "已结束的任务不能取消")
+		other := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u2"})
+		_, err = svc.GetReEncryptJob(other, "m_secret", job.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Requires a user", func(t *testing.T) {
+		_, err := svc.StartReEncrypt(context.Background(), "m_secret", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("One unfinished job per model", func(t *testing.T) {
+		require.NoError(t, jobDB.Create(&model.MdReEncryptJob{ID: "running", ModelID: "m_secret", Status: ReEncryptRunning}).Error)
+		_, err := svc.StartReEncrypt(ctx, "m_secret", 0)
+		assert.Error(t, err)
+
+		// 长时间未更新进度的任务视为已中断
+		jobDB.Exec("UPDATE md_re_encrypt_job SET update_at = ? WHERE id = 'running'", time.Now().Add(-2*ReEncryptJobStale))
+		job, err := svc.StartReEncrypt(ctx, "m_secret", 0)
+		require.NoError(t, err)
+		require.NoError(t, svc.CancelReEncryptJob(ctx, "m_secret", job.ID))
+		var stale model.MdReEncryptJob
+		jobDB.First(&stale, "id = 'running'")
+		assert.Equal(t, ReEncryptFailed, stale.Status)
+	})
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	auditModel "metadata-platform/internal/module/audit/model"
+	auditService "metadata-platform/internal/module/audit/service"
+	"metadata-platform/internal/module/metadata/engine"
+)
+
+// RecordVersion 记录时间线上的一次变更
+type RecordVersion struct {
+	ChangeID  string         `json:"change_id"`
+	TraceID   string         `json:"trace_id"`
+	Action    string         `json:"action"`
+	Operator  string         `json:"operator"`
+	ChangedAt time.Time      `json:"changed_at"`
+	Diff      []FieldChange  `json:"diff"`
+	Data      map[string]any `json:"data"` // 变更后的记录，删除后为 nil
+}
+
+// RecordHistoryService 基于数据变更日志的记录历史服务接口
+type RecordHistoryService interface {
+	// GetHistory 获取记录的变更时间线，按时间正序
+	GetHistory(ctx context.Context, modelID, recordID string) ([]*RecordVersion, error)
+	// GetAsOf 获取记录在指定时间点的状态，当时不存在时返回 nil
+	GetAsOf(ctx context.Context, modelID, recordID string, at time.Time) (map[string]any, error)
+	// RestoreAsOf 将记录恢复为指定时间点的状态，记录在最后一次变更后被改动过时返回冲突
+	RestoreAsOf(ctx context.Context, modelID, recordID string, at time.Time) error
+	// UndoChange 撤销一次变更，被撤销的字段在该变更后又被改动过时返回冲突
+	UndoChange(ctx context.Context, modelID, changeID string) error
+}
+
+type recordHistoryService struct {
+	sqlBuilder *engine.SQLBuilder
+	crudSvc    CRUDService
+	auditSvc   auditService.AuditService
+}
+
+// NewRecordHistoryService 创建记录历史服务实例
+func NewRecordHistoryService(sqlBuilder *engine.SQLBuilder, crudSvc CRUDService, auditSvc auditService.AuditService) RecordHistoryService {
+	return &recordHistoryService{
+		sqlBuilder: sqlBuilder,
+		crudSvc:    crudSvc,
+		auditSvc:   auditSvc,
+	}
+}
+
+// recordImage 解析后的变更日志
+type recordImage struct {
+	log    auditModel.SysDataChangeLog
+	before map[string]any
+	after  map[string]any
+}
+
+// GetHistory 获取记录的变更时间线
+func (s *recordHistoryService) GetHistory(ctx context.Context, modelID, recordID string) ([]*RecordVersion, error) {
+	md, images, err := s.load(ctx, modelID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*RecordVersion, 0, len(images))
+	for _, img := range images {
+		before, err := s.visible(ctx, md, img.before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := s.visible(ctx, md, img.after)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, &RecordVersion{
+			ChangeID:  img.log.ID,
+			TraceID:   img.log.TraceID,
+			Action:    img.log.Action,
+			Operator:  img.log.CreateBy,
+			ChangedAt: img.log.CreateAt,
+			Diff:      DiffRecords(before, after),
+			Data:      after,
+		})
+	}
+	return versions, nil
+}
+
+// GetAsOf 获取记录在指定时间点的状态
+func (s *recordHistoryService) GetAsOf(ctx context.Context, modelID, recordID string, at time.Time) (map[string]any, error) {
+	md, images, err := s.load(ctx, modelID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	img := imageAsOf(images, at)
+	if img == nil {
+		return nil, nil
+	}
+	return s.visible(ctx, md, img.after)
+}
+
+// RestoreAsOf 将记录恢复为指定时间点的状态
+func (s *recordHistoryService) RestoreAsOf(ctx context.Context, modelID, recordID string, at time.Time) error {
+	_, images, err := s.load(ctx, modelID, recordID)
+	if err != nil {
+		return err
+	}
+	var target map[string]any
+	if img := imageAsOf(images, at); img != nil {
+		target = img.after
+	}
+	// 以最后一次变更后的镜像作为当前应有状态
+	expected := images[len(images)-1].after
+	return s.crudSvc.Revert(ctx, modelID, recordID, expected, target)
+}
+
+// UndoChange 撤销一次变更
+func (s *recordHistoryService) UndoChange(ctx context.Context, modelID, changeID string) error {
+	if s.auditSvc == nil {
+		return errors.New("未启用数据变更日志")
+	}
+	log, err := s.auditSvc.GetDataChangeLogByID(changeID)
+	if err != nil {
+		return fmt.Errorf("查询变更日志失败: %w", err)
+	}
+	if log.ModelID != modelID {
+		return fmt.Errorf("变更 %s 不属于模型 %s", changeID, modelID)
+	}
+	// 校验当前用户对该记录的数据权限
+	if _, _, err := s.load(ctx, modelID, log.RecordID); err != nil {
+		return err
+	}
+
+	before, err := decodeImage(log.BeforeData)
+	if err != nil {
+		return err
+	}
+	after, err := decodeImage(log.AfterData)
+	if err != nil {
+		return err
+	}
+
+	// 新增/删除整条撤销，更新仅撤销本次变更涉及的字段
+	expected, target := after, before
+	if before != nil && after != nil {
+		expected = make(map[string]any)
+		target = make(map[string]any)
+		for _, c := range DiffRecords(before, after) {
+			expected[c.Field] = after[c.Field]
+			target[c.Field] = before[c.Field]
+		}
+	}
+	return s.crudSvc.Revert(ctx, modelID, log.RecordID, expected, target)
+}
+
+// load 加载模型及记录的全部变更日志，并校验当前用户对该记录的数据权限
+func (s *recordHistoryService) load(ctx context.Context, modelID, recordID string) (*engine.ModelData, []*recordImage, error) {
+	if s.auditSvc == nil {
+		return nil, nil, errors.New("未启用数据变更日志")
+	}
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	logs, err := s.auditSvc.GetRecordChanges(modelID, recordID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询变更日志失败: %w", err)
+	}
+	if len(logs) == 0 {
+		return nil, nil, fmt.Errorf("记录 %s 无变更历史", recordID)
+	}
+
+	images := make([]*recordImage, 0, len(logs))
+	for _, log := range logs {
+		before, err := decodeImage(log.BeforeData)
+		if err != nil {
+			return nil, nil, err
+		}
+		after, err := decodeImage(log.AfterData)
+		if err != nil {
+			return nil, nil, err
+		}
+		images = append(images, &recordImage{log: log, before: before, after: after})
+	}
+
+	// 以最近一次存在的镜像判断数据权限
+	scope := engine.DataScopeFromContext(ctx)
+	for i := len(images) - 1; i >= 0; i-- {
+		latest := images[i].after
+		if latest == nil {
+			latest = images[i].before
+		}
+		if latest != nil {
+			if !md.DataScopeAllows(scope, latest) {
+				return nil, nil, fmt.Errorf("无权访问记录 %s", recordID)
+			}
+			break
+		}
+	}
+	return md, images, nil
+}
+
+// visible 解密并脱敏镜像，返回当前用户可见的数据
+func (s *recordHistoryService) visible(ctx context.Context, md *engine.ModelData, image map[string]any) (map[string]any, error) {
+	if image == nil {
+		return nil, nil
+	}
+	row := make(map[string]any, len(image))
+	for k, v := range image {
+		row[k] = v
+	}
+	rows := []map[string]any{row}
+	if err := md.DecryptRows(rows); err != nil {
+		return nil, err
+	}
+	engine.ApplyMasks(rows, md.Masks, engine.DataScopeFromContext(ctx))
+	return row, nil
+}
+
+// imageAsOf 返回指定时间点前最后一次变更，之前无变更时返回 nil
+func imageAsOf(images []*recordImage, at time.Time) *recordImage {
+	var found *recordImage
+	for _, img := range images {
+		if img.log.CreateAt.After(at) {
+			break
+		}
+		found = img
+	}
+	return found
+}
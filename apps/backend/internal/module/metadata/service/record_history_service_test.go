@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+func TestRecordHistoryService(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_items (id INTEGER PRIMARY KEY, name TEXT, qty INTEGER)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	modelID := "m_history"
+	connID := "c_history"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	audit := &recordingAudit{}
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, audit)
+	svc := NewRecordHistoryService(builder, crud, audit)
+
+	migrateModelConfig(metaDB)
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: modelID, TableNameStr: "test_items", IsMain: true, ConnID: connID})
+	metaDB.Create(&model.MdModelField{ID: "f1", ModelID: modelID, ColumnName: "id", IsPrimaryKey: true})
+	metaDB.Create(&model.MdModelField{ID: "f2", ModelID: modelID, ColumnName: "name"})
+	metaDB.Create(&model.MdModelField{ID: "f3", ModelID: modelID, ColumnName: "qty"})
+	modelRepo.On("GetModelByID", modelID).Return(&model.MdModel{ID: modelID, ConnID: connID}, nil)
+
+	ctx := context.Background()
+	_, err := crud.Create(ctx, modelID, map[string]any{"id": 1, "name": "a", "qty": 1})
+	require.NoError(t, err)
+	require.NoError(t, crud.Update(ctx, modelID, "1", map[string]any{"name": "b"}))
+	require.NoError(t, crud.Update(ctx, modelID, "1", map[string]any{"qty": 2}))
+
+	current := func() map[string]any {
+		row, err := crud.Get(ctx, modelID, "1")
+		require.NoError(t, err)
+		return row
+	}
+
+	t.Run("History", func(t *testing.T) {
+		versions, err := svc.GetHistory(ctx, modelID, "1")
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		assert.Equal(t, ChangeCreate, versions[0].Action)
+		assert.Equal(t, ChangeUpdate, versions[1].Action)
+		require.Len(t, versions[1].Diff, 1)
+		assert.Equal(t, "name", versions[1].Diff[0].Field)
+		assert.Equal(t, "b", versions[1].Data["name"])
+	})
+
+	t.Run("AsOf", func(t *testing.T) {
+		first := audit.changes[0].CreateAt
+		row, err := svc.GetAsOf(ctx, modelID, "1", first)
+		require.NoError(t, err)
+		assert.Equal(t, "a", row["name"])
+
+		row, err = svc.GetAsOf(ctx, modelID, "1", first.Add(-time.Second))
+		require.NoError(t, err)
+		assert.Nil(t, row)
+	})
+
+	t.Run("Undo only reverts the changed fields", func(t *testing.T) {
+		require.NoError(t, svc.UndoChange(ctx, modelID, audit.changes[1].ID))
+		row := current()
+		assert.Equal(t, "a", row["name"])
+		assert.EqualValues(t, 2, row["qty"])
+		assert.Equal(t, ChangeRevert, audit.changes[len(audit.changes)-1].Action)
+	})
+
+	t.Run("Undo conflicts when field changed since", func(t *testing.T) {
+		// 第 2 条变更将 name 改为 b，撤销后当前为 a，再次撤销应冲突
+		err := svc.UndoChange(ctx, modelID, audit.changes[1].ID)
+		var conflict *VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "a", conflict.Current["name"])
+	})
+
+	t.Run("Restore as of creation", func(t *testing.T) {
+		require.NoError(t, svc.RestoreAsOf(ctx, modelID, "1", audit.changes[0].CreateAt))
+		row := current()
+		assert.Equal(t, "a", row["name"])
+		assert.EqualValues(t, 1, row["qty"])
+	})
+
+	t.Run("Restore conflicts on out-of-band change", func(t *testing.T) {
+		targetDB.Exec("UPDATE test_items SET qty = 9 WHERE id = 1")
+		err := svc.RestoreAsOf(ctx, modelID, "1", audit.changes[1].CreateAt)
+		var conflict *VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+	})
+
+	t.Run("Restore before creation deletes", func(t *testing.T) {
+		targetDB.Exec("UPDATE test_items SET qty = 1 WHERE id = 1")
+		require.NoError(t, svc.RestoreAsOf(ctx, modelID, "1", audit.changes[0].CreateAt.Add(-time.Second)))
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_items").Scan(&count)
+		assert.Zero(t, count)
+	})
+}
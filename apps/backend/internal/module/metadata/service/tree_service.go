@@ -4,236 +4,764 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
 	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
 	"metadata-platform/internal/module/metadata/repository"
 )
 
+// 树形结构错误
+var (
+	ErrNotTreeModel     = errors.New("模型未配置为树形结构")
+	ErrTreeNodeNotFound = errors.New("树节点不存在")
+	ErrTreeCycle        = errors.New("不能将节点移动到自身或其子孙节点下")
+	ErrTreeTooLarge     = errors.New("树节点数量超过一次加载上限，请按层级懒加载")
+)
+
+// 删除树节点时子节点的处理方式
+const (
+	TreeDeleteCascade  = "cascade"  // 级联删除整棵子树 (默认)
+	TreeDeleteReparent = "reparent" // 子节点上移到被删除节点的父节点下
+)
+
+// TreeRootParent 未配置时根节点的父节点值
+const TreeRootParent = "0"
+
+// 根节点父节点值的特殊配置
+const (
+	TreeRootNull  = "NULL"  // 根节点的父节点为空值
+	TreeRootEmpty = "EMPTY" // 根节点的父节点为空字符串
+)
+
+// 树节点附加属性
+const (
+	TreeChildrenKey    = "children"
+	TreeHasChildrenKey = "has_children"
+	TreeMatchedKey     = "matched"
+)
+
+// 树形加载限制
+const (
+	MaxTreeNodes           = 10000 // 一次加载的最大节点数，超出时须按层级懒加载
+	DefaultTreePageSize    = 100   // 子节点分页默认每页数量
+	MaxTreePageSize        = 1000  // 子节点分页每页数量上限
+	DefaultTreeSearchLimit = 100   // 搜索默认最多匹配的节点数
+	MaxTreeSearchLimit     = 1000  // 搜索最多匹配的节点数上限
+)
+
+// treePathSeparator 路径分隔符，路径形如 /1/5/9/，依次为根节点到节点自身的ID
+const treePathSeparator = "/"
+
 // TreeService 树形结构服务接口
 type TreeService interface {
-	GetTree(modelID string) ([]map[string]any, error)
-	GetChildren(modelID string, parentID string) ([]map[string]any, error)
-	GetPath(modelID string, id string) ([]map[string]any, error)
-	AddNode(modelID string, data map[string]any) (map[string]any, error)
-	MoveNode(modelID string, id string, targetParentID string) error
-	DeleteNode(modelID string, id string) error
+	GetTree(ctx context.Context, modelID string, depth int) ([]map[string]any, error)
+	GetChildren(ctx context.Context, modelID string, parentID string, page, pageSize int) ([]map[string]any, int64, error)
+	SearchTree(ctx context.Context, modelID string, field, keyword string, limit int) ([]map[string]any, error)
+	GetPath(ctx context.Context, modelID string, id string) ([]map[string]any, error)
+	GetDescendants(ctx context.Context, modelID string, id string) ([]map[string]any, error)
+	AddNode(ctx context.Context, modelID string, data map[string]any) (map[string]any, error)
+	MoveNode(ctx context.Context, modelID string, id string, targetParentID string) error
+	DeleteNode(ctx context.Context, modelID string, id string, mode string) error
 }
 
 type treeService struct {
-	modelRepo repository.MdModelRepository
-	crudSvc   CRUDService
-	executor  *engine.SQLExecutor
+	modelRepo  repository.MdModelRepository
+	crudSvc    CRUDService
+	sqlBuilder *engine.SQLBuilder
+	executor   *engine.SQLExecutor
 }
 
 // NewTreeService 创建树形结构服务实例
-func NewTreeService(modelRepo repository.MdModelRepository, crudSvc CRUDService, executor *engine.SQLExecutor) TreeService {
+func NewTreeService(modelRepo repository.MdModelRepository, crudSvc CRUDService, sqlBuilder *engine.SQLBuilder, executor *engine.SQLExecutor) TreeService {
 	return &treeService{
-		modelRepo: modelRepo,
-		crudSvc:   crudSvc,
-		executor:  executor,
+		modelRepo:  modelRepo,
+		crudSvc:    crudSvc,
+		sqlBuilder: sqlBuilder,
+		executor:   executor,
 	}
 }
 
-// GetTree 获取完整树结构
-func (s *treeService) GetTree(modelID string) ([]map[string]any, error) {
-	md, err := s.modelRepo.GetModelByID(modelID)
+// GetTree 从根节点开始逐层加载树，depth 大于 0 时只加载指定层数，最末层节点以 has_children 标记是否可继续展开
+// 加载的节点数超过 MaxTreeNodes 时返回 ErrTreeTooLarge
+func (s *treeService) GetTree(ctx context.Context, modelID string, depth int) ([]map[string]any, error) {
+	md, db, err := s.load(modelID)
 	if err != nil {
 		return nil, err
 	}
-	if !md.IsTree {
-		return nil, errors.New("model is not configured as tree structure")
-	}
 
-	// 获取所有数据
-	// TODO: 大数据量时应考虑懒加载，这里假设数据量适中，一次性加载
-	list, _, err := s.crudSvc.List(modelID, map[string]any{
-		"page_size": 10000,
-	})
+	where, args := treeRootCondition(md)
+	level, _, err := s.crudSvc.QueryWhereWithTx(ctx, modelID, WhereQuery{
+		Where: where, Args: args, OrderBy: treeSortFields(md), Limit: MaxTreeNodes + 1,
+	}, db)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.buildTree(list, "0", md.TreeParentField, "id")
+	var all []map[string]any
+	for d := 1; len(level) > 0; d++ {
+		all = append(all, level...)
+		if len(all) > MaxTreeNodes {
+			return nil, fmt.Errorf("%w: %d", ErrTreeTooLarge, MaxTreeNodes)
+		}
+		if depth > 0 && d >= depth {
+			if err := s.markHasChildren(ctx, db, md, level); err != nil {
+				return nil, err
+			}
+			break
+		}
+		for _, row := range level {
+			row[TreeHasChildrenKey] = false
+		}
+		if level, err = s.childrenOf(ctx, db, md, level, MaxTreeNodes-len(all)+1); err != nil {
+			return nil, err
+		}
+	}
+	return nestTree(md, all), nil
 }
 
-// GetChildren 获取直接子节点
-func (s *treeService) GetChildren(modelID string, parentID string) ([]map[string]any, error) {
-	md, err := s.modelRepo.GetModelByID(modelID)
+// GetChildren 分页获取直接子节点，parentID 为空或根节点值时获取根节点
+func (s *treeService) GetChildren(ctx context.Context, modelID string, parentID string, page, pageSize int) ([]map[string]any, int64, error) {
+	md, db, err := s.load(modelID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	if !md.IsTree {
-		return nil, errors.New("model is not configured as tree structure")
+	if pageSize <= 0 {
+		pageSize = DefaultTreePageSize
 	}
+	pageSize = min(pageSize, MaxTreePageSize)
+	page = max(page, 1)
 
-	// 构造查询参数
-	queryParams := map[string]any{
-		"filters": []any{
-			map[string]any{
-				"column_name": md.TreeParentField,
-				"operator":    "=",
-				"value":       parentID,
-			},
-		},
+	where, args := treeRootCondition(md)
+	if !isTreeRoot(md, parentID) {
+		where, args = fmt.Sprintf("`%s` = ?", md.Model.TreeParentField), []any{parentID}
 	}
-
-	list, _, err := s.crudSvc.List(modelID, queryParams)
-	return list, err
+	rows, total, err := s.crudSvc.QueryWhereWithTx(ctx, modelID, WhereQuery{
+		Where: where, Args: args, OrderBy: treeSortFields(md),
+		Limit: pageSize, Offset: (page - 1) * pageSize, Count: true,
+	}, db)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.markHasChildren(ctx, db, md, rows); err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
 }
 
-// GetPath 获取节点路径
-func (s *treeService) GetPath(modelID string, id string) ([]map[string]any, error) {
-	// 简单的实现：向上递归查找
-	// 优化实现：如果表中存了 path 字段，直接解析 path
-	md, err := s.modelRepo.GetModelByID(modelID)
+// SearchTree 按字段模糊搜索节点，返回匹配节点及其祖先组成的树，匹配节点以 matched 标记
+func (s *treeService) SearchTree(ctx context.Context, modelID string, field, keyword string, limit int) ([]map[string]any, error) {
+	md, db, err := s.load(modelID)
 	if err != nil {
 		return nil, err
 	}
+	if !hasColumn(md, field) {
+		return nil, fmt.Errorf("模型 %s 不存在字段 %s", modelID, field)
+	}
+	// 脱敏字段可被逐字符试探出原值，加密字段只能匹配到密文，均不允许搜索
+	encrypted := slices.ContainsFunc(md.EncryptedFields(), func(f *model.MdModelField) bool { return f.ColumnName == field })
+	if encrypted || md.MaskedColumns(engine.DataScopeFromContext(ctx))[field] {
+		return nil, fmt.Errorf("脱敏或加密字段 %s 不能用于搜索", field)
+	}
+	if keyword = strings.TrimSpace(keyword); keyword == "" {
+		return nil, errors.New("搜索关键字不能为空")
+	}
+	if limit <= 0 {
+		limit = DefaultTreeSearchLimit
+	}
+	limit = min(limit, MaxTreeSearchLimit)
 
-	path := make([]map[string]any, 0)
-	currentID := id
+	// 1. 查询匹配节点
+	matched, _, err := s.crudSvc.QueryWhereWithTx(ctx, modelID, WhereQuery{
+		Where: fmt.Sprintf("`%s` LIKE ? ESCAPE '!'", field), Args: []any{"%" + escapeLike(keyword) + "%"},
+		OrderBy: treeSortFields(md), Limit: limit,
+	}, db)
+	if err != nil {
+		return nil, err
+	}
 
-	for currentID != "0" && currentID != "" {
-		node, err := s.crudSvc.Get(modelID, currentID)
-		if err != nil || node == nil {
-			break
+	// 2. 收集祖先节点：有路径时直接解析，否则逐层按父节点查询
+	seen := make(map[string]bool, len(matched))
+	ids := make([]string, 0, len(matched))
+	for _, row := range matched {
+		id := treeID(row[md.PrimaryKey()])
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	for pending := matched; len(pending) > 0; {
+		var missing []string
+		for _, row := range pending {
+			ancestors, climb := parseTreePath(md, row), false
+			if ancestors == nil {
+				if parentID := treeID(row[md.Model.TreeParentField]); !isTreeRoot(md, parentID) {
+					ancestors, climb = []string{parentID}, true
+				}
+			}
+			for _, id := range ancestors {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+					if climb {
+						missing = append(missing, id)
+					}
+				}
+			}
 		}
-		// prepend
-		path = append([]map[string]any{node}, path...)
-
-		if pID, ok := node[md.TreeParentField]; ok {
-			currentID = fmt.Sprintf("%v", pID)
-		} else {
-			break
+		if pending, err = s.nodesByID(ctx, db, md, missing); err != nil {
+			return nil, err
 		}
 	}
 
+	// 3. 按排序字段重新读取全部节点并组装
+	rows, err := s.nodesByID(ctx, db, md, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.markHasChildren(ctx, db, md, rows); err != nil {
+		return nil, err
+	}
+	matchedIDs := make(map[string]bool, len(matched))
+	for _, id := range ids[:len(matched)] {
+		matchedIDs[id] = true
+	}
+	for _, row := range rows {
+		row[TreeMatchedKey] = matchedIDs[treeID(row[md.PrimaryKey()])]
+	}
+	return nestTree(md, rows), nil
+}
+
+// GetPath 获取从根节点到该节点的路径，配置了路径字段时以一次 IN 查询读取全部祖先节点
+func (s *treeService) GetPath(ctx context.Context, modelID string, id string) ([]map[string]any, error) {
+	md, db, err := s.load(modelID)
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.getNode(ctx, db, md, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := parseTreePath(md, node)
+	if ids == nil {
+		return s.walkUp(ctx, db, md, node)
+	}
+
+	rows, err := s.nodesByID(ctx, db, md, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]map[string]any, len(rows))
+	for _, row := range rows {
+		byID[treeID(row[md.PrimaryKey()])] = row
+	}
+	path := make([]map[string]any, 0, len(ids))
+	for _, ancestorID := range ids {
+		if row, ok := byID[ancestorID]; ok {
+			path = append(path, row)
+		}
+	}
 	return path, nil
 }
 
-// AddNode 添加节点
-func (s *treeService) AddNode(modelID string, data map[string]any) (map[string]any, error) {
+// GetDescendants 获取节点的全部子孙节点，父节点总在其子节点之前
+func (s *treeService) GetDescendants(ctx context.Context, modelID string, id string) ([]map[string]any, error) {
+	md, db, err := s.load(modelID)
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.getNode(ctx, db, md, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.descendants(ctx, db, md, node)
+}
+
+// AddNode 添加节点，配置了路径和层级字段时按父节点计算
+func (s *treeService) AddNode(ctx context.Context, modelID string, data map[string]any) (map[string]any, error) {
 	md, err := s.modelRepo.GetModelByID(modelID)
 	if err != nil {
 		return nil, err
 	}
 	if !md.IsTree {
 		// 非树形模型，直接调用普通创建
-		return s.crudSvc.Create(context.Background(), modelID, data)
+		return s.crudSvc.Create(ctx, modelID, data)
 	}
-
-	// 1. 自动计算 path 和 level (如果配置了字段)
-	parentID := "0"
-	if p, ok := data[md.TreeParentField]; ok {
-		parentID = fmt.Sprintf("%v", p)
+	tree, db, err := s.load(modelID)
+	if err != nil {
+		return nil, err
 	}
 
-	if md.TreePathField != "" || md.TreeLevelField != "" {
-		// parentPath := "/"
-		// parentLevel := 0
-
-		if parentID != "0" {
-			parent, err := s.crudSvc.Get(modelID, parentID)
+	var created map[string]any
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		// 1. 读取父节点的路径
+		var lineage []string
+		parentID := treeID(data[md.TreeParentField])
+		if !isTreeRoot(tree, parentID) {
+			parent, err := s.getNode(ctx, tx, tree, parentID)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			if parent != nil {
-				// if md.TreePathField != "" {
-				// 	if pp, ok := parent[md.TreePathField].(string); ok {
-				// 		parentPath = pp
-				// 	}
-				// }
-				// if md.TreeLevelField != "" {
-				// 	// 假设 level 是 int
-				// 	// 这里需要根据实际类型处理，简单处理先略过类型断言错误
-				// 	parentLevel = 1 // default
-				// }
+			if lineage, err = s.lineage(ctx, tx, tree, parent); err != nil {
+				return err
 			}
 		}
 
-		// 暂时无法在创建前知道 ID (除非使用 UUID 并且预生成)
-		// 这里的 Path 计算策略需要调整：
-		// 策略 A: 插入后更新 Path (需要两次 DB 操作)
-		// 策略 B: Path 不包含自身 ID (Parent Path)
-
-		// 采用策略 A
+		// 2. 创建节点，主键由数据库生成时路径在插入后补写
+		row := maps.Clone(data)
+		if isTreeRoot(tree, parentID) {
+			row[md.TreeParentField] = treeRoot(tree)
+		}
+		if md.TreeLevelField != "" {
+			row[md.TreeLevelField] = len(lineage) + 1
+		}
+		id := treeID(row[tree.PrimaryKey()])
+		if md.TreePathField != "" && id != "" {
+			row[md.TreePathField] = formatTreePath(append(lineage, id))
+		}
+		node, err := s.crudSvc.CreateWithTx(ctx, modelID, row, tx)
+		if err != nil {
+			return err
+		}
+		if md.TreePathField != "" && id == "" {
+			id = treeID(node[tree.PrimaryKey()])
+			if err := s.relocate(ctx, tx, tree, node, append(lineage, id), nil); err != nil {
+				return err
+			}
+			if node, err = s.getNode(ctx, tx, tree, id); err != nil {
+				return err
+			}
+		}
+		created = node
+		return nil
+	}); err != nil {
+		return nil, err
 	}
+	commitChanges()
+	return created, nil
+}
 
-	// 2. 创建节点
-	newNode, err := s.crudSvc.Create(context.Background(), modelID, data)
+// MoveNode 移动节点到目标父节点下，拒绝移动到自身或子孙节点下，并在同一事务中重算子树的路径和层级
+func (s *treeService) MoveNode(ctx context.Context, modelID string, id string, targetParentID string) error {
+	md, db, err := s.load(modelID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if targetParentID == id {
+		return ErrTreeCycle
 	}
 
-	// 3. 后置处理 Path (如果 ID 已生成)
-	// TODO: 更新 Path
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		node, err := s.getNode(ctx, tx, md, id)
+		if err != nil {
+			return err
+		}
 
-	return newNode, nil
+		// 1. 循环引用检测：目标父节点的祖先中不能包含该节点
+		var lineage []string
+		var parent any = targetParentID
+		if isTreeRoot(md, targetParentID) {
+			parent = treeRoot(md)
+		} else {
+			target, err := s.getNode(ctx, tx, md, targetParentID)
+			if err != nil {
+				return err
+			}
+			if lineage, err = s.lineage(ctx, tx, md, target); err != nil {
+				return err
+			}
+			if slices.Contains(lineage, id) {
+				return ErrTreeCycle
+			}
+		}
+
+		// 2. 更新父节点并重算子树
+		return s.relocate(ctx, tx, md, node, append(lineage, id), map[string]any{
+			md.Model.TreeParentField: parent,
+		})
+	}); err != nil {
+		return err
+	}
+	commitChanges()
+	return nil
 }
 
-// MoveNode 移动节点
-func (s *treeService) MoveNode(modelID string, id string, targetParentID string) error {
-	md, err := s.modelRepo.GetModelByID(modelID)
+// DeleteNode 删除节点，mode 为 cascade 时级联删除整棵子树，为 reparent 时子节点上移到被删除节点的父节点下
+func (s *treeService) DeleteNode(ctx context.Context, modelID string, id string, mode string) error {
+	if mode == "" {
+		mode = TreeDeleteCascade
+	}
+	if mode != TreeDeleteCascade && mode != TreeDeleteReparent {
+		return fmt.Errorf("不支持的删除方式: %s", mode)
+	}
+	md, db, err := s.load(modelID)
 	if err != nil {
 		return err
 	}
 
-	// 1. 循环引用检测
-	if id == targetParentID {
-		return errors.New("cannot move node to itself")
-	}
-	// 检查 targetParentID 是否是 id 的后代
-	if targetParentID != "0" {
-		path, err := s.GetPath(modelID, targetParentID)
+	ctx, commitChanges := beginChanges(ctx)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		node, err := s.getNode(ctx, tx, md, id)
 		if err != nil {
 			return err
 		}
-		for _, node := range path {
-			if fmt.Sprintf("%v", node["id"]) == id { // 假设 ID 字段名为 id
-				return errors.New("cannot move node to its descendant")
+
+		if mode == TreeDeleteReparent {
+			// 子节点挂到被删除节点的父节点下，并重算各自子树
+			lineage, err := s.lineage(ctx, tx, md, node)
+			if err != nil {
+				return err
+			}
+			// 子节点不论是否可见均须挂到新的父节点下
+			unscoped := engine.WithoutDataScope(ctx)
+			children, err := s.crudSvc.ListByFieldWithTx(unscoped, modelID, md.Model.TreeParentField, node[md.PrimaryKey()], tx)
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				childLineage := append(slices.Clone(lineage[:len(lineage)-1]), treeID(child[md.PrimaryKey()]))
+				if err := s.relocate(unscoped, tx, md, child, childLineage, map[string]any{
+					md.Model.TreeParentField: node[md.Model.TreeParentField],
+				}); err != nil {
+					return err
+				}
+			}
+		} else {
+			// 由深到浅删除子孙节点，子树不论是否可见均随节点删除
+			unscoped := engine.WithoutDataScope(ctx)
+			descendants, err := s.descendants(unscoped, tx, md, node)
+			if err != nil {
+				return err
+			}
+			for _, row := range slices.Backward(descendants) {
+				if err := s.crudSvc.DeleteWithTx(unscoped, modelID, treeID(row[md.PrimaryKey()]), tx); err != nil {
+					return err
+				}
 			}
 		}
+		return s.crudSvc.DeleteWithTx(ctx, modelID, id, tx)
+	}); err != nil {
+		return err
 	}
+	commitChanges()
+	return nil
+}
 
-	// 2. 更新 ParentID
-	err = s.crudSvc.Update(context.Background(), modelID, id, map[string]any{
-		md.TreeParentField: targetParentID,
-	})
-
-	// 3. 级联更新子节点 Path (如果使用了 Path 字段)
-	// TODO
+// load 加载树形模型及其数据库连接
+func (s *treeService) load(modelID string) (*engine.ModelData, *gorm.DB, error) {
+	md, err := s.sqlBuilder.LoadModelData(modelID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载模型失败: %w", err)
+	}
+	if !md.Model.IsTree || md.Model.TreeParentField == "" {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotTreeModel, modelID)
+	}
+	db, err := s.executor.GetConnection(md.Model.ConnID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return md, db, nil
+}
 
-	return err
+// getNode 读取单个节点
+func (s *treeService) getNode(ctx context.Context, tx *gorm.DB, md *engine.ModelData, id string) (map[string]any, error) {
+	rows, err := s.crudSvc.ListByFieldWithTx(ctx, md.Model.ID, md.PrimaryKey(), id, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTreeNodeNotFound, id)
+	}
+	return rows[0], nil
 }
 
-// DeleteNode 删除节点
-func (s *treeService) DeleteNode(modelID string, id string) error {
-	// 级联删除子节点
-	children, err := s.GetChildren(modelID, id)
+// lineage 返回从根节点到该节点的ID链，优先解析路径字段，未维护路径时逐级向上查找
+func (s *treeService) lineage(ctx context.Context, tx *gorm.DB, md *engine.ModelData, node map[string]any) ([]string, error) {
+	if ids := parseTreePath(md, node); ids != nil {
+		return ids, nil
+	}
+	chain, err := s.walkUp(ctx, tx, md, node)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	ids := make([]string, len(chain))
+	for i, row := range chain {
+		ids[i] = treeID(row[md.PrimaryKey()])
+	}
+	return ids, nil
+}
+
+// walkUp 逐级向上查找祖先节点，返回从根节点到该节点的记录
+func (s *treeService) walkUp(ctx context.Context, tx *gorm.DB, md *engine.ModelData, node map[string]any) ([]map[string]any, error) {
+	chain := []map[string]any{node}
+	seen := map[string]bool{treeID(node[md.PrimaryKey()]): true}
+	for parentID := treeID(node[md.Model.TreeParentField]); !isTreeRoot(md, parentID); {
+		if seen[parentID] {
+			return nil, fmt.Errorf("%w: 节点 %s 的祖先中存在循环", ErrTreeCycle, parentID)
+		}
+		seen[parentID] = true
+		parent, err := s.getNode(ctx, tx, md, parentID)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, parent)
+		parentID = treeID(parent[md.Model.TreeParentField])
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+// descendants 查询节点的全部子孙节点，父节点总在其子节点之前
+// 配置了路径字段时以路径前缀一次查询，否则逐层按父节点 IN 查询
+func (s *treeService) descendants(ctx context.Context, tx *gorm.DB, md *engine.ModelData, node map[string]any) ([]map[string]any, error) {
+	pk, pathField := md.PrimaryKey(), md.Model.TreePathField
+	if path := treeID(node[pathField]); pathField != "" && path != "" {
+		rows, err := s.crudSvc.ListWhereWithTx(ctx, md.Model.ID,
+			fmt.Sprintf("`%s` LIKE ? ESCAPE '!' AND `%s` <> ?", pathField, pk),
+			[]any{escapeLike(path) + "%", node[pk]}, tx)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(rows, func(a, b map[string]any) int {
+			return strings.Count(treeID(a[pathField]), treePathSeparator) - strings.Count(treeID(b[pathField]), treePathSeparator)
+		})
+		return rows, nil
+	}
+
+	var result []map[string]any
+	seen := map[string]bool{treeID(node[pk]): true}
+	for level := []map[string]any{node}; len(level) > 0; {
+		children, err := s.childrenOf(ctx, tx, md, level, 0)
+		if err != nil {
+			return nil, err
+		}
+		level = level[:0:0]
+		for _, row := range children {
+			if id := treeID(row[pk]); !seen[id] {
+				seen[id] = true
+				result = append(result, row)
+				level = append(level, row)
+			}
+		}
+	}
+	return result, nil
+}
+
+// childrenOf 以 IN 查询批量读取多个节点的直接子节点，按排序字段排序，limit 大于 0 时最多读取 limit 条
+func (s *treeService) childrenOf(ctx context.Context, tx *gorm.DB, md *engine.ModelData, parents []map[string]any, limit int) ([]map[string]any, error) {
+	var result []map[string]any
+	for start := 0; start < len(parents) && (limit <= 0 || len(result) < limit); start += DefaultBatchChunkSize {
+		part := parents[start:min(start+DefaultBatchChunkSize, len(parents))]
+		keys := make([]any, len(part))
+		for i, row := range part {
+			keys[i] = row[md.PrimaryKey()]
+		}
+		query := WhereQuery{Where: inCondition(md.Model.TreeParentField, len(keys)), Args: keys, OrderBy: treeSortFields(md)}
+		if limit > 0 {
+			query.Limit = limit - len(result)
+		}
+		rows, _, err := s.crudSvc.QueryWhereWithTx(ctx, md.Model.ID, query, tx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rows...)
+	}
+	return result, nil
+}
+
+// nodesByID 以 IN 查询批量读取节点，按排序字段排序
+func (s *treeService) nodesByID(ctx context.Context, tx *gorm.DB, md *engine.ModelData, ids []string) ([]map[string]any, error) {
+	var result []map[string]any
+	for start := 0; start < len(ids); start += DefaultBatchChunkSize {
+		part := toAnySlice(ids[start:min(start+DefaultBatchChunkSize, len(ids))])
+		rows, _, err := s.crudSvc.QueryWhereWithTx(ctx, md.Model.ID, WhereQuery{
+			Where: inCondition(md.PrimaryKey(), len(part)), Args: part, OrderBy: treeSortFields(md),
+		}, tx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rows...)
+	}
+	return result, nil
+}
+
+// markHasChildren 以分组计数标记节点是否有子节点
+func (s *treeService) markHasChildren(ctx context.Context, tx *gorm.DB, md *engine.ModelData, rows []map[string]any) error {
+	for start := 0; start < len(rows); start += DefaultBatchChunkSize {
+		part := rows[start:min(start+DefaultBatchChunkSize, len(rows))]
+		keys := make([]any, len(part))
+		for i, row := range part {
+			keys[i] = row[md.PrimaryKey()]
+		}
+		counts, err := s.crudSvc.CountByWhereWithTx(ctx, md.Model.ID, md.Model.TreeParentField,
+			inCondition(md.Model.TreeParentField, len(keys)), keys, tx)
+		if err != nil {
+			return err
+		}
+		for _, row := range part {
+			row[TreeHasChildrenKey] = counts[treeID(row[md.PrimaryKey()])] > 0
+		}
 	}
-	for _, child := range children {
-		childID := fmt.Sprintf("%v", child["id"])
-		if err := s.DeleteNode(modelID, childID); err != nil {
+	return nil
+}
+
+// relocate 将节点置于新的ID链下，在当前事务中逐条重算节点及其子树的路径和层级
+// changes 为节点自身需要同时更新的字段 (如父节点)；数据权限只作用于节点自身，子树不论是否可见均须重算
+func (s *treeService) relocate(ctx context.Context, tx *gorm.DB, md *engine.ModelData, node map[string]any, lineage []string, changes map[string]any) error {
+	pk, parentField := md.PrimaryKey(), md.Model.TreeParentField
+	pathField, levelField := md.Model.TreePathField, md.Model.TreeLevelField
+	unscoped := engine.WithoutDataScope(ctx)
+
+	rows := []map[string]any{node}
+	if pathField != "" || levelField != "" {
+		descendants, err := s.descendants(unscoped, tx, md, node)
+		if err != nil {
 			return err
 		}
+		rows = append(rows, descendants...)
+	}
+
+	lineages := map[string][]string{treeID(node[pk]): lineage}
+	for i, row := range rows {
+		id := treeID(row[pk])
+		data := make(map[string]any)
+		if i == 0 {
+			maps.Copy(data, changes)
+		} else {
+			parentLineage, ok := lineages[treeID(row[parentField])]
+			if !ok {
+				return fmt.Errorf("节点 %s 的路径与父节点不一致", id)
+			}
+			lineages[id] = append(slices.Clone(parentLineage), id)
+		}
+		ids := lineages[id]
+		if path := formatTreePath(ids); pathField != "" && treeID(row[pathField]) != path {
+			data[pathField] = path
+		}
+		if levelField != "" && treeID(row[levelField]) != strconv.Itoa(len(ids)) {
+			data[levelField] = len(ids)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if md.HasVersionField() {
+			data[md.Model.VersionField] = row[md.Model.VersionField]
+		}
+		rowCtx := ctx
+		if i > 0 {
+			rowCtx = unscoped
+		}
+		if err := s.crudSvc.UpdateWithTx(rowCtx, md.Model.ID, id, data, tx); err != nil {
+			return fmt.Errorf("更新节点 %s 失败: %w", id, err)
+		}
 	}
+	return nil
+}
 
-	return s.crudSvc.Delete(context.Background(), modelID, id)
+// treeRoot 根节点的父节点值，nil 表示空值
+func treeRoot(md *engine.ModelData) any {
+	switch value := md.Model.TreeRootValue; strings.ToUpper(value) {
+	case "":
+		return TreeRootParent
+	case TreeRootNull:
+		return nil
+	case TreeRootEmpty:
+		return ""
+	default:
+		return value
+	}
 }
 
-func (s *treeService) buildTree(list []map[string]any, parentID string, parentField string, idField string) ([]map[string]any, error) {
+// isTreeRoot 父节点值为空或等于根节点值时为根节点
+func isTreeRoot(md *engine.ModelData, parentID string) bool {
+	return parentID == "" || parentID == treeID(treeRoot(md))
+}
+
+// treeRootCondition 根节点的查询条件，父节点为空值的节点始终视为根节点
+func treeRootCondition(md *engine.ModelData) (string, []any) {
+	root := treeRoot(md)
+	if root == nil {
+		return fmt.Sprintf("`%s` IS NULL", md.Model.TreeParentField), nil
+	}
+	return fmt.Sprintf("`%s` IS NULL OR `%s` = ?", md.Model.TreeParentField, md.Model.TreeParentField), []any{root}
+}
+
+// treeSortFields 解析同级节点排序字段
+func treeSortFields(md *engine.ModelData) []string {
+	var fields []string
+	for _, f := range strings.Split(md.Model.TreeSortField, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// nestTree 按父节点将节点组装为树，父节点不在列表中的节点作为顶层节点，保持原有顺序
+func nestTree(md *engine.ModelData, rows []map[string]any) []map[string]any {
+	byID := make(map[string]map[string]any, len(rows))
+	for _, row := range rows {
+		byID[treeID(row[md.PrimaryKey()])] = row
+	}
 	tree := make([]map[string]any, 0)
-	for _, item := range list {
-		pID := fmt.Sprintf("%v", item[parentField])
-		if pID == parentID || (parentID == "0" && (pID == "" || pID == "<nil>")) {
-			id := fmt.Sprintf("%v", item[idField])
-			children, _ := s.buildTree(list, id, parentField, idField)
-			if len(children) > 0 {
-				item["children"] = children
-			}
-			tree = append(tree, item)
+	for _, row := range rows {
+		parent, ok := byID[treeID(row[md.Model.TreeParentField])]
+		if !ok || treeID(parent[md.PrimaryKey()]) == treeID(row[md.PrimaryKey()]) {
+			tree = append(tree, row)
+			continue
 		}
+		children, _ := parent[TreeChildrenKey].([]map[string]any)
+		parent[TreeChildrenKey] = append(children, row)
+		parent[TreeHasChildrenKey] = true
+	}
+	return tree
+}
+
+// treeID 节点ID或字段值的字符串形式，空值为空字符串
+func treeID(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", normalizeValue(v))
+}
+
+// formatTreePath 由ID链生成路径
+func formatTreePath(ids []string) string {
+	return treePathSeparator + strings.Join(ids, treePathSeparator) + treePathSeparator
+}
+
+// parseTreePath 解析节点的路径字段，未配置或未维护路径时返回 nil
+func parseTreePath(md *engine.ModelData, node map[string]any) []string {
+	if md.Model.TreePathField == "" {
+		return nil
+	}
+	path := strings.Trim(treeID(node[md.Model.TreePathField]), treePathSeparator)
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, treePathSeparator)
+}
+
+// inCondition 构造 IN 查询条件
+func inCondition(column string, n int) string {
+	return fmt.Sprintf("`%s` IN (%s)", column, strings.TrimSuffix(strings.Repeat("?, ", n), ", "))
+}
+
+// escapeLike 转义 LIKE 通配符，配合 ESCAPE '!' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// toAnySlice 字符串切片转为查询参数
+func toAnySlice(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
 	}
-	return tree, nil
+	return args
 }
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"metadata-platform/internal/module/metadata/engine"
+	"metadata-platform/internal/module/metadata/model"
+	"metadata-platform/internal/utils"
+)
+
+func TestTreeService_Maintenance(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_depts (id INTEGER PRIMARY KEY AUTOINCREMENT, parent_id INTEGER DEFAULT 0, name TEXT, path TEXT DEFAULT '', level INTEGER DEFAULT 0, ver INTEGER DEFAULT 1)")
+	targetDB.Exec("CREATE TABLE test_areas (id TEXT PRIMARY KEY, parent_id TEXT, name TEXT)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_tree"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+	svc := NewTreeService(modelRepo, crud, builder, executor)
+
+	migrateModelConfig(metaDB)
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: "m_dept", TableNameStr: "test_depts", IsMain: true, ConnID: connID})
+	for i, column := range []string{"id", "parent_id", "name", "path", "level", "ver"} {
+		metaDB.Create(&model.MdModelField{ID: "fd" + column, ModelID: "m_dept", ColumnName: column, IsPrimaryKey: i == 0, IsAutoIncrement: i == 0})
+	}
+	metaDB.Create(&model.MdModelTable{ID: "t2", ModelID: "m_area", TableNameStr: "test_areas", IsMain: true, ConnID: connID})
+	for i, column := range []string{"id", "parent_id", "name"} {
+		metaDB.Create(&model.MdModelField{ID: "fa" + column, ModelID: "m_area", ColumnName: column, IsPrimaryKey: i == 0})
+	}
+	modelRepo.On("GetModelByID", "m_dept").Return(&model.MdModel{
+		ID: "m_dept", ConnID: connID, IsTree: true, TreeParentField: "parent_id",
+		TreePathField: "path", TreeLevelField: "level", VersionField: "ver",
+	}, nil)
+	modelRepo.On("GetModelByID", "m_area").Return(&model.MdModel{ID: "m_area", ConnID: connID, IsTree: true, TreeParentField: "parent_id"}, nil)
+
+	ctx := context.Background()
+	add := func(modelID string, data map[string]any) map[string]any {
+		node, err := svc.AddNode(ctx, modelID, data)
+		require.NoError(t, err)
+		return node
+	}
+	node := func(id string) map[string]any {
+		row, err := crud.Get(ctx, "m_dept", id)
+		require.NoError(t, err)
+		return row
+	}
+
+	// 1 ─ 2 ─ 3 ─ 4
+	//   └ 5
+	add("m_dept", map[string]any{"name": "root"})
+	add("m_dept", map[string]any{"name": "a", "parent_id": 1})
+	add("m_dept", map[string]any{"name": "b", "parent_id": 2})
+	add("m_dept", map[string]any{"name": "c", "parent_id": 3})
+	add("m_dept", map[string]any{"name": "d", "parent_id": 1})
+
+	t.Run("AddNode maintains path and level", func(t *testing.T) {
+		assert.Equal(t, "/1/", node("1")["path"])
+		assert.Equal(t, "/1/2/3/4/", node("4")["path"])
+		assert.EqualValues(t, 4, node("4")["level"])
+
+		_, err := svc.AddNode(ctx, "m_dept", map[string]any{"name": "x", "parent_id": 99})
+		assert.ErrorIs(t, err, ErrTreeNodeNotFound)
+	})
+
+	t.Run("Path and descendants", func(t *testing.T) {
+		path, err := svc.GetPath(ctx, "m_dept", "4")
+		require.NoError(t, err)
+		require.Len(t, path, 4)
+		assert.Equal(t, "root", path[0]["name"])
+		assert.Equal(t, "c", path[3]["name"])
+
+		descendants, err := svc.GetDescendants(ctx, "m_dept", "2")
+		require.NoError(t, err)
+		require.Len(t, descendants, 2)
+		assert.Equal(t, "b", descendants[0]["name"])
+	})
+
+	t.Run("MoveNode rejects cycles", func(t *testing.T) {
+		assert.ErrorIs(t, svc.MoveNode(ctx, "m_dept", "2", "2"), ErrTreeCycle)
+		assert.ErrorIs(t, svc.MoveNode(ctx, "m_dept", "2", "4"), ErrTreeCycle)
+		assert.Equal(t, "/1/2/3/4/", node("4")["path"])
+	})
+
+	t.Run("MoveNode recomputes the subtree", func(t *testing.T) {
+		require.NoError(t, svc.MoveNode(ctx, "m_dept", "3", "5"))
+		assert.EqualValues(t, 5, node("3")["parent_id"])
+		assert.Equal(t, "/1/5/3/", node("3")["path"])
+		assert.Equal(t, "/1/5/3/4/", node("4")["path"])
+		assert.EqualValues(t, 4, node("4")["level"])
+		assert.EqualValues(t, 3, node("4")["ver"])
+
+		require.NoError(t, svc.MoveNode(ctx, "m_dept", "5", ""))
+		assert.Equal(t, "/5/3/4/", node("4")["path"])
+		assert.EqualValues(t, 3, node("4")["level"])
+	})
+
+	t.Run("DeleteNode reparents children", func(t *testing.T) {
+		require.NoError(t, svc.DeleteNode(ctx, "m_dept", "3", TreeDeleteReparent))
+		assert.Nil(t, node("3"))
+		assert.EqualValues(t, 5, node("4")["parent_id"])
+		assert.Equal(t, "/5/4/", node("4")["path"])
+		assert.EqualValues(t, 2, node("4")["level"])
+	})
+
+	t.Run("DeleteNode cascades", func(t *testing.T) {
+		require.NoError(t, svc.DeleteNode(ctx, "m_dept", "5", ""))
+		assert.Nil(t, node("5"))
+		assert.Nil(t, node("4"))
+		assert.NotNil(t, node("2"))
+
+		assert.Error(t, svc.DeleteNode(ctx, "m_dept", "2", "orphan"))
+	})
+
+	t.Run("Tree without path column", func(t *testing.T) {
+		add("m_area", map[string]any{"id": "cn", "name": "China"})
+		add("m_area", map[string]any{"id": "sh", "parent_id": "cn", "name": "Shanghai"})
+		add("m_area", map[string]any{"id": "pd", "parent_id": "sh", "name": "Pudong"})
+
+		path, err := svc.GetPath(ctx, "m_area", "pd")
+		require.NoError(t, err)
+		require.Len(t, path, 3)
+		assert.Equal(t, "cn", path[0]["id"])
+
+		assert.ErrorIs(t, svc.MoveNode(ctx, "m_area", "cn", "pd"), ErrTreeCycle)
+
+		require.NoError(t, svc.DeleteNode(ctx, "m_area", "cn", TreeDeleteCascade))
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_areas").Scan(&count)
+		assert.Zero(t, count)
+	})
+}
+
+func TestTreeService_MaintenanceIgnoresSubtreeScope(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_folders (id INTEGER PRIMARY KEY, parent_id INTEGER DEFAULT 0, path TEXT, level INTEGER, owner_id TEXT)")
+	// 1 ─ 2 ─ 3, 4 为根节点；2 属于其他用户，对当前用户不可见
+	targetDB.Exec(`INSERT INTO test_folders VALUES (1, 0, '/1/', 1, 'u1'), (2, 1, '/1/2/', 2, 'u2'), (3, 2, '/1/2/3/', 3, 'u1'), (4, 0, '/4/', 1, 'u1')`)
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_tree_scope"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+	svc := NewTreeService(modelRepo, crud, builder, executor)
+
+	migrateModelConfig(metaDB)
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: "m_folder", TableNameStr: "test_folders", IsMain: true, ConnID: connID})
+	for i, column := range []string{"id", "parent_id", "path", "level", "owner_id"} {
+		metaDB.Create(&model.MdModelField{ID: "ff" + column, ModelID: "m_folder", ColumnName: column, IsPrimaryKey: i == 0})
+	}
+	modelRepo.On("GetModelByID", "m_folder").Return(&model.MdModel{
+		ID: "m_folder", ConnID: connID, IsTree: true, TreeParentField: "parent_id",
+		TreePathField: "path", TreeLevelField: "level", DataOwnerField: "owner_id",
+	}, nil)
+
+	ctx := engine.WithDataScope(context.Background(), &engine.DataScope{UserID: "u1", Self: true})
+	path := func(id int) string {
+		var p string
+		targetDB.Raw("SELECT path FROM test_folders WHERE id = ?", id).Scan(&p)
+		return p
+	}
+
+	t.Run("MoveNode recomputes hidden descendants", func(t *testing.T) {
+		require.NoError(t, svc.MoveNode(ctx, "m_folder", "1", "4"))
+		assert.Equal(t, "/4/1/2/", path(2))
+		assert.Equal(t, "/4/1/2/3/", path(3))
+	})
+
+	t.Run("DeleteNode cascades into hidden descendants", func(t *testing.T) {
+		require.NoError(t, svc.DeleteNode(ctx, "m_folder", "1", TreeDeleteCascade))
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_folders").Scan(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestTreeService_Loading(t *testing.T) {
+	if utils.SugarLogger == nil {
+		utils.SugarLogger = zap.NewNop().Sugar()
+	}
+
+	metaDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	targetDB.Exec("CREATE TABLE test_menus (id INTEGER PRIMARY KEY, parent_id INTEGER, name TEXT, sort INTEGER)")
+
+	modelRepo := new(MockMdModelRepo)
+	connRepo := new(MockMdConnRepo)
+	connID := "c_tree"
+
+	builder := engine.NewSQLBuilder(metaDB, modelRepo)
+	executor := engine.NewSQLExecutor(metaDB, connRepo)
+	executor.SetCustomConnection(connID, targetDB)
+	crud := NewCRUDService(builder, executor, NewDataValidator(), nil, nil)
+	svc := NewTreeService(modelRepo, crud, builder, executor)
+
+	migrateModelConfig(metaDB)
+	metaDB.Create(&model.MdModelTable{ID: "t1", ModelID: "m_menu", TableNameStr: "test_menus", IsMain: true, ConnID: connID})
+	for i, column := range []string{"id", "parent_id", "name", "sort"} {
+		metaDB.Create(&model.MdModelField{ID: "fm" + column, ModelID: "m_menu", ColumnName: column, IsPrimaryKey: i == 0})
+	}
+	modelRepo.On("GetModelByID", "m_menu").Return(&model.MdModel{
+		ID: "m_menu", ConnID: connID, IsTree: true, TreeParentField: "parent_id",
+		TreeRootValue: TreeRootNull, TreeSortField: "sort desc",
+	}, nil)
+
+	ctx := context.Background()
+	// system(1) ─ users(3) ─ user roles(5)
+	//           └ logs(4)
+	// reports(2)
+	for _, row := range []map[string]any{
+		{"id": 1, "name": "system", "sort": 2},
+		{"id": 2, "name": "reports", "sort": 1},
+		{"id": 3, "parent_id": 1, "name": "users", "sort": 2},
+		{"id": 4, "parent_id": 1, "name": "logs", "sort": 1},
+		{"id": 5, "parent_id": 3, "name": "user roles", "sort": 1},
+	} {
+		_, err := svc.AddNode(ctx, "m_menu", row)
+		require.NoError(t, err)
+	}
+
+	t.Run("Root value NULL is written for root nodes", func(t *testing.T) {
+		var count int64
+		targetDB.Raw("SELECT COUNT(*) FROM test_menus WHERE parent_id IS NULL").Scan(&count)
+		assert.EqualValues(t, 2, count)
+	})
+
+	t.Run("GetTree loads by level with has_children", func(t *testing.T) {
+		roots, err := svc.GetTree(ctx, "m_menu", 1)
+		require.NoError(t, err)
+		require.Len(t, roots, 2)
+		assert.Equal(t, "system", roots[0]["name"])
+		assert.Equal(t, true, roots[0][TreeHasChildrenKey])
+		assert.Equal(t, false, roots[1][TreeHasChildrenKey])
+		assert.Nil(t, roots[0][TreeChildrenKey])
+
+		full, err := svc.GetTree(ctx, "m_menu", 0)
+		require.NoError(t, err)
+		children := full[0][TreeChildrenKey].([]map[string]any)
+		require.Len(t, children, 2)
+		assert.Equal(t, "users", children[0]["name"])
+		assert.Len(t, children[0][TreeChildrenKey], 1)
+		assert.Equal(t, false, children[1][TreeHasChildrenKey])
+	})
+
+	t.Run("GetChildren paginates", func(t *testing.T) {
+		rows, total, err := svc.GetChildren(ctx, "m_menu", "1", 2, 1)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, total)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "logs", rows[0]["name"])
+
+		roots, total, err := svc.GetChildren(ctx, "m_menu", "", 1, 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, total)
+		assert.Len(t, roots, 2)
+	})
+
+	t.Run("SearchTree returns matches with ancestors", func(t *testing.T) {
+		tree, err := svc.SearchTree(ctx, "m_menu", "name", "role", 0)
+		require.NoError(t, err)
+		require.Len(t, tree, 1)
+		assert.Equal(t, "system", tree[0]["name"])
+		assert.Equal(t, false, tree[0][TreeMatchedKey])
+		users := tree[0][TreeChildrenKey].([]map[string]any)
+		require.Len(t, users, 1)
+		roles := users[0][TreeChildrenKey].([]map[string]any)
+		require.Len(t, roles, 1)
+		assert.Equal(t, true, roles[0][TreeMatchedKey])
+
+		_, err = svc.SearchTree(ctx, "m_menu", "missing", "x", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("SearchTree rejects masked and encrypted fields", func(t *testing.T) {
+		metaDB.Create(&model.MdModelFieldEnhancement{ID: "e1", ModelID: "m_menu", FieldID: "fmsort", MaskType: "hide"})
+		_, err := svc.SearchTree(ctx, "m_menu", "sort", "1", 0)
+		assert.Error(t, err)
+		admin := engine.WithDataScope(ctx, &engine.DataScope{UserID: "root", All: true, Admin: true})
+		_, err = svc.SearchTree(admin, "m_menu", "sort", "1", 0)
+		assert.NoError(t, err, "豁免脱敏的用户可以搜索")
+
+		metaDB.Model(&model.MdModelField{}).Where("id = ?", "fmname").Update("encrypt_algo", utils.FieldCipherAES)
+		_, err = svc.SearchTree(admin, "m_menu", "name", "role", 0)
+		assert.Error(t, err)
+	})
+}
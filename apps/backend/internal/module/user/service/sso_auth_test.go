@@ -134,6 +134,14 @@ func (m *MockAuditService) GetAllDataChangeLogs(filters map[string]interface{})
 	return nil, nil
 }
 
+func (m *MockAuditService) GetDataChangeLogByID(id string) (*auditModel.SysDataChangeLog, error) {
+	return nil, nil
+}
+
+func (m *MockAuditService) GetRecordChanges(modelID, recordID string) ([]auditModel.SysDataChangeLog, error) {
+	return nil, nil
+}
+
 func (m *MockAuditService) GetRecentLoginLogs(limit int) ([]auditModel.SysLoginLog, error) {
 	return nil, nil
 }
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+// 字段加密算法
+const (
+	FieldCipherSM4 = "sm4"     // 国密 SM4-GCM
+	FieldCipherAES = "aes-gcm" // AES-256-GCM
+)
+
+// fieldCipherPrefix 密文前缀，完整格式为 ENC:<算法>:<密钥版本>:<base64(nonce|密文)>
+const fieldCipherPrefix = "ENC:"
+
+// FieldKeyRing 字段加密密钥环，支持多版本密钥以便轮换
+type FieldKeyRing struct {
+	keys     map[int][]byte
+	current  int
+	indexKey []byte
+}
+
+var (
+	fieldKeyRing   *FieldKeyRing
+	fieldKeyRingMu sync.RWMutex
+)
+
+// InitFieldKeyRing 初始化字段加密密钥环
+// keys 格式为 "版本:密钥,版本:密钥"，current 为加密使用的密钥版本，indexKey 为盲索引密钥
+func InitFieldKeyRing(keys string, current int, indexKey string) error {
+	ring, err := NewFieldKeyRing(keys, current, indexKey)
+	if err != nil {
+		return err
+	}
+	fieldKeyRingMu.Lock()
+	fieldKeyRing = ring
+	fieldKeyRingMu.Unlock()
+	return nil
+}
+
+// GetFieldKeyRing 获取全局字段加密密钥环，未初始化时返回 nil
+func GetFieldKeyRing() *FieldKeyRing {
+	fieldKeyRingMu.RLock()
+	defer fieldKeyRingMu.RUnlock()
+	return fieldKeyRing
+}
+
+// NewFieldKeyRing 创建字段加密密钥环
+func NewFieldKeyRing(keys string, current int, indexKey string) (*FieldKeyRing, error) {
+	ring := &FieldKeyRing{keys: make(map[int][]byte), current: current}
+	for _, item := range strings.Split(keys, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		version, secret, ok := strings.Cut(item, ":")
+		if !ok || secret == "" {
+			return nil, fmt.Errorf("字段加密密钥格式错误: %s", item)
+		}
+		v, err := strconv.Atoi(version)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("字段加密密钥版本无效: %s", version)
+		}
+		ring.keys[v] = sm3Sum([]byte(secret))
+	}
+	if len(ring.keys) == 0 {
+		return nil, errors.New("未配置字段加密密钥")
+	}
+	if _, ok := ring.keys[current]; !ok {
+		return nil, fmt.Errorf("当前字段加密密钥版本 %d 不存在", current)
+	}
+	if indexKey == "" {
+		return nil, errors.New("未配置盲索引密钥")
+	}
+	ring.indexKey = sm3Sum([]byte(indexKey))
+	return ring, nil
+}
+
+// CurrentVersion 返回当前加密使用的密钥版本
+func (r *FieldKeyRing) CurrentVersion() int {
+	return r.current
+}
+
+// Encrypt 使用当前版本密钥加密
+func (r *FieldKeyRing) Encrypt(algo, plaintext string) (string, error) {
+	aead, err := r.aead(algo, r.current)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return fmt.Sprintf("%s%s:%d:%s", fieldCipherPrefix, algo, r.current, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Decrypt 解密，返回明文及密文使用的密钥版本
+func (r *FieldKeyRing) Decrypt(ciphertext string) (string, int, error) {
+	algo, version, payload, err := ParseFieldCiphertext(ciphertext)
+	if err != nil {
+		return "", 0, err
+	}
+	aead, err := r.aead(algo, version)
+	if err != nil {
+		return "", version, err
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", version, fmt.Errorf("密文解码失败: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", version, errors.New("密文长度无效")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", version, fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plain), version, nil
+}
+
+// BlindIndex 计算确定性盲索引 (HMAC-SM3)，用于密文列的等值查询
+func (r *FieldKeyRing) BlindIndex(value string) string {
+	mac := hmac.New(sm3.New, r.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsFieldCiphertext 判断值是否为字段密文
+func IsFieldCiphertext(s string) bool {
+	return strings.HasPrefix(s, fieldCipherPrefix)
+}
+
+// ParseFieldCiphertext 解析字段密文，返回算法、密钥版本和密文载荷
+func ParseFieldCiphertext(s string) (algo string, version int, payload string, err error) {
+	if !IsFieldCiphertext(s) {
+		return "", 0, "", errors.New("不是有效的字段密文")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(s, fieldCipherPrefix), ":", 3)
+	if len(parts) != 3 {
+		return "", 0, "", errors.New("字段密文格式错误")
+	}
+	if version, err = strconv.Atoi(parts[1]); err != nil {
+		return "", 0, "", fmt.Errorf("字段密文版本无效: %s", parts[1])
+	}
+	return parts[0], version, parts[2], nil
+}
+
+// FieldCiphertextPrefix 返回指定算法和密钥版本的密文前缀，用于筛选待轮换数据
+func FieldCiphertextPrefix(algo string, version int) string {
+	return fmt.Sprintf("%s%s:%d:", fieldCipherPrefix, algo, version)
+}
+
+func (r *FieldKeyRing) aead(algo string, version int) (cipher.AEAD, error) {
+	key, ok := r.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("字段加密密钥版本 %d 不存在", version)
+	}
+	var block cipher.Block
+	var err error
+	switch algo {
+	case FieldCipherSM4:
+		block, err = sm4.NewCipher(key[:16])
+	case FieldCipherAES:
+		block, err = aes.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("不支持的字段加密算法: %s", algo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sm3Sum(data []byte) []byte {
+	h := sm3.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldKeyRing_EncryptDecrypt(t *testing.T) {
+	ring, err := NewFieldKeyRing("1:old-key", 1, "index-key")
+	require.NoError(t, err)
+
+	for _, algo := range []string{FieldCipherSM4, FieldCipherAES} {
+		cipherText, err := ring.Encrypt(algo, "13812345678")
+		require.NoError(t, err)
+		assert.True(t, IsFieldCiphertext(cipherText))
+		assert.Contains(t, cipherText, FieldCiphertextPrefix(algo, 1))
+
+		plain, version, err := ring.Decrypt(cipherText)
+		require.NoError(t, err)
+		assert.Equal(t, "13812345678", plain)
+		assert.Equal(t, 1, version)
+
+		// 随机 nonce，相同明文密文不同
+		again, _ := ring.Encrypt(algo, "13812345678")
+		assert.NotEqual(t, cipherText, again)
+	}
+
+	_, err = ring.Encrypt("des", "x")
+	assert.Error(t, err)
+}
+
+func TestFieldKeyRing_Rotation(t *testing.T) {
+	oldRing, err := NewFieldKeyRing("1:old-key", 1, "index-key")
+	require.NoError(t, err)
+	cipherText, err := oldRing.Encrypt(FieldCipherSM4, "secret")
+	require.NoError(t, err)
+
+	newRing, err := NewFieldKeyRing("1:old-key,2:new-key", 2, "index-key")
+	require.NoError(t, err)
+	plain, version, err := newRing.Decrypt(cipherText)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain)
+	assert.Equal(t, 1, version)
+
+	rotated, err := newRing.Encrypt(FieldCipherSM4, plain)
+	require.NoError(t, err)
+	assert.Contains(t, rotated, FieldCiphertextPrefix(FieldCipherSM4, 2))
+
+	// 盲索引不随数据密钥轮换变化
+	assert.Equal(t, oldRing.BlindIndex("secret"), newRing.BlindIndex("secret"))
+
+	// 旧密钥已移除时无法解密
+	retired, err := NewFieldKeyRing("2:new-key", 2, "index-key")
+	require.NoError(t, err)
+	_, _, err = retired.Decrypt(cipherText)
+	assert.Error(t, err)
+}
+
+func TestNewFieldKeyRing_Invalid(t *testing.T) {
+	_, err := NewFieldKeyRing("", 1, "index-key")
+	assert.Error(t, err)
+	_, err = NewFieldKeyRing("1:key", 2, "index-key")
+	assert.Error(t, err)
+	_, err = NewFieldKeyRing("x:key", 1, "index-key")
+	assert.Error(t, err)
+	_, err = NewFieldKeyRing("1:key", 1, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFileStoreDir 本地文件存储的默认目录
+const DefaultFileStoreDir = "data/files"
+
+// FileStore 文件存储接口，用于导出文件等需要保留一段时间的文件
+type FileStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+// LocalFileStore 本地磁盘文件存储
+type LocalFileStore struct {
+	dir string
+}
+
+// NewLocalFileStore 创建本地磁盘文件存储实例
+func NewLocalFileStore(dir string) *LocalFileStore {
+	return &LocalFileStore{dir: dir}
+}
+
+// path 返回文件路径，文件名不能包含目录
+func (s *LocalFileStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("文件名无效: %s", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// Create 创建文件，已存在时覆盖
+func (s *LocalFileStore) Create(name string) (io.WriteCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return os.Create(path)
+}
+
+// Open 打开文件
+func (s *LocalFileStore) Open(name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete 删除文件，文件不存在时忽略
+func (s *LocalFileStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+var (
+	fileStore   FileStore = NewLocalFileStore(DefaultFileStoreDir)
+	fileStoreMu sync.RWMutex
+)
+
+// InitFileStore 设置全局文件存储
+func InitFileStore(store FileStore) {
+	fileStoreMu.Lock()
+	fileStore = store
+	fileStoreMu.Unlock()
+}
+
+// GetFileStore 获取全局文件存储，未设置时为默认目录下的本地存储
+func GetFileStore() FileStore {
+	fileStoreMu.RLock()
+	defer fileStoreMu.RUnlock()
+	return fileStore
+}
+
+var (
+	urlSignKey   []byte
+	urlSignKeyMu sync.RWMutex
+)
+
+// InitURLSigner 设置下载链接的签名密钥
+func InitURLSigner(key string) {
+	urlSignKeyMu.Lock()
+	urlSignKey = []byte(key)
+	urlSignKeyMu.Unlock()
+}
+
+// URLSignerReady 是否已配置下载链接的签名密钥
+func URLSignerReady() bool {
+	urlSignKeyMu.RLock()
+	defer urlSignKeyMu.RUnlock()
+	return len(urlSignKey) > 0
+}
+
+// SignURL 计算路径在过期时间前有效的签名 (HMAC-SHA256)
+func SignURL(path string, expires time.Time) string {
+	urlSignKeyMu.RLock()
+	mac := hmac.New(sha256.New, urlSignKey)
+	urlSignKeyMu.RUnlock()
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyURL 校验路径的签名及过期时间，expires 为 Unix 秒
+func VerifyURL(path, expires, signature string) error {
+	if !URLSignerReady() {
+		return errors.New("未配置下载链接签名密钥")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("链接无效")
+	}
+	expireAt := time.Unix(unix, 0)
+	if !hmac.Equal([]byte(SignURL(path, expireAt)), []byte(signature)) {
+		return errors.New("链接签名无效")
+	}
+	if time.Now().After(expireAt) {
+		return errors.New("链接已过期")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileStore(t *testing.T) {
+	store := NewLocalFileStore(t.TempDir() + "/exports")
+
+	w, err := store.Create("a.csv")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("id\n1\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := store.Open("a.csv")
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "id\n1\n", string(content))
+
+	require.NoError(t, store.Delete("a.csv"))
+	require.NoError(t, store.Delete("a.csv"))
+	_, err = store.Open("a.csv")
+	assert.Error(t, err)
+
+	for _, name := range []string{"", "../a.csv", "sub/a.csv", ".hidden"} {
+		_, err := store.Create(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSignURL(t *testing.T) {
+	InitURLSigner("sign-key")
+	defer InitURLSigner("")
+
+	expires := time.Now().Add(time.Minute)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	signature := SignURL("/api/data/exports/1/download", expires)
+
+	assert.NoError(t, VerifyURL("/api/data/exports/1/download", unix, signature))
+	assert.Error(t, VerifyURL("/api/data/exports/2/download", unix, signature))
+	assert.Error(t, VerifyURL("/api/data/exports/1/download", unix, "00"))
+	assert.Error(t, VerifyURL("/api/data/exports/1/download", "x", signature))
+
+	past := time.Now().Add(-time.Minute)
+	assert.Error(t, VerifyURL("/api/data/exports/1/download", strconv.FormatInt(past.Unix(), 10), SignURL("/api/data/exports/1/download", past)))
+
+	// 未配置密钥时任何签名都无效
+	InitURLSigner("")
+	assert.False(t, URLSignerReady())
+	assert.Error(t, VerifyURL("/api/data/exports/1/download", unix, SignURL("/api/data/exports/1/download", expires)))
+}
@@ -0,0 +1,600 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ParquetType Parquet 列的逻辑类型，所有列均可为空 (OPTIONAL)
+type ParquetType int
+
+const (
+	ParquetString    ParquetType = iota // BYTE_ARRAY (UTF8)
+	ParquetBoolean                      // BOOLEAN
+	ParquetInt32                        // INT32
+	ParquetInt64                        // INT64
+	ParquetDouble                       // DOUBLE
+	ParquetDate                         // INT32 (DATE)，自 1970-01-01 起的天数
+	ParquetTimestamp                    // INT64 (TIMESTAMP_MILLIS)，UTC 毫秒
+)
+
+// Parquet 压缩算法
+const (
+	ParquetUncompressed = "uncompressed"
+	ParquetSnappy       = "snappy"
+	ParquetGzip         = "gzip"
+	ParquetZstd         = "zstd"
+)
+
+const (
+	DefaultParquetRowGroupSize = 100000  // 每个行组的默认行数
+	MaxParquetRowGroupSize     = 1000000 // 每个行组的最大行数
+	parquetRowGroupBytes       = 64 << 20
+	parquetPageBytes           = 1 << 20
+)
+
+var parquetMagic = []byte("PAR1")
+
+// ParquetColumn Parquet 列定义
+type ParquetColumn struct {
+	Name string
+	Type ParquetType
+}
+
+// ParquetOptions Parquet 写入选项
+type ParquetOptions struct {
+	Compression  string // 压缩算法，默认 snappy
+	RowGroupSize int    // 每个行组的行数，默认 DefaultParquetRowGroupSize；行组缓冲超过 64MB 时提前结束
+}
+
+// ParquetWriter 流式写入 Parquet 文件，仅缓冲当前行组，Close 时写入文件尾
+// 只实现导出所需的格式子集：扁平的 OPTIONAL 列 (无嵌套和重复)、v1 数据页、值按 PLAIN 编码、
+// 定义级别按 RLE/位打包混合编码，不写字典页、统计信息和页索引
+type ParquetWriter struct {
+	w            io.Writer
+	offset       int64
+	columns      []ParquetColumn
+	chunks       []*parquetChunk
+	codec        int32
+	compress     func([]byte) ([]byte, error)
+	closeCodec   func()
+	rowGroupSize int
+	rows         int
+	numRows      int64
+	rowGroups    []parquetRowGroup
+	values       []any
+}
+
+// parquetChunk 当前行组中一列的数据
+type parquetChunk struct {
+	defs         []bool // 当前页每行是否有值
+	values       []byte // 当前页的 PLAIN 编码值 (布尔列除外)
+	bools        []bool // 当前页的布尔值
+	pages        bytes.Buffer
+	numValues    int64
+	uncompressed int64
+}
+
+// parquetRowGroup 已写入的行组元数据
+type parquetRowGroup struct {
+	numRows int64
+	columns []parquetColumnChunk
+}
+
+type parquetColumnChunk struct {
+	offset       int64
+	numValues    int64
+	uncompressed int64
+	compressed   int64
+}
+
+// NewParquetWriter 创建 Parquet 写入器并写入文件头
+func NewParquetWriter(w io.Writer, columns []ParquetColumn, opts ParquetOptions) (*ParquetWriter, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("Parquet 文件至少需要一列")
+	}
+	p := &ParquetWriter{w: w, columns: columns, rowGroupSize: opts.RowGroupSize, closeCodec: func() {}}
+	if p.rowGroupSize <= 0 {
+		p.rowGroupSize = DefaultParquetRowGroupSize
+	}
+	if p.rowGroupSize > MaxParquetRowGroupSize {
+		return nil, fmt.Errorf("行组行数不能超过 %d", MaxParquetRowGroupSize)
+	}
+	if err := p.initCodec(strings.ToLower(opts.Compression)); err != nil {
+		return nil, err
+	}
+	p.chunks = make([]*parquetChunk, len(columns))
+	for i := range p.chunks {
+		p.chunks[i] = &parquetChunk{}
+	}
+	p.values = make([]any, len(columns))
+	if err := p.write(parquetMagic); err != nil {
+		p.closeCodec()
+		return nil, err
+	}
+	return p, nil
+}
+
+// initCodec 设置压缩算法，编号对应 Parquet 的 CompressionCodec
+func (p *ParquetWriter) initCodec(compression string) error {
+	switch compression {
+	case ParquetUncompressed:
+		p.codec = 0
+		p.compress = func(b []byte) ([]byte, error) { return b, nil }
+	case "", ParquetSnappy:
+		p.codec = 1
+		p.compress = func(b []byte) ([]byte, error) { return s2.EncodeSnappy(nil, b), nil }
+	case ParquetGzip:
+		p.codec = 2
+		p.compress = func(b []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(b); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+	case ParquetZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		p.codec = 6
+		p.compress = func(b []byte) ([]byte, error) { return enc.EncodeAll(b, nil), nil }
+		p.closeCodec = func() { enc.Close() }
+	default:
+		return fmt.Errorf("不支持的压缩算法: %s", compression)
+	}
+	return nil
+}
+
+// Write 写入一行，值按列顺序排列，nil 为空值
+func (p *ParquetWriter) Write(row []any) error {
+	if len(row) != len(p.columns) {
+		return fmt.Errorf("行的列数 %d 与 Parquet 列数 %d 不一致", len(row), len(p.columns))
+	}
+	// 1. 先转换整行，转换失败时不写入任何列
+	for i, c := range p.columns {
+		v, err := parquetValue(c.Type, row[i])
+		if err != nil {
+			return fmt.Errorf("列 %s 的值 %v 无法写入: %w", c.Name, row[i], err)
+		}
+		p.values[i] = v
+	}
+
+	// 2. 追加到各列的当前页，页或行组写满时结束
+	var buffered int
+	for i, chunk := range p.chunks {
+		chunk.append(p.values[i])
+		if len(chunk.values)+len(chunk.bools)/8 >= parquetPageBytes {
+			if err := p.flushPage(chunk); err != nil {
+				return err
+			}
+		}
+		buffered += chunk.pages.Len() + len(chunk.values)
+	}
+	p.rows++
+	if p.rows >= p.rowGroupSize || buffered >= parquetRowGroupBytes {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// Close 写入剩余的行组和文件尾，不关闭底层 writer
+func (p *ParquetWriter) Close() error {
+	defer p.closeCodec()
+	if p.rows > 0 {
+		if err := p.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+	footer := p.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return p.write(append(footer, parquetMagic...))
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// append 追加一个已转换的值
+func (c *parquetChunk) append(v any) {
+	c.defs = append(c.defs, v != nil)
+	switch val := v.(type) {
+	case bool:
+		c.bools = append(c.bools, val)
+	case int32:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(val))
+	case int64:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(val))
+	case float64:
+		c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(val))
+	case string:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(val)))
+		c.values = append(c.values, val...)
+	}
+}
+
+// flushPage 将当前页编码为数据页 (v1)：定义级别 + PLAIN 值，整体压缩
+func (p *ParquetWriter) flushPage(c *parquetChunk) error {
+	if len(c.defs) == 0 {
+		return nil
+	}
+	body := parquetLevels(c.defs)
+	if len(c.bools) > 0 {
+		body = append(body, packBits(c.bools)...)
+	} else {
+		body = append(body, c.values...)
+	}
+	compressed, err := p.compress(body)
+	if err != nil {
+		return fmt.Errorf("压缩数据页失败: %w", err)
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(len(body)))
+	t.i32(3, int32(len(compressed)))
+	t.begin(5)
+	t.i32(1, int32(len(c.defs)))
+	t.i32(2, 0) // PLAIN
+	t.i32(3, 3) // RLE
+	t.i32(4, 3)
+	t.end()
+	t.end()
+	header := t.bytes()
+
+	c.pages.Write(header)
+	c.pages.Write(compressed)
+	c.numValues += int64(len(c.defs))
+	c.uncompressed += int64(len(header) + len(body))
+	c.defs, c.values, c.bools = c.defs[:0], c.values[:0], c.bools[:0]
+	return nil
+}
+
+// flushRowGroup 依次写入各列的数据页并记录行组元数据
+func (p *ParquetWriter) flushRowGroup() error {
+	group := parquetRowGroup{numRows: int64(p.rows), columns: make([]parquetColumnChunk, len(p.chunks))}
+	for i, c := range p.chunks {
+		if err := p.flushPage(c); err != nil {
+			return err
+		}
+		group.columns[i] = parquetColumnChunk{
+			offset:       p.offset,
+			numValues:    c.numValues,
+			uncompressed: c.uncompressed,
+			compressed:   int64(c.pages.Len()),
+		}
+		if err := p.write(c.pages.Bytes()); err != nil {
+			return err
+		}
+		c.pages.Reset()
+		c.numValues, c.uncompressed = 0, 0
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.numRows += int64(p.rows)
+	p.rows = 0
+	return nil
+}
+
+// footer 编码文件元数据 FileMetaData
+func (p *ParquetWriter) footer() []byte {
+	t := newThriftWriter()
+	t.i32(1, 1)
+
+	// 1. schema：根节点 + 各列
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.beginElem()
+	t.str(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for _, c := range p.columns {
+		physical, converted := c.Type.physical()
+		t.beginElem()
+		t.i32(1, physical)
+		t.i32(3, 1) // OPTIONAL
+		t.str(4, c.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.end()
+	}
+	t.i64(3, p.numRows)
+
+	// 2. 行组及列元数据
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, g := range p.rowGroups {
+		t.beginElem()
+		t.list(1, thriftStruct, len(g.columns))
+		var total int64
+		for i, cc := range g.columns {
+			physical, _ := p.columns[i].Type.physical()
+			t.beginElem()
+			t.i64(2, cc.offset)
+			t.begin(3)
+			t.i32(1, physical)
+			t.list(2, thriftI32, 2)
+			t.rawI32(0) // PLAIN
+			t.rawI32(3) // RLE
+			t.list(3, thriftBinary, 1)
+			t.rawStr(p.columns[i].Name)
+			t.i32(4, p.codec)
+			t.i64(5, cc.numValues)
+			t.i64(6, cc.uncompressed)
+			t.i64(7, cc.compressed)
+			t.i64(9, cc.offset)
+			t.end()
+			t.end()
+			total += cc.uncompressed
+		}
+		t.i64(2, total)
+		t.i64(3, g.numRows)
+		t.end()
+	}
+	t.str(6, "metadata-platform")
+	t.end()
+	return t.bytes()
+}
+
+// physical 返回 Parquet 物理类型和转换类型 (无转换类型时为 -1)
+func (t ParquetType) physical() (int32, int32) {
+	switch t {
+	case ParquetBoolean:
+		return 0, -1
+	case ParquetInt32:
+		return 1, -1
+	case ParquetInt64:
+		return 2, -1
+	case ParquetDouble:
+		return 5, -1
+	case ParquetDate:
+		return 1, 6
+	case ParquetTimestamp:
+		return 2, 9
+	default:
+		return 6, 0
+	}
+}
+
+// parquetLevels 以 RLE/位打包混合编码定义级别 (位宽 1)，带 4 字节长度前缀
+func parquetLevels(defs []bool) []byte {
+	var encoded []byte
+	if !mixedBools(defs) {
+		encoded = binary.AppendUvarint(nil, uint64(len(defs))<<1)
+		if defs[0] {
+			encoded = append(encoded, 1)
+		} else {
+			encoded = append(encoded, 0)
+		}
+	} else {
+		encoded = binary.AppendUvarint(nil, uint64((len(defs)+7)/8)<<1|1)
+		encoded = append(encoded, packBits(defs)...)
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(encoded))), encoded...)
+}
+
+// mixedBools 是否同时包含 true 和 false
+func mixedBools(values []bool) bool {
+	for _, v := range values[1:] {
+		if v != values[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// packBits 按低位在前打包布尔值
+func packBits(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// parquetValue 将查询结果的值转换为列类型对应的 Go 值
+func parquetValue(typ ParquetType, v any) (any, error) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case ParquetBoolean:
+		switch val := v.(type) {
+		case bool:
+			return val, nil
+		case string:
+			return strconv.ParseBool(val)
+		}
+		n, err := parquetFloat(v)
+		return n != 0, err
+	case ParquetInt32:
+		n, err := parquetInt(v)
+		if err == nil && (n < math.MinInt32 || n > math.MaxInt32) {
+			err = errors.New("超出 INT32 范围")
+		}
+		return int32(n), err
+	case ParquetInt64:
+		return parquetInt(v)
+	case ParquetDouble:
+		return parquetFloat(v)
+	case ParquetDate:
+		t, err := parquetTime(v, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		y, m, d := t.Date()
+		return int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400), nil
+	case ParquetTimestamp:
+		t, err := parquetTime(v, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		return t.UnixMilli(), nil
+	default:
+		if t, ok := v.(time.Time); ok {
+			return t.Format(time.DateTime), nil
+		}
+		return ToString(v), nil
+	}
+}
+
+func parquetInt(v any) (int64, error) {
+	if s, ok := v.(string); ok {
+		if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	f, err := parquetFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, errors.New("不是整数")
+	}
+	return int64(f), nil
+}
+
+func parquetFloat(v any) (float64, error) {
+	if s, ok := v.(string); ok {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), nil
+	case rv.CanUint():
+		return float64(rv.Uint()), nil
+	case rv.CanFloat():
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("不支持的数值类型 %T", v)
+}
+
+// parquetTimeLayouts 字符串日期时间的解析格式，不带时区的按 loc 解析
+var parquetTimeLayouts = []string{time.RFC3339Nano, time.DateTime, "2006-01-02T15:04:05", time.DateOnly}
+
+func parquetTime(v any, loc *time.Location) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		for _, layout := range parquetTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(val), loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, errors.New("不是有效的日期时间")
+	}
+	return time.Time{}, fmt.Errorf("不支持的日期时间类型 %T", v)
+}
+
+// Thrift compact 协议的类型编号
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter Thrift compact 协议编码，仅支持 Parquet 元数据用到的类型
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // 每层结构体上一个字段的编号
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	top := len(t.last) - 1
+	if delta := id - t.last[top]; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.last[top] = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.rawI32(v)
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.rawStr(s)
+}
+
+func (t *thriftWriter) list(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xF0|elem)
+		t.buf = binary.AppendUvarint(t.buf, uint64(size))
+	}
+}
+
+func (t *thriftWriter) rawI32(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) rawStr(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// begin 开始结构体字段，beginElem 开始列表中的结构体元素，end 结束当前结构体
+func (t *thriftWriter) begin(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElem()
+}
+
+func (t *thriftWriter) beginElem() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) bytes() []byte {
+	return t.buf
+}
@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftReader 解码 Thrift compact 协议，结构体解码为字段编号到值的映射
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 4, thriftI32, thriftI64:
+		v, n := binary.Varint(r.buf[r.pos:])
+		r.pos += n
+		return v
+	case thriftBinary:
+		size := int(r.uvarint())
+		r.pos += size
+		return string(r.buf[r.pos-size : r.pos])
+	case thriftList:
+		header := r.buf[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0F)
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int16]any)
+		var last int16
+		for {
+			header := r.buf[r.pos]
+			r.pos++
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				last += delta
+			} else {
+				v, n := binary.Varint(r.buf[r.pos:])
+				r.pos += n
+				last = int16(v)
+			}
+			fields[last] = r.value(header & 0x0F)
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+func thriftStructAt(buf []byte) (map[int16]any, int) {
+	r := &thriftReader{buf: buf}
+	return r.value(thriftStruct).(map[int16]any), r.pos
+}
+
+// readParquetColumn 读取某列全部行组的数据页，返回定义级别和解压后的值区
+func readParquetColumn(t *testing.T, file []byte, meta map[int16]any, column int) ([]bool, []byte) {
+	codec := meta[4].([]any)[0].(map[int16]any)[1].([]any)[column].(map[int16]any)[3].(map[int16]any)[4].(int64)
+	var defs []bool
+	var values []byte
+	for _, g := range meta[4].([]any) {
+		chunk := g.(map[int16]any)[1].([]any)[column].(map[int16]any)[3].(map[int16]any)
+		pos, end := int(chunk[9].(int64)), int(chunk[9].(int64)+chunk[7].(int64))
+		for pos < end {
+			header, n := thriftStructAt(file[pos:])
+			pos += n
+			page := file[pos : pos+int(header[3].(int64))]
+			pos += len(page)
+
+			var body []byte
+			var err error
+			switch codec {
+			case 0:
+				body = page
+			case 1:
+				body, err = s2.Decode(nil, page)
+			case 2:
+				var zr *gzip.Reader
+				if zr, err = gzip.NewReader(bytes.NewReader(page)); err == nil {
+					body, err = io.ReadAll(zr)
+				}
+			case 6:
+				var dec *zstd.Decoder
+				if dec, err = zstd.NewReader(nil); err == nil {
+					body, err = dec.DecodeAll(page, nil)
+					dec.Close()
+				}
+			}
+			require.NoError(t, err)
+			require.Equal(t, int(header[2].(int64)), len(body))
+
+			// 定义级别：单个 RLE 段或位打包段
+			count := int(header[5].(map[int16]any)[1].(int64))
+			size := int(binary.LittleEndian.Uint32(body))
+			levels := body[4 : 4+size]
+			run, n := binary.Uvarint(levels)
+			for i := range count {
+				if run&1 == 0 {
+					defs = append(defs, levels[n] == 1)
+				} else {
+					defs = append(defs, levels[n+i/8]&(1<<(i%8)) != 0)
+				}
+			}
+			values = append(values, body[4+size:]...)
+		}
+	}
+	return defs, values
+}
+
+func TestParquetWriter(t *testing.T) {
+	columns := []ParquetColumn{
+		{Name: "id", Type: ParquetInt64},
+		{Name: "name", Type: ParquetString},
+		{Name: "score", Type: ParquetDouble},
+		{Name: "enabled", Type: ParquetBoolean},
+		{Name: "birthday", Type: ParquetDate},
+		{Name: "created_at", Type: ParquetTimestamp},
+		{Name: "age", Type: ParquetInt32},
+	}
+	created := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	rows := [][]any{
+		{int64(1), "张三", 90.5, true, "2000-01-02", created, 30},
+		{"2", []byte("李四"), nil, int64(0), nil, created.Format(time.RFC3339), "31"},
+		{3.0, nil, "77", "false", time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), nil, nil},
+		{int64(4), "", int64(60), nil, "1970-01-01 12:00:00", created.Add(time.Second), 33},
+		{int64(5), "王五", 88.25, true, "2024-02-29", created.Add(time.Hour), int64(34)},
+	}
+
+	for _, compression := range []string{ParquetUncompressed, ParquetSnappy, ParquetGzip, ParquetZstd} {
+		t.Run(compression, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewParquetWriter(&buf, columns, ParquetOptions{Compression: compression, RowGroupSize: 2})
+			require.NoError(t, err)
+			for _, row := range rows {
+				require.NoError(t, w.Write(row))
+			}
+			require.NoError(t, w.Close())
+
+			// 1. 文件头尾及元数据
+			file := buf.Bytes()
+			require.Equal(t, "PAR1", string(file[:4]))
+			require.Equal(t, "PAR1", string(file[len(file)-4:]))
+			size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+			meta, n := thriftStructAt(file[len(file)-8-size:])
+			require.Equal(t, size, n)
+			assert.EqualValues(t, 5, meta[3])
+			assert.Len(t, meta[4].([]any), 3, "5 行按每组 2 行分为 3 个行组")
+
+			schema := meta[2].([]any)
+			require.Len(t, schema, len(columns)+1)
+			assert.EqualValues(t, len(columns), schema[0].(map[int16]any)[5])
+			for i, c := range columns {
+				element := schema[i+1].(map[int16]any)
+				physical, converted := c.Type.physical()
+				assert.Equal(t, c.Name, element[4])
+				assert.EqualValues(t, physical, element[1])
+				if converted >= 0 {
+					assert.EqualValues(t, converted, element[6])
+				}
+			}
+
+			// 2. 各列的值
+			defs, values := readParquetColumn(t, file, meta, 0)
+			assert.Equal(t, []bool{true, true, true, true, true}, defs)
+			for i := range 5 {
+				assert.EqualValues(t, i+1, binary.LittleEndian.Uint64(values[i*8:]))
+			}
+
+			defs, values = readParquetColumn(t, file, meta, 1)
+			assert.Equal(t, []bool{true, true, false, true, true}, defs)
+			var names []string
+			for pos := 0; pos < len(values); {
+				l := int(binary.LittleEndian.Uint32(values[pos:]))
+				names = append(names, string(values[pos+4:pos+4+l]))
+				pos += 4 + l
+			}
+			assert.Equal(t, []string{"张三", "李四", "", "王五"}, names)
+
+			defs, values = readParquetColumn(t, file, meta, 2)
+			assert.Equal(t, []bool{true, false, true, true, true}, defs)
+			assert.Equal(t, 88.25, math.Float64frombits(binary.LittleEndian.Uint64(values[24:])))
+
+			defs, values = readParquetColumn(t, file, meta, 3)
+			assert.Equal(t, []bool{true, true, true, false, true}, defs)
+			assert.Equal(t, []byte{1, 0, 1}, values, "每个行组的布尔值单独按位打包")
+
+			_, values = readParquetColumn(t, file, meta, 4)
+			days := []int32{10958, -1, 0, 19782}
+			for i, d := range days {
+				assert.Equal(t, d, int32(binary.LittleEndian.Uint32(values[i*4:])))
+			}
+
+			defs, values = readParquetColumn(t, file, meta, 5)
+			assert.Equal(t, []bool{true, true, false, true, true}, defs)
+			assert.Equal(t, created.UnixMilli(), int64(binary.LittleEndian.Uint64(values)))
+			assert.Equal(t, created.UnixMilli(), int64(binary.LittleEndian.Uint64(values[8:])))
+		})
+	}
+}
+
+// TestParquetWriter_Golden 按 Parquet 格式规范和 Thrift compact 协议逐字节编码的最小文件，
+// 不依赖写入器和上面的测试解码器，用于校验两者对规范的理解一致
+func TestParquetWriter_Golden(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, []ParquetColumn{{Name: "id", Type: ParquetInt32}}, ParquetOptions{Compression: ParquetUncompressed})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]any{7}))
+	require.NoError(t, w.Write([]any{nil}))
+	require.NoError(t, w.Close())
+
+	var expected []byte
+	expected = append(expected, "PAR1"...)
+	// PageHeader{type: DATA_PAGE, uncompressed_page_size: 10, compressed_page_size: 10,
+	//   data_page_header: {num_values: 2, encoding: PLAIN, definition_level_encoding: RLE, repetition_level_encoding: RLE}}
+	expected = append(expected, 0x15, 0x00, 0x15, 0x14, 0x15, 0x14, 0x2c, 0x15, 0x04, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00)
+	// 定义级别：4 字节长度 + 位打包 1 组 (0b01)，值：PLAIN INT32 7
+	expected = append(expected, 0x02, 0x00, 0x00, 0x00, 0x03, 0x01, 0x07, 0x00, 0x00, 0x00)
+	footer := []byte{0x15, 0x02} // version: 1
+	// schema: [{name: "schema", num_children: 1}, {type: INT32, repetition_type: OPTIONAL, name: "id"}]
+	footer = append(footer, 0x19, 0x2c, 0x48, 0x06)
+	footer = append(footer, "schema"...)
+	footer = append(footer, 0x15, 0x02, 0x00, 0x15, 0x02, 0x25, 0x02, 0x18, 0x02, 'i', 'd', 0x00)
+	footer = append(footer, 0x16, 0x04) // num_rows: 2
+	// row_groups: [{columns: [{file_offset: 4, meta_data: {type: INT32, encodings: [PLAIN, RLE], path_in_schema: ["id"],
+	//   codec: UNCOMPRESSED, num_values: 2, total_uncompressed_size: 27, total_compressed_size: 27, data_page_offset: 4}}],
+	//   total_byte_size: 27, num_rows: 2}]
+	footer = append(footer, 0x19, 0x1c, 0x19, 0x1c, 0x26, 0x08, 0x1c)
+	footer = append(footer, 0x15, 0x02, 0x19, 0x25, 0x00, 0x06, 0x19, 0x18, 0x02, 'i', 'd')
+	footer = append(footer, 0x15, 0x00, 0x16, 0x04, 0x16, 0x36, 0x16, 0x36, 0x26, 0x08, 0x00, 0x00)
+	footer = append(footer, 0x16, 0x36, 0x16, 0x04, 0x00)
+	// created_by
+	footer = append(footer, 0x28, 0x11)
+	footer = append(footer, "metadata-platform"...)
+	footer = append(footer, 0x00)
+
+	expected = append(expected, footer...)
+	expected = binary.LittleEndian.AppendUint32(expected, uint32(len(footer)))
+	expected = append(expected, "PAR1"...)
+	assert.Equal(t, expected, buf.Bytes())
+}
+
+func TestParquetWriter_Errors(t *testing.T) {
+	_, err := NewParquetWriter(io.Discard, nil, ParquetOptions{})
+	assert.Error(t, err)
+	_, err = NewParquetWriter(io.Discard, []ParquetColumn{{Name: "id"}}, ParquetOptions{Compression: "lz4"})
+	assert.Error(t, err)
+	_, err = NewParquetWriter(io.Discard, []ParquetColumn{{Name: "id"}}, ParquetOptions{RowGroupSize: MaxParquetRowGroupSize + 1})
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewParquetWriter(&buf, []ParquetColumn{{Name: "id", Type: ParquetInt32}, {Name: "name"}}, ParquetOptions{})
+	require.NoError(t, err)
+	assert.Error(t, w.Write([]any{1}))
+	assert.Error(t, w.Write([]any{"abc", "x"}))
+	assert.Error(t, w.Write([]any{int64(math.MaxInt32) + 1, "x"}))
+	assert.Error(t, w.Write([]any{1.5, "x"}))
+	require.NoError(t, w.Write([]any{1, "x"}))
+	require.NoError(t, w.Close())
+
+	file := buf.Bytes()
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	meta, _ := thriftStructAt(file[len(file)-8-size:])
+	assert.EqualValues(t, 1, meta[3], "转换失败的行不写入")
+}
@@ -91,6 +91,15 @@ func NotFoundResponse(c *app.RequestContext, message string) {
 	})
 }
 
+// ConflictResponse 返回409冲突响应，data 可携带冲突时的当前数据
+func ConflictResponse(c *app.RequestContext, message string, data any) {
+	c.JSON(consts.StatusConflict, Response{
+		Code:    409,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // InternalServerErrorResponse 返回500错误响应
 func InternalServerErrorResponse(c *app.RequestContext, message string) {
 	c.JSON(consts.StatusInternalServerError, Response{
@@ -2,21 +2,24 @@ export const DATA_RANGE = {
   ALL: '1',
   CUSTOM: '2',
   DEPARTMENT: '3',
-  DEPT_AND_BELOW: '4'
+  DEPT_AND_BELOW: '4',
+  SELF: '5'
 } as const
 
 export const DATA_RANGE_OPTIONS = [
   { value: DATA_RANGE.ALL, label: '全部数据权限' },
   { value: DATA_RANGE.CUSTOM, label: '自定数据权限' },
   { value: DATA_RANGE.DEPARTMENT, label: '本部门数据权限' },
-  { value: DATA_RANGE.DEPT_AND_BELOW, label: '本部门及以下' }
+  { value: DATA_RANGE.DEPT_AND_BELOW, label: '本部门及以下' },
+  { value: DATA_RANGE.SELF, label: '仅本人数据权限' }
 ]
 
 export const DATA_RANGE_LABELS: Record<string, string> = {
   [DATA_RANGE.ALL]: '全部',
   [DATA_RANGE.CUSTOM]: '自定义',
   [DATA_RANGE.DEPARTMENT]: '本部门',
-  [DATA_RANGE.DEPT_AND_BELOW]: '本部门及以下'
+  [DATA_RANGE.DEPT_AND_BELOW]: '本部门及以下',
+  [DATA_RANGE.SELF]: '仅本人'
 }
 
 export const STATUS = {